@@ -3,8 +3,17 @@ package main
 import (
 	"clothesproject/internal/clothesstore"
 	"clothesproject/internal/config"
+	"clothesproject/internal/currency"
 	"clothesproject/internal/handlers"
+	"clothesproject/internal/invoices"
+	"clothesproject/internal/middleware"
+	"clothesproject/internal/orders"
+	"clothesproject/internal/payments"
+	"clothesproject/internal/promotions"
+	"clothesproject/internal/returns"
+	"clothesproject/internal/search"
 	"context"
+	"crypto/rand"
 	"log"
 	"time"
 
@@ -34,7 +43,50 @@ func main() {
 	defer db.Close() // ย้าย deferred close ให้อยู่ในที่ที่ไม่ขึ้นกับ nil check
 
 	cs := db // db จะเป็น *PostgresDatabase ซึ่ง implements ProductStore
-	h := handlers.NewClothesHandlers(cs)
+	searchIndex := search.NewIndex(db)
+	db.SetSearcher(searchIndex)
+	// อัตราแลกเปลี่ยนสำหรับแสดงราคา ใช้ไฟล์ถ้าตั้ง EXCHANGE_RATES_FILE ไว้ ไม่เช่นนั้นใช้ตาราง exchange_rates
+	var rates currency.Source = currency.NewPostgresSource(db.DB)
+	if cfg.ExchangeRatesFile != "" {
+		if rates, err = currency.NewFileSource(cfg.ExchangeRatesFile); err != nil {
+			log.Fatalf("Failed to load exchange rates: %v", err)
+		}
+	}
+	h := handlers.NewClothesHandlers(cs, rates)
+	orderStore := orders.NewPostgresOrderStore(db)
+	oh := handlers.NewOrderHandlers(orderStore, rates)
+	ch := handlers.NewCurrencyHandlers(rates)
+	ih := handlers.NewInvoiceHandlers(invoices.NewPostgresStore(db, orderStore))
+
+	// ผู้ให้บริการชำระเงิน เปิดเฉพาะวิธีที่ตั้งค่าไว้
+	paymentStore := payments.NewPostgresStore(db)
+	if cfg.PromptPayID != "" {
+		if cfg.PromptPaySecret == "" {
+			log.Fatalf("PAYMENTS_PROMPTPAY_SECRET is required to verify PromptPay callbacks")
+		}
+		promptPay, err := payments.NewPromptPayProvider(cfg.PromptPayID, []byte(cfg.PromptPaySecret))
+		if err != nil {
+			log.Fatalf("Failed to configure PromptPay: %v", err)
+		}
+		paymentStore.Register(promptPay)
+	}
+	if cfg.FakeCardPayments {
+		// บัตรจำลองเซ็นและตรวจ callback เอง ถ้าไม่ตั้ง secret จะสุ่มใหม่ทุกครั้งที่เริ่มโปรแกรม
+		secret := []byte(cfg.FakeCardSecret)
+		if len(secret) == 0 {
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				log.Fatalf("Failed to generate fake card secret: %v", err)
+			}
+		}
+		paymentStore.Register(payments.NewFakeCardProvider(secret, paymentStore.HandleCallback))
+		log.Printf("Fake card payments are enabled; do not use in production")
+	}
+	payh := handlers.NewPaymentHandlers(paymentStore)
+	idempotencyStore := middleware.NewPostgresIdempotencyStore(db.DB)
+	sessionStore := middleware.NewPostgresSessionStore(db.DB)
+	rh := handlers.NewReturnHandlers(returns.NewPostgresStore(db), cfg.UploadDir)
+	ph := handlers.NewPromotionHandlers(promotions.NewPostgresStore(db.DB))
 	go func() {
 		for {
 			time.Sleep(10 * time.Second)
@@ -49,6 +101,66 @@ func main() {
 		}
 	}()
 
+	// สร้าง search index ตอนเริ่ม สินค้าที่เพิ่ม/แก้ไข/ลบผ่าน store จะอัพเดต index ทันที
+	// การสร้างใหม่ทุก 15 นาทีเป็นเพียงตัวกันพลาด (ข้อมูลที่แก้นอก store และความนิยมของคำแนะนำ)
+	// ระหว่างที่ยังสร้างครั้งแรกไม่เสร็จ การค้นหาจะใช้ ILIKE แทน
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			_, err := searchIndex.Rebuild(ctx)
+			cancel()
+			if err != nil {
+				log.Printf("Failed to rebuild search index: %v", err)
+			}
+			time.Sleep(15 * time.Minute)
+		}
+	}()
+
+	// sweeper ปล่อยสต็อกที่จองไว้ในตะกร้าเมื่อการจองหมดอายุ
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			released, err := db.ReleaseExpiredReservations(ctx)
+			cancel()
+			if err != nil {
+				log.Printf("Failed to release expired reservations: %v", err)
+			} else if released > 0 {
+				log.Printf("Released %d expired stock reservations", released)
+			}
+		}
+	}()
+
+	// sweeper ปล่อยสต็อกของสาขาเมื่อคำสั่งซื้อแบบรับที่สาขาเลยเวลารับแล้วยังไม่มารับ
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			expired, err := orderStore.ExpirePickups(ctx)
+			cancel()
+			if err != nil {
+				log.Printf("Failed to expire pickup orders: %v", err)
+			} else if expired > 0 {
+				log.Printf("Expired %d uncollected pickup orders", expired)
+			}
+		}
+	}()
+
+	// sweeper ลบ Idempotency-Key ที่หมดอายุแล้ว
+	go func() {
+		for {
+			time.Sleep(time.Hour)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			deleted, err := idempotencyStore.DeleteExpired(ctx)
+			cancel()
+			if err != nil {
+				log.Printf("Failed to delete expired idempotency keys: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Deleted %d expired idempotency keys", deleted)
+			}
+		}
+	}()
+
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 
@@ -56,56 +168,22 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"}, // ระบุ Origin ที่อนุญาต
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Authorization", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Authorization", "Content-Type", middleware.GuestTokenHeader, handlers.AcceptCurrencyHeader, middleware.IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"Content-Length", middleware.GuestTokenHeader, middleware.IdempotentReplayedHeader, "Content-Disposition"},
 		AllowCredentials: true,
 	}))
 
 	r.Use(TimeoutMiddleware(5 * time.Second))
 
-	r.GET("/health", h.HealthCheck)
-
-	// API v1
-	v1 := r.Group("/api/v1")
-	{
-		v1.GET("/products/:id", h.GetProduct)
-		v1.POST("/products", h.AddProduct)
-		v1.DELETE("/products/:id", h.DeleteProduct)
-		v1.PUT("/products/:id", h.UpdateProduct)
-
-		// เพิ่ม API สำหรับดูข้อมูลสินค้าทั้งหมด
-		v1.GET("/products", h.GetAllProducts)
-
-		// เพิ่ม API สำหรับดูข้อมูลสินค้าตามหมวดหมู่
-		v1.GET("/products/category/:category", h.GetProductByCategory)
-
-		// เพิ่ม API สำหรับ About Page
-		v1.GET("/about/:brand_id", h.GetAboutPage)
-
-		v1.GET("/brand", h.GetAllBrands)
-		v1.GET("/brand/:brandID", h.GetBrandByID)
-		v1.POST("/brand", h.AddBrand)
-		v1.DELETE("/brand/:brandID", h.DeleteBrand)
-		v1.PUT("/brand/:brandID", h.UpdateBrand)
-
-		v1.GET("/products/brand/:brandID", h.GetProductsByBrand)
-
-		v1.GET("/products/search", h.SearchProducts)
-
-		// API สำหรับดึงข้อมูลสาขาทั้งหมด
-		v1.GET("/branches", h.GetAllBranches)
-
-		// API สำหรับดึงข้อมูลสาขาตามแบรนด์
-		v1.GET("/branches/brand/:brand_id", h.GetBranchesByBrand)
-
-		// API สำหรับดึงข้อมูลสาขาตามแบรนด์และจังหวัด
-		v1.GET("/branches/brand/:brand_id/province/:province", h.GetBranchesByBrandAndProvince)
-
-		// Cart APIs
-		v1.GET("/cart", h.GetAllCart)
-		v1.POST("/cart", h.AddProductToCart)
-		v1.DELETE("/cart/:cartID", h.DeleteProductFromCart)
-	}
+	registerRoutes(r, &cfg, sessionStore, idempotencyStore, routeHandlers{
+		clothes:    h,
+		orders:     oh,
+		currencies: ch,
+		invoices:   ih,
+		payments:   payh,
+		returns:    rh,
+		promotions: ph,
+	})
 
 	if err := r.Run(":" + cfg.AppPort); err != nil {
 		log.Printf("Failed to run server: %v", err)
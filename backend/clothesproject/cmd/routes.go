@@ -0,0 +1,165 @@
+package main
+
+import (
+	"time"
+
+	"clothesproject/internal/config"
+	"clothesproject/internal/handlers"
+	"clothesproject/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeHandlers คือ handler ของแต่ละกลุ่ม API ที่ registerRoutes ผูกกับ route
+type routeHandlers struct {
+	clothes    *handlers.ClothesHandlers
+	orders     *handlers.OrderHandlers
+	currencies *handlers.CurrencyHandlers
+	invoices   *handlers.InvoiceHandlers
+	payments   *handlers.PaymentHandlers
+	returns    *handlers.ReturnHandlers
+	promotions *handlers.PromotionHandlers
+}
+
+// registerRoutes ผูก route ทั้งหมดของ API กับ r พร้อม middleware ตรวจ JWT และสิทธิ์ของแต่ละ route
+func registerRoutes(r *gin.Engine, cfg *config.Config, sessions middleware.SessionChecker, idempotency middleware.IdempotencyStore, hs routeHandlers) {
+	r.GET("/health", hs.clothes.HealthCheck)
+
+	// route ที่แก้ไขข้อมูลต้องล็อกอินและ role ใน JWT ต้องมีสิทธิ์ตาม permission matrix (middleware.rolePermissions)
+	auth := middleware.AuthMiddleware(cfg, sessions)
+	can := middleware.RequirePermission
+	// POST ที่ส่ง Idempotency-Key มาจะทำงานครั้งเดียว request ซ้ำภายใน 24 ชั่วโมงได้ response เดิม
+	// วางหลังการตรวจ JWT และสิทธิ์ เพื่อให้คีย์ผูกกับผู้เรียกที่ตรวจแล้วและไม่เก็บผลของ request ที่ถูกปฏิเสธ
+	once := middleware.Idempotency(idempotency, 24*time.Hour)
+
+	// รูปสินค้าที่ขอคืนไม่เปิดเป็นไฟล์สาธารณะ ดูได้เฉพาะเจ้าของคำขอและพนักงานที่จัดการคำขอคืนสินค้า
+	r.GET("/uploads/"+handlers.ReturnPhotosPath+"/:name", auth, hs.returns.GetReturnPhoto)
+
+	// API v1
+	v1 := r.Group("/api/v1")
+	{
+		v1.GET("/products/:id", hs.clothes.GetProduct)
+		v1.POST("/products", auth, can(middleware.PermWriteProducts), once, hs.clothes.AddProduct)
+		v1.DELETE("/products/:id", auth, can(middleware.PermWriteProducts), hs.clothes.DeleteProduct)
+		v1.PUT("/products/:id", auth, can(middleware.PermWriteProducts), hs.clothes.UpdateProduct)
+
+		// API สำหรับ SKU (ไซซ์/สี) ของสินค้า
+		v1.GET("/products/:id/variants", hs.clothes.GetProductVariants)
+		v1.POST("/products/:id/variants", auth, can(middleware.PermWriteProducts), once, hs.clothes.AddVariant)
+		v1.PUT("/variants/:variantID", auth, can(middleware.PermWriteProducts), hs.clothes.UpdateVariant)
+		v1.DELETE("/variants/:variantID", auth, can(middleware.PermWriteProducts), hs.clothes.DeleteVariant)
+		v1.GET("/variants/:variantID/stock", hs.clothes.GetStock)
+		v1.PUT("/variants/:variantID/stock", auth, can(middleware.PermWriteStock), hs.clothes.SetStock)
+
+		// API สำหรับดูว่าสาขาไหนมีสินค้าในสต็อก (in stock near you)
+		v1.GET("/products/:id/availability", hs.clothes.GetProductAvailability)
+
+		// เพิ่ม API สำหรับดูข้อมูลสินค้าทั้งหมด
+		v1.GET("/products", hs.clothes.GetAllProducts)
+
+		// เพิ่ม API สำหรับดูข้อมูลสินค้าตามหมวดหมู่
+		v1.GET("/products/category/:category", hs.clothes.GetProductByCategory)
+
+		// เพิ่ม API สำหรับ About Page
+		v1.GET("/about/:brand_id", hs.clothes.GetAboutPage)
+		v1.PUT("/about/:brand_id", auth, can(middleware.PermWriteAboutPages), hs.clothes.SetAboutPage)
+		v1.DELETE("/about/:brand_id", auth, can(middleware.PermWriteAboutPages), hs.clothes.DeleteAboutPage)
+
+		v1.GET("/brand", hs.clothes.GetAllBrands)
+		v1.GET("/brand/:brandID", hs.clothes.GetBrandByID)
+		v1.POST("/brand", auth, can(middleware.PermWriteBrands), once, hs.clothes.AddBrand)
+		v1.DELETE("/brand/:brandID", auth, can(middleware.PermWriteBrands), hs.clothes.DeleteBrand)
+		v1.PUT("/brand/:brandID", auth, can(middleware.PermWriteBrands), hs.clothes.UpdateBrand)
+
+		v1.GET("/products/brand/:brandID", hs.clothes.GetProductsByBrand)
+
+		v1.GET("/products/search", hs.clothes.SearchProducts)
+
+		// API สำหรับเติมคำค้นอัตโนมัติขณะพิมพ์ (ชื่อสินค้าและแบรนด์ ทนต่อการพิมพ์ผิด)
+		v1.GET("/products/suggest", hs.clothes.SuggestProducts)
+
+		// API สำหรับดูสกุลเงินที่แสดงราคาได้และอัตราแลกเปลี่ยนปัจจุบัน
+		v1.GET("/currencies", hs.currencies.ListRates)
+
+		// API สำหรับดึงข้อมูลสาขาทั้งหมด
+		v1.GET("/branches", hs.clothes.GetAllBranches)
+
+		// API สำหรับดึงข้อมูลสาขาตามแบรนด์
+		v1.GET("/branches/brand/:brand_id", hs.clothes.GetBranchesByBrand)
+
+		// API สำหรับดึงข้อมูลสาขาตามแบรนด์และจังหวัด
+		v1.GET("/branches/brand/:brand_id/province/:province", hs.clothes.GetBranchesByBrandAndProvince)
+
+		// API สำหรับกำหนดสต็อกของ SKU ที่สาขา
+		v1.PUT("/branches/:branch_id/stock/:variantID", auth, can(middleware.PermWriteStock), hs.clothes.SetBranchStock)
+
+		// เพิ่ม แก้ไข และลบสาขา (ผู้ขายจัดการได้เฉพาะสาขาของแบรนด์ที่ตัวเองดูแล)
+		v1.POST("/branches", auth, can(middleware.PermWriteBranches), once, hs.clothes.AddBranch)
+		v1.PUT("/branches/:branch_id", auth, can(middleware.PermWriteBranches), hs.clothes.UpdateBranch)
+		v1.DELETE("/branches/:branch_id", auth, can(middleware.PermWriteBranches), hs.clothes.DeleteBranch)
+
+		// แบรนด์ที่ผู้ใช้ปัจจุบันดูแล
+		v1.GET("/users/me/brands", auth, hs.clothes.GetMyBrands)
+
+		// Cart APIs (ผูกกับ user_id ใน JWT หรือ guest token ถ้ายังไม่ล็อกอิน)
+		cart := v1.Group("/cart", middleware.CartOwnerMiddleware(cfg, sessions))
+		{
+			cart.GET("", hs.clothes.GetAllCart)
+			cart.POST("", once, hs.clothes.AddProductToCart)
+			cart.DELETE("/:cartID", hs.clothes.DeleteProductFromCart)
+		}
+
+		// Order APIs (ต้องล็อกอิน)
+		orderRoutes := v1.Group("/orders", auth)
+		{
+			orderRoutes.POST("", once, hs.orders.CreateOrder)
+			orderRoutes.GET("", hs.orders.GetMyOrders)
+			orderRoutes.GET("/:id", hs.orders.GetOrder)
+			orderRoutes.POST("/:id/payments", once, hs.payments.CreatePayment)
+			orderRoutes.GET("/:id/payments", hs.payments.GetPayments)
+			orderRoutes.GET("/:id/invoice.pdf", hs.invoices.GetInvoicePDF)
+			orderRoutes.POST("/:id/returns", once, hs.returns.CreateReturn)
+		}
+
+		// คำขอคืนสินค้าของผู้ใช้ (ต้องล็อกอิน)
+		v1.GET("/returns", auth, hs.returns.GetMyReturns)
+
+		// Callback จากผู้ให้บริการชำระเงิน (ตรวจลายเซ็นแทนการล็อกอิน)
+		v1.POST("/payments/callback/:method", hs.payments.PaymentCallback)
+
+		// Admin APIs สำหรับพนักงาน แต่ละกลุ่มใช้ permission ของตัวเอง
+		admin := v1.Group("/admin", auth)
+		{
+			adminOrders := admin.Group("/orders", can(middleware.PermManageOrders))
+			adminOrders.POST("/:id/status", once, hs.orders.UpdateOrderStatus)
+			adminOrders.GET("/:id/history", hs.orders.GetOrderHistory)
+			adminOrders.POST("/:id/collect", once, hs.orders.CollectOrder)
+
+			// จัดการโปรโมชันและคูปอง
+			promotionRoutes := admin.Group("/promotions", can(middleware.PermManagePromotions))
+			promotionRoutes.GET("", hs.promotions.ListPromotions)
+			promotionRoutes.GET("/:id", hs.promotions.GetPromotion)
+			promotionRoutes.POST("", once, hs.promotions.CreatePromotion)
+			promotionRoutes.PUT("/:id", hs.promotions.UpdatePromotion)
+
+			// จัดการอัตราแลกเปลี่ยน (บาทต่อเงิน 1 หน่วย)
+			rateRoutes := admin.Group("/exchange-rates", can(middleware.PermManageExchangeRates))
+			rateRoutes.GET("/:currency", hs.currencies.GetRateHistory)
+			rateRoutes.PUT("/:currency", hs.currencies.SetRate)
+
+			// จัดการคำขอคืนสินค้า: อนุมัติ/ปฏิเสธ → รับสินค้า → คืนเงินและคืนสต็อก
+			returnRoutes := admin.Group("/returns", can(middleware.PermManageReturns))
+			returnRoutes.GET("", hs.returns.ListReturns)
+			returnRoutes.POST("/:id/approve", once, hs.returns.ApproveReturn)
+			returnRoutes.POST("/:id/reject", once, hs.returns.RejectReturn)
+			returnRoutes.POST("/:id/receive", once, hs.returns.ReceiveReturn)
+			returnRoutes.POST("/:id/refund", once, hs.returns.RefundReturn)
+
+			// กำหนดแบรนด์ที่ผู้ขายแต่ละคนดูแล
+			sellerRoutes := admin.Group("/sellers", can(middleware.PermManageSellers))
+			sellerRoutes.GET("/:user_id/brands", hs.clothes.GetSellerBrands)
+			sellerRoutes.POST("/:user_id/brands", once, hs.clothes.LinkSellerBrand)
+			sellerRoutes.DELETE("/:user_id/brands/:brand_id", hs.clothes.UnlinkSellerBrand)
+		}
+	}
+}
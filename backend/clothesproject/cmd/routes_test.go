@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"clothesproject/internal/clothesstore"
+	"clothesproject/internal/config"
+	"clothesproject/internal/handlers"
+	"clothesproject/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+// fakeCatalog บันทึกการเรียก store ที่ route ในตารางใช้ เมธอดอื่นของ ProductStore ไม่ถูกเรียกใน test นี้
+type fakeCatalog struct {
+	clothesstore.ProductStore
+	calls []string
+}
+
+func (s *fakeCatalog) record(format string, args ...interface{}) {
+	s.calls = append(s.calls, fmt.Sprintf(format, args...))
+}
+
+func (s *fakeCatalog) GetBrandByID(ctx context.Context, brandID string) (clothesstore.Brands, error) {
+	s.record("GetBrandByID %s", brandID)
+	return clothesstore.Brands{BrandID: brandID}, nil
+}
+
+func (s *fakeCatalog) AddBrand(ctx context.Context, brand clothesstore.Brands) error {
+	s.record("AddBrand %s", brand.Brandname)
+	return nil
+}
+
+func (s *fakeCatalog) UpdateBrand(ctx context.Context, brand clothesstore.Brands) error {
+	s.record("UpdateBrand %s %s", brand.BrandID, brand.Brandname)
+	return nil
+}
+
+func (s *fakeCatalog) DeleteBrand(ctx context.Context, brandID string) error {
+	s.record("DeleteBrand %s", brandID)
+	return nil
+}
+
+func (s *fakeCatalog) DeleteProduct(ctx context.Context, actor clothesstore.Actor, id int) error {
+	s.record("DeleteProduct %d", id)
+	return nil
+}
+
+func (s *fakeCatalog) DeleteBranch(ctx context.Context, actor clothesstore.Actor, branchID int) error {
+	s.record("DeleteBranch %d", branchID)
+	return nil
+}
+
+func (s *fakeCatalog) DeleteAboutPage(ctx context.Context, actor clothesstore.Actor, brandID int) error {
+	s.record("DeleteAboutPage %d", brandID)
+	return nil
+}
+
+func (s *fakeCatalog) GetSellerBrands(ctx context.Context, userID string) ([]int, error) {
+	s.record("GetSellerBrands %s", userID)
+	return []int{}, nil
+}
+
+// activeSessions ถือว่าทุก session ยังใช้งานได้
+type activeSessions struct{}
+
+func (activeSessions) SessionActive(ctx context.Context, sessionID, tokenID string) (bool, error) {
+	return true, nil
+}
+
+func testToken(t *testing.T, role string) string {
+	t.Helper()
+	claims := middleware.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        "3f1c2b9e-8d47-4a55-9e0b-6c1d2f3a4b5c",
+			Subject:   "7b0e8c3a-1f2d-4e5b-8a9c-0d1e2f3a4b5c",
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		},
+		Role:      role,
+		SessionID: "c4d5e6f7-0a1b-4c2d-9e3f-4a5b6c7d8e9f",
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// TestPermissionMatrix ส่ง request จริงผ่าน route ที่ registerRoutes ผูกไว้ ด้วย token ของแต่ละ role
+// role ที่ได้รับอนุญาตต้องไปถึง store ด้วย ID จาก URL ส่วน role อื่นได้ 403 และไม่ได้รับ token ได้ 401
+func TestPermissionMatrix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWTSecret: testJWTSecret}
+	seller := "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+
+	tests := []struct {
+		method, path, body string
+		allowed            []string
+		status             int
+		call               string
+	}{
+		{method: http.MethodGet, path: "/api/v1/brand/7", allowed: []string{"", middleware.RoleCustomer, middleware.RoleSeller, middleware.RoleAdmin}, status: http.StatusOK, call: "GetBrandByID 7"},
+		{method: http.MethodPost, path: "/api/v1/brand", body: `{"brandname":"Acme"}`, allowed: []string{middleware.RoleAdmin}, status: http.StatusCreated, call: "AddBrand Acme"},
+		{method: http.MethodPut, path: "/api/v1/brand/7", body: `{"brandname":"Acme"}`, allowed: []string{middleware.RoleAdmin}, status: http.StatusOK, call: "UpdateBrand 7 Acme"},
+		{method: http.MethodDelete, path: "/api/v1/brand/7", allowed: []string{middleware.RoleAdmin}, status: http.StatusOK, call: "DeleteBrand 7"},
+		{method: http.MethodDelete, path: "/api/v1/products/3", allowed: []string{middleware.RoleSeller, middleware.RoleAdmin}, status: http.StatusOK, call: "DeleteProduct 3"},
+		{method: http.MethodDelete, path: "/api/v1/branches/4", allowed: []string{middleware.RoleSeller, middleware.RoleAdmin}, status: http.StatusOK, call: "DeleteBranch 4"},
+		{method: http.MethodDelete, path: "/api/v1/about/7", allowed: []string{middleware.RoleSeller, middleware.RoleAdmin}, status: http.StatusOK, call: "DeleteAboutPage 7"},
+		{method: http.MethodGet, path: "/api/v1/admin/sellers/" + seller + "/brands", allowed: []string{middleware.RoleAdmin}, status: http.StatusOK, call: "GetSellerBrands " + seller},
+	}
+	for _, tt := range tests {
+		for _, role := range []string{"", middleware.RoleCustomer, middleware.RoleSeller, middleware.RoleAdmin} {
+			store := &fakeCatalog{}
+			r := gin.New()
+			registerRoutes(r, cfg, activeSessions{}, nil, routeHandlers{clothes: handlers.NewClothesHandlers(store, nil)})
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if role != "" {
+				req.Header.Set("Authorization", "Bearer "+testToken(t, role))
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			wantStatus, wantCalls := http.StatusForbidden, []string(nil)
+			if role == "" {
+				wantStatus = http.StatusUnauthorized
+			}
+			for _, allowed := range tt.allowed {
+				if allowed == role {
+					wantStatus, wantCalls = tt.status, []string{tt.call}
+				}
+			}
+			if w.Code != wantStatus {
+				t.Errorf("%s %s as %q = %d, want %d (%s)", tt.method, tt.path, role, w.Code, wantStatus, w.Body.String())
+			}
+			if !reflect.DeepEqual(store.calls, wantCalls) {
+				t.Errorf("%s %s as %q called %v, want %v", tt.method, tt.path, role, store.calls, wantCalls)
+			}
+		}
+	}
+}
@@ -4,6 +4,8 @@ go 1.22.4
 
 require (
 	github.com/gin-gonic/gin v1.10.0
+	github.com/go-pdf/fpdf v0.9.0
+	github.com/golang-jwt/jwt v3.2.2+incompatible
 	github.com/lib/pq v1.10.9
 	github.com/spf13/viper v1.19.0
 	github.com/gin-contrib/cors v1.7.2 // CORS middleware ที่คุณใช้งานอยู่
@@ -0,0 +1,158 @@
+package clothesstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BranchAvailability คือสาขาที่มีสินค้าพร้อมขาย พร้อมไซซ์/สีที่มีสต็อกที่สาขานั้น
+type BranchAvailability struct {
+	Branch
+	Sizes []BranchStock `json:"sizes"`
+}
+
+// BranchStock คือจำนวนสต็อกของ SKU หนึ่งที่สาขาหนึ่ง
+type BranchStock struct {
+	VariantID int    `json:"variant_id"`
+	SKU       string `json:"sku"`
+	Size      string `json:"size"`
+	Colour    string `json:"colour"`
+	Quantity  int    `json:"quantity"`
+}
+
+// GetProductAvailability ดึงสาขาของแบรนด์สินค้าที่มี SKU ของสินค้านี้อยู่ในสต็อก
+// (ไม่นับสต็อกที่ถูกกันไว้ให้คำสั่งซื้อแบบรับที่สาขา) ถ้าระบุ province จะกรองเฉพาะสาขาในจังหวัดนั้น
+func (pdb *PostgresDatabase) GetProductAvailability(ctx context.Context, productID int, province string) ([]BranchAvailability, error) {
+	rows, err := pdb.db.QueryContext(ctx, `
+        SELECT b.id, b.brand_id, b.province, b.banch, b.banch_location,
+               v.variant_id, v.sku, v.size, v.colour, bi.quantity - COALESCE(h.held, 0)
+        FROM branch_inventory bi
+        JOIN branch b ON b.id = bi.branch_id
+        JOIN product_variants v ON v.variant_id = bi.variant_id
+        LEFT JOIN (
+            SELECT branch_id, variant_id, SUM(quantity) AS held
+            FROM branch_stock_holds
+            WHERE status = 'held'
+            GROUP BY branch_id, variant_id
+        ) h ON h.branch_id = bi.branch_id AND h.variant_id = bi.variant_id
+        WHERE v.product_id = $1
+          AND bi.quantity - COALESCE(h.held, 0) > 0
+          AND ($2 = '' OR b.province = $2)
+        ORDER BY b.province, b.id, v.variant_id
+    `, productID, province)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch product availability: %v", err)
+	}
+	defer rows.Close()
+
+	var availability []BranchAvailability
+	for rows.Next() {
+		var branch Branch
+		var stock BranchStock
+		if err := rows.Scan(&branch.ID, &branch.BrandID, &branch.Province, &branch.Banch, &branch.BanchLocation,
+			&stock.VariantID, &stock.SKU, &stock.Size, &stock.Colour, &stock.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan availability row: %v", err)
+		}
+
+		// แถวถูกเรียงตามสาขาแล้ว จึงรวม SKU เข้ากับสาขาล่าสุดได้เลย
+		if n := len(availability); n > 0 && availability[n-1].ID == branch.ID {
+			availability[n-1].Sizes = append(availability[n-1].Sizes, stock)
+			continue
+		}
+		availability = append(availability, BranchAvailability{Branch: branch, Sizes: []BranchStock{stock}})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return availability, nil
+}
+
+// SetBranchStock กำหนดจำนวนสต็อกของ SKU ที่สาขา actor ต้องดูแลทั้งแบรนด์ของสาขาและแบรนด์ของ SKU
+func (pdb *PostgresDatabase) SetBranchStock(ctx context.Context, actor Actor, branchID int, variantID int, quantity int) error {
+	if quantity < 0 {
+		return errors.New("stock cannot be negative")
+	}
+	if _, err := authorizeBranch(ctx, pdb.db, actor, branchID, false); err != nil {
+		return err
+	}
+	if err := authorizeVariant(ctx, pdb.db, actor, variantID); err != nil {
+		return err
+	}
+	_, err := pdb.db.ExecContext(ctx, `
+        INSERT INTO branch_inventory (branch_id, variant_id, quantity)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (branch_id, variant_id) DO UPDATE
+        SET quantity = EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP
+    `, branchID, variantID, quantity)
+	if err != nil {
+		return fmt.Errorf("failed to set branch stock: %v", err)
+	}
+	return nil
+}
+
+// HoldBranchStock กันสต็อกของ SKU ที่สาขาไว้ให้คำสั่งซื้อแบบรับที่สาขา (click-and-collect)
+// ภายใน transaction ของคำสั่งซื้อ สต็อกที่ถูกกันไว้จะไม่นับเป็นสต็อกที่พร้อมขายจนกว่าจะรับสินค้าหรือหมดเวลา
+func HoldBranchStock(ctx context.Context, tx *sql.Tx, orderID int, branchID int, variantID int, quantity int, expiresAt time.Time) error {
+	var onHand int
+	err := tx.QueryRowContext(ctx, "SELECT quantity FROM branch_inventory WHERE branch_id = $1 AND variant_id = $2 FOR UPDATE", branchID, variantID).Scan(&onHand)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to lock branch stock: %v", err)
+	}
+
+	var held int
+	err = tx.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(quantity), 0)
+        FROM branch_stock_holds
+        WHERE branch_id = $1 AND variant_id = $2 AND status = 'held'
+    `, branchID, variantID).Scan(&held)
+	if err != nil {
+		return fmt.Errorf("failed to sum branch holds: %v", err)
+	}
+
+	available := max(onHand-held, 0)
+	if quantity > available {
+		return &OutOfStockError{VariantID: variantID, Requested: quantity, Available: available}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO branch_stock_holds (order_id, branch_id, variant_id, quantity, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `, orderID, branchID, variantID, quantity, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to hold branch stock: %v", err)
+	}
+	return nil
+}
+
+// CollectBranchHolds ตัดสต็อกของสาขาตามที่กันไว้ให้คำสั่งซื้อ เมื่อลูกค้ามารับสินค้าแล้ว
+func CollectBranchHolds(ctx context.Context, tx *sql.Tx, orderID int) error {
+	_, err := tx.ExecContext(ctx, `
+        UPDATE branch_inventory bi
+        SET quantity = bi.quantity - h.quantity, updated_at = CURRENT_TIMESTAMP
+        FROM branch_stock_holds h
+        WHERE h.order_id = $1 AND h.status = 'held'
+          AND bi.branch_id = h.branch_id AND bi.variant_id = h.variant_id
+    `, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to deduct branch stock: %v", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE branch_stock_holds SET status = 'collected' WHERE order_id = $1 AND status = 'held'", orderID)
+	if err != nil {
+		return fmt.Errorf("failed to mark branch holds collected: %v", err)
+	}
+	return nil
+}
+
+// ReleaseBranchHolds ปล่อยสต็อกของสาขาที่กันไว้ให้คำสั่งซื้อ (หมดเวลารับหรือยกเลิก)
+func ReleaseBranchHolds(ctx context.Context, tx *sql.Tx, orderID int) error {
+	_, err := tx.ExecContext(ctx, "UPDATE branch_stock_holds SET status = 'released' WHERE order_id = $1 AND status = 'held'", orderID)
+	if err != nil {
+		return fmt.Errorf("failed to release branch holds: %v", err)
+	}
+	return nil
+}
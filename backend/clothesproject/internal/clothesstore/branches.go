@@ -0,0 +1,163 @@
+package clothesstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrBranchNotFound คืนค่าเมื่อไม่พบสาขาที่ระบุ
+	ErrBranchNotFound = errors.New("branch not found")
+	// ErrBranchInUse คืนค่าเมื่อลบสาขาที่เป็นสาขารับสินค้าของคำสั่งซื้อแล้ว
+	ErrBranchInUse = errors.New("branch is used by orders and cannot be deleted")
+	// ErrAboutPageNotFound คืนค่าเมื่อแบรนด์ยังไม่มีหน้า about
+	ErrAboutPageNotFound = errors.New("about page not found")
+)
+
+// requireBrand ตรวจสอบว่ามีแบรนด์ brandID อยู่จริง
+func requireBrand(ctx context.Context, q querier, brandID int) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM brand WHERE id = $1)", brandID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check brand: %v", err)
+	}
+	if !exists {
+		return ErrBrandNotFound
+	}
+	return nil
+}
+
+// authorizeBranch ตรวจสอบว่า actor แก้ไขสาขาได้ คืนแบรนด์ของสาขา
+// สาขาที่ไม่ได้ระบุแบรนด์แก้ไขได้เฉพาะผู้ดูแลระบบ lock เป็น true จะล็อกแถวสาขาไว้ (ต้องใช้ภายใน transaction)
+func authorizeBranch(ctx context.Context, q querier, actor Actor, branchID int, lock bool) (sql.NullInt64, error) {
+	query := "SELECT brand_id FROM branch WHERE id = $1"
+	if lock {
+		query += " FOR UPDATE"
+	}
+	var brandID sql.NullInt64
+	if err := q.QueryRowContext(ctx, query, branchID).Scan(&brandID); err != nil {
+		if err == sql.ErrNoRows {
+			return brandID, ErrBranchNotFound
+		}
+		return brandID, fmt.Errorf("failed to get branch brand: %v", err)
+	}
+	if !brandID.Valid {
+		if actor.Admin {
+			return brandID, nil
+		}
+		return brandID, ErrForbidden
+	}
+	return brandID, authorizeBrand(ctx, q, actor, int(brandID.Int64))
+}
+
+// AddBranch เพิ่มสาขาของแบรนด์ actor ต้องดูแลแบรนด์ของสาขา
+func (pdb *PostgresDatabase) AddBranch(ctx context.Context, actor Actor, branch Branch) (Branch, error) {
+	if err := authorizeBrand(ctx, pdb.db, actor, branch.BrandID); err != nil {
+		return Branch{}, err
+	}
+	if err := requireBrand(ctx, pdb.db, branch.BrandID); err != nil {
+		return Branch{}, err
+	}
+	err := pdb.db.QueryRowContext(ctx, "INSERT INTO branch (brand_id, province, banch, banch_location) VALUES ($1, $2, $3, $4) RETURNING id",
+		branch.BrandID, branch.Province, branch.Banch, branch.BanchLocation).Scan(&branch.ID)
+	if err != nil {
+		return Branch{}, fmt.Errorf("failed to add branch: %v", err)
+	}
+	return branch, nil
+}
+
+// UpdateBranch อัพเดตข้อมูลสาขา actor ต้องดูแลทั้งแบรนด์เดิมของสาขาและแบรนด์ใหม่
+func (pdb *PostgresDatabase) UpdateBranch(ctx context.Context, actor Actor, branch Branch) error {
+	tx, err := pdb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	current, err := authorizeBranch(ctx, tx, actor, branch.ID, true)
+	if err != nil {
+		return err
+	}
+	if !current.Valid || int(current.Int64) != branch.BrandID {
+		if err := authorizeBrand(ctx, tx, actor, branch.BrandID); err != nil {
+			return err
+		}
+		if err := requireBrand(ctx, tx, branch.BrandID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE branch SET brand_id = $1, province = $2, banch = $3, banch_location = $4 WHERE id = $5",
+		branch.BrandID, branch.Province, branch.Banch, branch.BanchLocation, branch.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update branch: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// DeleteBranch ลบสาขาพร้อมสต็อกของสาขา สาขาที่เคยเป็นสาขารับสินค้าของคำสั่งซื้อลบไม่ได้
+func (pdb *PostgresDatabase) DeleteBranch(ctx context.Context, actor Actor, branchID int) error {
+	tx, err := pdb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := authorizeBranch(ctx, tx, actor, branchID, true); err != nil {
+		return err
+	}
+	var inUse bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM orders WHERE pickup_branch_id = $1)", branchID).Scan(&inUse); err != nil {
+		return fmt.Errorf("failed to check branch orders: %v", err)
+	}
+	if inUse {
+		return ErrBranchInUse
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM branch WHERE id = $1", branchID); err != nil {
+		return fmt.Errorf("failed to delete branch: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// SetAboutPage สร้างหรือแทนที่หน้า about ของแบรนด์ (แบรนด์หนึ่งมีได้หน้าเดียว) actor ต้องดูแลแบรนด์
+func (pdb *PostgresDatabase) SetAboutPage(ctx context.Context, actor Actor, about AboutPage) (AboutPage, error) {
+	if err := authorizeBrand(ctx, pdb.db, actor, about.Brand_id); err != nil {
+		return AboutPage{}, err
+	}
+	if err := requireBrand(ctx, pdb.db, about.Brand_id); err != nil {
+		return AboutPage{}, err
+	}
+	err := pdb.db.QueryRowContext(ctx, `
+        INSERT INTO about_page (brand_id, img, title, description)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (brand_id) DO UPDATE
+        SET img = EXCLUDED.img, title = EXCLUDED.title, description = EXCLUDED.description
+        RETURNING id
+    `, about.Brand_id, about.Img, about.Title, about.Description).Scan(&about.ID)
+	if err != nil {
+		return AboutPage{}, fmt.Errorf("failed to set about page: %v", err)
+	}
+	return about, nil
+}
+
+// DeleteAboutPage ลบหน้า about ของแบรนด์ actor ต้องดูแลแบรนด์
+func (pdb *PostgresDatabase) DeleteAboutPage(ctx context.Context, actor Actor, brandID int) error {
+	if err := authorizeBrand(ctx, pdb.db, actor, brandID); err != nil {
+		return err
+	}
+	result, err := pdb.db.ExecContext(ctx, "DELETE FROM about_page WHERE brand_id = $1", brandID)
+	if err != nil {
+		return fmt.Errorf("failed to delete about page: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrAboutPageNotFound
+	}
+	return nil
+}
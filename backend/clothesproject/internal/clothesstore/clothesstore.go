@@ -7,47 +7,81 @@ import (
 	"fmt"
 	"time"
 
+	"clothesproject/internal/currency"
+	"clothesproject/internal/money"
+	"clothesproject/internal/pricing"
+	"clothesproject/internal/promotions"
+
 	_ "github.com/lib/pq"
 )
 
+// ErrCartItemNotFound คืนค่าเมื่อไม่พบรายการในตะกร้าของเจ้าของที่ระบุ
+var ErrCartItemNotFound = errors.New("cart item not found")
+
+// DefaultWeightGrams คือน้ำหนักต่อชิ้นของสินค้าที่ไม่ได้ระบุน้ำหนัก ใช้คำนวณค่าส่ง
+const DefaultWeightGrams = 500
+
 type ProductStore interface {
 	GetProducts(ctx context.Context, id int) (Clothes, error)
 	GetProduct(ctx context.Context, id int) (Clothes, error)
-	AddProduct(ctx context.Context, product Clothes) error
-	DeleteProduct(ctx context.Context, id int) error
-	GetAllProducts(ctx context.Context) ([]Clothes, error)
-	UpdateProduct(ctx context.Context, product Clothes) error
-	GetProductsByCategory(ctx context.Context, category string) ([]Clothes, error)
+	AddProduct(ctx context.Context, actor Actor, product Clothes) error
+	DeleteProduct(ctx context.Context, actor Actor, id int) error
+	UpdateProduct(ctx context.Context, actor Actor, product Clothes) error
+	ListProducts(ctx context.Context, query ProductQuery) (ProductPage, error)
+	SuggestProducts(ctx context.Context, text string, limit int) (Suggestions, error)
 	GetAboutPageByBrandID(ctx context.Context, brand_id int) (AboutPage, error)
+	SetAboutPage(ctx context.Context, actor Actor, about AboutPage) (AboutPage, error)
+	DeleteAboutPage(ctx context.Context, actor Actor, brandID int) error
 	GetAllBranches(ctx context.Context) ([]Branch, error)
 	GetBranchesByBrand(ctx context.Context, brandID int) ([]Branch, error)
 	GetBranchesByBrandAndProvince(ctx context.Context, brandID int, province string) ([]Branch, error)
+	AddBranch(ctx context.Context, actor Actor, branch Branch) (Branch, error)
+	UpdateBranch(ctx context.Context, actor Actor, branch Branch) error
+	DeleteBranch(ctx context.Context, actor Actor, branchID int) error
 	GetBrandByID(ctx context.Context, brandID string) (Brands, error)
 	GetAllBrands(ctx context.Context) ([]Brands, error)
-	GetProductsByBrand(ctx context.Context, brandID string) ([]Clothes, error)
-	SearchProducts(ctx context.Context, searchQuery string) ([]Clothes, error)
 	AddBrand(ctx context.Context, brand Brands) error
 	DeleteBrand(ctx context.Context, brandID string) error
 	UpdateBrand(ctx context.Context, brand Brands) error
-	GetAllCart(ctx context.Context) ([]CartItem, error)
-	AddProductToCart(ctx context.Context, productID int, quantity int) error
-	DeleteProductFromCart(ctx context.Context, cartID int) error
+	GetSellerBrands(ctx context.Context, userID string) ([]int, error)
+	LinkSellerBrand(ctx context.Context, userID string, brandID int) error
+	UnlinkSellerBrand(ctx context.Context, userID string, brandID int) error
+	GetAllCart(ctx context.Context, owner CartOwner, opts CartOptions) (Cart, error)
+	AddProductToCart(ctx context.Context, owner CartOwner, variantID int, quantity int) error
+	DeleteProductFromCart(ctx context.Context, owner CartOwner, cartID int) error
+	GetVariantsByProduct(ctx context.Context, productID int) ([]Variant, error)
+	GetVariant(ctx context.Context, variantID int) (Variant, error)
+	AddVariant(ctx context.Context, actor Actor, variant Variant) (Variant, error)
+	UpdateVariant(ctx context.Context, actor Actor, variant Variant) error
+	DeleteVariant(ctx context.Context, actor Actor, variantID int) error
+	GetStock(ctx context.Context, variantID int) (StockLevel, error)
+	SetStock(ctx context.Context, actor Actor, variantID int, onHand int) error
+	ReleaseExpiredReservations(ctx context.Context) (int64, error)
+	GetProductAvailability(ctx context.Context, productID int, province string) ([]BranchAvailability, error)
+	SetBranchStock(ctx context.Context, actor Actor, branchID int, variantID int, quantity int) error
 	Close() error
 	Ping() error
 	Reconnect(connStr string) error
 }
 
 type Clothes struct {
-	ID          int       `json:"id"`
-	Category    string    `json:"category"`
-	ImgSrc      string    `json:"imgsrc"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	BrandID     int       `json:"brand"`
-	Price       float64   `json:"price"`
-	IsNew       bool      `json:"isnew"`
-	Createdate  time.Time `json:"createdate"`
-	Updatedate  time.Time `json:"updatedate"`
+	ID          int         `json:"id"`
+	Category    string      `json:"category"`
+	ImgSrc      string      `json:"imgsrc"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	BrandID     int         `json:"brand"`
+	Price       money.Money `json:"price"`
+	WeightGrams int         `json:"weight_grams"`
+	IsNew       bool        `json:"isnew"`
+	Createdate  time.Time   `json:"createdate"`
+	Updatedate  time.Time   `json:"updatedate"`
+	Variants    []Variant   `json:"variants,omitempty"`
+	// DisplayPrice คือราคาในสกุลเงินที่ผู้ใช้เลือก (ไม่มีเมื่อเป็นบาท)
+	DisplayPrice *currency.Price `json:"display_price,omitempty"`
+	// Score และ Highlights มีค่าเฉพาะในผลการค้นหา
+	Score      float64     `json:"score,omitempty"`
+	Highlights []Highlight `json:"highlights,omitempty"`
 }
 
 type AboutPage struct {
@@ -66,25 +100,88 @@ type Branch struct {
 	BanchLocation string `json:"banch_location"`
 }
 
+// Brands คือแบรนด์ VATInclusive บอกว่าราคาสินค้าของแบรนด์รวม VAT แล้วหรือไม่ (ไม่ระบุตอนเพิ่มถือว่ารวม ตอนแก้ไขคือไม่เปลี่ยน)
+// FreeShippingThreshold คือยอดซื้อขั้นต่ำที่ส่งฟรี (nil คือไม่มีส่งฟรี ไม่ระบุตอนแก้ไขคือไม่เปลี่ยน)
 type Brands struct {
-	BrandID   string `json:"id"`
-	Brandname string `json:"brandname"`
-	Brandlogo string `json:"brandlogo"`
-}
-
+	BrandID               string       `json:"id"`
+	Brandname             string       `json:"brandname"`
+	Brandlogo             string       `json:"brandlogo"`
+	VATInclusive          *bool        `json:"vat_inclusive,omitempty"`
+	FreeShippingThreshold *money.Money `json:"free_shipping_threshold"`
+	// ReturnWindowDays คือจำนวนวันหลังได้รับสินค้าที่ยังขอคืนได้ (0 คือไม่รับคืน ไม่ระบุตอนเพิ่มคือ 14 วัน)
+	ReturnWindowDays *int `json:"return_window_days,omitempty" binding:"omitempty,min=0"`
+	// TaxID และ Address คือข้อมูลผู้ขายที่พิมพ์บนใบกำกับภาษีของแบรนด์ (ส่งค่าว่างตอนแก้ไขจะคงค่าเดิม)
+	TaxID   string `json:"tax_id,omitempty" binding:"omitempty,len=13,numeric"`
+	Address string `json:"address,omitempty"`
+}
+
+// CartItem คือสินค้าหนึ่ง SKU ในตะกร้า Price คือราคารวมของรายการ (ราคาต่อชิ้น x จำนวน) ก่อนหักส่วนลด
+// Discount คือส่วนลดระดับรายการรวม แยกตามโปรโมชันใน Discounts
 type CartItem struct {
-	CartID        int       `json:"cart_id"`
-	ProductID     int       `json:"product_id"`
-	ProductName   string    `json:"name"`
-	ProductImgSrc string    `json:"imgsrc"`
-	Quantity      int       `json:"quantity"`
-	Price         float64   `json:"price"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	CartID        int                  `json:"cart_id"`
+	ProductID     int                  `json:"product_id"`
+	VariantID     int                  `json:"variant_id"`
+	SKU           string               `json:"sku"`
+	Size          string               `json:"size"`
+	Colour        string               `json:"colour"`
+	ProductName   string               `json:"name"`
+	ProductImgSrc string               `json:"imgsrc"`
+	BrandID       int                  `json:"brand_id"`
+	Category      string               `json:"category"`
+	Quantity      int                  `json:"quantity"`
+	UnitPrice     money.Money          `json:"unit_price"`
+	WeightGrams   int                  `json:"weight_grams"`
+	Price         money.Money          `json:"price"`
+	Discount      money.Money          `json:"discount"`
+	Discounts     []promotions.Applied `json:"discounts,omitempty"`
+	// DisplayUnitPrice และ DisplayPrice คือราคาในสกุลเงินที่ผู้ใช้เลือก (ไม่มีเมื่อเป็นบาท)
+	DisplayUnitPrice *currency.Price `json:"display_unit_price,omitempty"`
+	DisplayPrice     *currency.Price `json:"display_price,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// Cart คือตะกร้าพร้อมส่วนลด VAT ค่าส่งและยอดรวม (pricing.Totals) ส่วนลดระดับรายการอยู่ในแต่ละ CartItem
+// ส่วนลดระดับตะกร้าอยู่ใน CartDiscounts CouponError บอกเหตุผลเมื่อกรอกคูปองแต่ใช้ไม่ได้
+type Cart struct {
+	Items []CartItem `json:"items"`
+	pricing.Totals
+	LineDiscount  money.Money          `json:"line_discount"`
+	CartDiscounts []promotions.Applied `json:"cart_discounts"`
+	Coupon        string               `json:"coupon,omitempty"`
+	CouponError   string               `json:"coupon_error,omitempty"`
+	// Display คือยอดรวมในสกุลเงินที่ผู้ใช้เลือก (ไม่มีเมื่อเป็นบาท)
+	Display *currency.Totals `json:"display,omitempty"`
+}
+
+// CartOptions คือตัวเลือกตอนคำนวณตะกร้า Coupon คือคูปองที่จะใช้ (ว่างคือไม่ใช้)
+// Province คือจังหวัดปลายทางสำหรับประมาณค่าส่ง (ว่างคือใช้พื้นที่จัดส่งเริ่มต้น)
+type CartOptions struct {
+	Coupon   string
+	Province string
+}
+
+// CartOwner ระบุเจ้าของตะกร้า: ผู้ใช้ที่ล็อกอินแล้ว (UserID) หรือผู้เยี่ยมชมที่ยังไม่ล็อกอิน (GuestToken)
+type CartOwner struct {
+	UserID     string
+	GuestToken string
+}
+
+// filter คืนเงื่อนไข WHERE (ตาม placeholder ที่กำหนด) และค่าที่ใช้กรองตะกร้าของเจ้าของนี้
+func (o CartOwner) filter(placeholder string) (string, string, error) {
+	switch {
+	case o.UserID != "":
+		return "user_id = " + placeholder, o.UserID, nil
+	case o.GuestToken != "":
+		return "guest_token = " + placeholder, o.GuestToken, nil
+	default:
+		return "", "", errors.New("cart owner is required")
+	}
 }
 
 type PostgresDatabase struct {
-	db *sql.DB
+	db       *sql.DB
+	searcher Searcher
 }
 
 func NewPostgresDatabase(connStr string) (*PostgresDatabase, error) {
@@ -115,72 +212,105 @@ func (pdb *PostgresDatabase) GetProducts(ctx context.Context, id int) (Clothes,
 // GetProduct ดึงข้อมูลสินค้าจากฐานข้อมูลตาม ID
 func (pdb *PostgresDatabase) GetProduct(ctx context.Context, id int) (Clothes, error) {
 	var product Clothes
-	err := pdb.db.QueryRowContext(ctx, "SELECT id, category, imgsrc, name, description, brand, price, isnew, createdate, updatedate FROM products WHERE id = $1", id).Scan(
-		&product.ID, &product.Category, &product.ImgSrc, &product.Name, &product.Description, &product.BrandID, &product.Price, &product.IsNew, &product.Createdate, &product.Updatedate)
+	err := pdb.db.QueryRowContext(ctx, "SELECT id, category, imgsrc, name, description, brand, price, weight_grams, isnew, createdate, updatedate FROM products WHERE id = $1", id).Scan(
+		&product.ID, &product.Category, &product.ImgSrc, &product.Name, &product.Description, &product.BrandID, &product.Price, &product.WeightGrams, &product.IsNew, &product.Createdate, &product.Updatedate)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return Clothes{}, errors.New("product not found")
+			return Clothes{}, ErrProductNotFound
 		}
 		return Clothes{}, fmt.Errorf("failed to get product: %v", err)
 	}
-	return product, nil
-}
 
-// GetProductByCategory ดึงสินค้าจากฐานข้อมูลตามประเภท
-func (pdb *PostgresDatabase) GetProductsByCategory(ctx context.Context, category string) ([]Clothes, error) {
-	query := "SELECT id, category, imgsrc, name, description, brand, price, isnew FROM products WHERE category = $1"
-	rows, err := pdb.db.QueryContext(ctx, query, category)
+	product.Variants, err = pdb.GetVariantsByProduct(ctx, id)
 	if err != nil {
-		return nil, err
+		return Clothes{}, err
 	}
-	defer rows.Close()
-
-	var products []Clothes
+	return product, nil
+}
 
-	for rows.Next() {
-		var product Clothes
-		if err := rows.Scan(&product.ID, &product.Category, &product.ImgSrc, &product.Name, &product.Description, &product.BrandID, &product.Price, &product.IsNew); err != nil {
-			return nil, err
-		}
-		products = append(products, product)
+// AddProduct เพิ่มข้อมูลสินค้าใหม่ลงในฐานข้อมูล actor ต้องดูแลแบรนด์ของสินค้า
+func (pdb *PostgresDatabase) AddProduct(ctx context.Context, actor Actor, product Clothes) error {
+	if product.WeightGrams <= 0 {
+		product.WeightGrams = DefaultWeightGrams
 	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
+	if err := authorizeBrand(ctx, pdb.db, actor, product.BrandID); err != nil {
+		return err
 	}
-
-	return products, nil
-}
-
-// AddProduct เพิ่มข้อมูลสินค้าใหม่ลงในฐานข้อมูล
-func (pdb *PostgresDatabase) AddProduct(ctx context.Context, product Clothes) error {
-	_, err := pdb.db.ExecContext(ctx, "INSERT INTO products (category, imgsrc, name, description, brand, price, isnew) VALUES ($1, $2, $3, $4, $5, $6, $7)",
-		product.Category, product.ImgSrc, product.Name, product.Description, product.BrandID, product.Price, product.IsNew)
+	err := pdb.db.QueryRowContext(ctx, "INSERT INTO products (category, imgsrc, name, description, brand, price, weight_grams, isnew) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+		product.Category, product.ImgSrc, product.Name, product.Description, product.BrandID, product.Price, product.WeightGrams, product.IsNew).Scan(&product.ID)
 	if err != nil {
 		return fmt.Errorf("failed to add product: %v", err)
 	}
+	pdb.indexProduct(product)
 	return nil
 }
 
-// DeleteProduct ลบข้อมูลสินค้าจากฐานข้อมูล
-func (pdb *PostgresDatabase) DeleteProduct(ctx context.Context, id int) error {
-	_, err := pdb.db.ExecContext(ctx, "DELETE FROM products WHERE id = $1", id)
+// DeleteProduct ลบข้อมูลสินค้าจากฐานข้อมูล actor ต้องดูแลแบรนด์ของสินค้า
+func (pdb *PostgresDatabase) DeleteProduct(ctx context.Context, actor Actor, id int) error {
+	tx, err := pdb.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	brandID, err := productBrand(ctx, tx, id, true)
+	if err != nil {
+		return err
+	}
+	if err := authorizeBrand(ctx, tx, actor, brandID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM products WHERE id = $1", id); err != nil {
 		return fmt.Errorf("failed to delete product: %v", err)
 	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	pdb.unindexProduct(id)
 	return nil
 }
 
 // UpdateProduct อัพเดตข้อมูลสินค้าที่มีอยู่ในฐานข้อมูล
-func (pdb *PostgresDatabase) UpdateProduct(ctx context.Context, product Clothes) error {
-	_, err := pdb.db.ExecContext(ctx, "UPDATE products SET category = $1, imgsrc = $2, name = $3, description = $4, brand = $5, price = $6, isnew = $7 WHERE id = $8",
-		product.Category, product.ImgSrc, product.Name, product.Description, product.BrandID, product.Price, product.IsNew, product.ID)
+// actor ต้องดูแลทั้งแบรนด์เดิมของสินค้าและแบรนด์ใหม่ (ย้ายสินค้าไปแบรนด์ที่ไม่ได้ดูแลไม่ได้)
+func (pdb *PostgresDatabase) UpdateProduct(ctx context.Context, actor Actor, product Clothes) error {
+	tx, err := pdb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	brandID, err := productBrand(ctx, tx, product.ID, true)
+	if err != nil {
+		return err
+	}
+	if err := authorizeBrand(ctx, tx, actor, brandID); err != nil {
+		return err
+	}
+	if product.BrandID != brandID {
+		if err := authorizeBrand(ctx, tx, actor, product.BrandID); err != nil {
+			return err
+		}
+	}
+
+	// weight_grams เป็น 0 คือไม่เปลี่ยนน้ำหนัก
+	_, err = tx.ExecContext(ctx, "UPDATE products SET category = $1, imgsrc = $2, name = $3, description = $4, brand = $5, price = $6, isnew = $7, weight_grams = COALESCE(NULLIF($9, 0), weight_grams) WHERE id = $8",
+		product.Category, product.ImgSrc, product.Name, product.Description, product.BrandID, product.Price, product.IsNew, product.ID, product.WeightGrams)
 	if err != nil {
 		return fmt.Errorf("failed to update product: %v", err)
 	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	pdb.indexProduct(product)
 	return nil
 }
 
+// DB คืน connection pool ปัจจุบันให้ store อื่นที่ใช้ฐานข้อมูลเดียวกัน (เช่น orders)
+// ควรเรียกทุกครั้งที่ใช้งานเพราะ Reconnect จะเปลี่ยน pool ใหม่
+func (pdb *PostgresDatabase) DB() *sql.DB {
+	return pdb.db
+}
+
 func (pdb *PostgresDatabase) Close() error {
 	return pdb.db.Close()
 }
@@ -199,39 +329,14 @@ func (pdb *PostgresDatabase) Reconnect(connStr string) error {
 	return pdb.Ping()
 }
 
-func (pdb *PostgresDatabase) GetAllProducts(ctx context.Context) ([]Clothes, error) {
-	query := "SELECT id, category, imgsrc, name, description, brand, price, isnew FROM products"
-	rows, err := pdb.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var products []Clothes
-
-	for rows.Next() {
-		var product Clothes
-		if err := rows.Scan(&product.ID, &product.Category, &product.ImgSrc, &product.Name, &product.Description, &product.BrandID, &product.Price, &product.IsNew); err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return products, nil
-}
-
 // เพิ่มฟังก์ชัน GetAboutPage ใน ProductStore
 func (pdb *PostgresDatabase) GetAboutPageByBrandID(ctx context.Context, brand_id int) (AboutPage, error) {
 	var about AboutPage
-	query := `SELECT brand_id, img, title, description FROM about_page WHERE brand_id = $1`
-	err := pdb.db.QueryRowContext(ctx, query, brand_id).Scan(&about.Brand_id, &about.Img, &about.Title, &about.Description)
+	query := `SELECT id, brand_id, img, title, description FROM about_page WHERE brand_id = $1`
+	err := pdb.db.QueryRowContext(ctx, query, brand_id).Scan(&about.ID, &about.Brand_id, &about.Img, &about.Title, &about.Description)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return AboutPage{}, fmt.Errorf("about page not found for brand_id %d", brand_id)
+			return AboutPage{}, fmt.Errorf("%w for brand_id %d", ErrAboutPageNotFound, brand_id)
 		}
 		return AboutPage{}, fmt.Errorf("failed to get about page: %v", err)
 	}
@@ -305,10 +410,10 @@ func (pdb *PostgresDatabase) GetBrandByID(ctx context.Context, brandID string) (
 
 	// สร้างตัวแปรที่จะเก็บข้อมูลแบรนด์
 	var brand Brands
-	query := "SELECT id, brandname, brandlogo FROM brand WHERE id = $1"
+	query := "SELECT id, brandname, brandlogo, vat_inclusive, free_shipping_threshold, return_window_days, COALESCE(tax_id, ''), COALESCE(address, '') FROM brand WHERE id = $1"
 
 	// ดึงข้อมูลจากฐานข้อมูลตาม brandID
-	err := pdb.db.QueryRowContext(ctx, query, brandID).Scan(&brand.BrandID, &brand.Brandname, &brand.Brandlogo)
+	err := pdb.db.QueryRowContext(ctx, query, brandID).Scan(&brand.BrandID, &brand.Brandname, &brand.Brandlogo, &brand.VATInclusive, &brand.FreeShippingThreshold, &brand.ReturnWindowDays, &brand.TaxID, &brand.Address)
 	if err != nil {
 		// ตรวจสอบกรณีที่ไม่พบข้อมูล (sql.ErrNoRows)
 		if err == sql.ErrNoRows {
@@ -323,7 +428,7 @@ func (pdb *PostgresDatabase) GetBrandByID(ctx context.Context, brandID string) (
 }
 
 func (pdb *PostgresDatabase) GetAllBrands(ctx context.Context) ([]Brands, error) {
-	query := "SELECT id, brandname, brandlogo FROM brand"
+	query := "SELECT id, brandname, brandlogo, vat_inclusive, free_shipping_threshold, return_window_days, COALESCE(tax_id, ''), COALESCE(address, '') FROM brand"
 	rows, err := pdb.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
@@ -334,7 +439,7 @@ func (pdb *PostgresDatabase) GetAllBrands(ctx context.Context) ([]Brands, error)
 
 	for rows.Next() {
 		var brand Brands
-		if err := rows.Scan(&brand.BrandID, &brand.Brandname, &brand.Brandlogo); err != nil {
+		if err := rows.Scan(&brand.BrandID, &brand.Brandname, &brand.Brandlogo, &brand.VATInclusive, &brand.FreeShippingThreshold, &brand.ReturnWindowDays, &brand.TaxID, &brand.Address); err != nil {
 			return nil, err
 		}
 		brands = append(brands, brand)
@@ -347,62 +452,10 @@ func (pdb *PostgresDatabase) GetAllBrands(ctx context.Context) ([]Brands, error)
 	return brands, nil
 }
 
-// GetProductsByBrand ดึงข้อมูลสินค้าจากฐานข้อมูลตาม BrandID
-func (pdb *PostgresDatabase) GetProductsByBrand(ctx context.Context, brandID string) ([]Clothes, error) {
-	query := "SELECT id, category, imgsrc, name, description, brand, price, isnew FROM products WHERE brand = $1"
-	rows, err := pdb.db.QueryContext(ctx, query, brandID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var products []Clothes
-	for rows.Next() {
-		var product Clothes
-		if err := rows.Scan(&product.ID, &product.Category, &product.ImgSrc, &product.Name, &product.Description, &product.BrandID, &product.Price, &product.IsNew); err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return products, nil
-}
-
-// สร้างฟังก์ชัน SearchProducts ใน PostgresDatabase
-func (pdb *PostgresDatabase) SearchProducts(ctx context.Context, searchQuery string) ([]Clothes, error) {
-	// ใช้ LIKE เพื่อค้นหาคำที่ระบุในชื่อหรือคำอธิบายของผลิตภัณฑ์
-	query := "SELECT id, category, imgsrc, name, description, brand, price, isnew FROM products WHERE name ILIKE $1 OR description ILIKE $1"
-	rows, err := pdb.db.QueryContext(ctx, query, "%"+searchQuery+"%")
-	if err != nil {
-		return nil, fmt.Errorf("failed to search products: %v", err)
-	}
-	defer rows.Close()
-
-	var products []Clothes
-
-	for rows.Next() {
-		var product Clothes
-		if err := rows.Scan(&product.ID, &product.Category, &product.ImgSrc, &product.Name, &product.Description, &product.BrandID, &product.Price, &product.IsNew); err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return products, nil
-
-}
-
 // AddProduct เพิ่มข้อมูลสินค้าใหม่ลงในฐานข้อมูล
 func (pdb *PostgresDatabase) AddBrand(ctx context.Context, brand Brands) error {
-	_, err := pdb.db.ExecContext(ctx, "INSERT INTO brand (brandname, brandlogo) VALUES ($1, $2)",
-		brand.Brandlogo, brand.Brandname)
+	_, err := pdb.db.ExecContext(ctx, "INSERT INTO brand (brandname, brandlogo, vat_inclusive, free_shipping_threshold, return_window_days, tax_id, address) VALUES ($1, $2, COALESCE($3, TRUE), $4, COALESCE($5, 14), NULLIF($6, ''), NULLIF($7, ''))",
+		brand.Brandname, brand.Brandlogo, brand.VATInclusive, brand.FreeShippingThreshold, brand.ReturnWindowDays, brand.TaxID, brand.Address)
 	if err != nil {
 		return fmt.Errorf("failed to add brand: %v", err)
 	}
@@ -420,65 +473,145 @@ func (pdb *PostgresDatabase) DeleteBrand(ctx context.Context, brandID string) er
 
 // UpdateProduct อัพเดตข้อมูลสินค้าที่มีอยู่ในฐานข้อมูล
 func (pdb *PostgresDatabase) UpdateBrand(ctx context.Context, brand Brands) error {
-	_, err := pdb.db.ExecContext(ctx, "UPDATE brand SET brandname = $1, brandlogo = $2 WHERE id = $3",
-		brand.Brandlogo, brand.Brandname, brand.BrandID)
+	_, err := pdb.db.ExecContext(ctx, "UPDATE brand SET brandname = $1, brandlogo = $2, vat_inclusive = COALESCE($3, vat_inclusive), free_shipping_threshold = COALESCE($4, free_shipping_threshold), return_window_days = COALESCE($6, return_window_days), tax_id = COALESCE(NULLIF($7, ''), tax_id), address = COALESCE(NULLIF($8, ''), address) WHERE id = $5",
+		brand.Brandname, brand.Brandlogo, brand.VATInclusive, brand.FreeShippingThreshold, brand.BrandID, brand.ReturnWindowDays, brand.TaxID, brand.Address)
 	if err != nil {
 		return fmt.Errorf("failed to update brand: %v", err)
 	}
 	return nil
 }
 
-// GetAllCart ดึงข้อมูลสินค้าทั้งหมดในตะกร้า
-func (pdb *PostgresDatabase) GetAllCart(ctx context.Context) ([]CartItem, error) {
+// GetAllCart ดึงข้อมูลสินค้าทั้งหมดในตะกร้าของเจ้าของตะกร้า พร้อมส่วนลดจากโปรโมชันและคูปองใน opts (ถ้ามี)
+// แล้วคิด VAT และค่าส่งด้วย pricing.Quote เช่นเดียวกับตอนสร้างคำสั่งซื้อ
+func (pdb *PostgresDatabase) GetAllCart(ctx context.Context, owner CartOwner, opts CartOptions) (Cart, error) {
+	where, ownerValue, err := owner.filter("$1")
+	if err != nil {
+		return Cart{}, err
+	}
 	query := `
-        SELECT c.cart_id, p.name AS product_name, p.imgsrc AS product_imgsrc, c.quantity, (p.price * c.quantity) AS total_price
+        SELECT c.cart_id, v.product_id, v.variant_id, v.sku, v.size, v.colour, p.name AS product_name,
+               COALESCE(v.imgsrc, p.imgsrc) AS product_imgsrc, p.brand, p.category, c.quantity, COALESCE(v.price, p.price) AS unit_price, p.weight_grams
         FROM cart c
-        JOIN products p ON c.product_id = p.id;
+        JOIN product_variants v ON c.variant_id = v.variant_id
+        JOIN products p ON v.product_id = p.id
+        WHERE c.` + where + `
+        ORDER BY c.created_at;
     `
-	rows, err := pdb.db.QueryContext(ctx, query)
+	rows, err := pdb.db.QueryContext(ctx, query, ownerValue)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query cart items: %v", err)
+		return Cart{}, fmt.Errorf("failed to query cart items: %v", err)
 	}
 	defer rows.Close()
 
-	var cartItems []CartItem
+	cartItems := []CartItem{}
+	var lines []promotions.Line
 	for rows.Next() {
 		var item CartItem
-		if err := rows.Scan(&item.CartID, &item.ProductName, &item.ProductImgSrc, &item.Quantity, &item.Price); err != nil {
-			return nil, fmt.Errorf("failed to scan cart item: %v", err)
+		if err := rows.Scan(&item.CartID, &item.ProductID, &item.VariantID, &item.SKU, &item.Size, &item.Colour, &item.ProductName, &item.ProductImgSrc,
+			&item.BrandID, &item.Category, &item.Quantity, &item.UnitPrice, &item.WeightGrams); err != nil {
+			return Cart{}, fmt.Errorf("failed to scan cart item: %v", err)
 		}
 		cartItems = append(cartItems, item)
+		lines = append(lines, promotions.Line{Key: item.CartID, BrandID: item.BrandID, Category: item.Category, UnitPrice: item.UnitPrice, Quantity: item.Quantity})
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %v", err)
+		return Cart{}, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	result, err := promotions.Apply(ctx, pdb.db, lines, opts.Coupon, owner.UserID)
+	if err != nil {
+		return Cart{}, err
+	}
+	priced := make([]pricing.Line, len(cartItems))
+	for i := range cartItems {
+		cartItems[i].Price = result.Lines[i].Subtotal
+		cartItems[i].Discount = result.Lines[i].Discount
+		cartItems[i].Discounts = result.Lines[i].Discounts
+		priced[i] = pricing.Line{
+			BrandID:     cartItems[i].BrandID,
+			Subtotal:    cartItems[i].Price,
+			Discount:    cartItems[i].Discount,
+			WeightGrams: cartItems[i].WeightGrams * cartItems[i].Quantity,
+		}
+	}
+
+	totals, err := pricing.Quote(ctx, pdb.db, priced, result.DiscountTotal.Sub(result.LineDiscount), opts.Province, true)
+	if err != nil {
+		return Cart{}, err
 	}
 
-	return cartItems, nil
+	cart := Cart{
+		Items:         cartItems,
+		Totals:        totals,
+		LineDiscount:  result.LineDiscount,
+		CartDiscounts: result.CartDiscounts,
+		Coupon:        result.Coupon,
+	}
+	if cart.CartDiscounts == nil {
+		cart.CartDiscounts = []promotions.Applied{}
+	}
+	if result.CouponErr != nil {
+		cart.CouponError = result.CouponErr.Error()
+	}
+	return cart, nil
 }
 
-// AddProductToCart เพิ่มสินค้าใหม่หรืออัพเดตจำนวนสินค้าในตะกร้า
-func (pdb *PostgresDatabase) AddProductToCart(ctx context.Context, productID int, quantity int) error {
+// AddProductToCart เพิ่ม SKU ใหม่หรืออัพเดตจำนวนในตะกร้าของเจ้าของตะกร้า
+func (pdb *PostgresDatabase) AddProductToCart(ctx context.Context, owner CartOwner, variantID int, quantity int) error {
+	where, ownerValue, err := owner.filter("$1")
+	if err != nil {
+		return err
+	}
+
 	// เริ่มต้น transaction เพื่อให้มั่นใจว่าการเพิ่ม/อัพเดตข้อมูลเป็นไปอย่างถูกต้อง
-	tx, err := pdb.db.Begin()
+	tx, err := pdb.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
 	defer tx.Rollback()
 
-	// ตรวจสอบว่าในตะกร้ามีสินค้านี้อยู่แล้วหรือไม่
-	var existingCartID int
-	err = tx.QueryRowContext(ctx, "SELECT cart_id FROM cart WHERE product_id = $1", productID).Scan(&existingCartID)
+	// ราคาของ SKU (ถ้าไม่ได้กำหนดไว้ใช้ราคาของสินค้าหลัก)
+	var price money.Money
+	err = tx.QueryRowContext(ctx, `
+        SELECT COALESCE(v.price, p.price)
+        FROM product_variants v
+        JOIN products p ON p.id = v.product_id
+        WHERE v.variant_id = $1
+    `, variantID).Scan(&price)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrVariantNotFound
+		}
+		return fmt.Errorf("failed to get variant price: %v", err)
+	}
+
+	// ตรวจสอบว่าในตะกร้าของเจ้าของนี้มี SKU นี้อยู่แล้วหรือไม่
+	var existingCartID, existingQuantity int
+	err = tx.QueryRowContext(ctx, "SELECT cart_id, quantity FROM cart WHERE "+where+" AND variant_id = $2 FOR UPDATE", ownerValue, variantID).Scan(&existingCartID, &existingQuantity)
 	if err != nil && err != sql.ErrNoRows {
 		return fmt.Errorf("failed to check if product exists in cart: %v", err)
 	}
+	isNewLine := err == sql.ErrNoRows
+
+	// ตรวจสอบสต็อก โดยไม่นับการจองของรายการนี้เอง
+	available, err := lockAvailableStock(ctx, tx, variantID, existingCartID)
+	if err != nil {
+		return err
+	}
+	newQuantity := existingQuantity + quantity
+	if newQuantity > available {
+		return &OutOfStockError{VariantID: variantID, Requested: newQuantity, Available: available}
+	}
 
-	if err == sql.ErrNoRows {
+	cartID := existingCartID
+	if isNewLine {
 		// ถ้าไม่มีสินค้าในตะกร้า ให้เพิ่มสินค้าใหม่
-		_, err = tx.ExecContext(ctx, `
-            INSERT INTO cart (product_id, quantity, price) 
-            VALUES ($1, $2, (SELECT price FROM products WHERE id = $1))
-        `, productID, quantity)
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO cart (user_id, guest_token, variant_id, quantity, price) 
+            VALUES (NULLIF($1, '')::uuid, NULLIF($2, ''), $3, $4, $5)
+            RETURNING cart_id
+        `, owner.UserID, owner.GuestToken, variantID, quantity, price).Scan(&cartID)
 		if err != nil {
 			return fmt.Errorf("failed to insert product into cart: %v", err)
 		}
@@ -486,14 +619,19 @@ func (pdb *PostgresDatabase) AddProductToCart(ctx context.Context, productID int
 		// ถ้ามีสินค้าในตะกร้าแล้ว ให้ทำการอัพเดตจำนวนสินค้า
 		_, err = tx.ExecContext(ctx, `
             UPDATE cart 
-            SET quantity = quantity + $2 
-            WHERE product_id = $1
-        `, productID, quantity)
+            SET quantity = $2, updated_at = CURRENT_TIMESTAMP
+            WHERE cart_id = $1
+        `, cartID, newQuantity)
 		if err != nil {
 			return fmt.Errorf("failed to update quantity in cart: %v", err)
 		}
 	}
 
+	// จองสต็อกตามจำนวนทั้งหมดของรายการนี้ และต่ออายุการจอง
+	if err := reserveStock(ctx, tx, cartID, variantID, newQuantity); err != nil {
+		return err
+	}
+
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %v", err)
@@ -502,11 +640,22 @@ func (pdb *PostgresDatabase) AddProductToCart(ctx context.Context, productID int
 	return nil
 }
 
-func (pdb *PostgresDatabase) DeleteProductFromCart(ctx context.Context, cartID int) error {
-	// ลบข้อมูลสินค้าในตะกร้า
-	_, err := pdb.db.ExecContext(ctx, "DELETE FROM cart WHERE cart_id = $1", cartID)
+// DeleteProductFromCart ลบสินค้าออกจากตะกร้า โดยลบได้เฉพาะรายการที่เป็นของเจ้าของตะกร้าเท่านั้น
+func (pdb *PostgresDatabase) DeleteProductFromCart(ctx context.Context, owner CartOwner, cartID int) error {
+	where, ownerValue, err := owner.filter("$2")
+	if err != nil {
+		return err
+	}
+	result, err := pdb.db.ExecContext(ctx, "DELETE FROM cart WHERE cart_id = $1 AND "+where, cartID, ownerValue)
 	if err != nil {
 		return fmt.Errorf("failed to delete product from cart: %v", err)
 	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete product from cart: %v", err)
+	}
+	if affected == 0 {
+		return ErrCartItemNotFound
+	}
 	return nil
 }
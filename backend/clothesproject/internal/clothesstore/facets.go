@@ -0,0 +1,159 @@
+package clothesstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"clothesproject/internal/money"
+)
+
+// PriceBucket คือช่วงราคาสำหรับ facet ครอบคลุม Min ถึงก่อน Max (Max เป็น nil คือไม่มีเพดาน)
+// ใช้ค่า Min/Max เป็น min_price/max_price ของ ProductFilter ได้โดยตรง
+type PriceBucket struct {
+	Key string       `json:"key"`
+	Min money.Money  `json:"min"`
+	Max *money.Money `json:"max,omitempty"`
+}
+
+func priceCeiling(baht int64) *money.Money {
+	v := money.Baht(baht)
+	return &v
+}
+
+// PriceBuckets คือช่วงราคาที่ใช้นับ facet เรียงจากถูกไปแพง
+var PriceBuckets = []PriceBucket{
+	{Key: "under_500", Min: money.Baht(0), Max: priceCeiling(500)},
+	{Key: "500_1000", Min: money.Baht(500), Max: priceCeiling(1000)},
+	{Key: "1000_2000", Min: money.Baht(1000), Max: priceCeiling(2000)},
+	{Key: "2000_5000", Min: money.Baht(2000), Max: priceCeiling(5000)},
+	{Key: "5000_plus", Min: money.Baht(5000)},
+}
+
+// FacetCount คือจำนวนสินค้าของค่าหนึ่งใน facet (เช่น แบรนด์หนึ่ง หรือหมวดหมู่หนึ่ง)
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// PriceBucketCount คือจำนวนสินค้าในช่วงราคาหนึ่ง
+type PriceBucketCount struct {
+	PriceBucket
+	Count int `json:"count"`
+}
+
+// ProductFacets คือจำนวนสินค้าแยกตามแบรนด์ หมวดหมู่ และช่วงราคา
+// แต่ละ facet ใช้ทุกเงื่อนไขที่เลือกไว้ยกเว้นเงื่อนไขของตัวเอง เพื่อให้ยังเห็นตัวเลือกอื่นในกลุ่มเดียวกันพร้อมจำนวน
+type ProductFacets struct {
+	Brands       []FacetCount       `json:"brands"`
+	Categories   []FacetCount       `json:"categories"`
+	PriceBuckets []PriceBucketCount `json:"price_buckets"`
+}
+
+// productFacets นับจำนวนสินค้าตาม facet สำหรับ filter ที่เลือกไว้
+func (pdb *PostgresDatabase) productFacets(ctx context.Context, filter ProductFilter) (ProductFacets, error) {
+	var facets ProductFacets
+	var err error
+
+	withoutBrands := filter
+	withoutBrands.BrandIDs = nil
+	facets.Brands, err = pdb.countFacet(ctx, withoutBrands, "p.brand", "COALESCE(b.brandname, '')")
+	if err != nil {
+		return ProductFacets{}, err
+	}
+
+	withoutCategories := filter
+	withoutCategories.Categories = nil
+	facets.Categories, err = pdb.countFacet(ctx, withoutCategories, "p.category", "''")
+	if err != nil {
+		return ProductFacets{}, err
+	}
+
+	withoutPrice := filter
+	withoutPrice.MinPrice, withoutPrice.MaxPrice = nil, nil
+	facets.PriceBuckets, err = pdb.countPriceBuckets(ctx, withoutPrice)
+	if err != nil {
+		return ProductFacets{}, err
+	}
+
+	return facets, nil
+}
+
+// countFacet นับจำนวนสินค้าแยกตามคอลัมน์ column พร้อมชื่อที่แสดง label เรียงจากมากไปน้อย
+func (pdb *PostgresDatabase) countFacet(ctx context.Context, filter ProductFilter, column string, label string) ([]FacetCount, error) {
+	var args sqlArgs
+	where := filter.whereClause(&args)
+	rows, err := pdb.db.QueryContext(ctx, `
+        SELECT `+column+`, `+label+`, COUNT(*)
+        FROM products p
+        LEFT JOIN brand b ON b.id::text = p.brand
+        WHERE `+where+`
+        GROUP BY 1, 2
+        ORDER BY 3 DESC, 1
+    `, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count facet %s: %v", column, err)
+	}
+	defer rows.Close()
+
+	counts := []FacetCount{}
+	for rows.Next() {
+		var count FacetCount
+		if err := rows.Scan(&count.Value, &count.Label, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan facet count: %v", err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// countPriceBuckets นับจำนวนสินค้าในแต่ละช่วงราคา ช่วงที่ไม่มีสินค้าจะได้ 0
+func (pdb *PostgresDatabase) countPriceBuckets(ctx context.Context, filter ProductFilter) ([]PriceBucketCount, error) {
+	var args sqlArgs
+	where := filter.whereClause(&args)
+
+	var cases []string
+	for _, bucket := range PriceBuckets {
+		condition := "p.price >= " + args.add(bucket.Min)
+		if bucket.Max != nil {
+			condition += " AND p.price < " + args.add(*bucket.Max)
+		}
+		cases = append(cases, fmt.Sprintf("WHEN %s THEN %s", condition, args.add(bucket.Key)))
+	}
+
+	rows, err := pdb.db.QueryContext(ctx, `
+        SELECT CASE `+strings.Join(cases, " ")+` END AS bucket, COUNT(*)
+        FROM products p
+        WHERE `+where+`
+        GROUP BY bucket
+    `, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count price buckets: %v", err)
+	}
+	defer rows.Close()
+
+	byKey := make(map[string]int)
+	for rows.Next() {
+		var key *string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan price bucket: %v", err)
+		}
+		if key != nil {
+			byKey[*key] = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	counts := make([]PriceBucketCount, len(PriceBuckets))
+	for i, bucket := range PriceBuckets {
+		counts[i] = PriceBucketCount{PriceBucket: bucket, Count: byKey[bucket.Key]}
+	}
+	return counts, nil
+}
@@ -0,0 +1,160 @@
+package clothesstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReservationTTL คือระยะเวลาที่สินค้าในตะกร้าถูกจองไว้ หลังจากนั้น sweeper จะปล่อยสต็อกคืน
+const ReservationTTL = 15 * time.Minute
+
+// ErrOutOfStock คืนค่าเมื่อสต็อกของ SKU ไม่พอ (ใช้ errors.Is ตรวจ และ errors.As กับ *OutOfStockError เพื่อดูรายละเอียด)
+var ErrOutOfStock = errors.New("out of stock")
+
+// OutOfStockError บอกว่า SKU ใดสต็อกไม่พอ ขอไปเท่าไรและเหลือให้เท่าไร
+type OutOfStockError struct {
+	VariantID int
+	Requested int
+	Available int
+}
+
+func (e *OutOfStockError) Error() string {
+	return fmt.Sprintf("out of stock: variant %d requested %d, available %d", e.VariantID, e.Requested, e.Available)
+}
+
+func (e *OutOfStockError) Is(target error) bool {
+	return target == ErrOutOfStock
+}
+
+// StockLevel คือสต็อกของ SKU: OnHand คือของจริงในคลัง Reserved คือที่ถูกจองในตะกร้าที่ยังไม่หมดอายุ
+type StockLevel struct {
+	VariantID int `json:"variant_id"`
+	OnHand    int `json:"on_hand"`
+	Reserved  int `json:"reserved"`
+	Available int `json:"available"`
+}
+
+// GetStock ดึงระดับสต็อกของ SKU
+func (pdb *PostgresDatabase) GetStock(ctx context.Context, variantID int) (StockLevel, error) {
+	stock := StockLevel{VariantID: variantID}
+	err := pdb.db.QueryRowContext(ctx, `
+        SELECT COALESCE(i.on_hand, 0),
+               COALESCE((SELECT SUM(r.quantity) FROM stock_reservations r
+                         WHERE r.variant_id = v.variant_id AND r.expires_at > NOW()), 0)
+        FROM product_variants v
+        LEFT JOIN inventory i ON i.variant_id = v.variant_id
+        WHERE v.variant_id = $1
+    `, variantID).Scan(&stock.OnHand, &stock.Reserved)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return StockLevel{}, ErrVariantNotFound
+		}
+		return StockLevel{}, fmt.Errorf("failed to get stock: %v", err)
+	}
+	stock.Available = max(stock.OnHand-stock.Reserved, 0)
+	return stock, nil
+}
+
+// SetStock กำหนดจำนวนสต็อกในคลังของ SKU
+func (pdb *PostgresDatabase) SetStock(ctx context.Context, actor Actor, variantID int, onHand int) error {
+	if onHand < 0 {
+		return errors.New("stock cannot be negative")
+	}
+	if err := authorizeVariant(ctx, pdb.db, actor, variantID); err != nil {
+		return err
+	}
+	_, err := pdb.db.ExecContext(ctx, `
+        INSERT INTO inventory (variant_id, on_hand)
+        VALUES ($1, $2)
+        ON CONFLICT (variant_id) DO UPDATE
+        SET on_hand = EXCLUDED.on_hand, updated_at = CURRENT_TIMESTAMP
+    `, variantID, onHand)
+	if err != nil {
+		return fmt.Errorf("failed to set stock: %v", err)
+	}
+	return nil
+}
+
+// ReleaseExpiredReservations ลบการจองที่หมดอายุแล้ว เรียกจาก sweeper เป็นระยะ
+func (pdb *PostgresDatabase) ReleaseExpiredReservations(ctx context.Context) (int64, error) {
+	result, err := pdb.db.ExecContext(ctx, "DELETE FROM stock_reservations WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, fmt.Errorf("failed to release expired reservations: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// lockAvailableStock ล็อกแถวสต็อกของ SKU (FOR UPDATE) แล้วคืนจำนวนที่ยังใช้ได้
+// โดยไม่นับการจองของรายการในตะกร้า excludeCartID (รายการของผู้เรียกเอง)
+// request อื่นที่จะจองหรือตัดสต็อก SKU เดียวกันต้องรอจน transaction นี้จบ
+func lockAvailableStock(ctx context.Context, tx *sql.Tx, variantID int, excludeCartID int) (int, error) {
+	var onHand int
+	err := tx.QueryRowContext(ctx, "SELECT on_hand FROM inventory WHERE variant_id = $1 FOR UPDATE", variantID).Scan(&onHand)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to lock stock: %v", err)
+	}
+
+	var reserved int
+	err = tx.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(quantity), 0)
+        FROM stock_reservations
+        WHERE variant_id = $1 AND cart_id <> $2 AND expires_at > NOW()
+    `, variantID, excludeCartID).Scan(&reserved)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum reservations: %v", err)
+	}
+
+	return max(onHand-reserved, 0), nil
+}
+
+// reserveStock จองสต็อกให้รายการในตะกร้า (แทนที่การจองเดิมของรายการนั้น) และต่ออายุการจอง
+func reserveStock(ctx context.Context, tx *sql.Tx, cartID int, variantID int, quantity int) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO stock_reservations (cart_id, variant_id, quantity, expires_at)
+        VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+        ON CONFLICT (cart_id) DO UPDATE
+        SET quantity = EXCLUDED.quantity, expires_at = EXCLUDED.expires_at
+    `, cartID, variantID, quantity, ReservationTTL.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to reserve stock: %v", err)
+	}
+	return nil
+}
+
+// DeductStock ตัดสต็อกของ SKU ตอนสร้างคำสั่งซื้อ ภายใน transaction ของคำสั่งซื้อ
+// การจองของรายการในตะกร้า cartID เองนับเป็นของผู้สั่งซื้อ ส่วนการจองของคนอื่นที่ยังไม่หมดอายุจะไม่ถูกแย่ง
+func DeductStock(ctx context.Context, tx *sql.Tx, cartID int, variantID int, quantity int) error {
+	available, err := lockAvailableStock(ctx, tx, variantID, cartID)
+	if err != nil {
+		return err
+	}
+	if quantity > available {
+		return &OutOfStockError{VariantID: variantID, Requested: quantity, Available: available}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE inventory SET on_hand = on_hand - $2, updated_at = CURRENT_TIMESTAMP WHERE variant_id = $1", variantID, quantity); err != nil {
+		return fmt.Errorf("failed to deduct stock: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM stock_reservations WHERE cart_id = $1", cartID); err != nil {
+		return fmt.Errorf("failed to release reservation: %v", err)
+	}
+	return nil
+}
+
+// RestockVariant คืนสต็อกของ SKU เข้าคลัง (เช่น สินค้าที่ลูกค้าส่งคืน) ภายใน transaction ของผู้เรียก
+func RestockVariant(ctx context.Context, tx *sql.Tx, variantID int, quantity int) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO inventory (variant_id, on_hand) VALUES ($1, $2)
+        ON CONFLICT (variant_id) DO UPDATE SET on_hand = inventory.on_hand + EXCLUDED.on_hand, updated_at = CURRENT_TIMESTAMP
+    `, variantID, quantity)
+	if err != nil {
+		return fmt.Errorf("failed to restock variant: %v", err)
+	}
+	return nil
+}
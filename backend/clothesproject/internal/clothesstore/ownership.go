@@ -0,0 +1,153 @@
+package clothesstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrForbidden คืนค่าเมื่อผู้ใช้แก้ไขข้อมูลของแบรนด์ที่ตัวเองไม่ได้ดูแล
+	ErrForbidden = errors.New("not allowed to manage this brand")
+	// ErrProductNotFound คืนค่าเมื่อไม่พบสินค้าที่ระบุ
+	ErrProductNotFound = errors.New("product not found")
+	// ErrBrandNotFound คืนค่าเมื่อไม่พบแบรนด์ที่ระบุ
+	ErrBrandNotFound = errors.New("brand not found")
+	// ErrNotSeller คืนค่าเมื่อผูกแบรนด์ให้ผู้ใช้ที่ไม่มีอยู่หรือไม่ได้เป็น seller
+	ErrNotSeller = errors.New("user is not a seller")
+)
+
+// Actor คือผู้ใช้ที่สั่งแก้ไขข้อมูลแคตตาล็อก ผู้ดูแลระบบแก้ไขได้ทุกแบรนด์
+// ผู้ใช้อื่นแก้ไขได้เฉพาะแบรนด์ที่ผูกไว้ใน seller_brands
+type Actor struct {
+	UserID string
+	Admin  bool
+}
+
+// querier คือ *sql.DB หรือ *sql.Tx
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// authorizeBrand ตรวจสอบว่า actor แก้ไขข้อมูลของแบรนด์ brandID ได้ คืน ErrForbidden ถ้าไม่ได้
+func authorizeBrand(ctx context.Context, q querier, actor Actor, brandID int) error {
+	if actor.Admin {
+		return nil
+	}
+	if actor.UserID == "" {
+		return ErrForbidden
+	}
+	var owns bool
+	err := q.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM seller_brands WHERE user_id = $1::uuid AND brand_id = $2)",
+		actor.UserID, brandID).Scan(&owns)
+	if err != nil {
+		return fmt.Errorf("failed to check brand ownership: %v", err)
+	}
+	if !owns {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// productBrand ดึงแบรนด์ของสินค้า lock เป็น true จะล็อกแถวสินค้าไว้ (ต้องใช้ภายใน transaction)
+func productBrand(ctx context.Context, q querier, productID int, lock bool) (int, error) {
+	query := "SELECT brand FROM products WHERE id = $1"
+	if lock {
+		query += " FOR UPDATE"
+	}
+	var brandID int
+	if err := q.QueryRowContext(ctx, query, productID).Scan(&brandID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrProductNotFound
+		}
+		return 0, fmt.Errorf("failed to get product brand: %v", err)
+	}
+	return brandID, nil
+}
+
+// variantBrand ดึงแบรนด์ของสินค้าที่ SKU เป็นของ
+func variantBrand(ctx context.Context, q querier, variantID int) (int, error) {
+	var brandID int
+	err := q.QueryRowContext(ctx, `
+        SELECT p.brand FROM product_variants v
+        JOIN products p ON p.id = v.product_id
+        WHERE v.variant_id = $1
+    `, variantID).Scan(&brandID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrVariantNotFound
+		}
+		return 0, fmt.Errorf("failed to get variant brand: %v", err)
+	}
+	return brandID, nil
+}
+
+// authorizeVariant ตรวจสอบว่า actor แก้ไข SKU (และสต็อกของ SKU) ได้
+func authorizeVariant(ctx context.Context, q querier, actor Actor, variantID int) error {
+	brandID, err := variantBrand(ctx, q, variantID)
+	if err != nil {
+		return err
+	}
+	return authorizeBrand(ctx, q, actor, brandID)
+}
+
+// GetSellerBrands ดึง ID ของแบรนด์ที่ผู้ขายดูแล
+func (pdb *PostgresDatabase) GetSellerBrands(ctx context.Context, userID string) ([]int, error) {
+	rows, err := pdb.db.QueryContext(ctx, "SELECT brand_id FROM seller_brands WHERE user_id = $1::uuid ORDER BY brand_id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch seller brands: %v", err)
+	}
+	defer rows.Close()
+
+	brandIDs := []int{}
+	for rows.Next() {
+		var brandID int
+		if err := rows.Scan(&brandID); err != nil {
+			return nil, fmt.Errorf("failed to scan seller brand row: %v", err)
+		}
+		brandIDs = append(brandIDs, brandID)
+	}
+	return brandIDs, rows.Err()
+}
+
+// LinkSellerBrand ให้ผู้ใช้ role seller ดูแลแบรนด์ brandID (ผูกซ้ำได้โดยไม่มีผล)
+func (pdb *PostgresDatabase) LinkSellerBrand(ctx context.Context, userID string, brandID int) error {
+	var isSeller bool
+	err := pdb.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE user_id = $1::uuid AND role = 'seller')", userID).Scan(&isSeller)
+	if err != nil {
+		return fmt.Errorf("failed to check seller: %v", err)
+	}
+	if !isSeller {
+		return ErrNotSeller
+	}
+
+	result, err := pdb.db.ExecContext(ctx, `
+        INSERT INTO seller_brands (user_id, brand_id)
+        SELECT $1::uuid, id FROM brand WHERE id = $2
+        ON CONFLICT (user_id, brand_id) DO NOTHING
+    `, userID, brandID)
+	if err != nil {
+		return fmt.Errorf("failed to link seller brand: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		// ไม่มีแถวใหม่อาจเพราะผูกไว้แล้ว หรือไม่มีแบรนด์นี้
+		var exists bool
+		if err := pdb.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM brand WHERE id = $1)", brandID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check brand: %v", err)
+		}
+		if !exists {
+			return ErrBrandNotFound
+		}
+	}
+	return nil
+}
+
+// UnlinkSellerBrand เลิกให้ผู้ขายดูแลแบรนด์ brandID
+func (pdb *PostgresDatabase) UnlinkSellerBrand(ctx context.Context, userID string, brandID int) error {
+	_, err := pdb.db.ExecContext(ctx, "DELETE FROM seller_brands WHERE user_id = $1::uuid AND brand_id = $2", userID, brandID)
+	if err != nil {
+		return fmt.Errorf("failed to unlink seller brand: %v", err)
+	}
+	return nil
+}
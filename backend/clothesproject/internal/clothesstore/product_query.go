@@ -0,0 +1,324 @@
+package clothesstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"clothesproject/internal/money"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// DefaultPageSize คือจำนวนสินค้าต่อหน้าเมื่อไม่ได้ระบุ limit
+	DefaultPageSize = 24
+	// MaxPageSize คือจำนวนสินค้าต่อหน้าสูงสุดที่ยอมให้ขอ
+	MaxPageSize = 100
+)
+
+var (
+	// ErrInvalidCursor คืนค่าเมื่อ cursor ถอดรหัสไม่ได้ หรือสร้างมาจากการเรียงลำดับแบบอื่น
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort คืนค่าเมื่อไม่รู้จักวิธีเรียงลำดับที่ขอ
+	ErrInvalidSort = errors.New("invalid sort")
+)
+
+// ProductSort คือวิธีเรียงลำดับรายการสินค้า
+type ProductSort string
+
+const (
+	SortRelevance ProductSort = "relevance"
+	SortNewest    ProductSort = "newest"
+	SortPriceAsc  ProductSort = "price_asc"
+	SortPriceDesc ProductSort = "price_desc"
+	SortNameAsc   ProductSort = "name_asc"
+	SortNameDesc  ProductSort = "name_desc"
+)
+
+// IsValid ตรวจสอบว่าเป็นวิธีเรียงลำดับที่รู้จัก
+func (s ProductSort) IsValid() bool {
+	switch s {
+	case SortRelevance, SortNewest, SortPriceAsc, SortPriceDesc, SortNameAsc, SortNameDesc:
+		return true
+	}
+	return false
+}
+
+// ProductFilter คือเงื่อนไขกรองสินค้า ทุกเงื่อนไขใช้ร่วมกันแบบ AND ค่าว่างหรือ nil หมายถึงไม่กรอง
+// Categories และ BrandIDs ใช้แบบ OR ภายในกลุ่มเดียวกัน ช่วงราคารวม MinPrice แต่ไม่รวม MaxPrice (ตรงกับ PriceBuckets)
+type ProductFilter struct {
+	Query      string
+	Categories []string
+	BrandIDs   []string
+	MinPrice   *money.Money
+	MaxPrice   *money.Money
+	IsNew      *bool
+
+	// matchIDs คือสินค้าที่ Searcher คืนมาสำหรับ Query เรียงตามความเกี่ยวข้อง (nil เมื่อไม่ได้ใช้ Searcher)
+	matchIDs []int64
+}
+
+// ProductQuery คือคำขอดึงรายการสินค้าแบบแบ่งหน้า Cursor ว่างหมายถึงหน้าแรก
+// WithFacets ให้นับจำนวนสินค้าตาม facet มาด้วย
+type ProductQuery struct {
+	Filter     ProductFilter
+	Sort       ProductSort
+	Limit      int
+	Cursor     string
+	WithFacets bool
+}
+
+// ProductPage คือสินค้าหนึ่งหน้า Total คือจำนวนสินค้าทั้งหมดที่ตรงเงื่อนไข
+// NextCursor ว่างเมื่อเป็นหน้าสุดท้าย
+type ProductPage struct {
+	Items      []Clothes      `json:"items"`
+	Total      int            `json:"total"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Facets     *ProductFacets `json:"facets,omitempty"`
+}
+
+// productCursor คือตำแหน่งของสินค้าตัวสุดท้ายในหน้าก่อน ตามคีย์ของการเรียงลำดับ (keyset pagination)
+type productCursor struct {
+	Sort    ProductSort `json:"s"`
+	ID      int         `json:"id"`
+	Rank    int         `json:"r,omitempty"`
+	Price   money.Money `json:"p"`
+	Name    string      `json:"n,omitempty"`
+	Created string      `json:"c,omitempty"`
+}
+
+func encodeCursor(sort ProductSort, last Clothes, rank int) string {
+	cursor := productCursor{Sort: sort, ID: last.ID}
+	switch sort {
+	case SortRelevance:
+		cursor.Rank = rank
+	case SortPriceAsc, SortPriceDesc:
+		cursor.Price = last.Price
+	case SortNameAsc, SortNameDesc:
+		cursor.Name = last.Name
+	default:
+		cursor.Created = last.Createdate.Format(time.DateOnly)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string, sort ProductSort) (productCursor, error) {
+	var cursor productCursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return productCursor{}, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort {
+		return productCursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// sqlArgs สะสม argument ของ query และคืน placeholder ($n) ของค่าที่เพิ่มเข้าไป
+type sqlArgs []interface{}
+
+func (a *sqlArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// likeEscaper escape อักขระพิเศษของ LIKE ใช้คู่กับ ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike ทำให้ % _ และ \ ในคำค้นของผู้ใช้ถูกค้นหาตามตัวอักษร ไม่ใช่ wildcard
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// whereClause สร้างเงื่อนไข WHERE จาก filter (ตาราง products ใช้ alias p)
+func (f ProductFilter) whereClause(args *sqlArgs) string {
+	var conditions []string
+	if f.matchIDs != nil {
+		conditions = append(conditions, "p.id = ANY("+args.add(pq.Array(f.matchIDs))+"::int[])")
+	} else if f.Query != "" {
+		ph := args.add("%" + escapeLike(f.Query) + "%")
+		conditions = append(conditions, fmt.Sprintf(`(p.name ILIKE %s ESCAPE '\' OR p.description ILIKE %s ESCAPE '\')`, ph, ph))
+	}
+	if len(f.Categories) > 0 {
+		conditions = append(conditions, "p.category = ANY("+args.add(pq.Array(f.Categories))+")")
+	}
+	if len(f.BrandIDs) > 0 {
+		conditions = append(conditions, "p.brand = ANY("+args.add(pq.Array(f.BrandIDs))+")")
+	}
+	if f.MinPrice != nil {
+		conditions = append(conditions, "p.price >= "+args.add(*f.MinPrice))
+	}
+	if f.MaxPrice != nil {
+		conditions = append(conditions, "p.price < "+args.add(*f.MaxPrice))
+	}
+	if f.IsNew != nil {
+		conditions = append(conditions, "COALESCE(p.isnew, false) = "+args.add(*f.IsNew))
+	}
+	if len(conditions) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// orderAndSeek คืน ORDER BY ของการเรียงลำดับ และเงื่อนไขสำหรับข้ามไปหลัง cursor (ถ้ามี)
+// ทุกแบบใช้ id เป็นตัวตัดสินเมื่อคีย์หลักเท่ากัน เพื่อให้ลำดับคงที่และไม่มีสินค้าซ้ำหรือหายระหว่างหน้า
+func orderAndSeek(sort ProductSort, filter ProductFilter, cursor *productCursor, args *sqlArgs) (orderBy string, seek string) {
+	switch sort {
+	case SortRelevance:
+		// ลำดับใน matchIDs คือลำดับความเกี่ยวข้องจาก Searcher
+		rank := "array_position(" + args.add(pq.Array(filter.matchIDs)) + "::int[], p.id)"
+		orderBy = rank + " ASC"
+		if cursor != nil {
+			seek = rank + " > " + args.add(cursor.Rank)
+		}
+	case SortPriceAsc:
+		orderBy = "p.price ASC, p.id ASC"
+		if cursor != nil {
+			seek = fmt.Sprintf("(p.price, p.id) > (%s, %s)", args.add(cursor.Price), args.add(cursor.ID))
+		}
+	case SortPriceDesc:
+		orderBy = "p.price DESC, p.id DESC"
+		if cursor != nil {
+			seek = fmt.Sprintf("(p.price, p.id) < (%s, %s)", args.add(cursor.Price), args.add(cursor.ID))
+		}
+	case SortNameAsc:
+		orderBy = "p.name ASC, p.id ASC"
+		if cursor != nil {
+			seek = fmt.Sprintf("(p.name, p.id) > (%s, %s)", args.add(cursor.Name), args.add(cursor.ID))
+		}
+	case SortNameDesc:
+		orderBy = "p.name DESC, p.id DESC"
+		if cursor != nil {
+			seek = fmt.Sprintf("(p.name, p.id) < (%s, %s)", args.add(cursor.Name), args.add(cursor.ID))
+		}
+	default:
+		orderBy = "p.createdate DESC, p.id DESC"
+		if cursor != nil {
+			seek = fmt.Sprintf("(p.createdate, p.id) < (%s::date, %s)", args.add(cursor.Created), args.add(cursor.ID))
+		}
+	}
+	return orderBy, seek
+}
+
+// ListProducts ดึงรายการสินค้าตาม filter แบบแบ่งหน้าด้วย cursor พร้อมจำนวนทั้งหมดที่ตรงเงื่อนไข
+// ถ้ามีคำค้นและกำหนด Searcher ไว้ จะค้นด้วย Searcher และเรียงตามความเกี่ยวข้องเป็นค่าเริ่มต้น
+func (pdb *PostgresDatabase) ListProducts(ctx context.Context, query ProductQuery) (ProductPage, error) {
+	if !query.Sort.IsValid() && query.Sort != "" {
+		return ProductPage{}, ErrInvalidSort
+	}
+
+	var matches map[int]SearchMatch
+	if query.Filter.Query != "" && pdb.searcher != nil {
+		found, err := pdb.searcher.Search(ctx, query.Filter.Query, MaxSearchMatches)
+		switch {
+		case errors.Is(err, ErrSearchUnavailable):
+			// ใช้ ILIKE แทนจนกว่า Searcher จะพร้อม
+		case err != nil:
+			return ProductPage{}, fmt.Errorf("failed to search products: %v", err)
+		default:
+			matches = make(map[int]SearchMatch, len(found))
+			query.Filter.matchIDs = make([]int64, 0, len(found))
+			for _, match := range found {
+				matches[match.ProductID] = match
+				query.Filter.matchIDs = append(query.Filter.matchIDs, int64(match.ProductID))
+			}
+		}
+	}
+
+	// เรียงตามความเกี่ยวข้องได้เฉพาะเมื่อค้นด้วย Searcher
+	if query.Sort == "" && query.Filter.matchIDs != nil {
+		query.Sort = SortRelevance
+	}
+	if query.Sort == "" || (query.Sort == SortRelevance && query.Filter.matchIDs == nil) {
+		query.Sort = SortNewest
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultPageSize
+	}
+	query.Limit = min(query.Limit, MaxPageSize)
+
+	var cursor *productCursor
+	if query.Cursor != "" {
+		decoded, err := decodeCursor(query.Cursor, query.Sort)
+		if err != nil {
+			return ProductPage{}, err
+		}
+		cursor = &decoded
+	}
+
+	page := ProductPage{Items: []Clothes{}}
+
+	var countArgs sqlArgs
+	where := query.Filter.whereClause(&countArgs)
+	if err := pdb.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM products p WHERE "+where, countArgs...).Scan(&page.Total); err != nil {
+		return ProductPage{}, fmt.Errorf("failed to count products: %v", err)
+	}
+
+	var args sqlArgs
+	where = query.Filter.whereClause(&args)
+	orderBy, seek := orderAndSeek(query.Sort, query.Filter, cursor, &args)
+	if seek != "" {
+		where += " AND " + seek
+	}
+	// ดึงเกิน 1 แถวเพื่อดูว่ายังมีหน้าถัดไปหรือไม่
+	limit := args.add(query.Limit + 1)
+
+	rows, err := pdb.db.QueryContext(ctx, `
+        SELECT p.id, p.category, p.imgsrc, p.name, COALESCE(p.description, ''), p.brand, p.price, p.weight_grams, COALESCE(p.isnew, false), p.createdate, p.updatedate
+        FROM products p
+        WHERE `+where+`
+        ORDER BY `+orderBy+`
+        LIMIT `+limit, args...)
+	if err != nil {
+		return ProductPage{}, fmt.Errorf("failed to list products: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product Clothes
+		if err := rows.Scan(&product.ID, &product.Category, &product.ImgSrc, &product.Name, &product.Description, &product.BrandID,
+			&product.Price, &product.WeightGrams, &product.IsNew, &product.Createdate, &product.Updatedate); err != nil {
+			return ProductPage{}, fmt.Errorf("failed to scan product: %v", err)
+		}
+		if match, ok := matches[product.ID]; ok {
+			product.Score = match.Score
+			product.Highlights = match.Highlights
+		}
+		page.Items = append(page.Items, product)
+	}
+	if err := rows.Err(); err != nil {
+		return ProductPage{}, err
+	}
+
+	if len(page.Items) > query.Limit {
+		page.Items = page.Items[:query.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = encodeCursor(query.Sort, last, rankOf(query.Filter.matchIDs, last.ID))
+	}
+
+	if query.WithFacets {
+		facets, err := pdb.productFacets(ctx, query.Filter)
+		if err != nil {
+			return ProductPage{}, err
+		}
+		page.Facets = &facets
+	}
+
+	return page, nil
+}
+
+// rankOf คืนลำดับ (เริ่มที่ 1) ของสินค้าใน ids ตรงกับ array_position ของ PostgreSQL
+func rankOf(ids []int64, productID int) int {
+	for i, id := range ids {
+		if id == int64(productID) {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package clothesstore
+
+import (
+	"context"
+	"errors"
+)
+
+// MaxSearchMatches คือจำนวนผลการค้นหาสูงสุดที่นำมากรอง/แบ่งหน้าต่อ
+const MaxSearchMatches = 1000
+
+// ErrSearchUnavailable คืนค่าจาก Searcher เมื่อยังค้นหาไม่ได้ (เช่น index ยังสร้างไม่เสร็จ)
+// ListProducts จะกลับไปใช้การค้นหาแบบ ILIKE แทน
+var ErrSearchUnavailable = errors.New("search is unavailable")
+
+// Searcher ค้นหาสินค้าด้วยข้อความ คืนสินค้าที่ตรงเรียงจากเกี่ยวข้องมากไปน้อย ไม่เกิน limit รายการ
+// ใช้ร่วมกับ ProductStore ผ่าน PostgresDatabase.SetSearcher เพื่อให้ ListProducts ค้นหาด้วยตัวนี้
+type Searcher interface {
+	Search(ctx context.Context, text string, limit int) ([]SearchMatch, error)
+}
+
+// SearchMatch คือสินค้าหนึ่งชิ้นที่ตรงกับคำค้น พร้อมคะแนนความเกี่ยวข้องและข้อความที่ไฮไลต์คำที่ตรง
+type SearchMatch struct {
+	ProductID  int
+	Score      float64
+	Highlights []Highlight
+}
+
+// Highlight คือข้อความบางส่วนของ field ที่ตรงกับคำค้น คำที่ตรงครอบด้วย <mark></mark>
+// และข้อความส่วนอื่น escape HTML แล้ว จึงแสดงเป็น HTML ได้เลย
+type Highlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// SetSearcher กำหนด Searcher ที่ ListProducts ใช้ค้นหาเมื่อมีคำค้น (nil คือใช้ ILIKE)
+// ต้องเรียกก่อนเริ่มรับ request
+func (pdb *PostgresDatabase) SetSearcher(searcher Searcher) {
+	pdb.searcher = searcher
+}
+
+// SearchIndexer คือ Searcher ที่อัพเดต index ทีละสินค้าได้ ถ้า Searcher ที่กำหนดผ่าน SetSearcher เป็น SearchIndexer ด้วย
+// PostgresDatabase จะแจ้งทุกครั้งที่เพิ่ม แก้ไข หรือลบสินค้าสำเร็จ ผลการค้นหาจึงเห็นการเปลี่ยนแปลงทันที
+type SearchIndexer interface {
+	IndexProduct(product Clothes)
+	RemoveProduct(productID int)
+}
+
+// indexProduct แจ้ง SearchIndexer (ถ้ามี) ว่าสินค้าถูกเพิ่มหรือแก้ไข
+func (pdb *PostgresDatabase) indexProduct(product Clothes) {
+	if indexer, ok := pdb.searcher.(SearchIndexer); ok {
+		indexer.IndexProduct(product)
+	}
+}
+
+// unindexProduct แจ้ง SearchIndexer (ถ้ามี) ว่าสินค้าถูกลบ
+func (pdb *PostgresDatabase) unindexProduct(productID int) {
+	if indexer, ok := pdb.searcher.(SearchIndexer); ok {
+		indexer.RemoveProduct(productID)
+	}
+}
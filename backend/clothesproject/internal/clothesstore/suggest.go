@@ -0,0 +1,127 @@
+package clothesstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultSuggestLimit คือจำนวนคำแนะนำเริ่มต้นต่อการพิมพ์หนึ่งครั้ง
+	DefaultSuggestLimit = 8
+	// MaxSuggestLimit คือจำนวนคำแนะนำสูงสุดที่ขอได้
+	MaxSuggestLimit = 20
+)
+
+// ประเภทของคำแนะนำ
+const (
+	SuggestionProduct = "product"
+	SuggestionBrand   = "brand"
+)
+
+// Suggestion คือคำแนะนำหนึ่งรายการ (ชื่อสินค้าหรือชื่อแบรนด์) พร้อมความนิยม
+// ID เป็นรหัสสินค้าที่ขายดีที่สุดในชื่อนั้น หรือรหัสแบรนด์ ตาม Type
+type Suggestion struct {
+	Text       string `json:"text"`
+	Type       string `json:"type"`
+	ID         int    `json:"id"`
+	Popularity int    `json:"popularity"`
+}
+
+// Suggestions คือผลการเติมคำค้นอัตโนมัติ DidYouMean เป็นคำค้นที่แก้คำสะกดผิดแล้ว (ว่างถ้าไม่มีคำที่ต้องแก้)
+type Suggestions struct {
+	Query       string       `json:"query"`
+	Suggestions []Suggestion `json:"suggestions"`
+	DidYouMean  string       `json:"did_you_mean,omitempty"`
+}
+
+// Suggester เติมคำค้นจากข้อความที่ผู้ใช้กำลังพิมพ์ ทนต่อการพิมพ์ผิด และเรียงตามความนิยม
+// ถ้า Searcher ที่กำหนดผ่าน SetSearcher เป็น Suggester ด้วย SuggestProducts จะใช้ตัวนั้น
+type Suggester interface {
+	Suggest(ctx context.Context, text string, limit int) (Suggestions, error)
+}
+
+// suggestionTermsQuery ดึงชื่อสินค้า (รวมชื่อซ้ำเป็นรายการเดียว) และชื่อแบรนด์ทั้งหมด
+// ความนิยมคือจำนวนชิ้นที่ขายได้จากคำสั่งซื้อที่ไม่ถูกยกเลิก คืนเงิน หรือไม่มารับ
+const suggestionTermsQuery = `
+    WITH sold AS (
+        SELECT oi.product_id, oi.brand_id, SUM(oi.quantity) AS quantity
+        FROM order_items oi
+        JOIN orders o ON o.order_id = oi.order_id
+        WHERE o.status NOT IN ('cancelled', 'refunded', 'expired')
+        GROUP BY oi.product_id, oi.brand_id
+    ), product_sales AS (
+        SELECT product_id, SUM(quantity) AS quantity FROM sold GROUP BY product_id
+    )
+    SELECT 'product' AS type, (array_agg(p.id ORDER BY COALESCE(ps.quantity, 0) DESC, p.id))[1] AS id,
+           p.name AS text, COALESCE(SUM(ps.quantity), 0)::int AS popularity
+    FROM products p
+    LEFT JOIN product_sales ps ON ps.product_id = p.id
+    WHERE p.name <> ''
+    GROUP BY p.name
+    UNION ALL
+    SELECT 'brand', b.id, b.brandname, COALESCE(SUM(s.quantity), 0)::int
+    FROM brand b
+    LEFT JOIN sold s ON s.brand_id = b.id
+    WHERE COALESCE(b.brandname, '') <> ''
+    GROUP BY b.id, b.brandname
+`
+
+// ListSuggestionTerms ดึงชื่อสินค้าและชื่อแบรนด์ทั้งหมดพร้อมความนิยม สำหรับสร้างตัวเติมคำค้น
+func (pdb *PostgresDatabase) ListSuggestionTerms(ctx context.Context) ([]Suggestion, error) {
+	rows, err := pdb.db.QueryContext(ctx, suggestionTermsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suggestion terms: %v", err)
+	}
+	return scanSuggestions(rows)
+}
+
+// SuggestProducts เติมคำค้นด้วย Suggester ถ้ามี ไม่เช่นนั้น (หรือ Suggester ยังไม่พร้อม)
+// ใช้ชื่อที่ขึ้นต้นด้วยข้อความที่พิมพ์จากฐานข้อมูลแทน ซึ่งไม่ทนต่อการพิมพ์ผิด
+func (pdb *PostgresDatabase) SuggestProducts(ctx context.Context, text string, limit int) (Suggestions, error) {
+	if limit <= 0 {
+		limit = DefaultSuggestLimit
+	}
+	limit = min(limit, MaxSuggestLimit)
+
+	if suggester, ok := pdb.searcher.(Suggester); ok {
+		result, err := suggester.Suggest(ctx, text, limit)
+		if !errors.Is(err, ErrSearchUnavailable) {
+			return result, err
+		}
+	}
+
+	rows, err := pdb.db.QueryContext(ctx, `
+        SELECT type, id, text, popularity
+        FROM (`+suggestionTermsQuery+`) t
+        WHERE text ILIKE $1 || '%' ESCAPE '\' OR text ILIKE '% ' || $1 || '%' ESCAPE '\'
+        ORDER BY popularity DESC, length(text), text
+        LIMIT $2
+    `, escapeLike(text), limit)
+	if err != nil {
+		return Suggestions{}, fmt.Errorf("failed to suggest products: %v", err)
+	}
+	suggestions, err := scanSuggestions(rows)
+	if err != nil {
+		return Suggestions{}, err
+	}
+	return Suggestions{Query: text, Suggestions: suggestions}, nil
+}
+
+func scanSuggestions(rows *sql.Rows) ([]Suggestion, error) {
+	defer rows.Close()
+
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		var s Suggestion
+		if err := rows.Scan(&s.Type, &s.ID, &s.Text, &s.Popularity); err != nil {
+			return nil, fmt.Errorf("failed to scan suggestion: %v", err)
+		}
+		suggestions = append(suggestions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
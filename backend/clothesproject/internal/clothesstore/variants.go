@@ -0,0 +1,150 @@
+package clothesstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"clothesproject/internal/currency"
+	"clothesproject/internal/money"
+)
+
+// ErrVariantNotFound คืนค่าเมื่อไม่พบ SKU ที่ระบุ
+var ErrVariantNotFound = errors.New("variant not found")
+
+// Variant คือ SKU ของสินค้าแต่ละไซซ์/สี
+// Price เป็น nil เมื่อใช้ราคาเดียวกับสินค้าหลัก และ ImgSrc ว่างเมื่อใช้รูปของสินค้าหลัก
+type Variant struct {
+	ID        int          `json:"id"`
+	ProductID int          `json:"product_id"`
+	SKU       string       `json:"sku"`
+	Size      string       `json:"size"`
+	Colour    string       `json:"colour"`
+	Price     *money.Money `json:"price,omitempty"`
+	// DisplayPrice คือราคาของ SKU ที่กำหนดราคาเอง ในสกุลเงินที่ผู้ใช้เลือก
+	DisplayPrice *currency.Price `json:"display_price,omitempty"`
+	Barcode      string          `json:"barcode,omitempty"`
+	ImgSrc       string          `json:"imgsrc,omitempty"`
+	Available    int             `json:"available"`
+}
+
+// GetVariantsByProduct ดึง SKU ทั้งหมดของสินค้า
+func (pdb *PostgresDatabase) GetVariantsByProduct(ctx context.Context, productID int) ([]Variant, error) {
+	rows, err := pdb.db.QueryContext(ctx, `
+        SELECT v.variant_id, v.product_id, v.sku, v.size, v.colour, v.price, COALESCE(v.barcode, ''), COALESCE(v.imgsrc, ''),
+               GREATEST(COALESCE(i.on_hand, 0) - COALESCE((SELECT SUM(r.quantity) FROM stock_reservations r
+                                                          WHERE r.variant_id = v.variant_id AND r.expires_at > NOW()), 0), 0)
+        FROM product_variants v
+        LEFT JOIN inventory i ON i.variant_id = v.variant_id
+        WHERE v.product_id = $1
+        ORDER BY v.variant_id
+    `, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch variants: %v", err)
+	}
+	defer rows.Close()
+
+	var variants []Variant
+	for rows.Next() {
+		variant, err := scanVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// GetVariant ดึงข้อมูล SKU ตาม ID
+func (pdb *PostgresDatabase) GetVariant(ctx context.Context, variantID int) (Variant, error) {
+	row := pdb.db.QueryRowContext(ctx, `
+        SELECT v.variant_id, v.product_id, v.sku, v.size, v.colour, v.price, COALESCE(v.barcode, ''), COALESCE(v.imgsrc, ''),
+               GREATEST(COALESCE(i.on_hand, 0) - COALESCE((SELECT SUM(r.quantity) FROM stock_reservations r
+                                                          WHERE r.variant_id = v.variant_id AND r.expires_at > NOW()), 0), 0)
+        FROM product_variants v
+        LEFT JOIN inventory i ON i.variant_id = v.variant_id
+        WHERE v.variant_id = $1
+    `, variantID)
+	variant, err := scanVariant(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Variant{}, ErrVariantNotFound
+		}
+		return Variant{}, err
+	}
+	return variant, nil
+}
+
+// AddVariant เพิ่ม SKU ใหม่ให้สินค้า
+func (pdb *PostgresDatabase) AddVariant(ctx context.Context, actor Actor, variant Variant) (Variant, error) {
+	brandID, err := productBrand(ctx, pdb.db, variant.ProductID, false)
+	if err != nil {
+		return Variant{}, err
+	}
+	if err := authorizeBrand(ctx, pdb.db, actor, brandID); err != nil {
+		return Variant{}, err
+	}
+	err = pdb.db.QueryRowContext(ctx, `
+        INSERT INTO product_variants (product_id, sku, size, colour, price, barcode, imgsrc)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''))
+        RETURNING variant_id
+    `, variant.ProductID, variant.SKU, variant.Size, variant.Colour, variant.Price, variant.Barcode, variant.ImgSrc).Scan(&variant.ID)
+	if err != nil {
+		return Variant{}, fmt.Errorf("failed to add variant: %v", err)
+	}
+	return variant, nil
+}
+
+// UpdateVariant อัพเดตข้อมูล SKU
+func (pdb *PostgresDatabase) UpdateVariant(ctx context.Context, actor Actor, variant Variant) error {
+	if err := authorizeVariant(ctx, pdb.db, actor, variant.ID); err != nil {
+		return err
+	}
+	result, err := pdb.db.ExecContext(ctx, `
+        UPDATE product_variants
+        SET sku = $1, size = $2, colour = $3, price = $4, barcode = NULLIF($5, ''), imgsrc = NULLIF($6, '')
+        WHERE variant_id = $7
+    `, variant.SKU, variant.Size, variant.Colour, variant.Price, variant.Barcode, variant.ImgSrc, variant.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update variant: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrVariantNotFound
+	}
+	return nil
+}
+
+// DeleteVariant ลบ SKU
+func (pdb *PostgresDatabase) DeleteVariant(ctx context.Context, actor Actor, variantID int) error {
+	if err := authorizeVariant(ctx, pdb.db, actor, variantID); err != nil {
+		return err
+	}
+	result, err := pdb.db.ExecContext(ctx, "DELETE FROM product_variants WHERE variant_id = $1", variantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete variant: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrVariantNotFound
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVariant(row rowScanner) (Variant, error) {
+	var variant Variant
+	if err := row.Scan(&variant.ID, &variant.ProductID, &variant.SKU, &variant.Size, &variant.Colour, &variant.Price, &variant.Barcode, &variant.ImgSrc, &variant.Available); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Variant{}, err
+		}
+		return Variant{}, fmt.Errorf("failed to scan variant: %v", err)
+	}
+	return variant, nil
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/spf13/viper"
 )
 
+// minJWTSecretLength คือความยาวขั้นต่ำ (ไบต์) ของ JWT.SECRET สำหรับ HS256 ต้องเป็นค่าเดียวกับ JWT_SECRET ของ testlogin
+const minJWTSecretLength = 32
+
 type Config struct {
 	AppPort          string
 	DatabaseHost     string
@@ -17,6 +20,17 @@ type Config struct {
 	DatabasePassword string
 	DatabaseName     string
 	DatabaseSSLMode  string
+	JWTSecret        string
+	// ExchangeRatesFile คือไฟล์ JSON อัตราแลกเปลี่ยน เช่น {"USD": 35.5} ถ้าไม่ระบุจะใช้อัตราในฐานข้อมูล
+	ExchangeRatesFile string
+	// PromptPayID คือบัญชีพร้อมเพย์ที่รับเงิน PromptPaySecret ใช้ตรวจลายเซ็น callback จากธนาคาร
+	PromptPayID     string
+	PromptPaySecret string
+	// FakeCardPayments เปิดผู้ให้บริการบัตรจำลองสำหรับทดสอบในเครื่อง (ห้ามเปิดบน production)
+	FakeCardPayments bool
+	FakeCardSecret   string
+	// UploadDir คือโฟลเดอร์ที่เก็บไฟล์ที่ผู้ใช้อัปโหลด (เช่น รูปสินค้าที่ขอคืน) ไม่เปิดเป็นไฟล์สาธารณะ ส่งผ่าน handler ที่ตรวจสิทธิ์
+	UploadDir string
 }
 
 func LoadConfig() (Config, error) {
@@ -31,16 +45,29 @@ func LoadConfig() (Config, error) {
 	viper.SetDefault("POSTGRES.PASSWORD", "")
 	viper.SetDefault("POSTGRES.DBNAME", "bookstore")
 	viper.SetDefault("POSTGRES.SSLMODE", "disable")
+	viper.SetDefault("UPLOADS.DIR", "uploads")
 
 	// Set config values
 	config := Config{
-		AppPort:          viper.GetString("APP.PORT"),
-		DatabaseHost:     viper.GetString("POSTGRES.HOST"),
-		DatabasePort:     viper.GetInt("POSTGRES.PORT"),
-		DatabaseUser:     viper.GetString("POSTGRES.USER"),
-		DatabasePassword: viper.GetString("POSTGRES.PASSWORD"),
-		DatabaseName:     viper.GetString("POSTGRES.DBNAME"),
-		DatabaseSSLMode:  viper.GetString("POSTGRES.SSLMODE"),
+		AppPort:           viper.GetString("APP.PORT"),
+		DatabaseHost:      viper.GetString("POSTGRES.HOST"),
+		DatabasePort:      viper.GetInt("POSTGRES.PORT"),
+		DatabaseUser:      viper.GetString("POSTGRES.USER"),
+		DatabasePassword:  viper.GetString("POSTGRES.PASSWORD"),
+		DatabaseName:      viper.GetString("POSTGRES.DBNAME"),
+		DatabaseSSLMode:   viper.GetString("POSTGRES.SSLMODE"),
+		JWTSecret:         viper.GetString("JWT.SECRET"),
+		ExchangeRatesFile: viper.GetString("EXCHANGE_RATES.FILE"),
+		PromptPayID:       viper.GetString("PAYMENTS.PROMPTPAY_ID"),
+		PromptPaySecret:   viper.GetString("PAYMENTS.PROMPTPAY_SECRET"),
+		FakeCardPayments:  viper.GetBool("PAYMENTS.FAKE_CARD"),
+		FakeCardSecret:    viper.GetString("PAYMENTS.FAKE_CARD_SECRET"),
+		UploadDir:         viper.GetString("UPLOADS.DIR"),
+	}
+
+	// secret ว่างหรือสั้นเกินไปทำให้ใครก็ปลอม token ได้ จึงไม่ยอมให้เซิร์ฟเวอร์เริ่มทำงาน
+	if len(config.JWTSecret) < minJWTSecretLength {
+		return Config{}, fmt.Errorf("JWT.SECRET must be at least %d bytes", minJWTSecretLength)
 	}
 
 	return config, nil
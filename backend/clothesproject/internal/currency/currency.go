@@ -0,0 +1,95 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"clothesproject/internal/money"
+	"clothesproject/internal/pricing"
+)
+
+var (
+	// ErrUnsupportedCurrency คืนค่าเมื่อขอสกุลเงินที่ไม่มีอัตราแลกเปลี่ยน
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	// ErrReadOnlyRates คืนค่าเมื่อพยายามแก้ไขอัตราแลกเปลี่ยนที่อ่านมาจากไฟล์
+	ErrReadOnlyRates = errors.New("exchange rates are loaded from a file and cannot be changed")
+)
+
+// ExchangeRate คืออัตราแลกเปลี่ยนของสกุลเงินหนึ่ง ณ เวลาที่มีผล (EffectiveAt)
+// ID เป็น 0 เมื่ออัตรามาจากไฟล์ ราคาทั้งหมดในระบบเป็นบาท อัตรานี้ใช้แปลงเพื่อแสดงผลเท่านั้น
+type ExchangeRate struct {
+	ID          int            `json:"id,omitempty"`
+	Currency    money.Currency `json:"currency"`
+	Rate        money.Rate     `json:"thb_per_unit"`
+	EffectiveAt time.Time      `json:"effective_at"`
+	ChangedBy   string         `json:"changed_by,omitempty"`
+}
+
+// Source คือแหล่งอัตราแลกเปลี่ยน (ตารางที่ผู้ดูแลระบบจัดการ หรือไฟล์)
+type Source interface {
+	ListRates(ctx context.Context) ([]ExchangeRate, error)
+	GetRate(ctx context.Context, currency money.Currency) (ExchangeRate, error)
+	RateHistory(ctx context.Context, currency money.Currency) ([]ExchangeRate, error)
+	SetRate(ctx context.Context, currency money.Currency, rate money.Rate, changedBy string) (ExchangeRate, error)
+}
+
+// Parse แปลงรหัสสกุลเงินจาก query parameter หรือ header Accept-Currency เช่น "usd" หรือ "USD, EUR;q=0.5"
+// (ใช้สกุลเงินแรกที่ระบุ) ค่าว่างคือบาท
+func Parse(raw string) (money.Currency, error) {
+	first, _, _ := strings.Cut(raw, ",")
+	first, _, _ = strings.Cut(first, ";")
+	code := money.Currency(strings.ToUpper(strings.TrimSpace(first)))
+	if code == "" {
+		return money.THB, nil
+	}
+	if !code.IsValid() {
+		return "", ErrUnsupportedCurrency
+	}
+	return code, nil
+}
+
+// Price คือราคาที่แปลงเป็นสกุลเงินที่ผู้ใช้เลือก
+type Price struct {
+	Amount   money.Money    `json:"amount"`
+	Currency money.Currency `json:"currency"`
+}
+
+// Price แปลงเงินบาท m เป็นสกุลเงินของอัตรานี้
+func (r ExchangeRate) Price(m money.Money) *Price {
+	return &Price{Amount: r.Rate.FromTHB(m, r.Currency), Currency: r.Currency}
+}
+
+// Totals คือยอดรวมที่แปลงเป็นสกุลเงินที่ผู้ใช้เลือก พร้อมอัตราที่ใช้ (ยอดที่ต้องชำระจริงยังเป็นบาท)
+// แต่ละยอดแปลงแยกกันจากยอดบาท จึงอาจต่างจากผลรวมของยอดที่แปลงแล้วได้เล็กน้อยจากการปัดเศษ
+type Totals struct {
+	Currency      money.Currency `json:"currency"`
+	Rate          money.Rate     `json:"thb_per_unit"`
+	RateID        int            `json:"rate_id,omitempty"`
+	Subtotal      money.Money    `json:"subtotal"`
+	DiscountTotal money.Money    `json:"discount_total"`
+	ShippingFee   money.Money    `json:"shipping_fee"`
+	VAT           money.Money    `json:"vat"`
+	Total         money.Money    `json:"total"`
+}
+
+// Totals แปลงยอดรวมบาทเป็นสกุลเงินของอัตรานี้
+func (r ExchangeRate) Totals(t pricing.Totals) *Totals {
+	convert := func(m money.Money) money.Money { return r.Rate.FromTHB(m, r.Currency) }
+	return &Totals{
+		Currency:      r.Currency,
+		Rate:          r.Rate,
+		RateID:        r.ID,
+		Subtotal:      convert(t.Subtotal),
+		DiscountTotal: convert(t.DiscountTotal),
+		ShippingFee:   convert(t.ShippingFee),
+		VAT:           convert(t.VAT),
+		Total:         convert(t.Total),
+	}
+}
+
+// ExchangeRate คืนอัตราที่ใช้แปลงยอดรวมนี้ เพื่อแปลงยอดอื่นของคำสั่งซื้อเดียวกันด้วยอัตราเดิม
+func (t Totals) ExchangeRate() ExchangeRate {
+	return ExchangeRate{ID: t.RateID, Currency: t.Currency, Rate: t.Rate}
+}
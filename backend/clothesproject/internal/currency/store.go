@@ -0,0 +1,165 @@
+package currency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"clothesproject/internal/money"
+)
+
+// PostgresSource อ่านอัตราแลกเปลี่ยนจากตาราง exchange_rates การเปลี่ยนอัตราเพิ่มแถวใหม่แทนการแก้ไข
+// อัตราเดิมจึงยังอยู่สำหรับคำสั่งซื้อที่อ้างถึง
+type PostgresSource struct {
+	// db คืน pool ปัจจุบันของฐานข้อมูล (เรียกทุกครั้งที่ query เพราะ pool ถูกสร้างใหม่เมื่อ reconnect)
+	db func() *sql.DB
+}
+
+func NewPostgresSource(db func() *sql.DB) *PostgresSource {
+	return &PostgresSource{db: db}
+}
+
+const rateColumns = `rate_id, currency, thb_per_unit, created_at, COALESCE(created_by::text, '')`
+
+func scanRates(rows *sql.Rows) ([]ExchangeRate, error) {
+	defer rows.Close()
+	rates := []ExchangeRate{}
+	for rows.Next() {
+		var rate ExchangeRate
+		if err := rows.Scan(&rate.ID, &rate.Currency, &rate.Rate, &rate.EffectiveAt, &rate.ChangedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan exchange rate: %v", err)
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return rates, nil
+}
+
+// ListRates ดึงอัตราล่าสุดของทุกสกุลเงิน
+func (s *PostgresSource) ListRates(ctx context.Context) ([]ExchangeRate, error) {
+	rows, err := s.db().QueryContext(ctx, `
+        SELECT DISTINCT ON (currency) `+rateColumns+`
+        FROM exchange_rates
+        ORDER BY currency, created_at DESC, rate_id DESC
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange rates: %v", err)
+	}
+	return scanRates(rows)
+}
+
+// GetRate ดึงอัตราล่าสุดของสกุลเงิน
+func (s *PostgresSource) GetRate(ctx context.Context, currency money.Currency) (ExchangeRate, error) {
+	var rate ExchangeRate
+	err := s.db().QueryRowContext(ctx, `
+        SELECT `+rateColumns+`
+        FROM exchange_rates
+        WHERE currency = $1
+        ORDER BY created_at DESC, rate_id DESC
+        LIMIT 1
+    `, currency).Scan(&rate.ID, &rate.Currency, &rate.Rate, &rate.EffectiveAt, &rate.ChangedBy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ExchangeRate{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+		}
+		return ExchangeRate{}, fmt.Errorf("failed to get exchange rate: %v", err)
+	}
+	return rate, nil
+}
+
+// RateHistory ดึงอัตราทั้งหมดของสกุลเงิน เรียงจากล่าสุด
+func (s *PostgresSource) RateHistory(ctx context.Context, currency money.Currency) ([]ExchangeRate, error) {
+	rows, err := s.db().QueryContext(ctx, `
+        SELECT `+rateColumns+`
+        FROM exchange_rates
+        WHERE currency = $1
+        ORDER BY created_at DESC, rate_id DESC
+    `, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange rate history: %v", err)
+	}
+	return scanRates(rows)
+}
+
+// SetRate บันทึกอัตราใหม่ของสกุลเงิน changedBy คือผู้ดูแลระบบที่เปลี่ยน (ว่างได้)
+func (s *PostgresSource) SetRate(ctx context.Context, currency money.Currency, rate money.Rate, changedBy string) (ExchangeRate, error) {
+	if !currency.IsValid() || currency == money.THB {
+		return ExchangeRate{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+	result := ExchangeRate{Currency: currency, Rate: rate, ChangedBy: changedBy}
+	err := s.db().QueryRowContext(ctx, `
+        INSERT INTO exchange_rates (currency, thb_per_unit, created_by)
+        VALUES ($1, $2, NULLIF($3, '')::uuid)
+        RETURNING rate_id, created_at
+    `, currency, rate, changedBy).Scan(&result.ID, &result.EffectiveAt)
+	if err != nil {
+		return ExchangeRate{}, fmt.Errorf("failed to set exchange rate: %v", err)
+	}
+	return result, nil
+}
+
+// FileSource อ่านอัตราแลกเปลี่ยนจากไฟล์ JSON ตอนเริ่มระบบ รูปแบบ {"USD": 35.5, "JPY": "0.24"}
+// แก้ไขผ่าน API ไม่ได้ ต้องแก้ไฟล์แล้วเริ่มระบบใหม่ คำสั่งซื้อเก็บอัตราที่ใช้ไว้เองจึงไม่ขึ้นกับไฟล์
+type FileSource struct {
+	rates map[money.Currency]ExchangeRate
+}
+
+func NewFileSource(path string) (*FileSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates file: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates file: %v", err)
+	}
+	var raw map[string]money.Rate
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates file: %v", err)
+	}
+	rates := make(map[money.Currency]ExchangeRate, len(raw))
+	for code, rate := range raw {
+		currency := money.Currency(strings.ToUpper(code))
+		if !currency.IsValid() {
+			return nil, fmt.Errorf("invalid currency %q in exchange rates file", code)
+		}
+		rates[currency] = ExchangeRate{Currency: currency, Rate: rate, EffectiveAt: info.ModTime()}
+	}
+	return &FileSource{rates: rates}, nil
+}
+
+func (s *FileSource) ListRates(ctx context.Context) ([]ExchangeRate, error) {
+	rates := make([]ExchangeRate, 0, len(s.rates))
+	for _, rate := range s.rates {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Currency < rates[j].Currency })
+	return rates, nil
+}
+
+func (s *FileSource) GetRate(ctx context.Context, currency money.Currency) (ExchangeRate, error) {
+	rate, ok := s.rates[currency]
+	if !ok {
+		return ExchangeRate{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+	return rate, nil
+}
+
+func (s *FileSource) RateHistory(ctx context.Context, currency money.Currency) ([]ExchangeRate, error) {
+	rate, err := s.GetRate(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+	return []ExchangeRate{rate}, nil
+}
+
+func (s *FileSource) SetRate(ctx context.Context, currency money.Currency, rate money.Rate, changedBy string) (ExchangeRate, error) {
+	return ExchangeRate{}, ErrReadOnlyRates
+}
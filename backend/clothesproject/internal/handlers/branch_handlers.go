@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"clothesproject/internal/clothesstore"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddBranch เพิ่มสาขาของแบรนด์ ผู้ขายเพิ่มได้เฉพาะสาขาของแบรนด์ที่ตัวเองดูแล
+func (h *ClothesHandlers) AddBranch(c *gin.Context) {
+	var branch clothesstore.Branch
+	if err := c.ShouldBindJSON(&branch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if branch.BrandID <= 0 || branch.Banch == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "brand_id and banch are required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	branch, err := h.Store.AddBranch(ctx, storeActor(c), branch)
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, branch)
+}
+
+// UpdateBranch อัพเดตข้อมูลสาขา
+func (h *ClothesHandlers) UpdateBranch(c *gin.Context) {
+	branchID, err := strconv.Atoi(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch ID"})
+		return
+	}
+	var branch clothesstore.Branch
+	if err := c.ShouldBindJSON(&branch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if branch.BrandID <= 0 || branch.Banch == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "brand_id and banch are required"})
+		return
+	}
+	branch.ID = branchID
+
+	ctx := c.Request.Context()
+	if err := h.Store.UpdateBranch(ctx, storeActor(c), branch); err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, branch)
+}
+
+// DeleteBranch ลบสาขา
+func (h *ClothesHandlers) DeleteBranch(c *gin.Context) {
+	branchID, err := strconv.Atoi(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.Store.DeleteBranch(ctx, storeActor(c), branchID); err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Branch deleted"})
+}
+
+// SetAboutPage สร้างหรือแทนที่หน้า about ของแบรนด์
+func (h *ClothesHandlers) SetAboutPage(c *gin.Context) {
+	brandID, err := strconv.Atoi(c.Param("brand_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		return
+	}
+	var about clothesstore.AboutPage
+	if err := c.ShouldBindJSON(&about); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if about.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		return
+	}
+	about.Brand_id = brandID
+
+	ctx := c.Request.Context()
+	about, err = h.Store.SetAboutPage(ctx, storeActor(c), about)
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, about)
+}
+
+// DeleteAboutPage ลบหน้า about ของแบรนด์
+func (h *ClothesHandlers) DeleteAboutPage(c *gin.Context) {
+	brandID, err := strconv.Atoi(c.Param("brand_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.Store.DeleteAboutPage(ctx, storeActor(c), brandID); err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "About page deleted"})
+}
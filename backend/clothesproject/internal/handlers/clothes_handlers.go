@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"clothesproject/internal/clothesstore"
+	"clothesproject/internal/currency"
+	"clothesproject/internal/middleware"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -11,20 +14,17 @@ import (
 
 type ClothesHandlers struct {
 	Store clothesstore.ProductStore // ใช้ interface โดยตรง
+	Rates currency.Source           // อัตราแลกเปลี่ยนสำหรับแสดงราคาเป็นสกุลเงินอื่น
 }
 
-func NewClothesHandlers(store clothesstore.ProductStore) *ClothesHandlers {
-	return &ClothesHandlers{Store: store}
+func NewClothesHandlers(store clothesstore.ProductStore, rates currency.Source) *ClothesHandlers {
+	return &ClothesHandlers{Store: store, Rates: rates}
 }
 
+// GetAllProducts ดึงรายการสินค้าแบบแบ่งหน้า กรองและเรียงลำดับตาม query parameter
+// (q, category, brand, min_price, max_price, isnew, sort, limit, cursor, facets)
 func (h *ClothesHandlers) GetAllProducts(c *gin.Context) {
-	ctx := c.Request.Context()
-	products, err := h.Store.GetAllProducts(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, products)
+	h.listProducts(c, clothesstore.ProductFilter{}, false)
 }
 
 func (h *ClothesHandlers) AddProduct(c *gin.Context) {
@@ -34,8 +34,8 @@ func (h *ClothesHandlers) AddProduct(c *gin.Context) {
 		return
 	}
 	ctx := c.Request.Context()
-	if err := h.Store.AddProduct(ctx, product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.Store.AddProduct(ctx, storeActor(c), product); err != nil {
+		respondCatalogError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, product)
@@ -48,8 +48,8 @@ func (h *ClothesHandlers) DeleteProduct(c *gin.Context) {
 		return
 	}
 	ctx := c.Request.Context()
-	if err := h.Store.DeleteProduct(ctx, id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.Store.DeleteProduct(ctx, storeActor(c), id); err != nil {
+		respondCatalogError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Product deleted"})
@@ -68,8 +68,8 @@ func (h *ClothesHandlers) UpdateProduct(c *gin.Context) {
 	}
 	ctx := c.Request.Context()
 	product.ID = id
-	if err := h.Store.UpdateProduct(ctx, product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.Store.UpdateProduct(ctx, storeActor(c), product); err != nil {
+		respondCatalogError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, product)
@@ -81,12 +81,22 @@ func (h *ClothesHandlers) GetProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
+	rate, err := displayRate(c, h.Rates)
+	if err != nil {
+		respondCurrencyError(c, err)
+		return
+	}
 	ctx := c.Request.Context()
 	product, err := h.Store.GetProduct(ctx, id)
 	if err != nil {
+		if errors.Is(err, clothesstore.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	convertProducts(rate, []clothesstore.Clothes{product})
 	c.JSON(http.StatusOK, product)
 }
 
@@ -95,14 +105,7 @@ func (h *ClothesHandlers) HealthCheck(c *gin.Context) {
 }
 
 func (h *ClothesHandlers) GetProductByCategory(c *gin.Context) {
-	category := c.Param("category")
-	ctx := c.Request.Context()
-	products, err := h.Store.GetProductsByCategory(ctx, category)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, products)
+	h.listProducts(c, clothesstore.ProductFilter{Categories: []string{c.Param("category")}}, false)
 }
 
 // ฟังก์ชันสำหรับดึงข้อมูล About Page
@@ -115,6 +118,10 @@ func (h *ClothesHandlers) GetAboutPage(c *gin.Context) {
 	}
 	aboutPage, err := h.Store.GetAboutPageByBrandID(ctx, brandID)
 	if err != nil {
+		if errors.Is(err, clothesstore.ErrAboutPageNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -187,35 +194,33 @@ func (h *ClothesHandlers) GetAllBrands(c *gin.Context) {
 
 // Handler สำหรับค้นหาสินค้าตาม Brand
 func (h *ClothesHandlers) GetProductsByBrand(c *gin.Context) {
-	brandID := c.Param("brandID")
-	ctx := c.Request.Context()
-
-	// เรียกใช้ฟังก์ชัน GetProductsByBrand จาก Store เพื่อดึงข้อมูลสินค้าตาม Brand
-	products, err := h.Store.GetProductsByBrand(ctx, brandID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// ส่งผลลัพธ์กลับ
-	c.JSON(http.StatusOK, products)
+	h.listProducts(c, clothesstore.ProductFilter{BrandIDs: []string{c.Param("brandID")}}, false)
 }
 
 // เพิ่มฟังก์ชันสำหรับค้นหาสินค้าตามชื่อ
 func (h *ClothesHandlers) SearchProducts(c *gin.Context) {
-	// รับค่า query parameter ที่ชื่อ "name"
-	searchTerm := c.Query("name")
-	ctx := c.Request.Context()
+	// รับค่า query parameter ที่ชื่อ "name" ผลการค้นหามี facet สำหรับแถบตัวกรองเสมอ
+	h.listProducts(c, clothesstore.ProductFilter{Query: c.Query("name")}, true)
+}
 
-	// เรียกใช้ฟังก์ชัน SearchProductsByName จาก Store
-	products, err := h.Store.SearchProducts(ctx, searchTerm)
+func (h *ClothesHandlers) SuggestProducts(c *gin.Context) {
+	// รับข้อความที่ผู้ใช้กำลังพิมพ์จาก query parameter "q" และจำนวนคำแนะนำ "limit" (ถ้ามี)
+	limit := clothesstore.DefaultSuggestLimit
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid limit: %q", raw)})
+			return
+		}
+	}
+	ctx := c.Request.Context()
+	suggestions, err := h.Store.SuggestProducts(ctx, c.Query("q"), limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-
-	// ส่งผลลัพธ์เป็น JSON
-	c.JSON(http.StatusOK, products)
+	c.JSON(http.StatusOK, suggestions)
 }
 
 func (h *ClothesHandlers) AddBrand(c *gin.Context) {
@@ -233,7 +238,7 @@ func (h *ClothesHandlers) AddBrand(c *gin.Context) {
 }
 
 func (h *ClothesHandlers) DeleteBrand(c *gin.Context) {
-	brandID := c.Param("id") // ดึง id มาในรูปแบบ string โดยตรง
+	brandID := c.Param("brandID") // ดึง id มาในรูปแบบ string โดยตรง
 	ctx := c.Request.Context()
 	if err := h.Store.DeleteBrand(ctx, brandID); err != nil { // ส่งเป็น string
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -244,7 +249,7 @@ func (h *ClothesHandlers) DeleteBrand(c *gin.Context) {
 
 func (h *ClothesHandlers) UpdateBrand(c *gin.Context) {
 	var brand clothesstore.Brands
-	brandID := c.Param("id") // ดึง id มาในรูปแบบ string
+	brandID := c.Param("brandID") // ดึง id มาในรูปแบบ string
 	if err := c.ShouldBindJSON(&brand); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -258,27 +263,71 @@ func (h *ClothesHandlers) UpdateBrand(c *gin.Context) {
 	c.JSON(http.StatusOK, brand)
 }
 
-// GetAllCart คือ Handler สำหรับดึงข้อมูลสินค้าทั้งหมดในตะกร้า
+// cartOwner อ่านเจ้าของตะกร้าที่ CartOwnerMiddleware ระบุไว้ใน context
+func cartOwner(c *gin.Context) clothesstore.CartOwner {
+	return clothesstore.CartOwner{
+		UserID:     c.GetString("user_id"),
+		GuestToken: c.GetString("guest_token"),
+	}
+}
+
+// storeActor สร้าง clothesstore.Actor จากผู้ใช้ที่ AuthMiddleware ระบุไว้ใน context
+// store ใช้ตรวจสอบว่าผู้ใช้แก้ไขข้อมูลของแบรนด์ได้หรือไม่
+func storeActor(c *gin.Context) clothesstore.Actor {
+	return clothesstore.Actor{
+		UserID: c.GetString("user_id"),
+		Admin:  c.GetString("role") == middleware.RoleAdmin,
+	}
+}
+
+// respondCatalogError แปลง error จากการแก้ไขสินค้า สาขา และหน้า about เป็น HTTP status
+func respondCatalogError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, clothesstore.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, clothesstore.ErrProductNotFound),
+		errors.Is(err, clothesstore.ErrVariantNotFound),
+		errors.Is(err, clothesstore.ErrBranchNotFound),
+		errors.Is(err, clothesstore.ErrBrandNotFound),
+		errors.Is(err, clothesstore.ErrAboutPageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, clothesstore.ErrBranchInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetAllCart คือ Handler สำหรับดึงข้อมูลสินค้าทั้งหมดในตะกร้าของผู้ใช้หรือผู้เยี่ยมชม พร้อมส่วนลด
+// ส่ง query parameter "coupon" เพื่อดูส่วนลดของคูปอง และ "province" เพื่อดูค่าส่งก่อนสั่งซื้อ
+// ยอดในสกุลเงินอื่นเลือกด้วย "currency" หรือ header Accept-Currency
 func (h *ClothesHandlers) GetAllCart(c *gin.Context) {
+	rate, err := displayRate(c, h.Rates)
+	if err != nil {
+		respondCurrencyError(c, err)
+		return
+	}
 	ctx := c.Request.Context()
 
 	// ดึงข้อมูลตะกร้าจาก store
-	cartItems, err := h.Store.GetAllCart(ctx)
+	opts := clothesstore.CartOptions{Coupon: c.Query("coupon"), Province: c.Query("province")}
+	cart, err := h.Store.GetAllCart(ctx, cartOwner(c), opts)
 	if err != nil {
 		// หากเกิดข้อผิดพลาดในการดึงข้อมูล ส่งกลับ error 500
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// ส่งข้อมูลสินค้าทั้งหมดในตะกร้ากลับไปเป็น JSON
-	c.JSON(http.StatusOK, cartItems)
+	// ส่งข้อมูลตะกร้าและส่วนลดกลับไปเป็น JSON
+	convertCart(rate, &cart)
+	c.JSON(http.StatusOK, cart)
 }
 
-// AddProductToCart คือ Handler สำหรับการเพิ่มสินค้าไปยังตะกร้า
+// AddProductToCart คือ Handler สำหรับการเพิ่มสินค้าไปยังตะกร้าของผู้ใช้หรือผู้เยี่ยมชม
 func (h *ClothesHandlers) AddProductToCart(c *gin.Context) {
 	// อ่านข้อมูลจาก JSON body
 	var request struct {
-		ProductID int `json:"product_id"` // รหัสสินค้าที่จะเพิ่ม
+		VariantID int `json:"variant_id"` // รหัส SKU (ไซซ์/สี) ที่จะเพิ่ม
 		Quantity  int `json:"quantity"`   // จำนวนสินค้าที่จะเพิ่ม
 	}
 
@@ -296,8 +345,16 @@ func (h *ClothesHandlers) AddProductToCart(c *gin.Context) {
 
 	// เรียกฟังก์ชันเพิ่มสินค้าลงในตะกร้า
 	ctx := c.Request.Context()
-	err := h.Store.AddProductToCart(ctx, request.ProductID, request.Quantity)
+	err := h.Store.AddProductToCart(ctx, cartOwner(c), request.VariantID, request.Quantity)
 	if err != nil {
+		if errors.Is(err, clothesstore.ErrVariantNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, clothesstore.ErrOutOfStock) {
+			respondOutOfStock(c, err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -306,17 +363,19 @@ func (h *ClothesHandlers) AddProductToCart(c *gin.Context) {
 }
 
 func (h *ClothesHandlers) DeleteProductFromCart(c *gin.Context) {
-	// รับ cart_id จาก URL params
-	fmt.Println("Received cart_id:", c.Param("cart_id"))
-	cartID, err := strconv.Atoi(c.Param("cart_id"))
+	// รับ cartID จาก URL params
+	cartID, err := strconv.Atoi(c.Param("cartID"))
 	if err != nil {
-		fmt.Println("Error converting cart_id:", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cart ID"})
 		return
 	}
 
 	ctx := c.Request.Context()
-	if err := h.Store.DeleteProductFromCart(ctx, cartID); err != nil {
+	if err := h.Store.DeleteProductFromCart(ctx, cartOwner(c), cartID); err != nil {
+		if errors.Is(err, clothesstore.ErrCartItemNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"clothesproject/internal/clothesstore"
+	"clothesproject/internal/currency"
+	"clothesproject/internal/money"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AcceptCurrencyHeader คือ header ที่ใช้เลือกสกุลเงินสำหรับแสดงราคา (query parameter "currency" มาก่อน)
+const AcceptCurrencyHeader = "Accept-Currency"
+
+type CurrencyHandlers struct {
+	Rates currency.Source
+}
+
+func NewCurrencyHandlers(rates currency.Source) *CurrencyHandlers {
+	return &CurrencyHandlers{Rates: rates}
+}
+
+// displayRate คืนอัตราแลกเปลี่ยนของสกุลเงินที่ขอด้วย query parameter "currency" หรือ header Accept-Currency
+// คืน nil เมื่อขอเป็นบาทหรือไม่ได้ระบุ (ไม่ต้องแปลงราคา)
+func displayRate(c *gin.Context, rates currency.Source) (*currency.ExchangeRate, error) {
+	raw := c.Query("currency")
+	if raw == "" {
+		raw = c.GetHeader(AcceptCurrencyHeader)
+	}
+	code, err := currency.Parse(raw)
+	if err != nil || code == money.THB {
+		return nil, err
+	}
+	rate, err := rates.GetRate(c.Request.Context(), code)
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func respondCurrencyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, currency.ErrUnsupportedCurrency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, currency.ErrReadOnlyRates):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// convertProducts เติมราคาในสกุลเงินของ rate ให้สินค้าและ SKU ที่กำหนดราคาเอง
+func convertProducts(rate *currency.ExchangeRate, products []clothesstore.Clothes) {
+	if rate == nil {
+		return
+	}
+	for i := range products {
+		products[i].DisplayPrice = rate.Price(products[i].Price)
+		for j := range products[i].Variants {
+			if price := products[i].Variants[j].Price; price != nil {
+				products[i].Variants[j].DisplayPrice = rate.Price(*price)
+			}
+		}
+	}
+}
+
+// convertCart เติมราคาและยอดรวมในสกุลเงินของ rate ให้ตะกร้า
+func convertCart(rate *currency.ExchangeRate, cart *clothesstore.Cart) {
+	if rate == nil {
+		return
+	}
+	for i := range cart.Items {
+		cart.Items[i].DisplayUnitPrice = rate.Price(cart.Items[i].UnitPrice)
+		cart.Items[i].DisplayPrice = rate.Price(cart.Items[i].Price)
+	}
+	cart.Display = rate.Totals(cart.Totals)
+}
+
+// ListRates ดึงอัตราแลกเปลี่ยนปัจจุบันของทุกสกุลเงินที่แสดงราคาได้
+func (h *CurrencyHandlers) ListRates(c *gin.Context) {
+	ctx := c.Request.Context()
+	rates, err := h.Rates.ListRates(ctx)
+	if err != nil {
+		respondCurrencyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"base": money.THB, "rates": rates})
+}
+
+// GetRateHistory ดึงประวัติอัตราแลกเปลี่ยนของสกุลเงิน เรียงจากล่าสุด
+func (h *CurrencyHandlers) GetRateHistory(c *gin.Context) {
+	code, err := currency.Parse(c.Param("currency"))
+	if err != nil {
+		respondCurrencyError(c, err)
+		return
+	}
+	ctx := c.Request.Context()
+	history, err := h.Rates.RateHistory(ctx, code)
+	if err != nil {
+		respondCurrencyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// SetRate ตั้งอัตราแลกเปลี่ยนใหม่ของสกุลเงิน (บาทต่อเงิน 1 หน่วย) มีผลกับราคาที่แสดงทันที
+// คำสั่งซื้อเดิมยังใช้อัตราที่เก็บไว้ตอนสั่งซื้อ
+func (h *CurrencyHandlers) SetRate(c *gin.Context) {
+	code, err := currency.Parse(c.Param("currency"))
+	if err != nil {
+		respondCurrencyError(c, err)
+		return
+	}
+	var request struct {
+		Rate money.Rate `json:"thb_per_unit" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	rate, err := h.Rates.SetRate(ctx, code, request.Rate, c.GetString("user_id"))
+	if err != nil {
+		respondCurrencyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rate)
+}
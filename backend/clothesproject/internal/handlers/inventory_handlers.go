@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"clothesproject/internal/clothesstore"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondOutOfStock ส่ง 409 พร้อมรายละเอียดว่า SKU ใดสต็อกไม่พอ
+func respondOutOfStock(c *gin.Context, err error) {
+	var stockErr *clothesstore.OutOfStockError
+	if errors.As(err, &stockErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      clothesstore.ErrOutOfStock.Error(),
+			"variant_id": stockErr.VariantID,
+			"requested":  stockErr.Requested,
+			"available":  stockErr.Available,
+		})
+		return
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": clothesstore.ErrOutOfStock.Error()})
+}
+
+// GetStock ดึงระดับสต็อกของ SKU
+func (h *ClothesHandlers) GetStock(c *gin.Context) {
+	variantID, err := strconv.Atoi(c.Param("variantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	stock, err := h.Store.GetStock(ctx, variantID)
+	if err != nil {
+		if errors.Is(err, clothesstore.ErrVariantNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stock)
+}
+
+// SetStock กำหนดจำนวนสต็อกในคลังของ SKU
+func (h *ClothesHandlers) SetStock(c *gin.Context) {
+	variantID, err := strconv.Atoi(c.Param("variantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+	var request struct {
+		OnHand int `json:"on_hand"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.OnHand < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "on_hand must not be negative"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.Store.SetStock(ctx, storeActor(c), variantID, request.OnHand); err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+
+	stock, err := h.Store.GetStock(ctx, variantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stock)
+}
+
+// GetProductAvailability ดึงสาขาที่มีสินค้าในสต็อก แยกตามไซซ์/สี (กรองจังหวัดด้วย ?province=)
+func (h *ClothesHandlers) GetProductAvailability(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	availability, err := h.Store.GetProductAvailability(ctx, productID, c.Query("province"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, availability)
+}
+
+// SetBranchStock กำหนดจำนวนสต็อกของ SKU ที่สาขา
+func (h *ClothesHandlers) SetBranchStock(c *gin.Context) {
+	branchID, err := strconv.Atoi(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branch ID"})
+		return
+	}
+	variantID, err := strconv.Atoi(c.Param("variantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+	var request struct {
+		Quantity int `json:"quantity"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Quantity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must not be negative"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.Store.SetBranchStock(ctx, storeActor(c), branchID, variantID, request.Quantity); err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"branch_id": branchID, "variant_id": variantID, "quantity": request.Quantity})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"clothesproject/internal/invoices"
+	"clothesproject/internal/orders"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvoiceHandlers struct {
+	Store invoices.Store
+}
+
+func NewInvoiceHandlers(store invoices.Store) *InvoiceHandlers {
+	return &InvoiceHandlers{Store: store}
+}
+
+// GetInvoicePDF ดาวน์โหลดใบเสร็จรับเงิน/ใบกำกับภาษีของคำสั่งซื้อที่ชำระเงินแล้วเป็น PDF (หน้าละหนึ่งแบรนด์)
+// เลขที่เอกสารออกในครั้งแรกที่ดาวน์โหลด และดาวน์โหลดซ้ำได้เอกสารเดิม
+func (h *InvoiceHandlers) GetInvoicePDF(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	list, err := h.Store.IssueInvoices(ctx, c.GetString("user_id"), orderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, orders.ErrOrderNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, invoices.ErrNotInvoiceable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	pdf, err := invoices.Render(list)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="invoice-%d.pdf"`, orderID))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"clothesproject/internal/clothesstore"
+	"clothesproject/internal/currency"
+	"clothesproject/internal/orders"
+	"clothesproject/internal/promotions"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrderHandlers struct {
+	Store orders.OrderStore
+	Rates currency.Source
+}
+
+func NewOrderHandlers(store orders.OrderStore, rates currency.Source) *OrderHandlers {
+	return &OrderHandlers{Store: store, Rates: rates}
+}
+
+// CreateOrder สร้างคำสั่งซื้อจากสินค้าในตะกร้าของผู้ใช้ที่ล็อกอินอยู่
+// body เป็น optional: {"fulfilment": "pickup", "pickup_branch_id": 1} เพื่อรับที่สาขา ไม่ส่งมาถือว่าจัดส่ง
+// {"coupon": "SAVE10"} เพื่อใช้คูปอง และ {"tax_invoice": {"name", "tax_id", "address", "branch"}} เมื่อต้องการใบกำกับภาษีเต็มรูป
+// สกุลเงินที่แสดง (Accept-Currency) และอัตราแลกเปลี่ยนจะถูกเก็บไว้กับคำสั่งซื้อ
+func (h *OrderHandlers) CreateOrder(c *gin.Context) {
+	var request orders.CheckoutRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Fulfilment != "" && !request.Fulfilment.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fulfilment method"})
+		return
+	}
+	if request.Fulfilment == orders.FulfilmentPickup && request.PickupBranchID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pickup_branch_id is required for pickup"})
+		return
+	}
+	if request.Fulfilment != orders.FulfilmentPickup && strings.TrimSpace(request.Province) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "province is required for shipping"})
+		return
+	}
+	if request.TaxInvoice != nil && !orders.ValidTaxID(request.TaxInvoice.TaxID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tax_invoice.tax_id must be a valid 13-digit tax ID"})
+		return
+	}
+	rate, err := displayRate(c, h.Rates)
+	if err != nil {
+		respondCurrencyError(c, err)
+		return
+	}
+	request.Rate = rate
+
+	ctx := c.Request.Context()
+	order, err := h.Store.CreateOrder(ctx, c.GetString("user_id"), request)
+	if err != nil {
+		if errors.Is(err, orders.ErrEmptyCart) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, orders.ErrBranchNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, promotions.ErrInvalidCoupon) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, clothesstore.ErrOutOfStock) {
+			respondOutOfStock(c, err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, order)
+}
+
+// GetMyOrders ดึงคำสั่งซื้อทั้งหมดของผู้ใช้ที่ล็อกอินอยู่
+func (h *OrderHandlers) GetMyOrders(c *gin.Context) {
+	ctx := c.Request.Context()
+	userOrders, err := h.Store.GetOrdersByUser(ctx, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, userOrders)
+}
+
+// GetOrder ดึงคำสั่งซื้อตาม ID ของผู้ใช้ที่ล็อกอินอยู่
+func (h *OrderHandlers) GetOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	order, err := h.Store.GetOrder(ctx, c.GetString("user_id"), orderID)
+	if err != nil {
+		if errors.Is(err, orders.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// UpdateOrderStatus เปลี่ยนสถานะคำสั่งซื้อ (สำหรับพนักงาน) และบันทึกว่าใครเป็นผู้เปลี่ยน
+// ตั้งเป็น paid ไม่ได้ สถานะ paid มาจาก payment callback ที่ตรวจลายเซ็นแล้วเท่านั้น
+func (h *OrderHandlers) UpdateOrderStatus(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var request struct {
+		Status orders.Status `json:"status" binding:"required"`
+		Note   string        `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !request.Status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order status"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, err := h.Store.UpdateOrderStatus(ctx, orderID, request.Status, c.GetString("user_id"), request.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, orders.ErrOrderNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, orders.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// GetOrderHistory ดึงประวัติการเปลี่ยนสถานะของคำสั่งซื้อ (สำหรับพนักงาน)
+func (h *OrderHandlers) GetOrderHistory(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	history, err := h.Store.GetOrderHistory(ctx, orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// CollectOrder บันทึกว่าลูกค้ามารับสินค้าที่สาขาแล้ว (สำหรับพนักงาน) โดยตรวจรหัสรับสินค้า
+func (h *OrderHandlers) CollectOrder(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var request struct {
+		PickupCode string `json:"pickup_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, err := h.Store.CollectOrder(ctx, orderID, request.PickupCode, c.GetString("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, orders.ErrOrderNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, orders.ErrInvalidPickupCode):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, orders.ErrNotPickupOrder), errors.Is(err, orders.ErrPickupExpired), errors.Is(err, orders.ErrInvalidTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
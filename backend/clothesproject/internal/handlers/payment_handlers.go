@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"clothesproject/internal/orders"
+	"clothesproject/internal/payments"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentHandlers struct {
+	Store payments.Store
+}
+
+func NewPaymentHandlers(store payments.Store) *PaymentHandlers {
+	return &PaymentHandlers{Store: store}
+}
+
+func respondPaymentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, orders.ErrOrderNotFound), errors.Is(err, payments.ErrPaymentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, payments.ErrUnsupportedMethod), errors.Is(err, payments.ErrInvalidCard), errors.Is(err, payments.ErrInvalidCallback):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, payments.ErrInvalidSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, payments.ErrOrderNotPayable), errors.Is(err, payments.ErrPaymentFinalised):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, payments.ErrAmountMismatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// CreatePayment เริ่มชำระเงินคำสั่งซื้อของผู้ใช้ที่ล็อกอินอยู่
+// body: {"method": "promptpay"} ได้ qr_payload ไปสร้าง QR หรือ {"method": "card", "card": {...}}
+// คำสั่งซื้อจะเป็น paid เมื่อผู้ให้บริการแจ้งผลผ่าน callback แล้วเท่านั้น
+func (h *PaymentHandlers) CreatePayment(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	var request struct {
+		Method payments.Method `json:"method" binding:"required"`
+		Card   *payments.Card  `json:"card"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	payment, err := h.Store.StartPayment(ctx, c.GetString("user_id"), orderID, request.Method, request.Card)
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, payment)
+}
+
+// GetPayments ดึงการชำระเงินทั้งหมดของคำสั่งซื้อ
+func (h *PaymentHandlers) GetPayments(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	list, err := h.Store.GetPayments(ctx, c.GetString("user_id"), orderID)
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// PaymentCallback รับผลการชำระเงินจากผู้ให้บริการ (ไม่ต้องล็อกอิน แต่ต้องมีลายเซ็นใน X-Payment-Signature)
+func (h *PaymentHandlers) PaymentCallback(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	method := payments.Method(c.Param("method"))
+	payment, err := h.Store.HandleCallback(ctx, method, body, c.GetHeader(payments.SignatureHeader))
+	if err != nil {
+		if errors.Is(err, payments.ErrInvalidSignature) {
+			log.Printf("Rejected %s payment callback from %s: %v", method, c.ClientIP(), err)
+		}
+		respondPaymentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, payment)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"clothesproject/internal/clothesstore"
+	"clothesproject/internal/money"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listProducts ดึงสินค้าแบบแบ่งหน้าตาม query parameter โดยใช้ base เป็นเงื่อนไขตั้งต้นจาก path
+// (เช่น หมวดหมู่หรือแบรนด์) ซึ่ง query parameter จะเพิ่มเงื่อนไขได้แต่แทนที่ไม่ได้
+// withFacets บังคับให้นับ facet มาด้วยเสมอ ไม่เช่นนั้นจะนับเมื่อขอด้วย facets=true
+func (h *ClothesHandlers) listProducts(c *gin.Context, base clothesstore.ProductFilter, withFacets bool) {
+	query, err := parseProductQuery(c, base)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.WithFacets = query.WithFacets || withFacets
+	rate, err := displayRate(c, h.Rates)
+	if err != nil {
+		respondCurrencyError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	page, err := h.Store.ListProducts(ctx, query)
+	if err != nil {
+		if errors.Is(err, clothesstore.ErrInvalidCursor) || errors.Is(err, clothesstore.ErrInvalidSort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	convertProducts(rate, page.Items)
+	c.JSON(http.StatusOK, page)
+}
+
+func parseProductQuery(c *gin.Context, base clothesstore.ProductFilter) (clothesstore.ProductQuery, error) {
+	query := clothesstore.ProductQuery{
+		Filter: base,
+		Sort:   clothesstore.ProductSort(c.Query("sort")),
+		Cursor: c.Query("cursor"),
+	}
+	query.WithFacets, _ = strconv.ParseBool(c.Query("facets"))
+
+	if q := c.Query("q"); q != "" {
+		query.Filter.Query = q
+	}
+	if len(base.Categories) == 0 {
+		query.Filter.Categories = c.QueryArray("category")
+	}
+	if len(base.BrandIDs) == 0 {
+		query.Filter.BrandIDs = c.QueryArray("brand")
+	}
+
+	var err error
+	if query.Filter.MinPrice, err = optionalMoney(c, "min_price"); err != nil {
+		return clothesstore.ProductQuery{}, err
+	}
+	if query.Filter.MaxPrice, err = optionalMoney(c, "max_price"); err != nil {
+		return clothesstore.ProductQuery{}, err
+	}
+	if raw := c.Query("isnew"); raw != "" {
+		isNew, err := strconv.ParseBool(raw)
+		if err != nil {
+			return clothesstore.ProductQuery{}, fmt.Errorf("invalid isnew: %q", raw)
+		}
+		query.Filter.IsNew = &isNew
+	}
+	if raw := c.Query("limit"); raw != "" {
+		query.Limit, err = strconv.Atoi(raw)
+		if err != nil || query.Limit <= 0 {
+			return clothesstore.ProductQuery{}, fmt.Errorf("invalid limit: %q", raw)
+		}
+	}
+
+	return query, nil
+}
+
+func optionalMoney(c *gin.Context, key string) (*money.Money, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := money.Parse(raw)
+	if err != nil || value.IsNegative() {
+		return nil, fmt.Errorf("invalid %s: %q", key, raw)
+	}
+	return &value, nil
+}
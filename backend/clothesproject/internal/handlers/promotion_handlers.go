@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"clothesproject/internal/promotions"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromotionHandlers struct {
+	Store promotions.Store
+}
+
+func NewPromotionHandlers(store promotions.Store) *PromotionHandlers {
+	return &PromotionHandlers{Store: store}
+}
+
+// ListPromotions ดึงโปรโมชันและคูปองทั้งหมด (รวมที่ปิดไปแล้ว)
+func (h *PromotionHandlers) ListPromotions(c *gin.Context) {
+	ctx := c.Request.Context()
+	list, err := h.Store.ListPromotions(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetPromotion ดึงโปรโมชันตาม ID
+func (h *PromotionHandlers) GetPromotion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	promotion, err := h.Store.GetPromotion(ctx, id)
+	if err != nil {
+		respondPromotionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, promotion)
+}
+
+// CreatePromotion สร้างโปรโมชันใหม่ ไม่ระบุ code คือโปรโมชันอัตโนมัติ
+func (h *PromotionHandlers) CreatePromotion(c *gin.Context) {
+	var promotion promotions.Promotion
+	if err := c.ShouldBindJSON(&promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	promotion, err := h.Store.CreatePromotion(ctx, promotion)
+	if err != nil {
+		respondPromotionError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, promotion)
+}
+
+// UpdatePromotion แก้ไขโปรโมชัน (ปิดโปรโมชันด้วย "active": false)
+func (h *PromotionHandlers) UpdatePromotion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+	var promotion promotions.Promotion
+	if err := c.ShouldBindJSON(&promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	promotion.ID = id
+
+	ctx := c.Request.Context()
+	promotion, err = h.Store.UpdatePromotion(ctx, promotion)
+	if err != nil {
+		respondPromotionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, promotion)
+}
+
+func respondPromotionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, promotions.ErrPromotionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, promotions.ErrInvalidPromotion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, promotions.ErrDuplicateCode):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"clothesproject/internal/middleware"
+	"clothesproject/internal/orders"
+	"clothesproject/internal/returns"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ReturnPhotosPath คือ path ใต้ /uploads ที่เก็บรูปของคำขอคืนสินค้า
+	ReturnPhotosPath   = "returns"
+	maxReturnPhotoSize = 5 << 20 // 5 MB ต่อรูป
+)
+
+// returnPhotoTypes คือชนิดรูปที่รับ (ตรวจจากเนื้อไฟล์ ไม่เชื่อนามสกุลที่ส่งมา) และนามสกุลที่ใช้บันทึก
+var returnPhotoTypes = map[string]string{"image/jpeg": ".jpg", "image/png": ".png", "image/webp": ".webp"}
+
+type ReturnHandlers struct {
+	Store     returns.Store
+	UploadDir string
+}
+
+func NewReturnHandlers(store returns.Store, uploadDir string) *ReturnHandlers {
+	return &ReturnHandlers{Store: store, UploadDir: uploadDir}
+}
+
+func respondReturnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, orders.ErrOrderNotFound), errors.Is(err, returns.ErrReturnNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, returns.ErrInvalidReturn):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, returns.ErrNotReturnable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, returns.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// CreateReturn สร้างคำขอคืนสินค้าของรายการในคำสั่งซื้อของผู้ใช้ที่ล็อกอินอยู่
+// รับ multipart/form-data: order_item_id, quantity, reason และรูปในช่อง photos (ไม่เกิน 5 รูป รูปละไม่เกิน 5 MB)
+func (h *ReturnHandlers) CreateReturn(c *gin.Context) {
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	var form struct {
+		OrderItemID int                     `form:"order_item_id" binding:"required"`
+		Quantity    int                     `form:"quantity" binding:"required"`
+		Reason      string                  `form:"reason" binding:"required"`
+		Photos      []*multipart.FileHeader `form:"photos"`
+	}
+	if err := c.ShouldBind(&form); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(form.Photos) > returns.MaxPhotos {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d photos are allowed", returns.MaxPhotos)})
+		return
+	}
+
+	// บันทึกรูปก่อนสร้างคำขอ ถ้าสร้างไม่สำเร็จจะลบรูปทิ้ง
+	var saved []string
+	removeSaved := func() {
+		for _, path := range saved {
+			os.Remove(path)
+		}
+	}
+	photos := make([]string, 0, len(form.Photos))
+	for _, header := range form.Photos {
+		path, url, err := h.savePhoto(header)
+		if err != nil {
+			removeSaved()
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		saved = append(saved, path)
+		photos = append(photos, url)
+	}
+
+	ctx := c.Request.Context()
+	r, err := h.Store.CreateReturn(ctx, c.GetString("user_id"), orderID, returns.Request{
+		OrderItemID: form.OrderItemID,
+		Quantity:    form.Quantity,
+		Reason:      form.Reason,
+		Photos:      photos,
+	})
+	if err != nil {
+		removeSaved()
+		respondReturnError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, r)
+}
+
+// savePhoto ตรวจชนิดและขนาดของรูปแล้วบันทึกลง UploadDir/returns ด้วยชื่อสุ่ม
+// คืน path ของไฟล์และ URL ที่ให้บริการผ่าน GetReturnPhoto
+func (h *ReturnHandlers) savePhoto(header *multipart.FileHeader) (string, string, error) {
+	if header.Size > maxReturnPhotoSize {
+		return "", "", fmt.Errorf("photo %q is larger than 5 MB", header.Filename)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read photo %q: %v", header.Filename, err)
+	}
+	defer file.Close()
+
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", "", fmt.Errorf("failed to read photo %q: %v", header.Filename, err)
+	}
+	ext, ok := returnPhotoTypes[http.DetectContentType(sniff[:n])]
+	if !ok {
+		return "", "", fmt.Errorf("photo %q must be a JPEG, PNG or WebP image", header.Filename)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", "", fmt.Errorf("failed to read photo %q: %v", header.Filename, err)
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to name photo: %v", err)
+	}
+	name := hex.EncodeToString(b) + ext
+	dir := filepath.Join(h.UploadDir, ReturnPhotosPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("failed to create upload directory: %v", err)
+	}
+	path := filepath.Join(dir, name)
+	out, err := os.Create(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to save photo: %v", err)
+	}
+	if _, err := io.Copy(out, file); err != nil {
+		out.Close()
+		os.Remove(path)
+		return "", "", fmt.Errorf("failed to save photo: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(path)
+		return "", "", fmt.Errorf("failed to save photo: %v", err)
+	}
+	return path, "/uploads/" + ReturnPhotosPath + "/" + name, nil
+}
+
+// GetReturnPhoto ส่งไฟล์รูปของคำขอคืนสินค้า (/uploads/returns/:name) ให้เฉพาะลูกค้าเจ้าของคำขอ
+// หรือพนักงานที่มีสิทธิ์จัดการคำขอคืนสินค้า ผู้ใช้อื่นได้ 404 เหมือนไม่มีรูปนี้
+func (h *ReturnHandlers) GetReturnPhoto(c *gin.Context) {
+	name := c.Param("name")
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	r, err := h.Store.GetReturnByPhoto(ctx, "/uploads/"+ReturnPhotosPath+"/"+name)
+	if err != nil {
+		if errors.Is(err, returns.ErrReturnNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+			return
+		}
+		respondReturnError(c, err)
+		return
+	}
+	if r.UserID != c.GetString("user_id") && !middleware.HasPermission(c.GetString("role"), middleware.PermManageReturns) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.File(filepath.Join(h.UploadDir, ReturnPhotosPath, name))
+}
+
+// GetMyReturns ดึงคำขอคืนสินค้าทั้งหมดของผู้ใช้ที่ล็อกอินอยู่
+func (h *ReturnHandlers) GetMyReturns(c *gin.Context) {
+	ctx := c.Request.Context()
+	list, err := h.Store.GetReturnsByUser(ctx, c.GetString("user_id"))
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// ListReturns ดึงคำขอคืนสินค้าสำหรับพนักงาน กรองด้วย query parameter "status" ได้
+func (h *ReturnHandlers) ListReturns(c *gin.Context) {
+	status := returns.Status(c.Query("status"))
+	if status != "" && !status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return status"})
+		return
+	}
+	ctx := c.Request.Context()
+	list, err := h.Store.ListReturns(ctx, status)
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// ApproveReturn อนุมัติคำขอคืนสินค้า body: {"note": "..."} (ไม่บังคับ)
+func (h *ReturnHandlers) ApproveReturn(c *gin.Context) {
+	h.review(c, true)
+}
+
+// RejectReturn ปฏิเสธคำขอคืนสินค้า body: {"note": "เหตุผล"} (บังคับ)
+func (h *ReturnHandlers) RejectReturn(c *gin.Context) {
+	h.review(c, false)
+}
+
+func (h *ReturnHandlers) review(c *gin.Context, approve bool) {
+	returnID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return ID"})
+		return
+	}
+	var request struct {
+		Note string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !approve && request.Note == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "note is required when rejecting a return"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	r, err := h.Store.Review(ctx, returnID, approve, c.GetString("user_id"), request.Note)
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// ReceiveReturn บันทึกว่าได้รับสินค้าที่ลูกค้าส่งคืนแล้ว
+func (h *ReturnHandlers) ReceiveReturn(c *gin.Context) {
+	returnID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	r, err := h.Store.Receive(ctx, returnID, c.GetString("user_id"))
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// RefundReturn คืนเงินและคืนสต็อกของสินค้าที่ได้รับคืนแล้ว
+func (h *ReturnHandlers) RefundReturn(c *gin.Context) {
+	returnID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	r, err := h.Store.Refund(ctx, returnID, c.GetString("user_id"))
+	if err != nil {
+		respondReturnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
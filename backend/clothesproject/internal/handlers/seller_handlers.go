@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"clothesproject/internal/clothesstore"
+	"clothesproject/internal/middleware"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validSellerID ตรวจว่า :user_id เป็น UUID ถ้าไม่ใช่จะตอบ 400 แล้วคืน false
+func validSellerID(c *gin.Context) bool {
+	if !middleware.ValidUUID(c.Param("user_id")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return false
+	}
+	return true
+}
+
+// GetSellerBrands ดึงแบรนด์ที่ผู้ขายดูแล
+func (h *ClothesHandlers) GetSellerBrands(c *gin.Context) {
+	if !validSellerID(c) {
+		return
+	}
+	ctx := c.Request.Context()
+	brandIDs, err := h.Store.GetSellerBrands(ctx, c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user_id": c.Param("user_id"), "brand_ids": brandIDs})
+}
+
+// GetMyBrands ดึงแบรนด์ที่ผู้ใช้ปัจจุบันดูแล (ว่างสำหรับผู้ที่ไม่ใช่ผู้ขาย)
+func (h *ClothesHandlers) GetMyBrands(c *gin.Context) {
+	ctx := c.Request.Context()
+	brandIDs, err := h.Store.GetSellerBrands(ctx, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"brand_ids": brandIDs})
+}
+
+// LinkSellerBrand ให้ผู้ขายดูแลแบรนด์ที่ระบุใน body
+func (h *ClothesHandlers) LinkSellerBrand(c *gin.Context) {
+	if !validSellerID(c) {
+		return
+	}
+	var request struct {
+		BrandID int `json:"brand_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.Store.LinkSellerBrand(ctx, c.Param("user_id"), request.BrandID); err != nil {
+		switch {
+		case errors.Is(err, clothesstore.ErrNotSeller):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, clothesstore.ErrBrandNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user_id": c.Param("user_id"), "brand_id": request.BrandID})
+}
+
+// UnlinkSellerBrand เลิกให้ผู้ขายดูแลแบรนด์
+func (h *ClothesHandlers) UnlinkSellerBrand(c *gin.Context) {
+	if !validSellerID(c) {
+		return
+	}
+	brandID, err := strconv.Atoi(c.Param("brand_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.Store.UnlinkSellerBrand(ctx, c.Param("user_id"), brandID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Seller brand unlinked"})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"clothesproject/internal/clothesstore"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProductVariants ดึง SKU ทั้งหมด (ไซซ์/สี) ของสินค้า
+func (h *ClothesHandlers) GetProductVariants(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	variants, err := h.Store.GetVariantsByProduct(ctx, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, variants)
+}
+
+// AddVariant เพิ่ม SKU ใหม่ให้สินค้า
+func (h *ClothesHandlers) AddVariant(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var variant clothesstore.Variant
+	if err := c.ShouldBindJSON(&variant); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if variant.SKU == "" || variant.Size == "" || variant.Colour == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sku, size and colour are required"})
+		return
+	}
+	variant.ProductID = productID
+
+	ctx := c.Request.Context()
+	variant, err = h.Store.AddVariant(ctx, storeActor(c), variant)
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, variant)
+}
+
+// UpdateVariant อัพเดตข้อมูล SKU
+func (h *ClothesHandlers) UpdateVariant(c *gin.Context) {
+	variantID, err := strconv.Atoi(c.Param("variantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+	var variant clothesstore.Variant
+	if err := c.ShouldBindJSON(&variant); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	variant.ID = variantID
+
+	ctx := c.Request.Context()
+	if err := h.Store.UpdateVariant(ctx, storeActor(c), variant); err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, variant)
+}
+
+// DeleteVariant ลบ SKU
+func (h *ClothesHandlers) DeleteVariant(c *gin.Context) {
+	variantID, err := strconv.Atoi(c.Param("variantID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.Store.DeleteVariant(ctx, storeActor(c), variantID); err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Variant deleted"})
+}
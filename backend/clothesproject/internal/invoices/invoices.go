@@ -0,0 +1,129 @@
+package invoices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"clothesproject/internal/money"
+	"clothesproject/internal/orders"
+	"clothesproject/internal/pricing"
+)
+
+// ErrNotInvoiceable คืนค่าเมื่อคำสั่งซื้อยังไม่ได้ชำระเงินหรือถูกยกเลิกไปก่อนออกเอกสาร
+var ErrNotInvoiceable = errors.New("order has not been paid, no invoice can be issued")
+
+// Kind คือชนิดของเอกสาร
+type Kind string
+
+const (
+	// KindTaxInvoice คือใบกำกับภาษี/ใบเสร็จรับเงินเต็มรูป ออกเมื่อลูกค้าให้ข้อมูลผู้ซื้อตอนสั่งซื้อ
+	KindTaxInvoice Kind = "tax_invoice"
+	// KindReceipt คือใบเสร็จรับเงิน/ใบกำกับภาษีอย่างย่อ
+	KindReceipt Kind = "receipt"
+)
+
+// Seller คือข้อมูลผู้ขาย (แบรนด์) ณ เวลาที่ออกเอกสาร
+type Seller struct {
+	Name    string `json:"name"`
+	TaxID   string `json:"tax_id,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// Invoice คือเอกสารของแบรนด์หนึ่งในคำสั่งซื้อ แต่ละแบรนด์เป็นผู้ขายเองจึงออกเลขที่ของตัวเองต่อเนื่องกัน
+// Subtotal คือราคาสินค้าก่อนส่วนลด Discount คือส่วนลดระดับรายการรวมกับส่วนแบ่งส่วนลดระดับตะกร้า
+// Total คือยอดที่ลูกค้าจ่ายให้แบรนด์นี้ (รวมค่าส่งและ VAT) VAT คือภาษีที่อยู่ใน Total
+// ยอดของทุกเอกสารในคำสั่งซื้อรวมกันเท่ากับยอดของคำสั่งซื้อพอดี
+type Invoice struct {
+	ID           int                `json:"id"`
+	Number       string             `json:"number"`
+	Kind         Kind               `json:"kind"`
+	OrderID      int                `json:"order_id"`
+	BrandID      int                `json:"brand_id"`
+	Seller       Seller             `json:"seller"`
+	Buyer        *orders.TaxBuyer   `json:"buyer,omitempty"`
+	Items        []orders.OrderItem `json:"items"`
+	VATInclusive bool               `json:"vat_inclusive"`
+	Subtotal     money.Money        `json:"subtotal"`
+	Discount     money.Money        `json:"discount"`
+	ShippingFee  money.Money        `json:"shipping_fee"`
+	VAT          money.Money        `json:"vat"`
+	Total        money.Money        `json:"total"`
+	IssuedAt     time.Time          `json:"issued_at"`
+}
+
+// NetAmount คือมูลค่าสินค้าและบริการก่อน VAT
+func (inv Invoice) NetAmount() money.Money {
+	return inv.Total.Sub(inv.VAT)
+}
+
+// Store ออกและดึงเอกสารของคำสั่งซื้อ IssueInvoices ออกเลขที่ให้ทุกแบรนด์ในครั้งแรกที่เรียก
+// ครั้งต่อไปคืนเอกสารเดิมเสมอ (เลขที่ วันที่ และยอดไม่เปลี่ยน)
+type Store interface {
+	IssueInvoices(ctx context.Context, userID string, orderID int) ([]Invoice, error)
+}
+
+// invoiceable คือสถานะที่ชำระเงินแล้วและออกเอกสารได้
+func invoiceable(status orders.Status) bool {
+	switch status {
+	case orders.StatusPaid, orders.StatusPacked, orders.StatusShipped, orders.StatusDelivered:
+		return true
+	}
+	return false
+}
+
+// formatNumber สร้างเลขที่เอกสาร เช่น INV-003-000042 คือเอกสารลำดับที่ 42 ของแบรนด์ 3
+func formatNumber(brandID, sequence int) string {
+	return fmt.Sprintf("INV-%03d-%06d", brandID, sequence)
+}
+
+// split แบ่งคำสั่งซื้อเป็นเอกสารของแต่ละแบรนด์เรียงตาม brand ID โดยคิดส่วนลดและ VAT แบบเดียวกับ pricing.Calculate
+// vatInclusive คือการตั้งค่าราคารวม VAT ของแต่ละแบรนด์ ค่าส่งถือว่ารวม VAT แล้ว
+func split(order orders.Order, vatInclusive map[int]bool) []Invoice {
+	lines := make([]pricing.Line, len(order.Items))
+	cartDiscount := order.DiscountTotal
+	for i, item := range order.Items {
+		lines[i] = pricing.Line{BrandID: item.BrandID, Subtotal: item.LineTotal, Discount: item.Discount}
+		cartDiscount = cartDiscount.Sub(item.Discount)
+	}
+	taxables := pricing.Taxable(lines, cartDiscount)
+
+	byBrand := make(map[int]*Invoice)
+	var brandIDs []int
+	for i, item := range order.Items {
+		inv, ok := byBrand[item.BrandID]
+		if !ok {
+			// คำสั่งซื้อที่ไม่มี VAT บวกเพิ่มเลยแปลว่าทุกแบรนด์รวม VAT แล้วตอนสั่งซื้อ ไม่ต้องอิงค่าปัจจุบันของแบรนด์
+			inv = &Invoice{OrderID: order.ID, BrandID: item.BrandID, VATInclusive: order.VATAdded.IsZero() || vatInclusive[item.BrandID]}
+			byBrand[item.BrandID] = inv
+			brandIDs = append(brandIDs, item.BrandID)
+		}
+		inv.Items = append(inv.Items, item)
+		inv.Subtotal = inv.Subtotal.Add(item.LineTotal)
+		inv.Discount = inv.Discount.Add(item.LineTotal.Sub(taxables[i]))
+		inv.Total = inv.Total.Add(taxables[i])
+		if inv.VATInclusive {
+			inv.VAT = inv.VAT.Add(pricing.IncludedVAT(taxables[i]))
+		} else {
+			added := taxables[i].MulRatio(pricing.VATPercent, 100)
+			inv.VAT = inv.VAT.Add(added)
+			inv.Total = inv.Total.Add(added)
+		}
+	}
+	for _, shipment := range order.Shipments {
+		if inv, ok := byBrand[shipment.BrandID]; ok {
+			inv.ShippingFee = shipment.Fee
+			inv.VAT = inv.VAT.Add(pricing.IncludedVAT(shipment.Fee))
+			inv.Total = inv.Total.Add(shipment.Fee)
+		}
+	}
+
+	sort.Ints(brandIDs)
+	invoices := make([]Invoice, len(brandIDs))
+	for i, id := range brandIDs {
+		invoices[i] = *byBrand[id]
+	}
+	return invoices
+}
@@ -0,0 +1,131 @@
+package invoices
+
+import (
+	"testing"
+
+	"clothesproject/internal/money"
+	"clothesproject/internal/orders"
+	"clothesproject/internal/pricing"
+)
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		brandID, sequence int
+		want              string
+	}{
+		{brandID: 3, sequence: 42, want: "INV-003-000042"},
+		{brandID: 1, sequence: 1, want: "INV-001-000001"},
+		{brandID: 1234, sequence: 1234567, want: "INV-1234-1234567"},
+	}
+	for _, tt := range tests {
+		if got := formatNumber(tt.brandID, tt.sequence); got != tt.want {
+			t.Errorf("formatNumber(%d, %d) = %q, want %q", tt.brandID, tt.sequence, got, tt.want)
+		}
+	}
+}
+
+func TestInvoiceable(t *testing.T) {
+	tests := []struct {
+		status orders.Status
+		want   bool
+	}{
+		{status: orders.StatusPending, want: false},
+		{status: orders.StatusPaid, want: true},
+		{status: orders.StatusPacked, want: true},
+		{status: orders.StatusShipped, want: true},
+		{status: orders.StatusDelivered, want: true},
+		{status: orders.StatusCancelled, want: false},
+		{status: orders.StatusRefunded, want: false},
+	}
+	for _, tt := range tests {
+		if got := invoiceable(tt.status); got != tt.want {
+			t.Errorf("invoiceable(%q) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+// TestSplitMatchesOrderTotals ตรวจว่าเอกสารของทุกแบรนด์รวมกันได้ยอดเดียวกับที่ pricing.Calculate คิดตอนสั่งซื้อ
+func TestSplitMatchesOrderTotals(t *testing.T) {
+	threshold := money.Baht(1000)
+	brands := map[int]pricing.Brand{
+		1: {ID: 1, VATInclusive: true, FreeShippingThreshold: &threshold},
+		2: {ID: 2},
+		3: {ID: 3, VATInclusive: true},
+	}
+	zone := &pricing.Zone{BaseFee: money.Baht(50), BaseWeightGrams: 1000, PerKgFee: money.Baht(20)}
+	item := func(brandID int, lineTotal, discount money.Money) orders.OrderItem {
+		return orders.OrderItem{BrandID: brandID, Quantity: 1, LineTotal: lineTotal, Discount: discount}
+	}
+
+	tests := []struct {
+		name         string
+		items        []orders.OrderItem
+		cartDiscount money.Money
+		zone         *pricing.Zone
+		wantBrands   []int
+	}{
+		{
+			name:       "single VAT-inclusive brand",
+			items:      []orders.OrderItem{item(1, money.Baht(800), money.Money{})},
+			zone:       zone,
+			wantBrands: []int{1},
+		},
+		{
+			name: "mixed brands with line and cart discounts",
+			items: []orders.OrderItem{
+				item(2, money.Satang(19999), money.Baht(20)),
+				item(1, money.Baht(800), money.Money{}),
+				item(3, money.Satang(33333), money.Money{}),
+				item(2, money.Baht(150), money.Money{}),
+			},
+			cartDiscount: money.Satang(10001),
+			zone:         zone,
+			wantBrands:   []int{1, 2, 3},
+		},
+		{
+			name:         "pickup without shipping",
+			items:        []orders.OrderItem{item(3, money.Baht(100), money.Money{}), item(2, money.Baht(100), money.Money{})},
+			cartDiscount: money.Baht(15),
+			wantBrands:   []int{2, 3},
+		},
+	}
+	for _, tt := range tests {
+		lines := make([]pricing.Line, len(tt.items))
+		for i, it := range tt.items {
+			lines[i] = pricing.Line{BrandID: it.BrandID, Subtotal: it.LineTotal, Discount: it.Discount, WeightGrams: 500}
+		}
+		order := orders.Order{ID: 7, Items: tt.items, Totals: pricing.Calculate(lines, tt.cartDiscount, brands, tt.zone)}
+		vatInclusive := map[int]bool{}
+		for id, b := range brands {
+			vatInclusive[id] = b.VATInclusive
+		}
+
+		invoices := split(order, vatInclusive)
+		if len(invoices) != len(tt.wantBrands) {
+			t.Errorf("%s: got %d invoices, want %d", tt.name, len(invoices), len(tt.wantBrands))
+			continue
+		}
+		var subtotal, discount, shipping, vat, total money.Money
+		for i, inv := range invoices {
+			if inv.BrandID != tt.wantBrands[i] || inv.OrderID != order.ID {
+				t.Errorf("%s: invoice %d is for order %d brand %d, want order %d brand %d", tt.name, i, inv.OrderID, inv.BrandID, order.ID, tt.wantBrands[i])
+			}
+			if inv.VATInclusive != brands[inv.BrandID].VATInclusive {
+				t.Errorf("%s: invoice for brand %d VATInclusive = %v", tt.name, inv.BrandID, inv.VATInclusive)
+			}
+			if inv.NetAmount().Add(inv.VAT).Amount != inv.Total.Amount {
+				t.Errorf("%s: invoice for brand %d net %s + VAT %s != total %s", tt.name, inv.BrandID, inv.NetAmount(), inv.VAT, inv.Total)
+			}
+			subtotal = subtotal.Add(inv.Subtotal)
+			discount = discount.Add(inv.Discount)
+			shipping = shipping.Add(inv.ShippingFee)
+			vat = vat.Add(inv.VAT)
+			total = total.Add(inv.Total)
+		}
+		if subtotal.Amount != order.Subtotal.Amount || discount.Amount != order.DiscountTotal.Amount || shipping.Amount != order.ShippingFee.Amount ||
+			vat.Amount != order.VAT.Amount || total.Amount != order.Total.Amount {
+			t.Errorf("%s: invoices sum to subtotal %s, discount %s, shipping %s, VAT %s, total %s; order has %s, %s, %s, %s, %s", tt.name,
+				subtotal, discount, shipping, vat, total, order.Subtotal, order.DiscountTotal, order.ShippingFee, order.VAT, order.Total)
+		}
+	}
+}
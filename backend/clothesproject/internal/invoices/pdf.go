@@ -0,0 +1,266 @@
+package invoices
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"clothesproject/internal/money"
+	"clothesproject/internal/pricing"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// thaiFont คือฟอนต์ FreeSerif (GNU FreeFont) ซึ่งมีอักษรไทยครบ ฝังในโปรแกรมเพื่อไม่ต้องพึ่งฟอนต์ของเครื่อง
+// fpdf ฝังเฉพาะตัวอักษรที่ใช้ลงใน PDF (ใบอนุญาตของฟอนต์อนุญาตให้ฝังในเอกสารได้ ดู fonts/README)
+//
+//go:embed fonts/FreeSerif.ttf
+var thaiFont []byte
+
+const fontFamily = "FreeSerif"
+
+// bangkok คือเวลาประเทศไทย (UTC+7 ไม่มี daylight saving) ใช้พิมพ์วันที่บนเอกสาร
+var bangkok = time.FixedZone("ICT", 7*60*60)
+
+// columns คือหัวตารางรายการสินค้าและความกว้าง (มม.) รวมเท่ากับความกว้างของหน้าหลังหักขอบ
+var columns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"ลำดับ", 12, "C"},
+	{"รายการ", 86, "L"},
+	{"จำนวน", 18, "R"},
+	{"ราคาต่อหน่วย", 26, "R"},
+	{"จำนวนเงิน", 28, "R"},
+}
+
+// Render สร้าง PDF ของเอกสารทั้งหมดในคำสั่งซื้อ หน้าละหนึ่งเอกสาร (หนึ่งแบรนด์)
+func Render(invoices []Invoice) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 15, 20)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AddUTF8FontFromBytes(fontFamily, "", thaiFont)
+	if len(invoices) > 0 {
+		pdf.SetTitle(fmt.Sprintf("Order %d invoice", invoices[0].OrderID), true)
+	}
+	for _, inv := range invoices {
+		renderInvoice(pdf, inv)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render invoice PDF: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func renderInvoice(pdf *fpdf.Fpdf, inv Invoice) {
+	pdf.AddPage()
+	left, _, right, bottom := pdf.GetMargins()
+	pageWidth, pageHeight := pdf.GetPageSize()
+	width := pageWidth - left - right
+
+	// ผู้ขาย (ซ้าย) และชื่อเอกสาร (ขวา)
+	top := pdf.GetY()
+	pdf.SetFont(fontFamily, "", 18)
+	pdf.CellFormat(width/2, 9, inv.Seller.Name, "", 2, "L", false, 0, "")
+	pdf.SetFont(fontFamily, "", 11)
+	if inv.Seller.Address != "" {
+		pdf.MultiCell(width/2, 5.5, inv.Seller.Address, "", "L", false)
+	}
+	if inv.Seller.TaxID != "" {
+		pdf.CellFormat(width/2, 5.5, "เลขประจำตัวผู้เสียภาษี "+inv.Seller.TaxID, "", 2, "L", false, 0, "")
+	}
+	sellerBottom := pdf.GetY()
+
+	title, subtitle := "ใบเสร็จรับเงิน/ใบกำกับภาษีอย่างย่อ", "Receipt / Abbreviated Tax Invoice"
+	if inv.Kind == KindTaxInvoice {
+		title, subtitle = "ใบกำกับภาษี/ใบเสร็จรับเงิน", "Tax Invoice / Receipt"
+	}
+	pdf.SetXY(left+width/2, top)
+	pdf.SetFont(fontFamily, "", 16)
+	pdf.CellFormat(width/2, 8, title, "", 2, "R", false, 0, "")
+	pdf.SetFont(fontFamily, "", 11)
+	pdf.CellFormat(width/2, 5.5, subtitle, "", 2, "R", false, 0, "")
+	pdf.CellFormat(width/2, 5.5, "ต้นฉบับ (Original)", "", 2, "R", false, 0, "")
+	pdf.Ln(2)
+	for _, row := range [][2]string{
+		{"เลขที่ / No.", inv.Number},
+		{"วันที่ / Date", thaiDate(inv.IssuedAt)},
+		{"คำสั่งซื้อ / Order", fmt.Sprintf("#%d", inv.OrderID)},
+	} {
+		pdf.SetX(left + width/2)
+		pdf.CellFormat(width/4, 5.5, row[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(width/4, 5.5, row[1], "", 1, "R", false, 0, "")
+	}
+	if pdf.GetY() < sellerBottom {
+		pdf.SetY(sellerBottom)
+	}
+	pdf.Ln(4)
+
+	// ผู้ซื้อ (เฉพาะใบกำกับภาษีเต็มรูป)
+	if inv.Buyer != nil {
+		pdf.SetFont(fontFamily, "", 12)
+		pdf.CellFormat(width, 6, "ผู้ซื้อ / Customer", "", 1, "L", false, 0, "")
+		pdf.SetFont(fontFamily, "", 11)
+		name := inv.Buyer.Name
+		if inv.Buyer.Branch != "" {
+			name += " (" + inv.Buyer.Branch + ")"
+		}
+		pdf.CellFormat(width, 5.5, name, "", 1, "L", false, 0, "")
+		pdf.MultiCell(width, 5.5, inv.Buyer.Address, "", "L", false)
+		pdf.CellFormat(width, 5.5, "เลขประจำตัวผู้เสียภาษี "+inv.Buyer.TaxID, "", 1, "L", false, 0, "")
+		pdf.Ln(4)
+	}
+
+	// ตารางรายการสินค้า
+	pdf.SetFont(fontFamily, "", 11)
+	pdf.SetFillColor(235, 235, 235)
+	for _, col := range columns {
+		pdf.CellFormat(col.width, 8, col.title, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+	row := 0
+	addRow := func(description string, quantity int, unitPrice, amount money.Money) {
+		row++
+		lines := pdf.SplitText(description, columns[1].width-2)
+		height := 6.5 * float64(len(lines))
+		if pdf.GetY()+height > pageHeight-bottom {
+			pdf.AddPage()
+		}
+		values := []string{fmt.Sprint(row), "", fmt.Sprint(quantity), formatAmount(unitPrice), formatAmount(amount)}
+		x, y := pdf.GetXY()
+		for i, col := range columns {
+			pdf.Rect(x, y, col.width, height, "D")
+			if i == 1 {
+				for j, line := range lines {
+					pdf.SetXY(x+1, y+6.5*float64(j))
+					pdf.CellFormat(col.width-2, 6.5, line, "", 0, "L", false, 0, "")
+				}
+			} else {
+				pdf.SetXY(x+1, y)
+				pdf.CellFormat(col.width-2, 6.5, values[i], "", 0, col.align, false, 0, "")
+			}
+			x += col.width
+		}
+		pdf.SetXY(left, y+height)
+	}
+	for _, item := range inv.Items {
+		description := fmt.Sprintf("%s (%s / %s) %s", item.ProductName, item.Size, item.Colour, item.SKU)
+		addRow(description, item.Quantity, item.UnitPrice, item.LineTotal)
+	}
+	if inv.ShippingFee.IsPositive() {
+		addRow("ค่าจัดส่ง / Shipping", 1, inv.ShippingFee, inv.ShippingFee)
+	}
+	pdf.Ln(3)
+
+	// สรุปยอด
+	labelWidth, valueWidth := 62.0, 28.0
+	totals := [][2]string{
+		{"รวมเป็นเงิน / Subtotal", formatAmount(inv.Subtotal.Add(inv.ShippingFee))},
+		{"หักส่วนลด / Discount", formatAmount(inv.Discount)},
+		{"มูลค่าก่อนภาษี / Net amount", formatAmount(inv.NetAmount())},
+		{fmt.Sprintf("ภาษีมูลค่าเพิ่ม / VAT %d%%", pricing.VATPercent), formatAmount(inv.VAT)},
+	}
+	for _, t := range totals {
+		pdf.SetX(left + width - labelWidth - valueWidth)
+		pdf.CellFormat(labelWidth, 6, t[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(valueWidth, 6, t[1], "", 1, "R", false, 0, "")
+	}
+	pdf.SetFont(fontFamily, "", 13)
+	pdf.SetX(left + width - labelWidth - valueWidth)
+	pdf.CellFormat(labelWidth, 8, "จำนวนเงินรวมทั้งสิ้น / Total", "T", 0, "R", false, 0, "")
+	pdf.CellFormat(valueWidth, 8, formatAmount(inv.Total), "T", 1, "R", false, 0, "")
+	pdf.SetFont(fontFamily, "", 11)
+	pdf.CellFormat(width, 6, "("+bahtText(inv.Total)+")", "", 1, "R", false, 0, "")
+	pdf.Ln(4)
+
+	if !inv.VATInclusive {
+		pdf.CellFormat(width, 5.5, "ราคาสินค้าในตารางยังไม่รวมภาษีมูลค่าเพิ่ม ค่าจัดส่งรวมภาษีมูลค่าเพิ่มแล้ว", "", 1, "L", false, 0, "")
+	}
+	pdf.CellFormat(width, 5.5, "ได้รับเงินครบถ้วนแล้ว เอกสารนี้ออกโดยระบบคอมพิวเตอร์", "", 1, "L", false, 0, "")
+}
+
+// thaiDate คืนวันที่แบบไทย (พุทธศักราช) เช่น 18/10/2569
+func thaiDate(t time.Time) string {
+	t = t.In(bangkok)
+	return fmt.Sprintf("%02d/%02d/%d", t.Day(), int(t.Month()), t.Year()+543)
+}
+
+// formatAmount คืนจำนวนเงินพร้อมตัวคั่นหลักพัน เช่น 1,234.50
+func formatAmount(m money.Money) string {
+	s := m.String()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, fraction, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if fraction != "" {
+		return sign + b.String() + "." + fraction
+	}
+	return sign + b.String()
+}
+
+var (
+	thaiDigits    = []string{"", "หนึ่ง", "สอง", "สาม", "สี่", "ห้า", "หก", "เจ็ด", "แปด", "เก้า"}
+	thaiPositions = []string{"", "สิบ", "ร้อย", "พัน", "หมื่น", "แสน"}
+)
+
+// bahtText อ่านจำนวนเงินบาทเป็นตัวอักษรไทย เช่น 121.50 เป็น "หนึ่งร้อยยี่สิบเอ็ดบาทห้าสิบสตางค์"
+func bahtText(m money.Money) string {
+	baht, satang := m.Amount/100, m.Amount%100
+	if baht == 0 && satang == 0 {
+		return "ศูนย์บาทถ้วน"
+	}
+	var text string
+	if baht > 0 {
+		text = thaiNumber(baht) + "บาท"
+	}
+	if satang == 0 {
+		return text + "ถ้วน"
+	}
+	return text + thaiNumber(satang) + "สตางค์"
+}
+
+// thaiNumber อ่านจำนวนเต็มบวกเป็นตัวอักษรไทย ทีละกลุ่มหกหลักคั่นด้วย "ล้าน"
+// หลักหน่วยที่เป็นหนึ่งและมีหลักที่สูงกว่าอ่านว่า "เอ็ด" หลักสิบที่เป็นหนึ่งและสองอ่านว่า "สิบ" และ "ยี่สิบ"
+func thaiNumber(n int64) string {
+	var text string
+	if n >= 1000000 {
+		text = thaiNumber(n/1000000) + "ล้าน"
+		n %= 1000000
+	}
+	hasHigher := text != ""
+	for position := 5; position >= 0; position-- {
+		unit := int64(1)
+		for i := 0; i < position; i++ {
+			unit *= 10
+		}
+		digit := n / unit % 10
+		if digit == 0 {
+			continue
+		}
+		switch {
+		case position == 1 && digit == 1:
+			text += "สิบ"
+		case position == 1 && digit == 2:
+			text += "ยี่สิบ"
+		case position == 0 && digit == 1 && hasHigher:
+			text += "เอ็ด"
+		default:
+			text += thaiDigits[digit] + thaiPositions[position]
+		}
+		hasHigher = true
+	}
+	return text
+}
@@ -0,0 +1,194 @@
+package invoices
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"clothesproject/internal/clothesstore"
+	"clothesproject/internal/orders"
+
+	"github.com/lib/pq"
+)
+
+// PostgresStore เก็บเอกสารในตาราง invoices และนับเลขที่ของแต่ละแบรนด์ในตาราง brand_invoice_counters
+// รายการสินค้าของเอกสารมาจากคำสั่งซื้อ (ซึ่งแก้ไขไม่ได้หลังสร้าง) ผ่าน orders
+type PostgresStore struct {
+	pdb    *clothesstore.PostgresDatabase
+	orders orders.OrderStore
+}
+
+func NewPostgresStore(pdb *clothesstore.PostgresDatabase, orderStore orders.OrderStore) *PostgresStore {
+	return &PostgresStore{pdb: pdb, orders: orderStore}
+}
+
+const invoiceColumns = `invoice_id, invoice_number, kind, brand_id, seller_name, COALESCE(seller_tax_id, ''), COALESCE(seller_address, ''),
+               vat_inclusive, subtotal, discount, shipping_fee, vat, total, issued_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInvoice(row rowScanner) (Invoice, error) {
+	var inv Invoice
+	if err := row.Scan(&inv.ID, &inv.Number, &inv.Kind, &inv.BrandID, &inv.Seller.Name, &inv.Seller.TaxID, &inv.Seller.Address,
+		&inv.VATInclusive, &inv.Subtotal, &inv.Discount, &inv.ShippingFee, &inv.VAT, &inv.Total, &inv.IssuedAt); err != nil {
+		return Invoice{}, fmt.Errorf("failed to scan invoice: %v", err)
+	}
+	return inv, nil
+}
+
+// IssueInvoices คืนเอกสารของคำสั่งซื้อของผู้ใช้ ถ้ายังไม่เคยออกจะออกเลขที่ถัดไปของแต่ละแบรนด์ใน transaction เดียวกัน
+// (ล็อกคำสั่งซื้อไว้ไม่ให้ออกซ้ำ และล็อกตัวนับของแบรนด์ไว้จน commit เลขที่จึงต่อเนื่องไม่มีช่องว่าง)
+func (s *PostgresStore) IssueInvoices(ctx context.Context, userID string, orderID int) ([]Invoice, error) {
+	order, err := s.orders.GetOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.pdb.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, "SELECT status FROM orders WHERE order_id = $1 FOR UPDATE", orderID).Scan(&order.Status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, orders.ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("failed to lock order: %v", err)
+	}
+
+	invoices, err := getInvoices(ctx, tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		if !invoiceable(order.Status) {
+			return nil, fmt.Errorf("%w: order is %s", ErrNotInvoiceable, order.Status)
+		}
+		if invoices, err = issue(ctx, tx, order); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	for i := range invoices {
+		inv := &invoices[i]
+		inv.OrderID = order.ID
+		if inv.Kind == KindTaxInvoice {
+			inv.Buyer = order.TaxInvoice
+		}
+		var items []orders.OrderItem
+		for _, item := range order.Items {
+			if item.BrandID == inv.BrandID {
+				items = append(items, item)
+			}
+		}
+		inv.Items = items
+	}
+	return invoices, nil
+}
+
+func getInvoices(ctx context.Context, tx *sql.Tx, orderID int) ([]Invoice, error) {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT `+invoiceColumns+`
+        FROM invoices
+        WHERE order_id = $1
+        ORDER BY brand_id
+    `, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invoices: %v", err)
+	}
+	defer rows.Close()
+
+	var invoices []Invoice
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return invoices, nil
+}
+
+// issue ออกเอกสารใหม่ของทุกแบรนด์ในคำสั่งซื้อ ข้อมูลผู้ขายถูกบันทึกไว้ในเอกสาร เพื่อไม่ให้เปลี่ยนตามเมื่อแก้ไขแบรนด์ภายหลัง
+// แบรนด์ที่ถูกลบไปแล้วใช้ชื่อแบรนด์ที่บันทึกไว้ในรายการสินค้า
+func issue(ctx context.Context, tx *sql.Tx, order orders.Order) ([]Invoice, error) {
+	var brandIDs []int64
+	for _, item := range order.Items {
+		brandIDs = append(brandIDs, int64(item.BrandID))
+	}
+	rows, err := tx.QueryContext(ctx, `
+        SELECT id, COALESCE(brandname, ''), COALESCE(tax_id, ''), COALESCE(address, ''), vat_inclusive
+        FROM brand
+        WHERE id = ANY($1)
+    `, pq.Array(brandIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query brands: %v", err)
+	}
+	sellers := make(map[int]Seller)
+	vatInclusive := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		var seller Seller
+		var inclusive bool
+		if err := rows.Scan(&id, &seller.Name, &seller.TaxID, &seller.Address, &inclusive); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan brand: %v", err)
+		}
+		sellers[id] = seller
+		vatInclusive[id] = inclusive
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	kind := KindReceipt
+	if order.TaxInvoice != nil {
+		kind = KindTaxInvoice
+	}
+	// split เรียงตาม brand ID จึงล็อกตัวนับตามลำดับเดียวกันเสมอ ไม่เกิด deadlock ระหว่างคำสั่งซื้อพร้อมกัน
+	invoices := split(order, vatInclusive)
+	for i := range invoices {
+		inv := &invoices[i]
+		inv.Kind = kind
+		inv.Seller = sellers[inv.BrandID]
+		if inv.Seller.Name == "" {
+			inv.Seller.Name = inv.Items[0].BrandName
+		}
+
+		var sequence int
+		err := tx.QueryRowContext(ctx, `
+            INSERT INTO brand_invoice_counters (brand_id, last_number)
+            VALUES ($1, 1)
+            ON CONFLICT (brand_id) DO UPDATE SET last_number = brand_invoice_counters.last_number + 1
+            RETURNING last_number
+        `, inv.BrandID).Scan(&sequence)
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate invoice number: %v", err)
+		}
+		inv.Number = formatNumber(inv.BrandID, sequence)
+
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO invoices (invoice_number, kind, order_id, brand_id, sequence, seller_name, seller_tax_id, seller_address,
+                                  vat_inclusive, subtotal, discount, shipping_fee, vat, total)
+            VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10, $11, $12, $13, $14)
+            RETURNING invoice_id, issued_at
+        `, inv.Number, inv.Kind, order.ID, inv.BrandID, sequence, inv.Seller.Name, inv.Seller.TaxID, inv.Seller.Address,
+			inv.VATInclusive, inv.Subtotal, inv.Discount, inv.ShippingFee, inv.VAT, inv.Total).Scan(&inv.ID, &inv.IssuedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create invoice: %v", err)
+		}
+	}
+	return invoices, nil
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"clothesproject/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// Claims คือ claims ของ JWT ที่ออกโดย testlogin (utils.Claims) Subject คือ user_id และ Role คือ users.role
+// SessionID คือ user_sessions.session_id ที่ออก token และ Id (jti) คือ user_sessions.access_token_id ตอนออก token
+type Claims struct {
+	jwt.StandardClaims
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
+}
+
+// SessionChecker ตรวจว่า session ของ access token ยังไม่ถูก logout หรือ revoke ที่ testlogin
+// และ tokenID (jti) ยังเป็น access token ล่าสุดของ session
+type SessionChecker interface {
+	SessionActive(ctx context.Context, sessionID, tokenID string) (bool, error)
+}
+
+// AuthMiddleware ตรวจสอบ JWT ที่ออกโดย testlogin (utils.GenerateToken) และ session ของ token
+// แล้วเก็บ user_id กับ role ไว้ใน context
+func AuthMiddleware(cfg *config.Config, sessions SessionChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			c.Abort()
+			return
+		}
+
+		claims, ok := authenticate(c, cfg, sessions, authHeader)
+		if !ok {
+			return
+		}
+
+		c.Set("user_id", claims.Subject)
+		c.Set("role", claims.Role)
+		c.Next()
+	}
+}
+
+// authenticate ตรวจ Authorization header แบบ Bearer และ session ของ token
+// ถ้าไม่ผ่านจะตอบ error และ abort request แล้วคืน false
+// token ที่ไม่มี session หรือ jti (ออกก่อนมี refresh token) session ถูก logout แล้ว หรือ token ถูกแทนด้วยการ refresh จะถูกปฏิเสธ
+func authenticate(c *gin.Context, cfg *config.Config, sessions SessionChecker, authHeader string) (*Claims, bool) {
+	parts := strings.SplitN(authHeader, " ", 2)
+	if !(len(parts) == 2 && parts[0] == "Bearer") {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		c.Abort()
+		return nil, false
+	}
+
+	claims, err := verifyToken(parts[1], cfg.JWTSecret)
+	if err != nil || !ValidUUID(claims.Subject) || !ValidUUID(claims.SessionID) || !ValidUUID(claims.Id) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		c.Abort()
+		return nil, false
+	}
+
+	active, err := sessions.SessionActive(c.Request.Context(), claims.SessionID, claims.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify session"})
+		c.Abort()
+		return nil, false
+	}
+	if !active {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		c.Abort()
+		return nil, false
+	}
+	return claims, true
+}
+
+func verifyToken(tokenString string, secret string) (*Claims, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("JWT secret is not configured")
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		// รับเฉพาะ token ที่เซ็นด้วย HS256 เหมือนฝั่ง testlogin
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("invalid token")
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"clothesproject/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+// fakeSessions เก็บ access token ล่าสุด (jti) ของแต่ละ session เหมือน user_sessions.access_token_id
+// session ที่ logout แล้วถูกลบออก ส่วนการ refresh เปลี่ยน jti ของ session
+type fakeSessions struct {
+	current map[string]string
+	err     error
+}
+
+func (s *fakeSessions) SessionActive(ctx context.Context, sessionID, tokenID string) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	return s.current[sessionID] == tokenID, nil
+}
+
+func signTestToken(t *testing.T, sessionID, tokenID string) string {
+	t.Helper()
+	claims := Claims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID,
+			Subject:   "7b0e8c3a-1f2d-4e5b-8a9c-0d1e2f3a4b5c",
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		},
+		Role:      RoleCustomer,
+		SessionID: sessionID,
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRevokedAccessTokenRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const (
+		sessionID = "c4d5e6f7-0a1b-4c2d-9e3f-4a5b6c7d8e9f"
+		firstJTI  = "3f1c2b9e-8d47-4a55-9e0b-6c1d2f3a4b5c"
+		secondJTI = "5a6b7c8d-9e0f-4a1b-8c2d-3e4f5a6b7c8d"
+	)
+	cfg := &config.Config{JWTSecret: testJWTSecret}
+	sessions := &fakeSessions{current: map[string]string{sessionID: firstJTI}}
+
+	r := gin.New()
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id")}) }
+	r.GET("/me", AuthMiddleware(cfg, sessions), ok)
+	r.GET("/cart", CartOwnerMiddleware(cfg, sessions), ok)
+
+	get := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	first, second := signTestToken(t, sessionID, firstJTI), signTestToken(t, sessionID, secondJTI)
+
+	for _, path := range []string{"/me", "/cart"} {
+		sessions.current[sessionID], sessions.err = firstJTI, nil
+		if code := get(path, first); code != http.StatusOK {
+			t.Errorf("%s with the current token = %d, want %d", path, code, http.StatusOK)
+		}
+
+		// refresh หมุน jti ของ session token เดิมใช้ไม่ได้ทันที
+		sessions.current[sessionID] = secondJTI
+		if code := get(path, first); code != http.StatusUnauthorized {
+			t.Errorf("%s with the rotated token = %d, want %d", path, code, http.StatusUnauthorized)
+		}
+		if code := get(path, second); code != http.StatusOK {
+			t.Errorf("%s with the refreshed token = %d, want %d", path, code, http.StatusOK)
+		}
+
+		// logout ลบ session
+		delete(sessions.current, sessionID)
+		if code := get(path, second); code != http.StatusUnauthorized {
+			t.Errorf("%s after logout = %d, want %d", path, code, http.StatusUnauthorized)
+		}
+
+		// ตรวจ session ไม่ได้ต้องไม่ปล่อย request ผ่าน
+		sessions.current[sessionID], sessions.err = secondJTI, errors.New("connection refused")
+		if code := get(path, second); code != http.StatusInternalServerError {
+			t.Errorf("%s when the session check fails = %d, want %d", path, code, http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"clothesproject/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// GuestTokenHeader คือ header ที่ frontend ใช้ส่ง/รับ guest token ของตะกร้า
+	GuestTokenHeader = "X-Guest-Token"
+	// GuestTokenCookie คือชื่อ cookie ที่เก็บ guest token (testlogin อ่าน cookie นี้ตอน merge ตะกร้า)
+	GuestTokenCookie = "guest_token"
+
+	guestTokenMaxAge = 30 * 24 * 60 * 60 // 30 วัน
+)
+
+// CartOwnerMiddleware ระบุเจ้าของตะกร้าของ request:
+// ถ้ามี Authorization header จะตรวจ JWT และใช้ user_id เหมือน AuthMiddleware
+// ถ้าไม่มี จะใช้ guest token จาก header หรือ cookie และออก token ใหม่ให้ถ้ายังไม่มี
+func CartOwnerMiddleware(cfg *config.Config, sessions SessionChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			claims, ok := authenticate(c, cfg, sessions, authHeader)
+			if !ok {
+				return
+			}
+
+			c.Set("user_id", claims.Subject)
+			c.Next()
+			return
+		}
+
+		guestToken := c.GetHeader(GuestTokenHeader)
+		if guestToken == "" {
+			guestToken, _ = c.Cookie(GuestTokenCookie)
+		}
+		if !isValidGuestToken(guestToken) {
+			token, err := newGuestToken()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create guest cart"})
+				c.Abort()
+				return
+			}
+			guestToken = token
+		}
+
+		// ต่ออายุ cookie ทุกครั้งที่มีการใช้งานตะกร้า
+		c.SetCookie(GuestTokenCookie, guestToken, guestTokenMaxAge, "/", "", false, true)
+		c.Header(GuestTokenHeader, guestToken)
+
+		c.Set("guest_token", guestToken)
+		c.Next()
+	}
+}
+
+func newGuestToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func isValidGuestToken(token string) bool {
+	if len(token) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
@@ -0,0 +1,289 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader คือ header ที่ client ส่งมากับ POST เพื่อให้ส่งซ้ำได้อย่างปลอดภัย
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader ตอบเป็น "true" เมื่อ response มาจากผลที่เก็บไว้ของ request แรก
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize คือขนาด body สูงสุดที่อ่านมา hash (เท่ากับ MaxMultipartMemory ของ gin
+	// พอสำหรับคำขอคืนสินค้าที่แนบรูป 5 รูป รูปละไม่เกิน 5 MB) body ที่ใหญ่กว่านี้ได้ 413
+	maxIdempotentBodySize = 32 << 20
+)
+
+// replayedHeaders คือ header ของ response ที่เก็บไว้และส่งซ้ำพร้อม body
+// ไม่เก็บ Set-Cookie และ guest token เพราะเป็นข้อมูลยืนยันตัวตน ไม่ควรอยู่ในผลที่เก็บไว้ในฐานข้อมูล
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// IdempotentResponse คือ response ที่เก็บไว้ของ Idempotency-Key StatusCode เป็น 0 ขณะที่ request แรกยังทำงานอยู่
+type IdempotentResponse struct {
+	RequestHash string
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+}
+
+// IdempotencyStore เก็บ response ของแต่ละ Idempotency-Key แยกตาม scope (ผู้เรียก)
+// Reserve จองคีย์ให้ request นี้และคืน nil หรือคืน response ที่มีอยู่แล้วถ้าคีย์ยังไม่หมดอายุ
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, scope, key, requestHash string, ttl time.Duration) (*IdempotentResponse, error)
+	Save(ctx context.Context, scope, key string, response IdempotentResponse) error
+	Release(ctx context.Context, scope, key string) error
+}
+
+// Idempotency ทำให้ POST ที่มี header Idempotency-Key ทำงานครั้งเดียวภายใน ttl:
+// request ซ้ำที่ body เหมือนเดิมได้ response เดิม (พร้อม Idempotent-Replayed: true) โดยไม่เรียก handler อีก
+// คีย์เดิมกับ request ที่ต่างไป (path หรือ body) หรือคีย์ที่ request แรกยังทำงานไม่เสร็จจะได้ 409
+// เก็บเฉพาะ response 2xx และ 409/422 ที่เป็นผลทางธุรกิจ ส่วน 401/403 4xx อื่น 5xx หรือ handler ที่ panic
+// ไม่ถูกเก็บ เพื่อให้ลองใหม่ได้หลังแก้ request หรือล็อกอินใหม่
+// ต้องวางต่อจาก AuthMiddleware หรือ CartOwnerMiddleware ของแต่ละ route เพราะคีย์แยกตามผู้เรียกที่ตรวจแล้ว
+// (user_id หรือ guest token ใน context) request ที่ไม่มีผู้เรียกผ่านไปโดยไม่ใช้ Idempotency-Key
+func Idempotency(store IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		scope, ok := idempotencyScope(c)
+		if c.Request.Method != http.MethodPost || key == "" || !ok {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			}
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		fmt.Fprintf(hash, "%s %s\n", c.Request.Method, c.Request.URL.Path)
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		ctx := c.Request.Context()
+		existing, err := store.Reserve(ctx, scope, key, requestHash, ttl)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.RequestHash != requestHash:
+				c.JSON(http.StatusConflict, gin.H{"error": "Idempotency-Key was already used with a different request"})
+			case existing.StatusCode == 0:
+				c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+			default:
+				for _, name := range replayedHeaders {
+					for _, value := range existing.Header.Values(name) {
+						c.Writer.Header().Add(name, value)
+					}
+				}
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(existing.StatusCode, existing.Header.Get("Content-Type"), existing.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		// เก็บผลหรือปล่อยคีย์แม้ request จะหมดเวลาไปแล้ว เพราะ handler ทำงานเสร็จแล้ว
+		saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		// ปล่อยคีย์ใน defer เพื่อให้ทำงานแม้ handler จะ panic (ก่อน gin.Recovery ตอบ 500)
+		// ไม่อย่างนั้นคีย์จะค้างเป็น "still being processed" จนหมดอายุ
+		saved := false
+		defer func() {
+			if saved {
+				return
+			}
+			if err := store.Release(saveCtx, scope, key); err != nil {
+				c.Error(err)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if !storableStatus(status) {
+			return
+		}
+		response := IdempotentResponse{RequestHash: requestHash, StatusCode: status, Header: http.Header{}, Body: recorder.body.Bytes()}
+		for _, name := range replayedHeaders {
+			for _, value := range recorder.Header().Values(name) {
+				response.Header.Add(name, value)
+			}
+		}
+		if err := store.Save(saveCtx, scope, key, response); err != nil {
+			c.Error(err)
+			return
+		}
+		saved = true
+	}
+}
+
+// idempotencyScope ระบุผู้เรียกเพื่อไม่ให้คีย์ของคนหนึ่งชนกับอีกคน
+// ใช้ user_id ที่ AuthMiddleware หรือ CartOwnerMiddleware ตรวจ token และ session แล้ว ถ้าไม่มีใช้ guest token
+func idempotencyScope(c *gin.Context) (string, bool) {
+	var caller string
+	if userID := c.GetString("user_id"); userID != "" {
+		caller = "user:" + userID
+	} else if token := c.GetString("guest_token"); token != "" {
+		caller = "guest:" + token
+	} else {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(caller))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// storableStatus บอกว่า response นี้เป็นผลสุดท้ายของ request ที่ส่งซ้ำแล้วต้องได้ผลเดิมหรือไม่
+// 409 และ 422 คือ request ถูกปฏิเสธด้วยสถานะของข้อมูล (เช่นสต็อกไม่พอ) ส่วน 4xx อื่นอาจผ่านได้เมื่อลองใหม่
+func storableStatus(status int) bool {
+	switch {
+	case status >= http.StatusOK && status < http.StatusMultipleChoices:
+		return true
+	case status == http.StatusConflict, status == http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// responseRecorder เก็บสำเนา body ที่เขียนออกไป เพื่อบันทึกไว้ส่งซ้ำ
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// PostgresIdempotencyStore เก็บ Idempotency-Key ในตาราง idempotency_keys
+type PostgresIdempotencyStore struct {
+	// db คืน pool ที่ใช้อยู่ของ PostgresDatabase ซึ่งเปลี่ยนเมื่อ reconnect
+	db func() *sql.DB
+}
+
+func NewPostgresIdempotencyStore(db func() *sql.DB) *PostgresIdempotencyStore {
+	return &PostgresIdempotencyStore{db: db}
+}
+
+// maxReserveAttempts คือจำนวนครั้งที่ Reserve ลองจองใหม่เมื่อคีย์ถูกปล่อยระหว่างจองกับอ่านผลเดิม
+const maxReserveAttempts = 3
+
+func (s *PostgresIdempotencyStore) Reserve(ctx context.Context, scope, key, requestHash string, ttl time.Duration) (*IdempotentResponse, error) {
+	for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+		// คีย์ที่หมดอายุแล้วถูกจองใหม่ได้เหมือนไม่เคยใช้
+		var reserved bool
+		err := s.db().QueryRowContext(ctx, `
+            INSERT INTO idempotency_keys (scope, idempotency_key, request_hash, expires_at)
+            VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))
+            ON CONFLICT (scope, idempotency_key) DO UPDATE
+            SET request_hash = EXCLUDED.request_hash, status_code = NULL, response_headers = NULL, response_body = NULL,
+                created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+            WHERE idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+            RETURNING TRUE
+        `, scope, key, requestHash, ttl.Seconds()).Scan(&reserved)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %v", err)
+		}
+
+		var response IdempotentResponse
+		var statusCode sql.NullInt64
+		var headers []byte
+		err = s.db().QueryRowContext(ctx, `
+            SELECT request_hash, status_code, response_headers, response_body
+            FROM idempotency_keys
+            WHERE scope = $1 AND idempotency_key = $2
+        `, scope, key).Scan(&response.RequestHash, &statusCode, &headers, &response.Body)
+		if errors.Is(err, sql.ErrNoRows) {
+			// request แรกล้มเหลวและปล่อยคีย์ไปพอดี ให้ลองจองใหม่
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get idempotency key: %v", err)
+		}
+		response.StatusCode = int(statusCode.Int64)
+		if len(headers) > 0 {
+			if err := json.Unmarshal(headers, &response.Header); err != nil {
+				return nil, fmt.Errorf("failed to decode idempotent response headers: %v", err)
+			}
+		}
+		return &response, nil
+	}
+	return nil, fmt.Errorf("failed to reserve idempotency key: key kept changing after %d attempts", maxReserveAttempts)
+}
+
+func (s *PostgresIdempotencyStore) Save(ctx context.Context, scope, key string, response IdempotentResponse) error {
+	headers, err := json.Marshal(response.Header)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotent response headers: %v", err)
+	}
+	_, err = s.db().ExecContext(ctx, `
+        UPDATE idempotency_keys
+        SET status_code = $3, response_headers = $4, response_body = $5
+        WHERE scope = $1 AND idempotency_key = $2 AND request_hash = $6
+    `, scope, key, response.StatusCode, headers, response.Body, response.RequestHash)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response: %v", err)
+	}
+	return nil
+}
+
+func (s *PostgresIdempotencyStore) Release(ctx context.Context, scope, key string) error {
+	_, err := s.db().ExecContext(ctx, `
+        DELETE FROM idempotency_keys WHERE scope = $1 AND idempotency_key = $2 AND status_code IS NULL
+    `, scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %v", err)
+	}
+	return nil
+}
+
+// DeleteExpired ลบคีย์ที่หมดอายุแล้ว (เรียกจาก sweeper)
+func (s *PostgresIdempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := s.db().ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %v", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyStore ทำงานแบบเดียวกับ PostgresIdempotencyStore แต่เก็บในหน่วยความจำ
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]IdempotentResponse
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{responses: map[string]IdempotentResponse{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(ctx context.Context, scope, key, requestHash string, ttl time.Duration) (*IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.responses[scope+" "+key]; ok {
+		return &existing, nil
+	}
+	s.responses[scope+" "+key] = IdempotentResponse{RequestHash: requestHash}
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Save(ctx context.Context, scope, key string, response IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[scope+" "+key] = response
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.responses[scope+" "+key].StatusCode == 0 {
+		delete(s.responses, scope+" "+key)
+	}
+	return nil
+}
+
+// idempotencyRouter วาง Idempotency ต่อจาก middleware ที่ตั้ง user_id จาก header X-User (แทน AuthMiddleware)
+// handler นับจำนวนครั้งที่ถูกเรียกและตอบด้วย status ที่กำหนด
+func idempotencyRouter(store IdempotencyStore, status int, handler func(c *gin.Context)) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	r := gin.New()
+	setUser := func(c *gin.Context) {
+		if userID := c.GetHeader("X-User"); userID != "" {
+			c.Set("user_id", userID)
+		}
+	}
+	r.POST("/orders", setUser, Idempotency(store, time.Hour), func(c *gin.Context) {
+		calls++
+		if handler != nil {
+			handler(c)
+		}
+		c.JSON(status, gin.H{"call": calls})
+	})
+	return r, &calls
+}
+
+func postOrder(r http.Handler, user, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	r, calls := idempotencyRouter(newMemoryIdempotencyStore(), http.StatusCreated, nil)
+
+	first := postOrder(r, "u1", "k1", `{"item":1}`)
+	second := postOrder(r, "u1", "k1", `{"item":1}`)
+	if *calls != 1 {
+		t.Errorf("handler called %d times, want 1", *calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body.String(), first.Code, first.Body.String())
+	}
+	if second.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("replay is missing %s header", IdempotentReplayedHeader)
+	}
+	if first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("first response has %s header", IdempotentReplayedHeader)
+	}
+
+	// คีย์เดียวกันของผู้เรียกอีกคนเป็นคนละคีย์
+	if w := postOrder(r, "u2", "k1", `{"item":1}`); w.Code != http.StatusCreated || *calls != 2 {
+		t.Errorf("other caller = %d after %d calls, want %d after 2", w.Code, *calls, http.StatusCreated)
+	}
+	// ไม่มี Idempotency-Key หรือไม่มีผู้เรียกที่ตรวจแล้ว ไม่ใช้คีย์
+	postOrder(r, "u1", "", `{"item":1}`)
+	postOrder(r, "", "k1", `{"item":1}`)
+	postOrder(r, "", "k1", `{"item":1}`)
+	if *calls != 5 {
+		t.Errorf("handler called %d times, want 5", *calls)
+	}
+}
+
+func TestIdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	r, calls := idempotencyRouter(newMemoryIdempotencyStore(), http.StatusCreated, nil)
+
+	postOrder(r, "u1", "k1", `{"item":1}`)
+	w := postOrder(r, "u1", "k1", `{"item":2}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("different body = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if *calls != 1 {
+		t.Errorf("handler called %d times, want 1", *calls)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	r, calls := idempotencyRouter(newMemoryIdempotencyStore(), http.StatusCreated, func(c *gin.Context) {
+		close(started)
+		<-release
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postOrder(r, "u1", "k1", `{"item":1}`) }()
+	<-started
+
+	if w := postOrder(r, "u1", "k1", `{"item":1}`); w.Code != http.StatusConflict {
+		t.Errorf("in-flight duplicate = %d, want %d", w.Code, http.StatusConflict)
+	}
+	close(release)
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Errorf("first request = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if w := postOrder(r, "u1", "k1", `{"item":1}`); w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("request after the first finished was not replayed (%d)", w.Code)
+	}
+	if *calls != 1 {
+		t.Errorf("handler called %d times, want 1", *calls)
+	}
+}
+
+func TestIdempotencyStoredStatuses(t *testing.T) {
+	tests := []struct {
+		status int
+		stored bool
+	}{
+		{status: http.StatusOK, stored: true},
+		{status: http.StatusCreated, stored: true},
+		{status: http.StatusConflict, stored: true},
+		{status: http.StatusUnprocessableEntity, stored: true},
+		{status: http.StatusBadRequest, stored: false},
+		{status: http.StatusUnauthorized, stored: false},
+		{status: http.StatusForbidden, stored: false},
+		{status: http.StatusNotFound, stored: false},
+		{status: http.StatusInternalServerError, stored: false},
+	}
+	for _, tt := range tests {
+		r, calls := idempotencyRouter(newMemoryIdempotencyStore(), tt.status, nil)
+		postOrder(r, "u1", "k1", `{"item":1}`)
+		w := postOrder(r, "u1", "k1", `{"item":1}`)
+
+		wantCalls := 2
+		if tt.stored {
+			wantCalls = 1
+		}
+		if *calls != wantCalls {
+			t.Errorf("status %d: handler called %d times, want %d", tt.status, *calls, wantCalls)
+		}
+		if w.Code != tt.status {
+			t.Errorf("status %d: second request = %d", tt.status, w.Code)
+		}
+	}
+}
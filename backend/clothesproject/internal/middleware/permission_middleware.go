@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// role ของผู้ใช้ตาม enum users.role ซึ่ง testlogin ใส่ไว้ใน claim "role" ของ JWT
+const (
+	RoleCustomer = "customer"
+	RoleSeller   = "seller"
+	RoleAdmin    = "admin"
+)
+
+// Permission คือสิทธิ์ในการทำงานกลุ่มหนึ่ง route ที่แก้ไขข้อมูลระบุ permission ที่ต้องใช้ผ่าน RequirePermission
+// permission ของสินค้า สาขา หน้า about และสต็อกบอกแค่ว่าทำงานนั้นได้ ส่วนแบรนด์ที่แก้ไขได้ store ตรวจจาก seller_brands
+type Permission string
+
+const (
+	// PermWriteProducts เพิ่ม แก้ไข และลบสินค้าและ SKU
+	PermWriteProducts Permission = "products:write"
+	// PermWriteBrands เพิ่ม แก้ไข และลบแบรนด์
+	PermWriteBrands Permission = "brands:write"
+	// PermWriteBranches เพิ่ม แก้ไข และลบสาขา
+	PermWriteBranches Permission = "branches:write"
+	// PermWriteAboutPages สร้าง แก้ไข และลบหน้า about ของแบรนด์
+	PermWriteAboutPages Permission = "about_pages:write"
+	// PermWriteStock กำหนดสต็อกในคลังและที่สาขา
+	PermWriteStock Permission = "stock:write"
+	// PermManageSellers กำหนดว่าผู้ขายคนไหนดูแลแบรนด์ไหน
+	PermManageSellers Permission = "sellers:manage"
+	// PermManageOrders เปลี่ยนสถานะคำสั่งซื้อ ดูประวัติ และยืนยันการรับสินค้าที่สาขา
+	PermManageOrders Permission = "orders:manage"
+	// PermManagePromotions จัดการโปรโมชันและคูปอง
+	PermManagePromotions Permission = "promotions:manage"
+	// PermManageExchangeRates กำหนดอัตราแลกเปลี่ยน
+	PermManageExchangeRates Permission = "exchange_rates:manage"
+	// PermManageReturns อนุมัติ รับของ และคืนเงินคำขอคืนสินค้า
+	PermManageReturns Permission = "returns:manage"
+)
+
+// rolePermissions คือ permission matrix ของทั้งระบบ (ที่เดียวที่กำหนดว่า role ไหนทำอะไรได้)
+// role ที่ไม่อยู่ในนี้ รวมถึง token รุ่นเก่าที่ไม่มี claim role ไม่มีสิทธิ์ใดเลย
+var rolePermissions = map[string][]Permission{
+	RoleCustomer: {},
+	RoleSeller:   {PermWriteProducts, PermWriteBranches, PermWriteAboutPages, PermWriteStock},
+	RoleAdmin: {
+		PermWriteProducts, PermWriteBrands, PermWriteBranches, PermWriteAboutPages, PermWriteStock,
+		PermManageSellers, PermManageOrders, PermManagePromotions, PermManageExchangeRates, PermManageReturns,
+	},
+}
+
+// HasPermission ตรวจสอบว่า role มีสิทธิ์ perm ตาม rolePermissions
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission อนุญาตเฉพาะผู้ใช้ที่ role ใน JWT มีสิทธิ์ perm ต้องใช้หลัง AuthMiddleware
+func RequirePermission(perm Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c.GetString("role"), perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// PostgresSessionStore อ่าน user_sessions ที่ testlogin เขียน (ฐานข้อมูลเดียวกัน)
+type PostgresSessionStore struct {
+	// db คืน pool ปัจจุบัน ทุก request จึงตรวจ session ผ่าน pool ที่ reconnect แล้ว
+	db func() *sql.DB
+}
+
+func NewPostgresSessionStore(db func() *sql.DB) *PostgresSessionStore {
+	return &PostgresSessionStore{db: db}
+}
+
+// SessionActive ตรวจว่า session ยังไม่ถูก logout (ลบแถว) ถูก revoke หรือหมดอายุ
+// และ tokenID (jti) ยังเป็น access token ล่าสุดของ session (token ก่อนการ refresh ครั้งล่าสุดใช้ไม่ได้)
+func (s *PostgresSessionStore) SessionActive(ctx context.Context, sessionID, tokenID string) (bool, error) {
+	var active bool
+	err := s.db().QueryRowContext(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM user_sessions
+            WHERE session_id = $1::uuid AND access_token_id = $2::uuid
+              AND revoked_at IS NULL AND expires_at > NOW()
+        )
+    `, sessionID, tokenID).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %v", err)
+	}
+	return active, nil
+}
@@ -0,0 +1,23 @@
+package middleware
+
+// ValidUUID ตรวจว่า s เป็น UUID รูปแบบมาตรฐาน (xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx)
+// ใช้ตรวจค่าจาก token และ URL ก่อนส่งเป็น $n::uuid ให้ query ใช้ index ของคอลัมน์ UUID ได้
+func ValidUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,322 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Currency คือรหัสสกุลเงินตาม ISO 4217
+type Currency string
+
+// THB คือสกุลเงินบาท ซึ่งเป็นสกุลเงินของราคาทั้งหมดในระบบ
+const THB Currency = "THB"
+
+// zeroDecimal คือสกุลเงินที่ไม่มีหน่วยย่อย สกุลเงินอื่นมีหน่วยย่อย 2 ตำแหน่ง
+var zeroDecimal = map[Currency]bool{"JPY": true, "KRW": true, "VND": true, "IDR": true}
+
+// Exponent คือจำนวนตำแหน่งทศนิยมของหน่วยย่อย (2 สำหรับ THB/USD, 0 สำหรับ JPY)
+func (c Currency) Exponent() int {
+	if zeroDecimal[c] {
+		return 0
+	}
+	return 2
+}
+
+// IsValid ตรวจสอบว่าเป็นรหัสสกุลเงิน 3 ตัวอักษรพิมพ์ใหญ่
+func (c Currency) IsValid() bool {
+	if len(c) != 3 {
+		return false
+	}
+	for _, r := range c {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrInvalidAmount คืนค่าเมื่อแปลงข้อความเป็นจำนวนเงินไม่ได้ หรือมีทศนิยมเกิน 2 ตำแหน่ง
+var ErrInvalidAmount = errors.New("invalid money amount")
+
+// Money คือจำนวนเงินแบบแม่นยำ Amount เป็นหน่วยย่อยของสกุลเงิน (สตางค์สำหรับ THB)
+// Currency ว่าง (ค่าศูนย์ของ Money) ถือว่าเป็นสกุลเงินเดียวกับอีกฝั่งเมื่อคำนวณ
+//
+// กฎการปัดเศษ: การคำนวณที่ได้เศษของหน่วยย่อย (เปอร์เซ็นต์ สัดส่วน VAT) ปัดครึ่งออกจากศูนย์ (half away from zero)
+// เสมอ ส่วนการแบ่งยอดเป็นหลายส่วนใช้ Allocate ซึ่งรับประกันว่าผลรวมเท่ากับยอดเดิมพอดี
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+// New สร้างจำนวนเงินจากหน่วยย่อย
+func New(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Satang สร้างจำนวนเงินบาทจากสตางค์
+func Satang(satang int64) Money {
+	return Money{Amount: satang, Currency: THB}
+}
+
+// Baht สร้างจำนวนเงินบาทเต็มจำนวน
+func Baht(baht int64) Money {
+	return Money{Amount: baht * 100, Currency: THB}
+}
+
+// Parse แปลงข้อความทศนิยม เช่น "1494.50" เป็นเงินบาท ทศนิยมเกิน 2 ตำแหน่งถือว่าผิด (ไม่ปัดเศษเงียบๆ)
+func Parse(s string) (Money, error) {
+	return parse(s, false)
+}
+
+// parse แปลงข้อความทศนิยมเป็นสตางค์ lenient เป็น true จะปัดทศนิยมที่เกิน 2 ตำแหน่งแทนการคืน error
+// (ใช้กับค่าจากฐานข้อมูล เช่น ผลของ SUM หรือ NUMERIC ที่ไม่จำกัดทศนิยม)
+func parse(s string, lenient bool) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	var roundUp bool
+	if len(frac) > 2 {
+		if !lenient && strings.TrimRight(frac[2:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %q has more than 2 decimal places", ErrInvalidAmount, s)
+		}
+		roundUp = frac[2] >= '5'
+		frac = frac[:2]
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+	if whole == "" {
+		whole = "0"
+	}
+	amount, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, s)
+	}
+	if roundUp {
+		amount++
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: THB}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String คืนจำนวนเงินเป็นทศนิยมตามหน่วยย่อยของสกุลเงิน เช่น "1494.50" หรือ "5380" สำหรับ JPY (ไม่มีรหัสสกุลเงิน)
+func (m Money) String() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	if m.Currency.Exponent() == 0 {
+		return fmt.Sprintf("%s%d", sign, amount)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// currency คืนสกุลเงินของผลการคำนวณระหว่าง m กับ o และ panic เมื่อสกุลเงินต่างกัน
+// (การรวมเงินต่างสกุลโดยไม่แปลงค่าเป็นบั๊กของโปรแกรม ไม่ใช่ข้อมูลผิด)
+func (m Money) currency(o Money) Currency {
+	switch {
+	case m.Currency == "":
+		return o.Currency
+	case o.Currency == "" || o.Currency == m.Currency:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money: currency mismatch %s and %s", m.Currency, o.Currency))
+}
+
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.currency(o)}
+}
+
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.currency(o)}
+}
+
+// Mul คูณด้วยจำนวนเต็ม เช่น ราคาต่อชิ้น x จำนวน
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// Cmp เปรียบเทียบ m กับ o คืน -1, 0 หรือ 1
+func (m Money) Cmp(o Money) int {
+	m.currency(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Min คืนจำนวนเงินที่น้อยกว่า
+func Min(a, b Money) Money {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// Sum รวมจำนวนเงินทั้งหมด
+func Sum(amounts ...Money) Money {
+	var total Money
+	for _, m := range amounts {
+		total = total.Add(m)
+	}
+	return total
+}
+
+// MulRatio คืน m x num / den ปัดเศษครึ่งออกจากศูนย์ เช่น VAT ที่รวมในราคาคือ MulRatio(7, 107)
+func (m Money) MulRatio(num, den int64) Money {
+	return Money{Amount: divRound(m.Amount*num, den), Currency: m.Currency}
+}
+
+// Percent คืน m x percent / 100 โดย percent เป็นร้อยละแบบทศนิยม 2 ตำแหน่งในรูปหน่วยย่อย
+// (เช่น Money ที่มีค่า 12.50 คือร้อยละ 12.5) ปัดเศษแบบเดียวกับ MulRatio
+func (m Money) Percent(percent Money) Money {
+	return m.MulRatio(percent.Amount, 100*100)
+}
+
+func divRound(n, d int64) int64 {
+	if d < 0 {
+		n, d = -n, -d
+	}
+	q, r := n/d, n%d
+	if 2*abs(r) >= d {
+		if n < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Allocate แบ่ง m ตามสัดส่วน weights โดยผลรวมเท่ากับ m พอดี เศษที่เหลือจากการปัดลงแจกให้ส่วนที่มีเศษมากที่สุดก่อน
+// (ส่วนแรกก่อนเมื่อเศษเท่ากัน) ใช้กระจายส่วนลดระดับตะกร้าไปยังแต่ละรายการ weights ที่ติดลบถือว่าเป็นศูนย์
+func (m Money) Allocate(weights []int64) []Money {
+	parts := make([]Money, len(weights))
+	var total int64
+	for _, w := range weights {
+		if w > 0 {
+			total += w
+		}
+	}
+	for i := range parts {
+		parts[i].Currency = m.Currency
+	}
+	if total == 0 {
+		return parts
+	}
+
+	sign := int64(1)
+	amount := m.Amount
+	if amount < 0 {
+		sign, amount = -1, -amount
+	}
+	remainders := make([]int64, len(weights))
+	left := amount
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		parts[i].Amount = amount * w / total
+		remainders[i] = amount * w % total
+		left -= parts[i].Amount
+	}
+	for ; left > 0; left-- {
+		best := -1
+		for i, r := range remainders {
+			if weights[i] > 0 && (best < 0 || r > remainders[best]) {
+				best = i
+			}
+		}
+		parts[best].Amount++
+		remainders[best] = -1
+	}
+	for i := range parts {
+		parts[i].Amount *= sign
+	}
+	return parts
+}
+
+// MarshalJSON เขียนจำนวนเงินเป็นตัวเลขทศนิยม 2 ตำแหน่ง เช่น 1494.50 เพื่อไม่ให้ค่าคลาดเคลื่อนแบบ float
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON อ่านจำนวนเงินได้ทั้งแบบตัวเลข (1494.5) และข้อความ ("1494.50") เป็นเงินบาท
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value เขียนจำนวนเงินลงคอลัมน์ NUMERIC เป็นข้อความทศนิยม (สกุลเงินเก็บแยกคอลัมน์ถ้าจำเป็น)
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan อ่านจำนวนเงินจากคอลัมน์ NUMERIC เป็นเงินบาท ค่าที่มีทศนิยมเกิน 2 ตำแหน่งจะถูกปัดเศษ
+func (m *Money) Scan(src interface{}) error {
+	var parsed Money
+	var err error
+	switch v := src.(type) {
+	case []byte:
+		parsed, err = parse(string(v), true)
+	case string:
+		parsed, err = parse(v, true)
+	case int64:
+		parsed = Baht(v)
+	case float64:
+		parsed = Satang(int64(math.Round(v * 100)))
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	if err != nil {
+		return err
+	}
+	if m.Currency != "" {
+		parsed.Currency = m.Currency
+	}
+	*m = parsed
+	return nil
+}
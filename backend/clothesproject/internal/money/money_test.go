@@ -0,0 +1,237 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "1494.50", want: 149450},
+		{in: "1494.5", want: 149450},
+		{in: "12", want: 1200},
+		{in: ".5", want: 50},
+		{in: "-3.25", want: -325},
+		{in: "+1", want: 100},
+		{in: " 7.07 ", want: 707},
+		{in: "1.230", want: 123},
+		{in: "1.234", wantErr: true},
+		{in: "", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "1,000", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("Parse(%q) error = %v, want ErrInvalidAmount", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got != Satang(tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %d satang", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestScanRoundsExtraDecimals(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want int64
+	}{
+		{src: []byte("1.234"), want: 123},
+		{src: "1.235", want: 124},
+		{src: "-1.235", want: -124},
+		{src: "0.005", want: 1},
+		{src: int64(12), want: 1200},
+		{src: 19.99, want: 1999},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v) unexpected error: %v", tt.src, err)
+			continue
+		}
+		if m != Satang(tt.want) {
+			t.Errorf("Scan(%v) = %+v, want %d satang", tt.src, m, tt.want)
+		}
+	}
+
+	var m Money
+	if err := m.Scan(true); err == nil {
+		t.Error("Scan(bool) should fail")
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{in: Satang(149450), want: "1494.50"},
+		{in: Satang(5), want: "0.05"},
+		{in: Satang(-5), want: "-0.05"},
+		{in: Baht(-12), want: "-12.00"},
+		{in: Money{}, want: "0.00"},
+		{in: New(5380, "JPY"), want: "5380"},
+		{in: New(-7, "KRW"), want: "-7"},
+		{in: New(1999, "USD"), want: "19.99"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMulRatioRoundsHalfAwayFromZero(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		num, den int64
+		want     int64
+	}{
+		{amount: 10700, num: 7, den: 107, want: 700},
+		{amount: 100, num: 7, den: 107, want: 7},
+		{amount: -100, num: 7, den: 107, want: -7},
+		{amount: 1, num: 1, den: 2, want: 1},
+		{amount: -1, num: 1, den: 2, want: -1},
+		{amount: 3, num: 1, den: 4, want: 1},
+		{amount: 1, num: 1, den: 4, want: 0},
+		{amount: 5, num: 1, den: -2, want: -3},
+	}
+	for _, tt := range tests {
+		if got := Satang(tt.amount).MulRatio(tt.num, tt.den); got != Satang(tt.want) {
+			t.Errorf("Satang(%d).MulRatio(%d, %d) = %d, want %d", tt.amount, tt.num, tt.den, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		amount  int64
+		percent int64
+		want    int64
+	}{
+		{amount: 10000, percent: 1250, want: 1250},
+		{amount: 999, percent: 1000, want: 100},
+		{amount: 995, percent: 1000, want: 100},
+		{amount: 994, percent: 1000, want: 99},
+		{amount: 12345, percent: 10000, want: 12345},
+		{amount: 12345, percent: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := Satang(tt.amount).Percent(Satang(tt.percent)); got != Satang(tt.want) {
+			t.Errorf("Satang(%d).Percent(%d) = %d, want %d", tt.amount, tt.percent, got.Amount, tt.want)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		amount  int64
+		weights []int64
+		want    []int64
+	}{
+		{amount: 100, weights: []int64{1, 1, 1}, want: []int64{34, 33, 33}},
+		{amount: -100, weights: []int64{1, 1, 1}, want: []int64{-34, -33, -33}},
+		{amount: 5, weights: []int64{1, 2}, want: []int64{2, 3}},
+		{amount: 10, weights: []int64{3, 3, 4}, want: []int64{3, 3, 4}},
+		{amount: 100, weights: []int64{0, 1, -5, 3}, want: []int64{0, 25, 0, 75}},
+		{amount: 100, weights: []int64{0, 0}, want: []int64{0, 0}},
+		{amount: 0, weights: []int64{2, 5}, want: []int64{0, 0}},
+		{amount: 2, weights: []int64{1, 1, 1}, want: []int64{1, 1, 0}},
+		{amount: 1000, weights: []int64{14950, 29900, 150}, want: []int64{332, 665, 3}},
+	}
+	for _, tt := range tests {
+		parts := Satang(tt.amount).Allocate(tt.weights)
+		if len(parts) != len(tt.want) {
+			t.Fatalf("Satang(%d).Allocate(%v) returned %d parts, want %d", tt.amount, tt.weights, len(parts), len(tt.want))
+		}
+		for i, part := range parts {
+			if part != Satang(tt.want[i]) {
+				t.Errorf("Satang(%d).Allocate(%v)[%d] = %+v, want %d satang", tt.amount, tt.weights, i, part, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestArithmeticCurrency(t *testing.T) {
+	usd := New(150, "USD")
+	if got := (Money{}).Add(usd); got != usd {
+		t.Errorf("zero Money + %+v = %+v, want %+v", usd, got, usd)
+	}
+	if got := usd.Sub(Money{Amount: 50}); got != New(100, "USD") {
+		t.Errorf("%+v - 50 = %+v, want 1.00 USD", usd, got)
+	}
+	if got := Sum(Baht(1), Satang(50), Satang(-25)); got != Satang(125) {
+		t.Errorf("Sum = %+v, want 1.25 THB", got)
+	}
+	if got := Min(Baht(2), Satang(199)); got != Satang(199) {
+		t.Errorf("Min = %+v, want 1.99 THB", got)
+	}
+	if got := Satang(1999).Mul(3); got != Satang(5997) {
+		t.Errorf("Mul = %+v, want 59.97 THB", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("adding THB and USD should panic")
+		}
+	}()
+	Baht(1).Add(usd)
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Price Money `json:"price"`
+	}{Price: Satang(149450)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"price":1494.50}` {
+		t.Errorf("Marshal = %s, want {\"price\":1494.50}", data)
+	}
+
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: `1494.5`, want: 149450},
+		{in: `"12.34"`, want: 1234},
+		{in: `0`, want: 0},
+		{in: `"1.234"`, wantErr: true},
+		{in: `true`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var m Money
+		err := json.Unmarshal([]byte(tt.in), &m)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) should fail", tt.in)
+			}
+			continue
+		}
+		if err != nil || m != Satang(tt.want) {
+			t.Errorf("Unmarshal(%s) = %+v, %v, want %d satang", tt.in, m, err, tt.want)
+		}
+	}
+
+	m := Satang(42)
+	if err := json.Unmarshal([]byte(`null`), &m); err != nil || m != Satang(42) {
+		t.Errorf("Unmarshal(null) = %+v, %v, want value unchanged", m, err)
+	}
+}
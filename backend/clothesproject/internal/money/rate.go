@@ -0,0 +1,103 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// rateScale คือจำนวนตำแหน่งทศนิยมของอัตราแลกเปลี่ยน
+const rateScale = 6
+
+// Rate คืออัตราแลกเปลี่ยนเป็นบาทต่อเงิน 1 หน่วยของสกุลเงินอื่น (เช่น 1 USD = 35.5 บาท)
+// เก็บเป็นจำนวนเต็มของหนึ่งในล้านบาท เพื่อให้แปลงค่าได้ผลเดิมทุกครั้ง
+type Rate int64
+
+// ParseRate แปลงข้อความทศนิยม เช่น "35.5" เป็นอัตราแลกเปลี่ยน (ทศนิยมไม่เกิน 6 ตำแหน่ง ต้องมากกว่า 0)
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) || len(frac) > rateScale {
+		return 0, fmt.Errorf("invalid exchange rate %q", s)
+	}
+	frac += strings.Repeat("0", rateScale-len(frac))
+	n, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid exchange rate %q", s)
+	}
+	return Rate(n), nil
+}
+
+func (r Rate) String() string {
+	s := fmt.Sprintf("%0*d", rateScale+1, int64(r))
+	whole, frac := s[:len(s)-rateScale], strings.TrimRight(s[len(s)-rateScale:], "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+// FromTHB แปลงเงินบาท m เป็นสกุลเงิน to ด้วยอัตรานี้ ปัดเศษหน่วยย่อยครึ่งออกจากศูนย์
+// (คำนวณด้วยจำนวนเต็มทั้งหมด ผลจึงเหมือนเดิมทุกครั้งสำหรับยอดและอัตราเดียวกัน)
+func (r Rate) FromTHB(m Money, to Currency) Money {
+	if r <= 0 {
+		panic("money: exchange rate must be positive")
+	}
+	// หน่วยย่อยปลายทาง = สตางค์ x 10^(exp ปลายทาง + rateScale) / (100 x rate)
+	num := new(big.Int).Mul(big.NewInt(m.Amount), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(to.Exponent()+rateScale)), nil))
+	den := new(big.Int).Mul(big.NewInt(100), big.NewInt(int64(r)))
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Money{Amount: q.Int64(), Currency: to}
+}
+
+// MarshalJSON เขียนอัตราเป็นตัวเลขทศนิยม เช่น 35.5
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON อ่านอัตราได้ทั้งแบบตัวเลขและข้อความ
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value เขียนอัตราลงคอลัมน์ NUMERIC
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Scan อ่านอัตราจากคอลัมน์ NUMERIC
+func (r *Rate) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("money: cannot scan %T into Rate", src)
+	}
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
@@ -0,0 +1,65 @@
+package money
+
+import "testing"
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rate
+		str     string
+		wantErr bool
+	}{
+		{in: "35.5", want: 35500000, str: "35.5"},
+		{in: "0.24", want: 240000, str: "0.24"},
+		{in: "1", want: 1000000, str: "1"},
+		{in: "0.000001", want: 1, str: "0.000001"},
+		{in: " 36.123456 ", want: 36123456, str: "36.123456"},
+		{in: "0", wantErr: true},
+		{in: "1.1234567", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRate(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+			continue
+		}
+		if got.String() != tt.str {
+			t.Errorf("ParseRate(%q).String() = %q, want %q", tt.in, got.String(), tt.str)
+		}
+	}
+}
+
+func TestFromTHB(t *testing.T) {
+	tests := []struct {
+		rate   string
+		amount Money
+		to     Currency
+		want   Money
+	}{
+		{rate: "35.5", amount: Baht(100), to: "USD", want: New(282, "USD")},
+		{rate: "35.5", amount: Baht(-100), to: "USD", want: New(-282, "USD")},
+		{rate: "35.5", amount: Baht(355), to: "USD", want: New(1000, "USD")},
+		{rate: "0.24", amount: Baht(100), to: "JPY", want: New(417, "JPY")},
+		{rate: "0.24", amount: Satang(6), to: "JPY", want: New(0, "JPY")},
+		{rate: "0.24", amount: Satang(12), to: "JPY", want: New(1, "JPY")},
+		{rate: "1", amount: Satang(1999), to: "THB", want: Satang(1999)},
+	}
+	for _, tt := range tests {
+		rate, err := ParseRate(tt.rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rate.FromTHB(tt.amount, tt.to); got != tt.want {
+			t.Errorf("Rate(%s).FromTHB(%v, %s) = %+v, want %+v", tt.rate, tt.amount, tt.to, got, tt.want)
+		}
+	}
+}
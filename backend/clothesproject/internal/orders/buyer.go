@@ -0,0 +1,28 @@
+package orders
+
+// TaxBuyer คือข้อมูลผู้ซื้อสำหรับออกใบกำกับภาษีเต็มรูป ลูกค้าระบุตอนสั่งซื้อ (ไม่ระบุจะได้ใบเสร็จรับเงิน/ใบกำกับภาษีอย่างย่อ)
+// TaxID คือเลขประจำตัวผู้เสียภาษี 13 หลัก (ไม่มีขีด) Branch คือสาขาของนิติบุคคล เช่น "สำนักงานใหญ่" (ไม่บังคับ)
+type TaxBuyer struct {
+	Name    string `json:"name" binding:"required"`
+	TaxID   string `json:"tax_id" binding:"required"`
+	Address string `json:"address" binding:"required"`
+	Branch  string `json:"branch,omitempty"`
+}
+
+// ValidTaxID ตรวจสอบเลขประจำตัวผู้เสียภาษี 13 หลักพร้อมหลักตรวจสอบ (หลักสุดท้าย)
+// ตามวิธีเดียวกับเลขประจำตัวประชาชน: (11 - ผลรวมของหลักที่ i คูณ 13-i mod 11) mod 10
+func ValidTaxID(taxID string) bool {
+	if len(taxID) != 13 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 13; i++ {
+		if taxID[i] < '0' || taxID[i] > '9' {
+			return false
+		}
+		if i < 12 {
+			sum += int(taxID[i]-'0') * (13 - i)
+		}
+	}
+	return (11-sum%11)%10 == int(taxID[12]-'0')
+}
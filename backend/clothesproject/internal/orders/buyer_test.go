@@ -0,0 +1,27 @@
+package orders
+
+import "testing"
+
+func TestValidTaxID(t *testing.T) {
+	tests := []struct {
+		taxID string
+		want  bool
+	}{
+		{taxID: "0105550123451", want: true},
+		{taxID: "3100600445635", want: true},
+		{taxID: "1234567890121", want: true},
+		{taxID: "0999999999991", want: true},
+		{taxID: "0105550123452", want: false},
+		{taxID: "1234567890123", want: false},
+		{taxID: "010555012345", want: false},
+		{taxID: "01055501234510", want: false},
+		{taxID: "0-1055-50123-45-1", want: false},
+		{taxID: "010555012345a", want: false},
+		{taxID: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := ValidTaxID(tt.taxID); got != tt.want {
+			t.Errorf("ValidTaxID(%q) = %v, want %v", tt.taxID, got, tt.want)
+		}
+	}
+}
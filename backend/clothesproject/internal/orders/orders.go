@@ -0,0 +1,740 @@
+package orders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"clothesproject/internal/clothesstore"
+	"clothesproject/internal/currency"
+	"clothesproject/internal/money"
+	"clothesproject/internal/pricing"
+	"clothesproject/internal/promotions"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrEmptyCart คืนค่าเมื่อพยายามสร้างคำสั่งซื้อจากตะกร้าที่ว่างอยู่
+	ErrEmptyCart = errors.New("cart is empty")
+	// ErrOrderNotFound คืนค่าเมื่อไม่พบคำสั่งซื้อ หรือคำสั่งซื้อไม่ใช่ของผู้ใช้
+	ErrOrderNotFound = errors.New("order not found")
+)
+
+// OrderStore จัดการคำสั่งซื้อ ซึ่งสร้างจากตะกร้าของผู้ใช้และแก้ไขรายการสินค้าไม่ได้หลังสร้าง
+type OrderStore interface {
+	CreateOrder(ctx context.Context, userID string, req CheckoutRequest) (Order, error)
+	GetOrdersByUser(ctx context.Context, userID string) ([]Order, error)
+	GetOrder(ctx context.Context, userID string, orderID int) (Order, error)
+	UpdateOrderStatus(ctx context.Context, orderID int, status Status, changedBy string, note string) (Order, error)
+	GetOrderHistory(ctx context.Context, orderID int) ([]StatusChange, error)
+	CollectOrder(ctx context.Context, orderID int, code string, staffID string) (Order, error)
+}
+
+// Order คือคำสั่งซื้อ Pickup มีค่าเฉพาะเมื่อ Fulfilment เป็น pickup
+// ยอดรวม VAT และค่าส่ง (pricing.Totals) คำนวณตอนสร้างและไม่เปลี่ยนภายหลัง DiscountTotal แยกตามโปรโมชันใน Discounts
+type Order struct {
+	ID     int    `json:"id"`
+	UserID string `json:"user_id"`
+	Status Status `json:"status"`
+	pricing.Totals
+	Coupon     string               `json:"coupon,omitempty"`
+	Discounts  []promotions.Applied `json:"discounts"`
+	Fulfilment FulfilmentMethod     `json:"fulfilment"`
+	Pickup     *Pickup              `json:"pickup,omitempty"`
+	TaxInvoice *TaxBuyer            `json:"tax_invoice,omitempty"`
+	Items      []OrderItem          `json:"items"`
+	// Display คือยอดรวมในสกุลเงินที่ลูกค้าเลือกตอนสั่งซื้อ แปลงด้วยอัตราที่เก็บไว้ในคำสั่งซื้อ (ไม่มีเมื่อเป็นบาท)
+	Display   *currency.Totals `json:"display,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// OrderItem เก็บ snapshot ของสินค้า ณ เวลาที่สั่งซื้อ (ชื่อ แบรนด์ และราคา)
+// เพื่อให้คำสั่งซื้อไม่เปลี่ยนตามเมื่อสินค้าถูกแก้ไขหรือลบภายหลัง LineTotal เป็นราคาก่อนหัก Discount ระดับรายการ
+type OrderItem struct {
+	ID          int         `json:"id"`
+	ProductID   int         `json:"product_id"`
+	VariantID   int         `json:"variant_id"`
+	SKU         string      `json:"sku"`
+	Size        string      `json:"size"`
+	Colour      string      `json:"colour"`
+	ProductName string      `json:"name"`
+	BrandID     int         `json:"brand_id"`
+	BrandName   string      `json:"brand_name"`
+	ImgSrc      string      `json:"imgsrc"`
+	UnitPrice   money.Money `json:"unit_price"`
+	Quantity    int         `json:"quantity"`
+	LineTotal   money.Money `json:"line_total"`
+	Discount    money.Money `json:"discount"`
+	// DisplayUnitPrice และ DisplayLineTotal คือราคาในสกุลเงินของคำสั่งซื้อ (ไม่มีเมื่อเป็นบาท)
+	DisplayUnitPrice *currency.Price `json:"display_unit_price,omitempty"`
+	DisplayLineTotal *currency.Price `json:"display_line_total,omitempty"`
+}
+
+type PostgresOrderStore struct {
+	pdb *clothesstore.PostgresDatabase
+}
+
+func NewPostgresOrderStore(pdb *clothesstore.PostgresDatabase) *PostgresOrderStore {
+	return &PostgresOrderStore{pdb: pdb}
+}
+
+// CreateOrder สร้างคำสั่งซื้อจากสินค้าทั้งหมดในตะกร้าของผู้ใช้และล้างตะกร้าใน transaction เดียวกัน
+// ถ้าเลือกรับที่สาขา จะกันสต็อกของสาขานั้นไว้แทนการตัดสต็อกในคลัง
+func (s *PostgresOrderStore) CreateOrder(ctx context.Context, userID string, req CheckoutRequest) (Order, error) {
+	if req.Fulfilment == "" {
+		req.Fulfilment = FulfilmentShipping
+	}
+	if !req.Fulfilment.IsValid() {
+		return Order{}, fmt.Errorf("unknown fulfilment method %q", req.Fulfilment)
+	}
+
+	tx, err := s.pdb.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return Order{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// ล็อกแถวในตะกร้าไว้ เพื่อไม่ให้มีการเพิ่ม/ลบสินค้าระหว่างสร้างคำสั่งซื้อ
+	rows, err := tx.QueryContext(ctx, `
+        SELECT c.cart_id, v.product_id, v.variant_id, v.sku, v.size, v.colour, p.name, p.brand, COALESCE(b.brandname, ''),
+               COALESCE(v.imgsrc, p.imgsrc), p.category, COALESCE(v.price, p.price), c.quantity, p.weight_grams
+        FROM cart c
+        JOIN product_variants v ON v.variant_id = c.variant_id
+        JOIN products p ON p.id = v.product_id
+        LEFT JOIN brand b ON b.id::text = p.brand
+        WHERE c.user_id = $1
+        ORDER BY v.variant_id
+        FOR UPDATE OF c
+    `, userID)
+	if err != nil {
+		return Order{}, fmt.Errorf("failed to query cart items: %v", err)
+	}
+
+	var items []OrderItem
+	var cartIDs []int
+	var lines []promotions.Line
+	var weights []int
+	for rows.Next() {
+		var cartID, weight int
+		var category string
+		var item OrderItem
+		if err := rows.Scan(&cartID, &item.ProductID, &item.VariantID, &item.SKU, &item.Size, &item.Colour, &item.ProductName, &item.BrandID, &item.BrandName,
+			&item.ImgSrc, &category, &item.UnitPrice, &item.Quantity, &weight); err != nil {
+			rows.Close()
+			return Order{}, fmt.Errorf("failed to scan cart item: %v", err)
+		}
+		items = append(items, item)
+		cartIDs = append(cartIDs, cartID)
+		weights = append(weights, weight*item.Quantity)
+		lines = append(lines, promotions.Line{Key: cartID, BrandID: item.BrandID, Category: category, UnitPrice: item.UnitPrice, Quantity: item.Quantity})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Order{}, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	if len(items) == 0 {
+		return Order{}, ErrEmptyCart
+	}
+
+	// คำนวณส่วนลดแบบเดียวกับที่แสดงในตะกร้า คูปองที่ใช้ไม่ได้ทำให้สั่งซื้อไม่สำเร็จ (ไม่ตัดคูปองทิ้งเงียบๆ)
+	discounts, err := promotions.ApplyForUpdate(ctx, tx, lines, req.Coupon, userID)
+	if err != nil {
+		return Order{}, err
+	}
+	if discounts.CouponErr != nil {
+		return Order{}, discounts.CouponErr
+	}
+	priced := make([]pricing.Line, len(items))
+	for i := range items {
+		items[i].LineTotal = discounts.Lines[i].Subtotal
+		items[i].Discount = discounts.Lines[i].Discount
+		priced[i] = pricing.Line{BrandID: items[i].BrandID, Subtotal: items[i].LineTotal, Discount: items[i].Discount, WeightGrams: weights[i]}
+	}
+
+	// รับที่สาขาไม่มีค่าส่ง
+	totals, err := pricing.Quote(ctx, tx, priced, discounts.DiscountTotal.Sub(discounts.LineDiscount), req.Province, req.Fulfilment == FulfilmentShipping)
+	if err != nil {
+		return Order{}, err
+	}
+
+	order := Order{
+		UserID:     userID,
+		Status:     StatusPending,
+		Totals:     totals,
+		Coupon:     discounts.Coupon,
+		Discounts:  discounts.Applied(),
+		Fulfilment: req.Fulfilment,
+		TaxInvoice: req.TaxInvoice,
+		Items:      items,
+	}
+	var displayCurrency sql.NullString
+	var exchangeRate *money.Rate
+	var exchangeRateID sql.NullInt64
+	if req.Rate != nil {
+		order.Display = req.Rate.Totals(order.Totals)
+		displayCurrency = sql.NullString{String: string(req.Rate.Currency), Valid: true}
+		exchangeRate = &req.Rate.Rate
+		exchangeRateID = sql.NullInt64{Int64: int64(req.Rate.ID), Valid: req.Rate.ID != 0}
+	}
+
+	if order.Fulfilment == FulfilmentPickup {
+		order.Pickup, err = newPickup(ctx, tx, req.PickupBranchID, time.Now())
+		if err != nil {
+			return Order{}, err
+		}
+	} else {
+		// ตัดสต็อกทีละ SKU ตามลำดับ variant_id (ตามที่ query เรียงไว้) เพื่อไม่ให้เกิด deadlock ระหว่างคำสั่งซื้อพร้อมกัน
+		for i, item := range items {
+			if err := clothesstore.DeductStock(ctx, tx, cartIDs[i], item.VariantID, item.Quantity); err != nil {
+				return Order{}, err
+			}
+		}
+	}
+
+	var pickupBranchID sql.NullInt64
+	var pickupCode sql.NullString
+	var windowStart, windowEnd sql.NullTime
+	if order.Pickup != nil {
+		pickupBranchID = sql.NullInt64{Int64: int64(order.Pickup.BranchID), Valid: true}
+		pickupCode = sql.NullString{String: order.Pickup.Code, Valid: true}
+		windowStart = sql.NullTime{Time: order.Pickup.WindowStart, Valid: true}
+		windowEnd = sql.NullTime{Time: order.Pickup.WindowEnd, Valid: true}
+	}
+	var buyer TaxBuyer
+	if order.TaxInvoice != nil {
+		buyer = *order.TaxInvoice
+	}
+
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO orders (user_id, status, subtotal, discount_total, shipping_fee, vat_included, vat_added, total, coupon_code, shipping_province,
+                            display_currency, exchange_rate, exchange_rate_id, fulfilment, pickup_branch_id, pickup_code, pickup_window_start, pickup_window_end,
+                            buyer_name, buyer_tax_id, buyer_address, buyer_branch)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11, $12, $13, $14, $15, $16, $17, $18,
+                NULLIF($19, ''), NULLIF($20, ''), NULLIF($21, ''), NULLIF($22, ''))
+        RETURNING order_id, created_at, updated_at
+    `, userID, order.Status, order.Subtotal, order.DiscountTotal, order.ShippingFee, order.VATIncluded, order.VATAdded, order.Total, order.Coupon, order.Province,
+		displayCurrency, exchangeRate, exchangeRateID, order.Fulfilment, pickupBranchID, pickupCode, windowStart, windowEnd,
+		buyer.Name, buyer.TaxID, buyer.Address, buyer.Branch).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return Order{}, fmt.Errorf("failed to create order: %v", err)
+	}
+
+	// กันสต็อกของสาขาจนถึงเวลาสิ้นสุดการรับสินค้า (การจองในตะกร้าจะหายไปพร้อมกับการล้างตะกร้า)
+	if order.Pickup != nil {
+		for _, item := range items {
+			if err := clothesstore.HoldBranchStock(ctx, tx, order.ID, order.Pickup.BranchID, item.VariantID, item.Quantity, order.Pickup.WindowEnd); err != nil {
+				return Order{}, err
+			}
+		}
+	}
+
+	for i := range order.Items {
+		item := &order.Items[i]
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO order_items (order_id, product_id, variant_id, sku, size, colour, product_name, brand_id, brand_name, imgsrc, unit_price, quantity, line_total, discount)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+            RETURNING order_item_id
+        `, order.ID, item.ProductID, item.VariantID, item.SKU, item.Size, item.Colour, item.ProductName, item.BrandID, item.BrandName,
+			item.ImgSrc, item.UnitPrice, item.Quantity, item.LineTotal, item.Discount).Scan(&item.ID)
+		if err != nil {
+			return Order{}, fmt.Errorf("failed to create order item: %v", err)
+		}
+	}
+
+	if err := promotions.Record(ctx, tx, order.ID, discounts); err != nil {
+		return Order{}, err
+	}
+
+	for _, shipment := range order.Shipments {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO order_shipments (order_id, brand_id, weight_grams, fee)
+            VALUES ($1, $2, $3, $4)
+        `, order.ID, shipment.BrandID, shipment.WeightGrams, shipment.Fee)
+		if err != nil {
+			return Order{}, fmt.Errorf("failed to create order shipment: %v", err)
+		}
+	}
+
+	if err := insertStatusChange(ctx, tx, order.ID, "", order.Status, userID, "order created"); err != nil {
+		return Order{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM cart WHERE user_id = $1", userID); err != nil {
+		return Order{}, fmt.Errorf("failed to clear cart: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Order{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return order, nil
+}
+
+// GetOrdersByUser ดึงคำสั่งซื้อทั้งหมดของผู้ใช้ เรียงจากล่าสุด
+func (s *PostgresOrderStore) GetOrdersByUser(ctx context.Context, userID string) ([]Order, error) {
+	rows, err := s.pdb.DB().QueryContext(ctx, `
+        SELECT `+orderColumns+`
+        FROM orders o
+        LEFT JOIN branch b ON b.id = o.pickup_branch_id
+        WHERE o.user_id = $1
+        ORDER BY o.created_at DESC, o.order_id DESC
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %v", err)
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	details := make([]*Order, len(orders))
+	for i := range orders {
+		details[i] = &orders[i]
+	}
+	if err := s.loadDetails(ctx, details...); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// GetOrder ดึงคำสั่งซื้อตาม ID โดยต้องเป็นคำสั่งซื้อของผู้ใช้เท่านั้น
+func (s *PostgresOrderStore) GetOrder(ctx context.Context, userID string, orderID int) (Order, error) {
+	order, err := scanOrder(s.pdb.DB().QueryRowContext(ctx, `
+        SELECT `+orderColumns+`
+        FROM orders o
+        LEFT JOIN branch b ON b.id = o.pickup_branch_id
+        WHERE o.order_id = $1 AND o.user_id = $2
+    `, orderID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Order{}, ErrOrderNotFound
+		}
+		return Order{}, err
+	}
+
+	if err := s.loadDetails(ctx, &order); err != nil {
+		return Order{}, err
+	}
+
+	return order, nil
+}
+
+// UpdateOrderStatus เปลี่ยนสถานะคำสั่งซื้อตาม state machine และบันทึกประวัติว่าใครเปลี่ยนเมื่อไร
+func (s *PostgresOrderStore) UpdateOrderStatus(ctx context.Context, orderID int, status Status, changedBy string, note string) (Order, error) {
+	if !status.IsValid() {
+		return Order{}, fmt.Errorf("%w: unknown status %q", ErrInvalidTransition, status)
+	}
+
+	tx, err := s.pdb.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return Order{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	order, err := lockOrder(ctx, tx, orderID)
+	if err != nil {
+		return Order{}, err
+	}
+
+	if !canTransition(order.Fulfilment, order.Status, status) {
+		return Order{}, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, order.Status, status)
+	}
+	// การชำระเงินยืนยันได้จาก callback ที่ตรวจลายเซ็นแล้วเท่านั้น (MarkPaid) พนักงานตั้งเป็น paid เองไม่ได้
+	if status == StatusPaid {
+		return Order{}, fmt.Errorf("%w: orders are marked paid by verified payment callbacks", ErrInvalidTransition)
+	}
+	// คำสั่งซื้อที่ไม่มารับภายในเวลาเป็น expired ได้จาก ExpirePickups เท่านั้น
+	if status == StatusExpired {
+		return Order{}, fmt.Errorf("%w: pickup orders expire when the pickup window ends", ErrInvalidTransition)
+	}
+	// คำสั่งซื้อแบบรับที่สาขาต้องยืนยันรหัสรับสินค้าผ่าน CollectOrder จึงจะเป็น delivered ได้
+	if order.Fulfilment == FulfilmentPickup && status == StatusDelivered {
+		return Order{}, fmt.Errorf("%w: pickup orders are delivered by collecting them", ErrInvalidTransition)
+	}
+	// ยกเลิกหรือคืนเงินก่อนมารับสินค้า ต้องปล่อยสต็อกที่สาขากันไว้คืน
+	if order.Fulfilment == FulfilmentPickup && (status == StatusCancelled || status == StatusRefunded) {
+		if err := clothesstore.ReleaseBranchHolds(ctx, tx, orderID); err != nil {
+			return Order{}, err
+		}
+	}
+	// ยกเลิกหรือคืนเงินคำสั่งซื้อแบบจัดส่ง ต้องคืนสต็อกที่ตัดไปตอนสร้างคำสั่งซื้อ
+	if order.Fulfilment == FulfilmentShipping && (status == StatusCancelled || status == StatusRefunded) {
+		if err := restockOrder(ctx, tx, orderID); err != nil {
+			return Order{}, err
+		}
+	}
+
+	from := order.Status
+	err = tx.QueryRowContext(ctx, `
+        UPDATE orders SET status = $2 WHERE order_id = $1
+        RETURNING status, updated_at
+    `, orderID, status).Scan(&order.Status, &order.UpdatedAt)
+	if err != nil {
+		return Order{}, fmt.Errorf("failed to update order status: %v", err)
+	}
+
+	if err := insertStatusChange(ctx, tx, orderID, from, status, changedBy, note); err != nil {
+		return Order{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Order{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	if err := s.loadDetails(ctx, &order); err != nil {
+		return Order{}, err
+	}
+
+	return order, nil
+}
+
+// MarkPaid เปลี่ยนคำสั่งซื้อเป็น paid ภายใน transaction ของ payment callback ที่ตรวจลายเซ็นแล้ว
+// เป็นทางเดียวที่คำสั่งซื้อจะเป็น paid ได้ ประวัติบันทึกว่าระบบเป็นผู้เปลี่ยนพร้อม note อ้างอิงการชำระเงิน
+func MarkPaid(ctx context.Context, tx *sql.Tx, orderID int, note string) error {
+	order, err := lockOrder(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+	if !canTransition(order.Fulfilment, order.Status, StatusPaid) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, order.Status, StatusPaid)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE orders SET status = $2 WHERE order_id = $1", orderID, StatusPaid); err != nil {
+		return fmt.Errorf("failed to update order status: %v", err)
+	}
+	return insertStatusChange(ctx, tx, orderID, order.Status, StatusPaid, "", note)
+}
+
+// MarkRefunded เปลี่ยนคำสั่งซื้อเป็น refunded ภายใน transaction ของผู้เรียก (เช่น เมื่อคืนเงินสินค้าที่ส่งคืนครบทุกรายการ)
+func MarkRefunded(ctx context.Context, tx *sql.Tx, orderID int, changedBy string, note string) error {
+	order, err := lockOrder(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+	if !canTransition(order.Fulfilment, order.Status, StatusRefunded) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, order.Status, StatusRefunded)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE orders SET status = $2 WHERE order_id = $1", orderID, StatusRefunded); err != nil {
+		return fmt.Errorf("failed to update order status: %v", err)
+	}
+	return insertStatusChange(ctx, tx, orderID, order.Status, StatusRefunded, changedBy, note)
+}
+
+// GetOrderHistory ดึงประวัติการเปลี่ยนสถานะของคำสั่งซื้อ เรียงตามเวลา
+func (s *PostgresOrderStore) GetOrderHistory(ctx context.Context, orderID int) ([]StatusChange, error) {
+	rows, err := s.pdb.DB().QueryContext(ctx, `
+        SELECT history_id, order_id, COALESCE(from_status, ''), to_status, COALESCE(changed_by::text, ''), COALESCE(note, ''), changed_at
+        FROM order_status_history
+        WHERE order_id = $1
+        ORDER BY changed_at, history_id
+    `, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order history: %v", err)
+	}
+	defer rows.Close()
+
+	var history []StatusChange
+	for rows.Next() {
+		var change StatusChange
+		if err := rows.Scan(&change.ID, &change.OrderID, &change.FromStatus, &change.ToStatus, &change.ChangedBy, &change.Note, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan order history: %v", err)
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return history, nil
+}
+
+// orderColumns คือคอลัมน์ของคำสั่งซื้อที่ scanOrder อ่าน ต้องใช้กับ FROM orders o LEFT JOIN branch b
+const orderColumns = `o.order_id, o.user_id, o.status, o.subtotal, o.discount_total, o.shipping_fee, o.vat_included, o.vat_added, o.total,
+               COALESCE(o.coupon_code, ''), COALESCE(o.shipping_province, ''), COALESCE(o.display_currency, ''), o.exchange_rate,
+               COALESCE(o.exchange_rate_id, 0), o.fulfilment,
+               o.pickup_branch_id, COALESCE(b.banch, ''), COALESCE(o.pickup_code, ''),
+               o.pickup_window_start, o.pickup_window_end, o.collected_at,
+               COALESCE(o.buyer_name, ''), COALESCE(o.buyer_tax_id, ''), COALESCE(o.buyer_address, ''), COALESCE(o.buyer_branch, ''),
+               o.created_at, o.updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOrder(row rowScanner) (Order, error) {
+	var order Order
+	var pickupBranchID sql.NullInt64
+	var branchName, pickupCode string
+	var windowStart, windowEnd, collectedAt sql.NullTime
+	var displayCurrency string
+	var exchangeRate *money.Rate
+	var exchangeRateID int
+	var buyer TaxBuyer
+	if err := row.Scan(&order.ID, &order.UserID, &order.Status, &order.Subtotal, &order.DiscountTotal, &order.ShippingFee, &order.VATIncluded, &order.VATAdded, &order.Total,
+		&order.Coupon, &order.Province, &displayCurrency, &exchangeRate, &exchangeRateID, &order.Fulfilment,
+		&pickupBranchID, &branchName, &pickupCode, &windowStart, &windowEnd, &collectedAt,
+		&buyer.Name, &buyer.TaxID, &buyer.Address, &buyer.Branch,
+		&order.CreatedAt, &order.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Order{}, err
+		}
+		return Order{}, fmt.Errorf("failed to scan order: %v", err)
+	}
+	order.VAT = order.VATIncluded.Add(order.VATAdded)
+	if buyer.TaxID != "" {
+		order.TaxInvoice = &buyer
+	}
+	if displayCurrency != "" && exchangeRate != nil {
+		rate := currency.ExchangeRate{ID: exchangeRateID, Currency: money.Currency(displayCurrency), Rate: *exchangeRate}
+		order.Display = rate.Totals(order.Totals)
+	}
+	if pickupBranchID.Valid {
+		order.Pickup = &Pickup{
+			BranchID:    int(pickupBranchID.Int64),
+			BranchName:  branchName,
+			Code:        pickupCode,
+			WindowStart: windowStart.Time,
+			WindowEnd:   windowEnd.Time,
+		}
+		if collectedAt.Valid {
+			order.Pickup.CollectedAt = &collectedAt.Time
+		}
+	}
+	return order, nil
+}
+
+// lockOrder ดึงคำสั่งซื้อพร้อมล็อกไว้ (FOR UPDATE) เพื่อไม่ให้มีการเปลี่ยนสถานะพร้อมกันสองครั้ง
+func lockOrder(ctx context.Context, tx *sql.Tx, orderID int) (Order, error) {
+	order, err := scanOrder(tx.QueryRowContext(ctx, `
+        SELECT `+orderColumns+`
+        FROM orders o
+        LEFT JOIN branch b ON b.id = o.pickup_branch_id
+        WHERE o.order_id = $1
+        FOR UPDATE OF o
+    `, orderID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Order{}, ErrOrderNotFound
+		}
+		return Order{}, err
+	}
+	return order, nil
+}
+
+// stockReturn คือจำนวนที่คืนเข้าสต็อกของ SKU หนึ่ง
+type stockReturn struct {
+	VariantID int
+	Quantity  int
+}
+
+// restockQuantities รวมจำนวนที่ต้องคืนเข้าสต็อกของแต่ละ SKU เมื่อคำสั่งซื้อแบบจัดส่งถูกยกเลิกหรือคืนเงิน
+// returned คือจำนวนของแต่ละรายการ (order_item_id) ที่คืนเงินผ่านคำขอคืนสินค้าไปแล้ว ซึ่งคืนสต็อกไปตอนนั้นแล้ว
+// SKU ที่ถูกลบไปแล้ว (VariantID เป็น 0) ไม่คืนสต็อก ผลเรียงตาม variant_id เหมือนลำดับที่ตัดสต็อก เพื่อไม่ให้เกิด deadlock
+func restockQuantities(items []OrderItem, returned map[int]int) []stockReturn {
+	byVariant := make(map[int]int)
+	for _, item := range items {
+		quantity := item.Quantity - returned[item.ID]
+		if item.VariantID == 0 || quantity <= 0 {
+			continue
+		}
+		byVariant[item.VariantID] += quantity
+	}
+	restock := make([]stockReturn, 0, len(byVariant))
+	for variantID, quantity := range byVariant {
+		restock = append(restock, stockReturn{VariantID: variantID, Quantity: quantity})
+	}
+	sort.Slice(restock, func(i, j int) bool { return restock[i].VariantID < restock[j].VariantID })
+	return restock
+}
+
+// restockOrder คืนสต็อกของคำสั่งซื้อแบบจัดส่งที่ถูกยกเลิกหรือคืนเงินโดยไม่ผ่านคำขอคืนสินค้า ภายใน transaction ของผู้เรียก
+func restockOrder(ctx context.Context, tx *sql.Tx, orderID int) error {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT oi.order_item_id, COALESCE(oi.variant_id, 0), oi.quantity,
+               COALESCE((SELECT SUM(r.quantity) FROM return_requests r WHERE r.order_item_id = oi.order_item_id AND r.status = 'refunded'), 0)
+        FROM order_items oi
+        WHERE oi.order_id = $1
+    `, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to query order items to restock: %v", err)
+	}
+	defer rows.Close()
+
+	var items []OrderItem
+	returned := make(map[int]int)
+	for rows.Next() {
+		var item OrderItem
+		var refunded int
+		if err := rows.Scan(&item.ID, &item.VariantID, &item.Quantity, &refunded); err != nil {
+			return fmt.Errorf("failed to scan order item to restock: %v", err)
+		}
+		items = append(items, item)
+		returned[item.ID] = refunded
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %v", err)
+	}
+
+	for _, r := range restockQuantities(items, returned) {
+		if err := clothesstore.RestockVariant(ctx, tx, r.VariantID, r.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertStatusChange บันทึกประวัติการเปลี่ยนสถานะ changedBy ว่างหมายถึงระบบเป็นผู้เปลี่ยน
+func insertStatusChange(ctx context.Context, tx *sql.Tx, orderID int, from, to Status, changedBy string, note string) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, note)
+        VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, '')::uuid, NULLIF($5, ''))
+    `, orderID, from, to, changedBy, note)
+	if err != nil {
+		return fmt.Errorf("failed to record order status change: %v", err)
+	}
+	return nil
+}
+
+// loadDetails เติมรายการสินค้า ส่วนลด และพัสดุของคำสั่งซื้อ
+func (s *PostgresOrderStore) loadDetails(ctx context.Context, orders ...*Order) error {
+	orderIDs := make([]int64, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = int64(order.ID)
+	}
+	itemsByOrder, err := s.getOrderItems(ctx, orderIDs)
+	if err != nil {
+		return err
+	}
+	discountsByOrder, err := s.getOrderDiscounts(ctx, orderIDs)
+	if err != nil {
+		return err
+	}
+	shipmentsByOrder, err := s.getOrderShipments(ctx, orderIDs)
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		order.Items = itemsByOrder[order.ID]
+		if order.Display != nil {
+			rate := order.Display.ExchangeRate()
+			for i := range order.Items {
+				order.Items[i].DisplayUnitPrice = rate.Price(order.Items[i].UnitPrice)
+				order.Items[i].DisplayLineTotal = rate.Price(order.Items[i].LineTotal)
+			}
+		}
+		order.Discounts = discountsByOrder[order.ID]
+		if order.Discounts == nil {
+			order.Discounts = []promotions.Applied{}
+		}
+		order.Shipments = shipmentsByOrder[order.ID]
+		if order.Shipments == nil {
+			order.Shipments = []pricing.Shipment{}
+		}
+	}
+	return nil
+}
+
+func (s *PostgresOrderStore) getOrderItems(ctx context.Context, orderIDs []int64) (map[int][]OrderItem, error) {
+	rows, err := s.pdb.DB().QueryContext(ctx, `
+        SELECT order_id, order_item_id, COALESCE(product_id, 0), COALESCE(variant_id, 0), sku, size, colour, product_name,
+               COALESCE(brand_id, 0), COALESCE(brand_name, ''), COALESCE(imgsrc, ''), unit_price, quantity, line_total, discount
+        FROM order_items
+        WHERE order_id = ANY($1)
+        ORDER BY order_item_id
+    `, pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order items: %v", err)
+	}
+	defer rows.Close()
+
+	itemsByOrder := make(map[int][]OrderItem)
+	for rows.Next() {
+		var orderID int
+		var item OrderItem
+		if err := rows.Scan(&orderID, &item.ID, &item.ProductID, &item.VariantID, &item.SKU, &item.Size, &item.Colour, &item.ProductName,
+			&item.BrandID, &item.BrandName, &item.ImgSrc, &item.UnitPrice, &item.Quantity, &item.LineTotal, &item.Discount); err != nil {
+			return nil, fmt.Errorf("failed to scan order item: %v", err)
+		}
+		itemsByOrder[orderID] = append(itemsByOrder[orderID], item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return itemsByOrder, nil
+}
+
+func (s *PostgresOrderStore) getOrderDiscounts(ctx context.Context, orderIDs []int64) (map[int][]promotions.Applied, error) {
+	rows, err := s.pdb.DB().QueryContext(ctx, `
+        SELECT order_id, promotion_id, name, COALESCE(code, ''), amount
+        FROM order_discounts
+        WHERE order_id = ANY($1)
+        ORDER BY promotion_id
+    `, pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order discounts: %v", err)
+	}
+	defer rows.Close()
+
+	discountsByOrder := make(map[int][]promotions.Applied)
+	for rows.Next() {
+		var orderID int
+		var discount promotions.Applied
+		if err := rows.Scan(&orderID, &discount.PromotionID, &discount.Name, &discount.Code, &discount.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan order discount: %v", err)
+		}
+		discountsByOrder[orderID] = append(discountsByOrder[orderID], discount)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return discountsByOrder, nil
+}
+
+func (s *PostgresOrderStore) getOrderShipments(ctx context.Context, orderIDs []int64) (map[int][]pricing.Shipment, error) {
+	rows, err := s.pdb.DB().QueryContext(ctx, `
+        SELECT order_id, brand_id, weight_grams, fee
+        FROM order_shipments
+        WHERE order_id = ANY($1)
+        ORDER BY brand_id
+    `, pq.Array(orderIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order shipments: %v", err)
+	}
+	defer rows.Close()
+
+	shipmentsByOrder := make(map[int][]pricing.Shipment)
+	for rows.Next() {
+		var orderID int
+		var shipment pricing.Shipment
+		if err := rows.Scan(&orderID, &shipment.BrandID, &shipment.WeightGrams, &shipment.Fee); err != nil {
+			return nil, fmt.Errorf("failed to scan order shipment: %v", err)
+		}
+		shipmentsByOrder[orderID] = append(shipmentsByOrder[orderID], shipment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return shipmentsByOrder, nil
+}
@@ -0,0 +1,42 @@
+package orders
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRestockQuantities(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    []OrderItem
+		returned map[int]int
+		want     []stockReturn
+	}{
+		{
+			name:  "every item goes back to stock",
+			items: []OrderItem{{ID: 1, VariantID: 30, Quantity: 2}, {ID: 2, VariantID: 10, Quantity: 1}},
+			want:  []stockReturn{{VariantID: 10, Quantity: 1}, {VariantID: 30, Quantity: 2}},
+		},
+		{
+			name:  "lines of the same SKU are combined",
+			items: []OrderItem{{ID: 1, VariantID: 10, Quantity: 2}, {ID: 2, VariantID: 10, Quantity: 3}},
+			want:  []stockReturn{{VariantID: 10, Quantity: 5}},
+		},
+		{
+			name:     "units refunded through a return were already restocked",
+			items:    []OrderItem{{ID: 1, VariantID: 10, Quantity: 3}, {ID: 2, VariantID: 20, Quantity: 1}},
+			returned: map[int]int{1: 2, 2: 1},
+			want:     []stockReturn{{VariantID: 10, Quantity: 1}},
+		},
+		{
+			name:  "deleted SKUs are skipped",
+			items: []OrderItem{{ID: 1, VariantID: 0, Quantity: 2}},
+			want:  []stockReturn{},
+		},
+	}
+	for _, tt := range tests {
+		if got := restockQuantities(tt.items, tt.returned); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: restockQuantities = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
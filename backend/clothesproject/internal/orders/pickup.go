@@ -0,0 +1,252 @@
+package orders
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"clothesproject/internal/clothesstore"
+	"clothesproject/internal/currency"
+)
+
+const (
+	// PickupReadyDelay คือเวลาที่สาขาใช้เตรียมสินค้า ก่อนลูกค้าจะเริ่มมารับได้
+	PickupReadyDelay = 2 * time.Hour
+	// PickupHoldPeriod คือระยะเวลาที่สาขากันสินค้าไว้ให้ หลังจากนั้นคำสั่งซื้อจะหมดอายุและปล่อยสต็อกคืน
+	PickupHoldPeriod = 72 * time.Hour
+)
+
+var (
+	// ErrBranchNotFound คืนค่าเมื่อไม่พบสาขาที่เลือกรับสินค้า
+	ErrBranchNotFound = errors.New("pickup branch not found")
+	// ErrNotPickupOrder คืนค่าเมื่อทำรายการรับสินค้ากับคำสั่งซื้อที่ไม่ได้เลือกรับที่สาขา
+	ErrNotPickupOrder = errors.New("order is not a pickup order")
+	// ErrInvalidPickupCode คืนค่าเมื่อรหัสรับสินค้าไม่ตรงกับคำสั่งซื้อ
+	ErrInvalidPickupCode = errors.New("invalid pickup code")
+	// ErrPickupExpired คืนค่าเมื่อเลยช่วงเวลารับสินค้าแล้ว
+	ErrPickupExpired = errors.New("pickup window has expired")
+)
+
+// FulfilmentMethod คือวิธีส่งมอบสินค้าของคำสั่งซื้อ
+type FulfilmentMethod string
+
+const (
+	FulfilmentShipping FulfilmentMethod = "shipping"
+	FulfilmentPickup   FulfilmentMethod = "pickup"
+)
+
+// IsValid ตรวจสอบว่าเป็นวิธีส่งมอบที่รู้จัก
+func (m FulfilmentMethod) IsValid() bool {
+	return m == FulfilmentShipping || m == FulfilmentPickup
+}
+
+// CheckoutRequest คือตัวเลือกตอนสร้างคำสั่งซื้อ ถ้าไม่ระบุ Fulfilment จะเป็นการจัดส่ง
+// Coupon คือรหัสคูปองที่ต้องการใช้ (ไม่บังคับ) Province คือจังหวัดปลายทาง ใช้คิดค่าส่ง (บังคับเมื่อจัดส่ง)
+type CheckoutRequest struct {
+	Fulfilment     FulfilmentMethod `json:"fulfilment"`
+	PickupBranchID int              `json:"pickup_branch_id"`
+	Coupon         string           `json:"coupon"`
+	Province       string           `json:"province"`
+	// TaxInvoice คือข้อมูลผู้ซื้อเมื่อต้องการใบกำกับภาษีเต็มรูป (ไม่บังคับ)
+	TaxInvoice *TaxBuyer `json:"tax_invoice"`
+	// Rate คืออัตราแลกเปลี่ยนของสกุลเงินที่ลูกค้าเลือกดูราคา (nil คือบาท) เก็บไว้กับคำสั่งซื้อเพื่อแสดงยอดเดิมได้เสมอ
+	Rate *currency.ExchangeRate `json:"-"`
+}
+
+// Pickup คือข้อมูลการรับสินค้าที่สาขา ลูกค้าแสดง Code ให้พนักงานตอนมารับภายใน WindowStart-WindowEnd
+type Pickup struct {
+	BranchID    int        `json:"branch_id"`
+	BranchName  string     `json:"branch_name"`
+	Code        string     `json:"code"`
+	WindowStart time.Time  `json:"window_start"`
+	WindowEnd   time.Time  `json:"window_end"`
+	CollectedAt *time.Time `json:"collected_at,omitempty"`
+}
+
+// pickupTransitions ใช้แทน transitions สำหรับคำสั่งซื้อแบบรับที่สาขา
+// ไม่มีการจัดส่ง (shipped) และจะเป็น delivered ได้ผ่าน CollectOrder เท่านั้น
+// คำสั่งซื้อที่ชำระเงินแล้วแต่ไม่มารับภายในเวลาจะเป็น expired (ผ่าน ExpirePickups เท่านั้น) แล้วต้องคืนเงิน
+var pickupTransitions = map[Status][]Status{
+	StatusPending:   {StatusPaid, StatusCancelled},
+	StatusPaid:      {StatusPacked, StatusDelivered, StatusRefunded, StatusExpired},
+	StatusPacked:    {StatusDelivered, StatusRefunded, StatusExpired},
+	StatusDelivered: {StatusRefunded},
+	StatusExpired:   {StatusRefunded},
+}
+
+// canTransition ตรวจสอบการเปลี่ยนสถานะตามวิธีส่งมอบของคำสั่งซื้อ
+func canTransition(method FulfilmentMethod, from, to Status) bool {
+	if method != FulfilmentPickup {
+		return from.CanTransitionTo(to)
+	}
+	for _, allowed := range pickupTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// expiredStatus คือสถานะของคำสั่งซื้อแบบรับที่สาขาที่เลยเวลารับ ยังไม่ชำระเงินคือยกเลิก ชำระแล้วคือ expired
+func expiredStatus(from Status) Status {
+	if from == StatusPending {
+		return StatusCancelled
+	}
+	return StatusExpired
+}
+
+// newPickupCode สุ่มรหัสรับสินค้า 6 หลัก
+func newPickupCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate pickup code: %v", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// newPickup เตรียมข้อมูลการรับสินค้าที่สาขา ตรวจว่าสาขามีอยู่จริงและกำหนดช่วงเวลารับ
+func newPickup(ctx context.Context, tx *sql.Tx, branchID int, now time.Time) (*Pickup, error) {
+	pickup := &Pickup{BranchID: branchID}
+	err := tx.QueryRowContext(ctx, "SELECT COALESCE(banch, '') FROM branch WHERE id = $1", branchID).Scan(&pickup.BranchName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBranchNotFound
+		}
+		return nil, fmt.Errorf("failed to get pickup branch: %v", err)
+	}
+
+	code, err := newPickupCode()
+	if err != nil {
+		return nil, err
+	}
+	pickup.Code = code
+	pickup.WindowStart = now.Add(PickupReadyDelay)
+	pickup.WindowEnd = pickup.WindowStart.Add(PickupHoldPeriod)
+	return pickup, nil
+}
+
+// CollectOrder บันทึกว่าลูกค้ามารับสินค้าที่สาขาแล้ว (สำหรับพนักงาน)
+// ตรวจรหัสรับสินค้า ตัดสต็อกของสาขาที่กันไว้ และเปลี่ยนสถานะเป็น delivered
+func (s *PostgresOrderStore) CollectOrder(ctx context.Context, orderID int, code string, staffID string) (Order, error) {
+	tx, err := s.pdb.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return Order{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	order, err := lockOrder(ctx, tx, orderID)
+	if err != nil {
+		return Order{}, err
+	}
+
+	if order.Fulfilment != FulfilmentPickup || order.Pickup == nil {
+		return Order{}, ErrNotPickupOrder
+	}
+	if subtle.ConstantTimeCompare([]byte(order.Pickup.Code), []byte(code)) != 1 {
+		return Order{}, ErrInvalidPickupCode
+	}
+	if order.Pickup.CollectedAt == nil && time.Now().After(order.Pickup.WindowEnd) {
+		return Order{}, ErrPickupExpired
+	}
+	if !canTransition(order.Fulfilment, order.Status, StatusDelivered) {
+		return Order{}, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, order.Status, StatusDelivered)
+	}
+
+	if err := clothesstore.CollectBranchHolds(ctx, tx, orderID); err != nil {
+		return Order{}, err
+	}
+
+	from := order.Status
+	var collectedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+        UPDATE orders SET status = $2, collected_at = NOW() WHERE order_id = $1
+        RETURNING status, collected_at, updated_at
+    `, orderID, StatusDelivered).Scan(&order.Status, &collectedAt, &order.UpdatedAt)
+	if err != nil {
+		return Order{}, fmt.Errorf("failed to mark order collected: %v", err)
+	}
+	order.Pickup.CollectedAt = &collectedAt
+
+	if err := insertStatusChange(ctx, tx, orderID, from, StatusDelivered, staffID, "collected at branch"); err != nil {
+		return Order{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Order{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	if err := s.loadDetails(ctx, &order); err != nil {
+		return Order{}, err
+	}
+
+	return order, nil
+}
+
+// ExpirePickups ปล่อยสต็อกของคำสั่งซื้อแบบรับที่สาขาที่เลยเวลารับแล้วยังไม่มารับ เรียกจาก sweeper เป็นระยะ
+// คำสั่งซื้อที่ยังไม่ชำระเงินจะถูกยกเลิก ส่วนที่ชำระเงินแล้วจะเป็น expired ให้พนักงานคืนเงินผ่านผู้ให้บริการชำระเงิน
+// แล้วเปลี่ยนเป็น refunded ทุกการเปลี่ยนสถานะบันทึกประวัติว่าระบบเป็นผู้เปลี่ยน
+func (s *PostgresOrderStore) ExpirePickups(ctx context.Context) (int, error) {
+	tx, err := s.pdb.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED เพื่อไม่ให้รอคำสั่งซื้อที่พนักงานกำลังทำรายการรับสินค้าอยู่
+	rows, err := tx.QueryContext(ctx, `
+        SELECT order_id, status
+        FROM orders
+        WHERE fulfilment = 'pickup' AND collected_at IS NULL
+          AND pickup_window_end <= NOW()
+          AND status IN ('pending', 'paid', 'packed')
+        ORDER BY order_id
+        FOR UPDATE SKIP LOCKED
+    `)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired pickups: %v", err)
+	}
+
+	type expired struct {
+		orderID int
+		status  Status
+	}
+	var orders []expired
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.orderID, &e.status); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan expired pickup: %v", err)
+		}
+		orders = append(orders, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	for _, e := range orders {
+		to := expiredStatus(e.status)
+		if !canTransition(FulfilmentPickup, e.status, to) {
+			return 0, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, e.status, to)
+		}
+		if err := clothesstore.ReleaseBranchHolds(ctx, tx, e.orderID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE orders SET status = $2 WHERE order_id = $1", e.orderID, to); err != nil {
+			return 0, fmt.Errorf("failed to expire pickup order: %v", err)
+		}
+		if err := insertStatusChange(ctx, tx, e.orderID, e.status, to, "", "pickup window expired"); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return len(orders), nil
+}
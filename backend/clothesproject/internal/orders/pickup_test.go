@@ -0,0 +1,89 @@
+package orders
+
+import "testing"
+
+func TestCanTransitionByFulfilment(t *testing.T) {
+	tests := []struct {
+		method FulfilmentMethod
+		from   Status
+		to     []Status
+	}{
+		{method: FulfilmentPickup, from: StatusPending, to: []Status{StatusPaid, StatusCancelled}},
+		{method: FulfilmentPickup, from: StatusPaid, to: []Status{StatusPacked, StatusDelivered, StatusRefunded, StatusExpired}},
+		{method: FulfilmentPickup, from: StatusPacked, to: []Status{StatusDelivered, StatusRefunded, StatusExpired}},
+		{method: FulfilmentPickup, from: StatusShipped},
+		{method: FulfilmentPickup, from: StatusDelivered, to: []Status{StatusRefunded}},
+		{method: FulfilmentPickup, from: StatusCancelled},
+		{method: FulfilmentPickup, from: StatusExpired, to: []Status{StatusRefunded}},
+		{method: FulfilmentShipping, from: StatusPaid, to: []Status{StatusPacked, StatusRefunded}},
+		{method: FulfilmentShipping, from: StatusPacked, to: []Status{StatusShipped, StatusRefunded}},
+		// คำสั่งซื้อเก่าที่ไม่มีวิธีส่งมอบใช้กฎของการจัดส่ง
+		{method: "", from: StatusShipped, to: []Status{StatusDelivered}},
+	}
+	for _, tt := range tests {
+		got := allowedSet(tt.from, func(from, to Status) bool { return canTransition(tt.method, from, to) })
+		if len(got) != len(tt.to) {
+			t.Errorf("%s: %q allows %v, want exactly %v", tt.method, tt.from, got, tt.to)
+			continue
+		}
+		for _, to := range tt.to {
+			if !got[to] {
+				t.Errorf("%s: %q allows %v, want exactly %v", tt.method, tt.from, got, tt.to)
+			}
+		}
+	}
+}
+
+func TestExpiredStatus(t *testing.T) {
+	tests := []struct {
+		from Status
+		want Status
+	}{
+		{from: StatusPending, want: StatusCancelled},
+		{from: StatusPaid, want: StatusExpired},
+		{from: StatusPacked, want: StatusExpired},
+	}
+	for _, tt := range tests {
+		got := expiredStatus(tt.from)
+		if got != tt.want {
+			t.Errorf("expiredStatus(%q) = %q, want %q", tt.from, got, tt.want)
+		}
+		if !canTransition(FulfilmentPickup, tt.from, got) {
+			t.Errorf("expiring a %q pickup to %q is not an allowed transition", tt.from, got)
+		}
+	}
+}
+
+func TestFulfilmentMethodIsValid(t *testing.T) {
+	tests := []struct {
+		method FulfilmentMethod
+		want   bool
+	}{
+		{method: FulfilmentShipping, want: true},
+		{method: FulfilmentPickup, want: true},
+		{method: "", want: false},
+		{method: "delivery", want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.method.IsValid(); got != tt.want {
+			t.Errorf("%q.IsValid() = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestNewPickupCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := newPickupCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 6 {
+			t.Fatalf("newPickupCode() = %q, want 6 digits", code)
+		}
+		for _, r := range code {
+			if r < '0' || r > '9' {
+				t.Fatalf("newPickupCode() = %q, want 6 digits", code)
+			}
+		}
+	}
+}
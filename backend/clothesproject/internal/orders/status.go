@@ -0,0 +1,68 @@
+package orders
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidTransition คืนค่าเมื่อเปลี่ยนสถานะคำสั่งซื้อไปยังสถานะที่ไม่อนุญาต
+var ErrInvalidTransition = errors.New("invalid order status transition")
+
+// Status คือสถานะของคำสั่งซื้อ
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusPaid      Status = "paid"
+	StatusPacked    Status = "packed"
+	StatusShipped   Status = "shipped"
+	StatusDelivered Status = "delivered"
+	StatusCancelled Status = "cancelled"
+	StatusRefunded  Status = "refunded"
+	// StatusExpired ใช้กับคำสั่งซื้อแบบรับที่สาขาที่ชำระเงินแล้วแต่ไม่มารับภายในเวลา ต้องคืนเงินต่อ
+	StatusExpired Status = "expired"
+)
+
+// transitions กำหนดสถานะถัดไปที่อนุญาตจากแต่ละสถานะ
+// pending → paid → packed → shipped → delivered
+// ยกเลิกได้เฉพาะก่อนชำระเงิน หลังชำระเงินแล้วต้องคืนเงิน (refunded) แทน
+// cancelled และ refunded เป็นสถานะสุดท้าย
+// คำสั่งซื้อแบบรับที่สาขาใช้ pickupTransitions แทน
+var transitions = map[Status][]Status{
+	StatusPending:   {StatusPaid, StatusCancelled},
+	StatusPaid:      {StatusPacked, StatusRefunded},
+	StatusPacked:    {StatusShipped, StatusRefunded},
+	StatusShipped:   {StatusDelivered},
+	StatusDelivered: {StatusRefunded},
+}
+
+// IsValid ตรวจสอบว่าเป็นสถานะที่รู้จัก
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusPending, StatusPaid, StatusPacked, StatusShipped, StatusDelivered, StatusCancelled, StatusRefunded, StatusExpired:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo ตรวจสอบว่าเปลี่ยนจากสถานะนี้ไปยัง next ได้หรือไม่
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusChange คือประวัติการเปลี่ยนสถานะของคำสั่งซื้อ พร้อมผู้ที่เปลี่ยนและเวลา
+// ChangedBy ว่างเมื่อระบบเปลี่ยนสถานะเอง
+type StatusChange struct {
+	ID         int       `json:"id"`
+	OrderID    int       `json:"order_id"`
+	FromStatus Status    `json:"from_status"`
+	ToStatus   Status    `json:"to_status"`
+	ChangedBy  string    `json:"changed_by"`
+	Note       string    `json:"note"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
@@ -0,0 +1,58 @@
+package orders
+
+import "testing"
+
+var allStatuses = []Status{StatusPending, StatusPaid, StatusPacked, StatusShipped, StatusDelivered, StatusCancelled, StatusRefunded, StatusExpired}
+
+// allowedSet คืนสถานะปลายทางทั้งหมดที่ can อนุญาตจาก from
+func allowedSet(from Status, can func(from, to Status) bool) map[Status]bool {
+	allowed := make(map[Status]bool)
+	for _, to := range allStatuses {
+		if can(from, to) {
+			allowed[to] = true
+		}
+	}
+	return allowed
+}
+
+func TestStatusIsValid(t *testing.T) {
+	for _, s := range allStatuses {
+		if !s.IsValid() {
+			t.Errorf("%q.IsValid() = false, want true", s)
+		}
+	}
+	for _, s := range []Status{"", "PAID", "returned", "unknown"} {
+		if s.IsValid() {
+			t.Errorf("%q.IsValid() = true, want false", s)
+		}
+	}
+}
+
+func TestCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from Status
+		to   []Status
+	}{
+		{from: StatusPending, to: []Status{StatusPaid, StatusCancelled}},
+		{from: StatusPaid, to: []Status{StatusPacked, StatusRefunded}},
+		{from: StatusPacked, to: []Status{StatusShipped, StatusRefunded}},
+		{from: StatusShipped, to: []Status{StatusDelivered}},
+		{from: StatusDelivered, to: []Status{StatusRefunded}},
+		{from: StatusCancelled},
+		{from: StatusRefunded},
+		{from: StatusExpired},
+		{from: "unknown"},
+	}
+	for _, tt := range tests {
+		got := allowedSet(tt.from, Status.CanTransitionTo)
+		if len(got) != len(tt.to) {
+			t.Errorf("%q allows %v, want exactly %v", tt.from, got, tt.to)
+			continue
+		}
+		for _, to := range tt.to {
+			if !got[to] {
+				t.Errorf("%q allows %v, want exactly %v", tt.from, got, tt.to)
+			}
+		}
+	}
+}
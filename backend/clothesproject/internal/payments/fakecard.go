@@ -0,0 +1,107 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// บัตรทดสอบของ FakeCardProvider บัตรอื่นที่เลข Luhn ถูกต้องถือว่าจ่ายสำเร็จ
+const (
+	FakeCardDeclined          = "4000000000000002"
+	FakeCardInsufficientFunds = "4000000000009995"
+)
+
+// DeliverFunc ส่ง callback ที่เซ็นแล้วให้ระบบ เหมือนผู้ให้บริการจริงเรียก webhook
+type DeliverFunc func(ctx context.Context, method Method, body []byte, signature string) (Payment, error)
+
+// FakeCardProvider คือผู้ให้บริการบัตรจำลองสำหรับทดสอบในเครื่อง ไม่มีการตัดเงินจริง
+// CreateCharge ตรวจข้อมูลบัตรแล้วส่งผลกลับผ่าน Deliver เป็น callback ที่เซ็นด้วย secret
+// จึงผ่านการตรวจลายเซ็นแบบเดียวกับผู้ให้บริการจริง
+type FakeCardProvider struct {
+	signedCallback
+	Deliver DeliverFunc
+}
+
+func NewFakeCardProvider(secret []byte, deliver DeliverFunc) *FakeCardProvider {
+	return &FakeCardProvider{signedCallback: signedCallback{secret: secret}, Deliver: deliver}
+}
+
+func (p *FakeCardProvider) Method() Method { return MethodCard }
+
+func (p *FakeCardProvider) CreateCharge(ctx context.Context, req ChargeRequest) (Charge, error) {
+	if req.Card == nil {
+		return Charge{}, fmt.Errorf("%w: card details are required", ErrInvalidCard)
+	}
+	number := strings.ReplaceAll(req.Card.Number, " ", "")
+	if !luhnValid(number) {
+		return Charge{}, fmt.Errorf("%w: card number", ErrInvalidCard)
+	}
+	now := time.Now()
+	if req.Card.ExpiryMonth < 1 || req.Card.ExpiryMonth > 12 ||
+		req.Card.ExpiryYear < now.Year() || (req.Card.ExpiryYear == now.Year() && req.Card.ExpiryMonth < int(now.Month())) {
+		return Charge{}, fmt.Errorf("%w: card has expired", ErrInvalidCard)
+	}
+	if len(req.Card.CVC) < 3 || len(req.Card.CVC) > 4 || !isDigits(req.Card.CVC) {
+		return Charge{}, fmt.Errorf("%w: cvc", ErrInvalidCard)
+	}
+
+	event := Event{Reference: req.Reference, Status: StatusSucceeded, Amount: req.Amount}
+	switch number {
+	case FakeCardDeclined:
+		event.Status, event.Reason = StatusFailed, "card declined"
+	case FakeCardInsufficientFunds:
+		event.Status, event.Reason = StatusFailed, "insufficient funds"
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return Charge{}, fmt.Errorf("failed to encode card callback: %v", err)
+	}
+
+	// ส่ง callback แยกจาก request ของลูกค้าเหมือน webhook จริง ลูกค้าดูผลจากสถานะคำสั่งซื้อ
+	if p.Deliver != nil {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if _, err := p.Deliver(ctx, MethodCard, body, Sign(p.secret, body)); err != nil {
+				log.Printf("Failed to deliver fake card callback for %s: %v", req.Reference, err)
+			}
+		}()
+	}
+	return Charge{}, nil
+}
+
+func (p *FakeCardProvider) VerifyCallback(body []byte, signature string) (Event, error) {
+	return p.verify(body, signature)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// luhnValid ตรวจเลขบัตร 12-19 หลักด้วย Luhn checksum
+func luhnValid(number string) bool {
+	if len(number) < 12 || len(number) > 19 || !isDigits(number) {
+		return false
+	}
+	sum := 0
+	for i := 0; i < len(number); i++ {
+		digit := int(number[len(number)-1-i] - '0')
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
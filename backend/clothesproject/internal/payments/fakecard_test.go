@@ -0,0 +1,110 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"clothesproject/internal/money"
+)
+
+func TestLuhnValid(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{number: "4242424242424242", want: true},
+		{number: "4111111111111111", want: true},
+		{number: "5555555555554444", want: true},
+		{number: "378282246310005", want: true},
+		{number: "6011111111111117", want: true},
+		{number: FakeCardDeclined, want: true},
+		{number: FakeCardInsufficientFunds, want: true},
+		{number: "4242424242424241", want: false},
+		{number: "42424242424", want: false},
+		{number: "42424242424242424242", want: false},
+		{number: "4242-4242-4242-4242", want: false},
+		{number: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := luhnValid(tt.number); got != tt.want {
+			t.Errorf("luhnValid(%q) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestFakeCardCreateCharge(t *testing.T) {
+	secret := []byte("test-secret")
+	nextYear := time.Now().Year() + 1
+	tests := []struct {
+		name       string
+		card       *Card
+		wantErr    bool
+		wantStatus Status
+		wantReason string
+	}{
+		{name: "success", card: &Card{Number: "4242 4242 4242 4242", ExpiryMonth: 12, ExpiryYear: nextYear, CVC: "123"}, wantStatus: StatusSucceeded},
+		{name: "declined", card: &Card{Number: FakeCardDeclined, ExpiryMonth: 1, ExpiryYear: nextYear, CVC: "1234"}, wantStatus: StatusFailed, wantReason: "card declined"},
+		{name: "insufficient funds", card: &Card{Number: FakeCardInsufficientFunds, ExpiryMonth: 1, ExpiryYear: nextYear, CVC: "123"}, wantStatus: StatusFailed, wantReason: "insufficient funds"},
+		{name: "missing card", wantErr: true},
+		{name: "bad number", card: &Card{Number: "4242424242424241", ExpiryMonth: 1, ExpiryYear: nextYear, CVC: "123"}, wantErr: true},
+		{name: "expired", card: &Card{Number: "4242424242424242", ExpiryMonth: 1, ExpiryYear: 2000, CVC: "123"}, wantErr: true},
+		{name: "bad month", card: &Card{Number: "4242424242424242", ExpiryMonth: 13, ExpiryYear: nextYear, CVC: "123"}, wantErr: true},
+		{name: "bad cvc", card: &Card{Number: "4242424242424242", ExpiryMonth: 1, ExpiryYear: nextYear, CVC: "12a"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		delivered := make(chan Event, 1)
+		provider := NewFakeCardProvider(secret, func(ctx context.Context, method Method, body []byte, signature string) (Payment, error) {
+			// callback ต้องผ่านการตรวจลายเซ็นแบบเดียวกับ webhook จริง
+			event, err := NewFakeCardProvider(secret, nil).VerifyCallback(body, signature)
+			if err != nil {
+				t.Errorf("%s: VerifyCallback: %v", tt.name, err)
+			}
+			delivered <- event
+			return Payment{}, nil
+		})
+
+		_, err := provider.CreateCharge(context.Background(), ChargeRequest{Reference: "ref-1", Amount: money.Baht(100), Card: tt.card})
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidCard) {
+				t.Errorf("%s: CreateCharge error = %v, want ErrInvalidCard", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		select {
+		case event := <-delivered:
+			if event.Reference != "ref-1" || event.Status != tt.wantStatus || event.Reason != tt.wantReason || event.Amount != money.Baht(100) {
+				t.Errorf("%s: delivered %+v, want status %q reason %q", tt.name, event, tt.wantStatus, tt.wantReason)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s: callback was not delivered", tt.name)
+		}
+	}
+}
+
+func TestVerifyCallbackRejectsBadSignatures(t *testing.T) {
+	body := []byte(`{"reference":"ref-1","status":"succeeded","amount":100.00}`)
+	tests := []struct {
+		name      string
+		secret    []byte
+		body      []byte
+		signature string
+		wantErr   error
+	}{
+		{name: "wrong secret", secret: []byte("other"), body: body, signature: Sign([]byte("secret"), body), wantErr: ErrInvalidSignature},
+		{name: "not hex", secret: []byte("secret"), body: body, signature: "zz", wantErr: ErrInvalidSignature},
+		{name: "empty secret", body: body, signature: Sign(nil, body), wantErr: ErrInvalidSignature},
+		{name: "pending status", secret: []byte("secret"), body: []byte(`{"reference":"ref-1","status":"pending"}`), signature: Sign([]byte("secret"), []byte(`{"reference":"ref-1","status":"pending"}`)), wantErr: ErrInvalidCallback},
+	}
+	for _, tt := range tests {
+		_, err := signedCallback{secret: tt.secret}.verify(tt.body, tt.signature)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: verify error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
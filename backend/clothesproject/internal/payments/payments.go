@@ -0,0 +1,138 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"clothesproject/internal/money"
+)
+
+// SignatureHeader คือ header ที่ผู้ให้บริการชำระเงินส่งลายเซ็น HMAC-SHA256 (hex) ของ body มากับ callback
+const SignatureHeader = "X-Payment-Signature"
+
+var (
+	// ErrUnsupportedMethod คืนค่าเมื่อไม่มีผู้ให้บริการของวิธีชำระเงินที่เลือก
+	ErrUnsupportedMethod = errors.New("unsupported payment method")
+	// ErrInvalidSignature คืนค่าเมื่อลายเซ็นของ callback ไม่ถูกต้อง
+	ErrInvalidSignature = errors.New("invalid payment callback signature")
+	// ErrInvalidCallback คืนค่าเมื่อ body ของ callback อ่านไม่ได้หรือไม่ครบ
+	ErrInvalidCallback = errors.New("invalid payment callback")
+	// ErrInvalidCard คืนค่าเมื่อข้อมูลบัตรไม่ถูกต้อง
+	ErrInvalidCard = errors.New("invalid card")
+	// ErrOrderNotPayable คืนค่าเมื่อคำสั่งซื้อไม่ได้อยู่ในสถานะรอชำระเงิน
+	ErrOrderNotPayable = errors.New("order is not awaiting payment")
+	// ErrPaymentNotFound คืนค่าเมื่อไม่พบการชำระเงินตาม reference ของ callback
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrAmountMismatch คืนค่าเมื่อยอดใน callback ไม่ตรงกับยอดที่เรียกเก็บ
+	ErrAmountMismatch = errors.New("payment amount does not match")
+	// ErrPaymentFinalised คืนค่าเมื่อ callback ขัดกับผลการชำระเงินที่บันทึกไปแล้ว
+	ErrPaymentFinalised = errors.New("payment is already finalised")
+)
+
+// Method คือวิธีชำระเงิน แต่ละวิธีมีผู้ให้บริการหนึ่งราย
+type Method string
+
+const (
+	MethodPromptPay Method = "promptpay"
+	MethodCard      Method = "card"
+)
+
+// Status คือสถานะของการชำระเงิน
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Card คือข้อมูลบัตรที่ส่งให้ผู้ให้บริการบัตร (ไม่เก็บลงฐานข้อมูล)
+type Card struct {
+	Number      string `json:"number"`
+	ExpiryMonth int    `json:"expiry_month"`
+	ExpiryYear  int    `json:"expiry_year"`
+	CVC         string `json:"cvc"`
+}
+
+// ChargeRequest คือคำขอเรียกเก็บเงินของคำสั่งซื้อ Reference สร้างโดยระบบและผู้ให้บริการต้องส่งกลับมาใน callback
+type ChargeRequest struct {
+	Reference string
+	OrderID   int
+	Amount    money.Money
+	Card      *Card
+}
+
+// Charge คือผลการเรียกเก็บเงินเบื้องต้น ผลสุดท้าย (สำเร็จ/ไม่สำเร็จ) มาทาง callback เสมอ
+// QRPayload มีค่าสำหรับ PromptPay (ข้อความที่ต้องแปลงเป็น QR ให้ลูกค้าสแกน)
+type Charge struct {
+	QRPayload string
+}
+
+// Event คือผลการชำระเงินที่ผู้ให้บริการแจ้งกลับผ่าน callback
+type Event struct {
+	Reference string      `json:"reference"`
+	Status    Status      `json:"status"`
+	Amount    money.Money `json:"amount"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+// PaymentProvider คือผู้ให้บริการชำระเงิน CreateCharge เริ่มการเรียกเก็บเงิน ส่วน VerifyCallback
+// ตรวจลายเซ็นของ callback แล้วคืนผลการชำระเงิน คำสั่งซื้อจะเป็น paid ได้จาก callback ที่ผ่านการตรวจเท่านั้น
+type PaymentProvider interface {
+	Method() Method
+	CreateCharge(ctx context.Context, req ChargeRequest) (Charge, error)
+	VerifyCallback(body []byte, signature string) (Event, error)
+}
+
+// Payment คือการชำระเงินหนึ่งครั้งของคำสั่งซื้อ คำสั่งซื้อหนึ่งรายการลองชำระได้หลายครั้ง
+type Payment struct {
+	ID        int         `json:"id"`
+	OrderID   int         `json:"order_id"`
+	Method    Method      `json:"method"`
+	Reference string      `json:"reference"`
+	Amount    money.Money `json:"amount"`
+	Status    Status      `json:"status"`
+	QRPayload string      `json:"qr_payload,omitempty"`
+	Note      string      `json:"note,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	PaidAt    *time.Time  `json:"paid_at,omitempty"`
+}
+
+// Sign คืนลายเซ็น HMAC-SHA256 (hex) ของ body ด้วย secret ที่ตกลงกับผู้ให้บริการ
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signedCallback ตรวจลายเซ็นและอ่าน Event จาก body ใช้ร่วมกันในผู้ให้บริการที่เซ็น callback ด้วย HMAC
+type signedCallback struct {
+	secret []byte
+}
+
+func (s signedCallback) verify(body []byte, signature string) (Event, error) {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || len(s.secret) == 0 {
+		return Event{}, ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return Event{}, fmt.Errorf("%w: %v", ErrInvalidCallback, err)
+	}
+	if event.Reference == "" || (event.Status != StatusSucceeded && event.Status != StatusFailed) {
+		return Event{}, fmt.Errorf("%w: reference and a final status are required", ErrInvalidCallback)
+	}
+	return event, nil
+}
@@ -0,0 +1,121 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"clothesproject/internal/money"
+)
+
+// promptPayAID คือ Application ID ของ PromptPay ใน Merchant Account Information (tag 29)
+const promptPayAID = "A000000677010111"
+
+// PromptPayProvider สร้าง QR พร้อมเพย์ตามมาตรฐาน EMVCo ให้ลูกค้าสแกนจ่าย
+// ธนาคารหรือผู้ให้บริการที่รับเงินแจ้งผลกลับผ่าน callback ที่เซ็นด้วย secret
+type PromptPayProvider struct {
+	signedCallback
+	id string
+}
+
+// NewPromptPayProvider สร้างผู้ให้บริการพร้อมเพย์ของบัญชี id (เบอร์มือถือ เลขประจำตัวประชาชน/ผู้เสียภาษี หรือ e-Wallet ID)
+func NewPromptPayProvider(id string, secret []byte) (*PromptPayProvider, error) {
+	if _, _, err := promptPayTarget(id); err != nil {
+		return nil, err
+	}
+	return &PromptPayProvider{signedCallback: signedCallback{secret: secret}, id: id}, nil
+}
+
+func (p *PromptPayProvider) Method() Method { return MethodPromptPay }
+
+// CreateCharge สร้าง QR สำหรับยอดของคำสั่งซื้อ โดยใส่ Reference ไว้ใน QR ให้ธนาคารส่งกลับมา
+func (p *PromptPayProvider) CreateCharge(ctx context.Context, req ChargeRequest) (Charge, error) {
+	payload, err := PromptPayPayload(p.id, req.Amount, req.Reference)
+	if err != nil {
+		return Charge{}, err
+	}
+	return Charge{QRPayload: payload}, nil
+}
+
+func (p *PromptPayProvider) VerifyCallback(body []byte, signature string) (Event, error) {
+	return p.verify(body, signature)
+}
+
+// PromptPayPayload สร้างข้อความ QR พร้อมเพย์ (EMVCo Merchant-Presented QR) สำหรับบัญชี id
+// amount เป็นศูนย์คือ QR แบบไม่ระบุยอด (static) reference ใส่ใน Additional Data (tag 62) ถ้าไม่ว่าง
+func PromptPayPayload(id string, amount money.Money, reference string) (string, error) {
+	tag, target, err := promptPayTarget(id)
+	if err != nil {
+		return "", err
+	}
+	if amount.IsNegative() || (amount.Currency != "" && amount.Currency != money.THB) {
+		return "", fmt.Errorf("promptpay amount must be a non-negative THB amount: %s %s", amount, amount.Currency)
+	}
+
+	var b strings.Builder
+	b.WriteString(tlv("00", "01"))
+	if amount.IsZero() {
+		b.WriteString(tlv("01", "11"))
+	} else {
+		b.WriteString(tlv("01", "12"))
+	}
+	b.WriteString(tlv("29", tlv("00", promptPayAID)+tlv(tag, target)))
+	b.WriteString(tlv("58", "TH"))
+	b.WriteString(tlv("53", "764"))
+	if !amount.IsZero() {
+		b.WriteString(tlv("54", amount.String()))
+	}
+	if reference != "" {
+		b.WriteString(tlv("62", tlv("05", reference)))
+	}
+	b.WriteString("6304")
+	return b.String() + fmt.Sprintf("%04X", crc16(b.String())), nil
+}
+
+// promptPayTarget แปลงบัญชีพร้อมเพย์เป็น sub-tag และค่าใน tag 29
+// เบอร์มือถือ 10 หลักเป็น 0066 ตามด้วยเบอร์ที่ตัด 0 นำหน้า (sub-tag 01) เลข 13 หลักคือบัตรประชาชน/ผู้เสียภาษี (02)
+// และเลข 15 หลักคือ e-Wallet (03)
+func promptPayTarget(id string) (string, string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return 'x'
+	}, id)
+	if strings.ContainsRune(digits, 'x') {
+		return "", "", fmt.Errorf("invalid promptpay id %q", id)
+	}
+	switch {
+	case len(digits) == 10 && digits[0] == '0':
+		return "01", "0066" + digits[1:], nil
+	case len(digits) == 13:
+		return "02", digits, nil
+	case len(digits) == 15:
+		return "03", digits, nil
+	}
+	return "", "", fmt.Errorf("invalid promptpay id %q", id)
+}
+
+// tlv เข้ารหัสข้อมูลหนึ่งช่องของ EMVCo: ID 2 หลัก ความยาว 2 หลัก และค่า
+func tlv(id, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+// crc16 คือ CRC-16/CCITT-FALSE (polynomial 0x1021 ค่าเริ่มต้น 0xFFFF) ที่ EMVCo ใช้ใน tag 63
+func crc16(data string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
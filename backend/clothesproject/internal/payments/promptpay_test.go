@@ -0,0 +1,127 @@
+package payments
+
+import (
+	"strconv"
+	"testing"
+
+	"clothesproject/internal/money"
+)
+
+func TestCRC16(t *testing.T) {
+	tests := []struct {
+		data string
+		want uint16
+	}{
+		// ค่าตรวจสอบมาตรฐานของ CRC-16/CCITT-FALSE
+		{data: "123456789", want: 0x29B1},
+		{data: "", want: 0xFFFF},
+		{data: "A", want: 0xB915},
+	}
+	for _, tt := range tests {
+		if got := crc16(tt.data); got != tt.want {
+			t.Errorf("crc16(%q) = %04X, want %04X", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestTLV(t *testing.T) {
+	tests := []struct {
+		id, value string
+		want      string
+	}{
+		{id: "00", value: "01", want: "000201"},
+		{id: "58", value: "TH", want: "5802TH"},
+		{id: "54", value: "1494.50", want: "54071494.50"},
+		{id: "62", value: "", want: "6200"},
+		{id: "29", value: "0016A000000677010111011300668123456789", want: "29380016A000000677010111011300668123456789"},
+	}
+	for _, tt := range tests {
+		if got := tlv(tt.id, tt.value); got != tt.want {
+			t.Errorf("tlv(%q, %q) = %q, want %q", tt.id, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPromptPayTarget(t *testing.T) {
+	tests := []struct {
+		id      string
+		tag     string
+		target  string
+		wantErr bool
+	}{
+		{id: "0812345678", tag: "01", target: "0066812345678"},
+		{id: "081-234-5678", tag: "01", target: "0066812345678"},
+		{id: "1234567890121", tag: "02", target: "1234567890121"},
+		{id: "1 2345 67890 12 1", tag: "02", target: "1234567890121"},
+		{id: "123456789012345", tag: "03", target: "123456789012345"},
+		{id: "1812345678", wantErr: true},
+		{id: "081234567", wantErr: true},
+		{id: "08123456789", wantErr: true},
+		{id: "+66812345678", wantErr: true},
+		{id: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		tag, target, err := promptPayTarget(tt.id)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("promptPayTarget(%q) = %q, %q, want error", tt.id, tag, target)
+			}
+			continue
+		}
+		if err != nil || tag != tt.tag || target != tt.target {
+			t.Errorf("promptPayTarget(%q) = %q, %q, %v, want %q, %q", tt.id, tag, target, err, tt.tag, tt.target)
+		}
+	}
+}
+
+func TestPromptPayPayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        string
+		amount    money.Money
+		reference string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "static QR for a mobile number",
+			id:   "0812345678",
+			want: "00020101021129370016A000000677010111011300668123456785802TH530376463045D82",
+		},
+		{
+			// ตัวอย่างเดียวกับไลบรารี promptpay-qr ที่ธนาคารอ่านได้
+			name:   "dynamic QR with an amount",
+			id:     "000-000-0000",
+			amount: money.Satang(422),
+			want:   "00020101021229370016A000000677010111011300660000000005802TH530376454044.226304E469",
+		},
+		{
+			name:      "tax ID with amount and reference",
+			id:        "0105550123451",
+			amount:    money.Baht(1500),
+			reference: "ORDER42",
+			want:      "00020101021229370016A000000677010111021301055501234515802TH530376454071500.0062110507ORDER426304D2C7",
+		},
+		{name: "negative amount", id: "0812345678", amount: money.Baht(-1), wantErr: true},
+		{name: "foreign currency", id: "0812345678", amount: money.New(100, "USD"), wantErr: true},
+		{name: "invalid id", id: "12345", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := PromptPayPayload(tt.id, tt.amount, tt.reference)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: PromptPayPayload = %q, want error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: PromptPayPayload = %q, %v, want %q", tt.name, got, err, tt.want)
+			continue
+		}
+		// 4 ตัวท้ายคือ CRC ของข้อความทั้งหมดก่อนหน้า (รวม "6304")
+		body, checksum := got[:len(got)-4], got[len(got)-4:]
+		if crc, err := strconv.ParseUint(checksum, 16, 16); err != nil || uint16(crc) != crc16(body) {
+			t.Errorf("%s: checksum %q does not match crc16 %04X", tt.name, checksum, crc16(body))
+		}
+	}
+}
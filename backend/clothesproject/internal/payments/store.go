@@ -0,0 +1,224 @@
+package payments
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"clothesproject/internal/clothesstore"
+	"clothesproject/internal/money"
+	"clothesproject/internal/orders"
+)
+
+// Store จัดการการชำระเงินของคำสั่งซื้อ
+type Store interface {
+	StartPayment(ctx context.Context, userID string, orderID int, method Method, card *Card) (Payment, error)
+	GetPayments(ctx context.Context, userID string, orderID int) ([]Payment, error)
+	HandleCallback(ctx context.Context, method Method, body []byte, signature string) (Payment, error)
+}
+
+type PostgresStore struct {
+	pdb       *clothesstore.PostgresDatabase
+	providers map[Method]PaymentProvider
+}
+
+func NewPostgresStore(pdb *clothesstore.PostgresDatabase) *PostgresStore {
+	return &PostgresStore{pdb: pdb, providers: make(map[Method]PaymentProvider)}
+}
+
+// Register เพิ่มผู้ให้บริการของวิธีชำระเงิน วิธีที่ไม่ได้ register จะใช้ไม่ได้
+func (s *PostgresStore) Register(provider PaymentProvider) {
+	s.providers[provider.Method()] = provider
+}
+
+const paymentColumns = `payment_id, order_id, method, reference, amount, status, COALESCE(qr_payload, ''), COALESCE(note, ''), created_at, paid_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPayment(row rowScanner) (Payment, error) {
+	var payment Payment
+	var paidAt sql.NullTime
+	if err := row.Scan(&payment.ID, &payment.OrderID, &payment.Method, &payment.Reference, &payment.Amount, &payment.Status,
+		&payment.QRPayload, &payment.Note, &payment.CreatedAt, &paidAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Payment{}, err
+		}
+		return Payment{}, fmt.Errorf("failed to scan payment: %v", err)
+	}
+	if paidAt.Valid {
+		payment.PaidAt = &paidAt.Time
+	}
+	return payment, nil
+}
+
+// StartPayment เริ่มชำระเงินยอดรวมของคำสั่งซื้อที่รอชำระ ยอดมาจากคำสั่งซื้อเสมอ ไม่รับจาก client
+// บันทึกการชำระเงินก่อนเรียกผู้ให้บริการ เพื่อให้ callback ที่มาเร็วหา reference เจอ
+func (s *PostgresStore) StartPayment(ctx context.Context, userID string, orderID int, method Method, card *Card) (Payment, error) {
+	provider, ok := s.providers[method]
+	if !ok {
+		return Payment{}, fmt.Errorf("%w: %q", ErrUnsupportedMethod, method)
+	}
+
+	var status orders.Status
+	var total money.Money
+	err := s.pdb.DB().QueryRowContext(ctx, `
+        SELECT status, total FROM orders WHERE order_id = $1 AND user_id = $2
+    `, orderID, userID).Scan(&status, &total)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Payment{}, orders.ErrOrderNotFound
+		}
+		return Payment{}, fmt.Errorf("failed to get order: %v", err)
+	}
+	if status != orders.StatusPending {
+		return Payment{}, fmt.Errorf("%w: order is %s", ErrOrderNotPayable, status)
+	}
+
+	reference, err := newReference(orderID)
+	if err != nil {
+		return Payment{}, err
+	}
+	payment, err := scanPayment(s.pdb.DB().QueryRowContext(ctx, `
+        INSERT INTO payments (order_id, method, reference, amount)
+        VALUES ($1, $2, $3, $4)
+        RETURNING `+paymentColumns, orderID, method, reference, total))
+	if err != nil {
+		return Payment{}, fmt.Errorf("failed to create payment: %v", err)
+	}
+
+	charge, err := provider.CreateCharge(ctx, ChargeRequest{Reference: reference, OrderID: orderID, Amount: total, Card: card})
+	if err != nil {
+		if _, updateErr := s.pdb.DB().ExecContext(ctx, `
+            UPDATE payments SET status = $2, note = $3 WHERE payment_id = $1 AND status = $4
+        `, payment.ID, StatusFailed, err.Error(), StatusPending); updateErr != nil {
+			return Payment{}, fmt.Errorf("failed to update payment: %v", updateErr)
+		}
+		return Payment{}, err
+	}
+	if charge.QRPayload != "" {
+		if _, err := s.pdb.DB().ExecContext(ctx, "UPDATE payments SET qr_payload = $2 WHERE payment_id = $1", payment.ID, charge.QRPayload); err != nil {
+			return Payment{}, fmt.Errorf("failed to update payment: %v", err)
+		}
+		payment.QRPayload = charge.QRPayload
+	}
+	return payment, nil
+}
+
+// newReference สร้าง reference ของการชำระเงิน (ตัวอักษรและตัวเลขไม่เกิน 25 ตัว ตามข้อจำกัดของ QR พร้อมเพย์)
+func newReference(orderID int) (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate payment reference: %v", err)
+	}
+	return fmt.Sprintf("O%d%s", orderID, strings.ToUpper(hex.EncodeToString(b))), nil
+}
+
+// GetPayments ดึงการชำระเงินทั้งหมดของคำสั่งซื้อของผู้ใช้ เรียงจากล่าสุด
+func (s *PostgresStore) GetPayments(ctx context.Context, userID string, orderID int) ([]Payment, error) {
+	var exists bool
+	if err := s.pdb.DB().QueryRowContext(ctx, `
+        SELECT EXISTS (SELECT 1 FROM orders WHERE order_id = $1 AND user_id = $2)
+    `, orderID, userID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to get order: %v", err)
+	}
+	if !exists {
+		return nil, orders.ErrOrderNotFound
+	}
+
+	rows, err := s.pdb.DB().QueryContext(ctx, `
+        SELECT `+paymentColumns+`
+        FROM payments
+        WHERE order_id = $1
+        ORDER BY created_at DESC, payment_id DESC
+    `, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payments: %v", err)
+	}
+	defer rows.Close()
+
+	payments := []Payment{}
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate payments: %v", err)
+	}
+	return payments, nil
+}
+
+// HandleCallback ตรวจลายเซ็นของ callback กับผู้ให้บริการแล้วบันทึกผลการชำระเงิน
+// เมื่อสำเร็จจะเปลี่ยนคำสั่งซื้อเป็น paid ใน transaction เดียวกัน callback ซ้ำที่ผลเหมือนเดิมคืนผลเดิมโดยไม่ทำซ้ำ
+// ถ้าเงินเข้าหลังคำสั่งซื้อถูกยกเลิกไปแล้ว การชำระเงินยังบันทึกว่าสำเร็จ พร้อม note ว่าต้องคืนเงิน
+func (s *PostgresStore) HandleCallback(ctx context.Context, method Method, body []byte, signature string) (Payment, error) {
+	provider, ok := s.providers[method]
+	if !ok {
+		return Payment{}, fmt.Errorf("%w: %q", ErrUnsupportedMethod, method)
+	}
+	event, err := provider.VerifyCallback(body, signature)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	tx, err := s.pdb.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return Payment{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	payment, err := scanPayment(tx.QueryRowContext(ctx, `
+        SELECT `+paymentColumns+`
+        FROM payments
+        WHERE method = $1 AND reference = $2
+        FOR UPDATE
+    `, method, event.Reference))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Payment{}, fmt.Errorf("%w: %s", ErrPaymentNotFound, event.Reference)
+		}
+		return Payment{}, err
+	}
+	if payment.Status != StatusPending {
+		if payment.Status == event.Status {
+			return payment, nil
+		}
+		return Payment{}, fmt.Errorf("%w: %s is %s", ErrPaymentFinalised, payment.Reference, payment.Status)
+	}
+	if event.Status == StatusSucceeded && event.Amount.Cmp(payment.Amount) != 0 {
+		return Payment{}, fmt.Errorf("%w: expected %s, got %s", ErrAmountMismatch, payment.Amount, event.Amount)
+	}
+
+	note := event.Reason
+	if event.Status == StatusSucceeded {
+		err := orders.MarkPaid(ctx, tx, payment.OrderID, fmt.Sprintf("paid by %s (%s)", method, payment.Reference))
+		switch {
+		case errors.Is(err, orders.ErrInvalidTransition):
+			note = "order was no longer awaiting payment; refund required"
+		case err != nil:
+			return Payment{}, err
+		}
+	}
+
+	payment, err = scanPayment(tx.QueryRowContext(ctx, `
+        UPDATE payments
+        SET status = $2, note = NULLIF($3, ''), paid_at = CASE WHEN $2 = 'succeeded' THEN CURRENT_TIMESTAMP END
+        WHERE payment_id = $1
+        RETURNING `+paymentColumns, payment.ID, event.Status, note))
+	if err != nil {
+		return Payment{}, fmt.Errorf("failed to update payment: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Payment{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return payment, nil
+}
@@ -0,0 +1,160 @@
+package pricing
+
+import (
+	"sort"
+
+	"clothesproject/internal/money"
+)
+
+// VATPercent คืออัตราภาษีมูลค่าเพิ่มของไทย (ร้อยละ)
+const VATPercent = 7
+
+// Brand คือการตั้งค่าราคาของแบรนด์ VATInclusive คือราคาสินค้ารวม VAT แล้ว (ไม่เช่นนั้นบวก VAT เพิ่มตอนคิดเงิน)
+// FreeShippingThreshold คือยอดซื้อขั้นต่ำของแบรนด์ที่ส่งฟรี (nil คือไม่มีส่งฟรี)
+type Brand struct {
+	ID                    int
+	VATInclusive          bool
+	FreeShippingThreshold *money.Money
+}
+
+// Zone คือพื้นที่จัดส่ง ค่าส่งคือ BaseFee สำหรับน้ำหนักไม่เกิน BaseWeightGrams
+// และเพิ่ม PerKgFee ทุกกิโลกรัม (ปัดขึ้น) ที่เกิน
+type Zone struct {
+	ID              int
+	Name            string
+	BaseFee         money.Money
+	BaseWeightGrams int
+	PerKgFee        money.Money
+}
+
+// Fee คืนค่าส่งของพัสดุน้ำหนัก weightGrams กรัม
+func (z Zone) Fee(weightGrams int) money.Money {
+	if weightGrams <= z.BaseWeightGrams {
+		return z.BaseFee
+	}
+	extraKg := (weightGrams - z.BaseWeightGrams + 999) / 1000
+	return z.BaseFee.Add(z.PerKgFee.Mul(extraKg))
+}
+
+// Line คือสินค้าหนึ่งรายการ Subtotal คือราคารวมก่อนส่วนลด Discount คือส่วนลดระดับรายการ
+// WeightGrams คือน้ำหนักรวมของรายการ
+type Line struct {
+	BrandID     int
+	Subtotal    money.Money
+	Discount    money.Money
+	WeightGrams int
+}
+
+// Shipment คือพัสดุของแบรนด์หนึ่ง (แต่ละแบรนด์จัดส่งเอง) FreeShippingRemaining คือยอดที่ต้องซื้อเพิ่มเพื่อส่งฟรี
+type Shipment struct {
+	BrandID               int          `json:"brand_id"`
+	WeightGrams           int          `json:"weight_grams"`
+	Fee                   money.Money  `json:"fee"`
+	FreeShippingThreshold *money.Money `json:"free_shipping_threshold,omitempty"`
+	FreeShippingRemaining money.Money  `json:"free_shipping_remaining"`
+}
+
+// Totals คือยอดรวมของตะกร้าหรือคำสั่งซื้อ Total คือยอดที่ต้องชำระ
+// = Subtotal - DiscountTotal + VATAdded + ShippingFee
+// VATIncluded คือ VAT ที่รวมอยู่ในราคาแล้ว (สินค้าแบรนด์ที่ราคารวม VAT และค่าส่ง) VATAdded คือ VAT ที่บวกเพิ่ม
+type Totals struct {
+	Subtotal      money.Money `json:"subtotal"`
+	DiscountTotal money.Money `json:"discount_total"`
+	ShippingFee   money.Money `json:"shipping_fee"`
+	VATIncluded   money.Money `json:"vat_included"`
+	VATAdded      money.Money `json:"vat_added"`
+	VAT           money.Money `json:"vat"`
+	Total         money.Money `json:"total"`
+	Province      string      `json:"province,omitempty"`
+	Shipments     []Shipment  `json:"shipments"`
+}
+
+// IncludedVAT คือ VAT ที่รวมอยู่ในราคา amount แล้ว
+func IncludedVAT(amount money.Money) money.Money {
+	return amount.MulRatio(VATPercent, 100+VATPercent)
+}
+
+// Taxable คืนราคาหลังหักส่วนลดของแต่ละรายการ ซึ่งเป็นฐานคิด VAT: ราคาหลังหักส่วนลดระดับรายการ
+// หักส่วนแบ่งของ cartDiscount ตามสัดส่วนราคา (แบ่งด้วย money.Allocate ผลรวมจึงเท่ากับส่วนลดพอดี)
+// cartDiscount ที่เกินราคารวมถูกลดลงเหลือเท่าราคารวม
+func Taxable(lines []Line, cartDiscount money.Money) []money.Money {
+	net := make([]int64, len(lines))
+	var netTotal money.Money
+	for i, line := range lines {
+		lineNet := line.Subtotal.Sub(line.Discount)
+		net[i] = lineNet.Amount
+		netTotal = netTotal.Add(lineNet)
+	}
+	shares := money.Min(cartDiscount, netTotal).Allocate(net)
+
+	taxable := make([]money.Money, len(lines))
+	for i, line := range lines {
+		taxable[i] = line.Subtotal.Sub(line.Discount).Sub(shares[i])
+	}
+	return taxable
+}
+
+// Calculate คำนวณยอดรวม VAT และค่าส่ง cartDiscount คือส่วนลดระดับตะกร้า ซึ่งแบ่งไปยังแต่ละรายการด้วย Taxable เพื่อคิด VAT
+// zone เป็น nil คือไม่มีการจัดส่ง (เช่น รับที่สาขา) ค่าส่งคิดแยกตามแบรนด์ และแบรนด์ที่ซื้อถึงยอดขั้นต่ำหลังหักส่วนลดส่งฟรี
+// VAT คิดจากราคาหลังหักส่วนลดของแต่ละรายการ ค่าส่งถือว่ารวม VAT แล้ว
+func Calculate(lines []Line, cartDiscount money.Money, brands map[int]Brand, zone *Zone) Totals {
+	var totals Totals
+	var netTotal money.Money
+	for _, line := range lines {
+		totals.Subtotal = totals.Subtotal.Add(line.Subtotal)
+		totals.DiscountTotal = totals.DiscountTotal.Add(line.Discount)
+		netTotal = netTotal.Add(line.Subtotal.Sub(line.Discount))
+	}
+	totals.DiscountTotal = totals.DiscountTotal.Add(money.Min(cartDiscount, netTotal))
+	taxables := Taxable(lines, cartDiscount)
+
+	type group struct {
+		weight int
+		goods  money.Money
+	}
+	groups := make(map[int]*group)
+	for i, line := range lines {
+		taxable := taxables[i]
+		if brands[line.BrandID].VATInclusive {
+			totals.VATIncluded = totals.VATIncluded.Add(IncludedVAT(taxable))
+		} else {
+			totals.VATAdded = totals.VATAdded.Add(taxable.MulRatio(VATPercent, 100))
+		}
+
+		g, ok := groups[line.BrandID]
+		if !ok {
+			g = &group{}
+			groups[line.BrandID] = g
+		}
+		g.weight += line.WeightGrams
+		g.goods = g.goods.Add(taxable)
+	}
+
+	totals.Shipments = []Shipment{}
+	if zone != nil {
+		brandIDs := make([]int, 0, len(groups))
+		for id := range groups {
+			brandIDs = append(brandIDs, id)
+		}
+		sort.Ints(brandIDs)
+		for _, id := range brandIDs {
+			g := groups[id]
+			shipment := Shipment{BrandID: id, WeightGrams: g.weight, Fee: zone.Fee(g.weight)}
+			if threshold := brands[id].FreeShippingThreshold; threshold != nil {
+				shipment.FreeShippingThreshold = threshold
+				if g.goods.Cmp(*threshold) >= 0 {
+					shipment.Fee = money.Money{}
+				} else {
+					shipment.FreeShippingRemaining = threshold.Sub(g.goods)
+				}
+			}
+			totals.VATIncluded = totals.VATIncluded.Add(IncludedVAT(shipment.Fee))
+			totals.ShippingFee = totals.ShippingFee.Add(shipment.Fee)
+			totals.Shipments = append(totals.Shipments, shipment)
+		}
+	}
+
+	totals.VAT = totals.VATIncluded.Add(totals.VATAdded)
+	totals.Total = totals.Subtotal.Sub(totals.DiscountTotal).Add(totals.VATAdded).Add(totals.ShippingFee)
+	return totals
+}
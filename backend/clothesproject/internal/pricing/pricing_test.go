@@ -0,0 +1,158 @@
+package pricing
+
+import (
+	"testing"
+
+	"clothesproject/internal/money"
+)
+
+func TestZoneFee(t *testing.T) {
+	zone := Zone{BaseFee: money.Baht(50), BaseWeightGrams: 1000, PerKgFee: money.Baht(20)}
+	tests := []struct {
+		weightGrams int
+		want        money.Money
+	}{
+		{weightGrams: 0, want: money.Baht(50)},
+		{weightGrams: 1000, want: money.Baht(50)},
+		{weightGrams: 1001, want: money.Baht(70)},
+		{weightGrams: 2000, want: money.Baht(70)},
+		{weightGrams: 2001, want: money.Baht(90)},
+	}
+	for _, tt := range tests {
+		if got := zone.Fee(tt.weightGrams); got != tt.want {
+			t.Errorf("Fee(%d) = %s, want %s", tt.weightGrams, got, tt.want)
+		}
+	}
+}
+
+func TestIncludedVAT(t *testing.T) {
+	tests := []struct {
+		amount, want int64
+	}{
+		{amount: 10700, want: 700},
+		{amount: 80000, want: 5234},
+		{amount: 5000, want: 327},
+		{amount: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := IncludedVAT(money.Satang(tt.amount)); got != money.Satang(tt.want) {
+			t.Errorf("IncludedVAT(%s) = %s, want %s", money.Satang(tt.amount), got, money.Satang(tt.want))
+		}
+	}
+}
+
+func TestTaxable(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []Line
+		cartDiscount money.Money
+		want         []int64
+	}{
+		{
+			name:  "line discounts only",
+			lines: []Line{{Subtotal: money.Baht(100), Discount: money.Baht(10)}, {Subtotal: money.Baht(50)}},
+			want:  []int64{9000, 5000},
+		},
+		{
+			name:         "cart discount split by net price",
+			lines:        []Line{{Subtotal: money.Baht(800)}, {Subtotal: money.Baht(200), Discount: money.Baht(20)}},
+			cartDiscount: money.Baht(100),
+			want:         []int64{71837, 16163},
+		},
+		{
+			name:         "cart discount capped at the net total",
+			lines:        []Line{{Subtotal: money.Baht(30)}, {Subtotal: money.Baht(10)}},
+			cartDiscount: money.Baht(100),
+			want:         []int64{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		got := Taxable(tt.lines, tt.cartDiscount)
+		for i, want := range tt.want {
+			if got[i].Amount != want {
+				t.Errorf("%s: line %d = %s, want %s", tt.name, i, got[i], money.Satang(want))
+			}
+		}
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	threshold := money.Baht(1000)
+	brands := map[int]Brand{
+		1: {ID: 1, VATInclusive: true, FreeShippingThreshold: &threshold},
+		2: {ID: 2},
+	}
+	zone := &Zone{BaseFee: money.Baht(50), BaseWeightGrams: 1000, PerKgFee: money.Baht(20)}
+	lines := []Line{
+		{BrandID: 1, Subtotal: money.Baht(800), WeightGrams: 500},
+		{BrandID: 2, Subtotal: money.Baht(200), Discount: money.Baht(20), WeightGrams: 1500},
+	}
+
+	tests := []struct {
+		name          string
+		lines         []Line
+		cartDiscount  money.Money
+		zone          *Zone
+		wantDiscount  int64
+		wantVAT       int64
+		wantVATAdded  int64
+		wantShipping  int64
+		wantTotal     int64
+		wantShipments []Shipment
+	}{
+		{
+			name:         "shipping per brand",
+			lines:        lines,
+			zone:         zone,
+			wantDiscount: 2000,
+			wantVAT:      5234 + 327 + 458 + 1260,
+			wantVATAdded: 1260,
+			wantShipping: 12000,
+			wantTotal:    100000 - 2000 + 1260 + 12000,
+			wantShipments: []Shipment{
+				{BrandID: 1, WeightGrams: 500, Fee: money.Baht(50), FreeShippingThreshold: &threshold, FreeShippingRemaining: money.Baht(200)},
+				{BrandID: 2, WeightGrams: 1500, Fee: money.Baht(70)},
+			},
+		},
+		{
+			name:          "pickup with a cart discount",
+			lines:         lines,
+			cartDiscount:  money.Baht(100),
+			wantDiscount:  12000,
+			wantVAT:       4700 + 1131,
+			wantVATAdded:  1131,
+			wantTotal:     100000 - 12000 + 1131,
+			wantShipments: []Shipment{},
+		},
+		{
+			name:         "free shipping threshold reached",
+			lines:        []Line{{BrandID: 1, Subtotal: money.Baht(1000), WeightGrams: 3000}},
+			zone:         zone,
+			wantVAT:      6542,
+			wantTotal:    100000,
+			wantShipments: []Shipment{
+				{BrandID: 1, WeightGrams: 3000, FreeShippingThreshold: &threshold},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := Calculate(tt.lines, tt.cartDiscount, brands, tt.zone)
+		if got.DiscountTotal.Amount != tt.wantDiscount || got.VAT.Amount != tt.wantVAT || got.VATAdded.Amount != tt.wantVATAdded ||
+			got.ShippingFee.Amount != tt.wantShipping || got.Total.Amount != tt.wantTotal {
+			t.Errorf("%s: discount %s, VAT %s, VAT added %s, shipping %s, total %s; want %s, %s, %s, %s, %s", tt.name,
+				got.DiscountTotal, got.VAT, got.VATAdded, got.ShippingFee, got.Total,
+				money.Satang(tt.wantDiscount), money.Satang(tt.wantVAT), money.Satang(tt.wantVATAdded), money.Satang(tt.wantShipping), money.Satang(tt.wantTotal))
+		}
+		if len(got.Shipments) != len(tt.wantShipments) {
+			t.Errorf("%s: got %d shipments, want %d", tt.name, len(got.Shipments), len(tt.wantShipments))
+			continue
+		}
+		for i, want := range tt.wantShipments {
+			s := got.Shipments[i]
+			if s.BrandID != want.BrandID || s.WeightGrams != want.WeightGrams || s.Fee.Amount != want.Fee.Amount ||
+				s.FreeShippingThreshold != want.FreeShippingThreshold || s.FreeShippingRemaining.Amount != want.FreeShippingRemaining.Amount {
+				t.Errorf("%s: shipment %d = %+v, want %+v", tt.name, i, s, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package pricing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"clothesproject/internal/money"
+
+	"github.com/lib/pq"
+)
+
+// Querier คือสิ่งที่ query ได้ ใช้ได้ทั้ง *sql.DB และ *sql.Tx
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Quote โหลดการตั้งค่าของแบรนด์และพื้นที่จัดส่งของ province แล้วคำนวณยอดรวมด้วย Calculate
+// ship เป็น false คือไม่มีการจัดส่ง (ไม่คิดค่าส่ง) province ว่างจะประมาณค่าส่งด้วยพื้นที่จัดส่งเริ่มต้น
+func Quote(ctx context.Context, q Querier, lines []Line, cartDiscount money.Money, province string, ship bool) (Totals, error) {
+	brands, err := loadBrands(ctx, q, lines)
+	if err != nil {
+		return Totals{}, err
+	}
+
+	var zone *Zone
+	if ship {
+		found, err := zoneFor(ctx, q, province)
+		if err != nil {
+			return Totals{}, err
+		}
+		zone = &found
+	}
+
+	totals := Calculate(lines, cartDiscount, brands, zone)
+	if ship {
+		totals.Province = strings.TrimSpace(province)
+	}
+	return totals, nil
+}
+
+func loadBrands(ctx context.Context, q Querier, lines []Line) (map[int]Brand, error) {
+	var ids []int64
+	for _, line := range lines {
+		ids = append(ids, int64(line.BrandID))
+	}
+	rows, err := q.QueryContext(ctx, `
+        SELECT id, vat_inclusive, free_shipping_threshold FROM brand WHERE id = ANY($1)
+    `, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query brand pricing: %v", err)
+	}
+	defer rows.Close()
+
+	brands := make(map[int]Brand)
+	for rows.Next() {
+		var brand Brand
+		if err := rows.Scan(&brand.ID, &brand.VATInclusive, &brand.FreeShippingThreshold); err != nil {
+			return nil, fmt.Errorf("failed to scan brand pricing: %v", err)
+		}
+		brands[brand.ID] = brand
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return brands, nil
+}
+
+// zoneFor หาพื้นที่จัดส่งของจังหวัด จังหวัดที่ไม่ได้กำหนดไว้ใช้พื้นที่จัดส่งเริ่มต้น
+func zoneFor(ctx context.Context, q Querier, province string) (Zone, error) {
+	var zone Zone
+	err := q.QueryRowContext(ctx, `
+        SELECT z.zone_id, z.name, z.base_fee, z.base_weight_grams, z.per_kg_fee
+        FROM shipping_zones z
+        LEFT JOIN shipping_zone_provinces zp ON zp.zone_id = z.zone_id AND zp.province = $1
+        WHERE zp.province IS NOT NULL OR z.is_default
+        ORDER BY zp.province IS NULL
+        LIMIT 1
+    `, strings.TrimSpace(province)).Scan(&zone.ID, &zone.Name, &zone.BaseFee, &zone.BaseWeightGrams, &zone.PerKgFee)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Zone{}, fmt.Errorf("no shipping zone for province %q and no default zone", province)
+		}
+		return Zone{}, fmt.Errorf("failed to query shipping zone: %v", err)
+	}
+	return zone, nil
+}
@@ -0,0 +1,376 @@
+package promotions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"clothesproject/internal/money"
+)
+
+var (
+	// ErrInvalidCoupon คืนค่าเมื่อใช้คูปองไม่ได้ (ไม่พบ หมดอายุ ใช้ครบแล้ว หรือไม่ตรงเงื่อนไข) ข้อความบอกเหตุผล
+	ErrInvalidCoupon = errors.New("invalid coupon")
+	// ErrInvalidPromotion คืนค่าเมื่อข้อมูลโปรโมชันที่สร้าง/แก้ไขไม่ถูกต้อง
+	ErrInvalidPromotion = errors.New("invalid promotion")
+	// ErrPromotionNotFound คืนค่าเมื่อไม่พบโปรโมชัน
+	ErrPromotionNotFound = errors.New("promotion not found")
+	// ErrDuplicateCode คืนค่าเมื่อรหัสคูปองซ้ำกับโปรโมชันอื่น (ไม่สนตัวพิมพ์เล็ก/ใหญ่)
+	ErrDuplicateCode = errors.New("promotion code already exists")
+)
+
+// Kind คือชนิดของส่วนลด
+type Kind string
+
+const (
+	// KindPercentage ลดเป็นเปอร์เซ็นต์ของราคา (Value คือเปอร์เซ็นต์ ทศนิยมได้ 2 ตำแหน่ง)
+	KindPercentage Kind = "percentage"
+	// KindFixed ลดเป็นจำนวนเงิน (Value คือบาท ต่อชิ้นถ้าจำกัดแบรนด์/หมวดหมู่ ไม่เช่นนั้นต่อตะกร้า)
+	KindFixed Kind = "fixed"
+	// KindBuyXGetY ซื้อครบ BuyQuantity ชิ้นแถม GetQuantity ชิ้น (ชิ้นที่ถูกที่สุดฟรี)
+	KindBuyXGetY Kind = "buy_x_get_y"
+)
+
+func (k Kind) IsValid() bool {
+	switch k {
+	case KindPercentage, KindFixed, KindBuyXGetY:
+		return true
+	}
+	return false
+}
+
+// Promotion คือโปรโมชันหนึ่งรายการ Code ว่างคือโปรโมชันอัตโนมัติ (เช่น ลดทั้งแบรนด์) ไม่ว่างคือคูปองที่ต้องกรอกรหัส
+// BrandID/Category จำกัดสินค้าที่ร่วมรายการ (0/ว่างคือทุกสินค้า) UsageLimit/PerUserLimit เป็น 0 คือไม่จำกัด
+type Promotion struct {
+	ID           int         `json:"id"`
+	Name         string      `json:"name"`
+	Code         string      `json:"code,omitempty"`
+	Kind         Kind        `json:"kind"`
+	Value        money.Money `json:"value"`
+	BrandID      int         `json:"brand_id,omitempty"`
+	Category     string      `json:"category,omitempty"`
+	BuyQuantity  int         `json:"buy_quantity,omitempty"`
+	GetQuantity  int         `json:"get_quantity,omitempty"`
+	MinSpend     money.Money `json:"min_spend"`
+	UsageLimit   int         `json:"usage_limit,omitempty"`
+	PerUserLimit int         `json:"per_user_limit,omitempty"`
+	StartsAt     *time.Time  `json:"starts_at,omitempty"`
+	EndsAt       *time.Time  `json:"ends_at,omitempty"`
+	Active       bool        `json:"active"`
+}
+
+// Validate ตรวจสอบข้อมูลโปรโมชันก่อนบันทึก
+func (p Promotion) Validate() error {
+	switch {
+	case strings.TrimSpace(p.Name) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidPromotion)
+	case !p.Kind.IsValid():
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidPromotion, p.Kind)
+	case p.Kind == KindPercentage && (!p.Value.IsPositive() || p.Value.Cmp(money.Baht(100)) > 0):
+		return fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidPromotion)
+	case p.Kind == KindFixed && !p.Value.IsPositive():
+		return fmt.Errorf("%w: fixed amount must be greater than 0", ErrInvalidPromotion)
+	case p.Kind == KindBuyXGetY && (p.BuyQuantity <= 0 || p.GetQuantity <= 0):
+		return fmt.Errorf("%w: buy_quantity and get_quantity must be greater than 0", ErrInvalidPromotion)
+	case p.MinSpend.IsNegative() || p.UsageLimit < 0 || p.PerUserLimit < 0:
+		return fmt.Errorf("%w: min_spend and limits cannot be negative", ErrInvalidPromotion)
+	case p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidPromotion)
+	}
+	return nil
+}
+
+// lineLevel คือโปรโมชันที่ลดเป็นรายการสินค้า (จำกัดแบรนด์/หมวดหมู่ หรือซื้อ X แถม Y)
+// ที่เหลือลดจากยอดรวมทั้งตะกร้า
+func (p Promotion) lineLevel() bool {
+	return p.Kind == KindBuyXGetY || p.BrandID != 0 || p.Category != ""
+}
+
+func (p Promotion) covers(line Line) bool {
+	return (p.BrandID == 0 || p.BrandID == line.BrandID) && (p.Category == "" || p.Category == line.Category)
+}
+
+// Line คือสินค้าหนึ่งรายการในตะกร้า Key ใช้อ้างอิงกลับไปยังรายการเดิม (เช่น cart_id)
+type Line struct {
+	Key       int
+	BrandID   int
+	Category  string
+	UnitPrice money.Money
+	Quantity  int
+}
+
+// Applied คือส่วนลดจากโปรโมชันหนึ่งรายการ
+type Applied struct {
+	PromotionID int         `json:"promotion_id"`
+	Name        string      `json:"name"`
+	Code        string      `json:"code,omitempty"`
+	Amount      money.Money `json:"amount"`
+}
+
+// LineResult คือส่วนลดของสินค้าหนึ่งรายการ
+type LineResult struct {
+	Key       int
+	Subtotal  money.Money
+	Discount  money.Money
+	Discounts []Applied
+}
+
+// Result คือผลการคำนวณส่วนลดของทั้งตะกร้า Lines เรียงตามลำดับ Line ที่ส่งเข้ามา
+// CouponErr ไม่เป็น nil เมื่อกรอกคูปองแต่ใช้ไม่ได้ (ส่วนลดอื่นยังคำนวณตามปกติ)
+type Result struct {
+	Lines         []LineResult
+	Subtotal      money.Money
+	LineDiscount  money.Money
+	CartDiscounts []Applied
+	DiscountTotal money.Money
+	Total         money.Money
+	Coupon        string
+	CouponErr     error
+}
+
+// Applied รวมส่วนลดทั้งหมด (รายการสินค้าและทั้งตะกร้า) ตามโปรโมชัน เรียงตามรหัสโปรโมชัน
+func (r Result) Applied() []Applied {
+	byPromotion := make(map[int]*Applied)
+	var order []int
+	add := func(a Applied) {
+		if existing, ok := byPromotion[a.PromotionID]; ok {
+			existing.Amount = existing.Amount.Add(a.Amount)
+			return
+		}
+		copied := a
+		byPromotion[a.PromotionID] = &copied
+		order = append(order, a.PromotionID)
+	}
+	for _, line := range r.Lines {
+		for _, a := range line.Discounts {
+			add(a)
+		}
+	}
+	for _, a := range r.CartDiscounts {
+		add(a)
+	}
+	sort.Ints(order)
+	applied := make([]Applied, len(order))
+	for i, id := range order {
+		applied[i] = *byPromotion[id]
+	}
+	return applied
+}
+
+// Usage คือจำนวนครั้งที่โปรโมชันถูกใช้ไปแล้ว ทั้งหมด (Total) และของผู้ใช้ที่กำลังคำนวณ (ByUser) ตามรหัสโปรโมชัน
+type Usage struct {
+	Total  map[int]int
+	ByUser map[int]int
+}
+
+// Evaluate คำนวณส่วนลดของตะกร้าจากโปรโมชัน promos และคูปอง coupon (ว่างคือไม่ใช้คูปอง) ตามลำดับ:
+//  1. โปรโมชันอัตโนมัติระดับรายการ แต่ละรายการได้ส่วนลดที่มากที่สุดเพียงอย่างเดียว
+//  2. คูปองระดับรายการ ลดต่อจากราคาหลังข้อ 1
+//  3. โปรโมชันอัตโนมัติระดับตะกร้า แล้วคูปองระดับตะกร้า ลดจากยอดที่เหลือ
+//
+// ยอดขั้นต่ำคิดจากราคาเต็มของสินค้าที่ร่วมรายการ ส่วนลดรวมไม่เกินยอดสินค้า
+// ใช้ทั้งตอนแสดงตะกร้าและตอนสร้างคำสั่งซื้อ เพื่อให้ได้ผลเหมือนกัน
+func Evaluate(lines []Line, promos []Promotion, coupon string, usage Usage, now time.Time) Result {
+	result := Result{Lines: make([]LineResult, len(lines)), Coupon: strings.TrimSpace(coupon)}
+	remaining := make([]money.Money, len(lines))
+	for i, line := range lines {
+		subtotal := line.UnitPrice.Mul(line.Quantity)
+		result.Lines[i] = LineResult{Key: line.Key, Subtotal: subtotal}
+		remaining[i] = subtotal
+		result.Subtotal = result.Subtotal.Add(subtotal)
+	}
+
+	var automatic []Promotion
+	var couponPromo *Promotion
+	for i := range promos {
+		p := promos[i]
+		switch {
+		case p.Code == "":
+			if eligible(p, lines, usage, now) == nil {
+				automatic = append(automatic, p)
+			}
+		case result.Coupon != "" && strings.EqualFold(p.Code, result.Coupon):
+			couponPromo = &promos[i]
+		}
+	}
+	sort.Slice(automatic, func(i, j int) bool { return automatic[i].ID < automatic[j].ID })
+	if result.Coupon != "" {
+		if couponPromo == nil {
+			result.CouponErr = fmt.Errorf("%w: coupon %q not found", ErrInvalidCoupon, result.Coupon)
+		} else if err := eligible(*couponPromo, lines, usage, now); err != nil {
+			result.CouponErr = fmt.Errorf("%w: %v", ErrInvalidCoupon, err)
+			couponPromo = nil
+		}
+	}
+
+	applyLine := func(p Promotion, amounts []money.Money) money.Money {
+		var total money.Money
+		for i, amount := range amounts {
+			if !amount.IsPositive() {
+				continue
+			}
+			remaining[i] = remaining[i].Sub(amount)
+			result.Lines[i].Discount = result.Lines[i].Discount.Add(amount)
+			result.Lines[i].Discounts = append(result.Lines[i].Discounts, Applied{PromotionID: p.ID, Name: p.Name, Code: p.Code, Amount: amount})
+			total = total.Add(amount)
+		}
+		return total
+	}
+
+	// 1. ส่วนลดอัตโนมัติระดับรายการ: เลือกโปรโมชันที่ลดได้มากที่สุดของแต่ละรายการ
+	best := make([]money.Money, len(lines))
+	bestPromo := make([]int, len(lines))
+	for p, promo := range automatic {
+		if !promo.lineLevel() {
+			continue
+		}
+		for i, amount := range allocate(promo, lines, remaining) {
+			if amount.Cmp(best[i]) > 0 {
+				best[i], bestPromo[i] = amount, p
+			}
+		}
+	}
+	for p, promo := range automatic {
+		amounts := make([]money.Money, len(lines))
+		for i := range lines {
+			if best[i].IsPositive() && bestPromo[i] == p {
+				amounts[i] = best[i]
+			}
+		}
+		applyLine(promo, amounts)
+	}
+
+	// 2. คูปองระดับรายการ
+	var couponAmount money.Money
+	if couponPromo != nil && couponPromo.lineLevel() {
+		couponAmount = applyLine(*couponPromo, allocate(*couponPromo, lines, remaining))
+	}
+
+	// 3. ส่วนลดระดับตะกร้า
+	left := money.Sum(remaining...)
+	cartLevel := make([]Promotion, 0, len(automatic)+1)
+	for _, promo := range automatic {
+		if !promo.lineLevel() {
+			cartLevel = append(cartLevel, promo)
+		}
+	}
+	if couponPromo != nil && !couponPromo.lineLevel() {
+		cartLevel = append(cartLevel, *couponPromo)
+	}
+	for _, promo := range cartLevel {
+		amount := money.Min(promo.Value, left)
+		if promo.Kind == KindPercentage {
+			amount = left.Percent(promo.Value)
+		}
+		if !amount.IsPositive() {
+			continue
+		}
+		left = left.Sub(amount)
+		result.CartDiscounts = append(result.CartDiscounts, Applied{PromotionID: promo.ID, Name: promo.Name, Code: promo.Code, Amount: amount})
+		if couponPromo != nil && promo.ID == couponPromo.ID {
+			couponAmount = couponAmount.Add(amount)
+		}
+	}
+
+	if couponPromo != nil && couponAmount.IsZero() {
+		result.CouponErr = fmt.Errorf("%w: coupon %q does not apply to this cart", ErrInvalidCoupon, result.Coupon)
+	}
+
+	for _, line := range result.Lines {
+		result.LineDiscount = result.LineDiscount.Add(line.Discount)
+	}
+	result.DiscountTotal = result.LineDiscount
+	for _, a := range result.CartDiscounts {
+		result.DiscountTotal = result.DiscountTotal.Add(a.Amount)
+	}
+	result.Total = result.Subtotal.Sub(result.DiscountTotal)
+	return result
+}
+
+// eligible ตรวจสอบว่าใช้โปรโมชันกับตะกร้านี้ได้หรือไม่ คืนเหตุผลถ้าใช้ไม่ได้
+func eligible(p Promotion, lines []Line, usage Usage, now time.Time) error {
+	switch {
+	case !p.Active:
+		return errors.New("promotion is not active")
+	case p.StartsAt != nil && now.Before(*p.StartsAt):
+		return errors.New("promotion has not started yet")
+	case p.EndsAt != nil && !now.Before(*p.EndsAt):
+		return errors.New("promotion has expired")
+	case p.UsageLimit > 0 && usage.Total[p.ID] >= p.UsageLimit:
+		return errors.New("promotion has reached its usage limit")
+	case p.PerUserLimit > 0 && usage.ByUser[p.ID] >= p.PerUserLimit:
+		return errors.New("you have already used this promotion")
+	}
+
+	var base money.Money
+	for _, line := range lines {
+		if p.covers(line) {
+			base = base.Add(line.UnitPrice.Mul(line.Quantity))
+		}
+	}
+	if base.IsZero() {
+		return errors.New("no item in the cart is eligible")
+	}
+	if base.Cmp(p.MinSpend) < 0 {
+		return fmt.Errorf("spend at least %s on eligible items", p.MinSpend)
+	}
+	return nil
+}
+
+// allocate คำนวณส่วนลดระดับรายการของโปรโมชันจากราคาที่เหลือของแต่ละรายการ (ไม่เกินราคาที่เหลือ)
+func allocate(p Promotion, lines []Line, remaining []money.Money) []money.Money {
+	amounts := make([]money.Money, len(lines))
+	switch p.Kind {
+	case KindPercentage:
+		for i, line := range lines {
+			if p.covers(line) {
+				amounts[i] = remaining[i].Percent(p.Value)
+			}
+		}
+	case KindFixed:
+		for i, line := range lines {
+			if p.covers(line) {
+				amounts[i] = money.Min(p.Value.Mul(line.Quantity), remaining[i])
+			}
+		}
+	case KindBuyXGetY:
+		// ทุก BuyQuantity+GetQuantity ชิ้นของสินค้าที่ร่วมรายการ ชิ้นที่ถูกที่สุด GetQuantity ชิ้นฟรี
+		// ราคาที่เหลือของรายการแบ่งเท่ากันทุกชิ้น เศษสตางค์เพิ่มให้บางชิ้นชิ้นละ 1 สตางค์ ผลรวมจึงเท่ากับราคาที่เหลือพอดี
+		// คิดเป็นกลุ่มของชิ้นที่ราคาเท่ากันแทนการสร้างทีละชิ้น หน่วยความจำจึงไม่โตตามจำนวนสินค้า
+		type group struct {
+			line  int
+			price money.Money
+			count int
+		}
+		var groups []group
+		units := 0
+		for i, line := range lines {
+			if !p.covers(line) || line.Quantity <= 0 {
+				continue
+			}
+			low := remaining[i]
+			low.Amount = remaining[i].Amount / int64(line.Quantity)
+			extra := int(remaining[i].Amount % int64(line.Quantity))
+			if extra > 0 {
+				high := low
+				high.Amount++
+				groups = append(groups, group{line: i, price: high, count: extra})
+			}
+			groups = append(groups, group{line: i, price: low, count: line.Quantity - extra})
+			units += line.Quantity
+		}
+		sort.SliceStable(groups, func(i, j int) bool { return groups[i].price.Cmp(groups[j].price) < 0 })
+		free := units / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+		for _, g := range groups {
+			if free == 0 {
+				break
+			}
+			n := min(free, g.count)
+			amounts[g.line] = amounts[g.line].Add(g.price.Mul(n))
+			free -= n
+		}
+	}
+	return amounts
+}
@@ -0,0 +1,290 @@
+package promotions
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"clothesproject/internal/money"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestValidate(t *testing.T) {
+	before := testNow.Add(-time.Hour)
+	tests := []struct {
+		name    string
+		promo   Promotion
+		wantErr bool
+	}{
+		{name: "percentage", promo: Promotion{Name: "Sale", Kind: KindPercentage, Value: money.Baht(10)}},
+		{name: "full percentage", promo: Promotion{Name: "Free", Kind: KindPercentage, Value: money.Baht(100)}},
+		{name: "fixed", promo: Promotion{Name: "Fixed", Kind: KindFixed, Value: money.Baht(50)}},
+		{name: "buy x get y", promo: Promotion{Name: "B2G1", Kind: KindBuyXGetY, BuyQuantity: 2, GetQuantity: 1}},
+		{name: "missing name", promo: Promotion{Name: " ", Kind: KindFixed, Value: money.Baht(50)}, wantErr: true},
+		{name: "unknown kind", promo: Promotion{Name: "X", Kind: "bogus", Value: money.Baht(1)}, wantErr: true},
+		{name: "percentage over 100", promo: Promotion{Name: "X", Kind: KindPercentage, Value: money.Satang(10001)}, wantErr: true},
+		{name: "zero percentage", promo: Promotion{Name: "X", Kind: KindPercentage}, wantErr: true},
+		{name: "negative fixed", promo: Promotion{Name: "X", Kind: KindFixed, Value: money.Baht(-1)}, wantErr: true},
+		{name: "buy x without y", promo: Promotion{Name: "X", Kind: KindBuyXGetY, BuyQuantity: 2}, wantErr: true},
+		{name: "negative limit", promo: Promotion{Name: "X", Kind: KindFixed, Value: money.Baht(1), UsageLimit: -1}, wantErr: true},
+		{name: "ends before start", promo: Promotion{Name: "X", Kind: KindFixed, Value: money.Baht(1), StartsAt: &testNow, EndsAt: &before}, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := tt.promo.Validate()
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%s: Validate() = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidPromotion) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidPromotion", tt.name, err)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	expired := testNow.Add(-time.Minute)
+	cart := []Line{
+		{Key: 1, BrandID: 1, Category: "shirt", UnitPrice: money.Baht(500), Quantity: 2},
+		{Key: 2, BrandID: 2, Category: "pants", UnitPrice: money.Baht(300), Quantity: 1},
+	}
+	tees := []Line{
+		{Key: 1, BrandID: 1, Category: "tee", UnitPrice: money.Baht(100), Quantity: 2},
+		{Key: 2, BrandID: 2, Category: "tee", UnitPrice: money.Baht(60), Quantity: 1},
+	}
+	brandSale := Promotion{ID: 1, Name: "Brand 1 sale", Kind: KindPercentage, Value: money.Baht(10), BrandID: 1, Active: true}
+	brandFixed := Promotion{ID: 2, Name: "Brand 1 fixed", Kind: KindFixed, Value: money.Baht(80), BrandID: 1, Active: true}
+	cartFixed := Promotion{ID: 3, Name: "50 off", Kind: KindFixed, Value: money.Baht(50), Active: true}
+	coupon := Promotion{ID: 4, Name: "Coupon", Code: "SAVE10", Kind: KindPercentage, Value: money.Baht(10), Active: true}
+
+	with := func(p Promotion, change func(*Promotion)) Promotion {
+		change(&p)
+		return p
+	}
+
+	tests := []struct {
+		name          string
+		lines         []Line
+		promos        []Promotion
+		coupon        string
+		usage         Usage
+		wantLines     []int64
+		wantCart      []int64
+		wantTotal     int64
+		wantCouponErr bool
+	}{
+		{
+			name:      "no promotions",
+			lines:     cart,
+			wantLines: []int64{0, 0},
+			wantTotal: 130000,
+		},
+		{
+			name:      "automatic brand percentage",
+			lines:     cart,
+			promos:    []Promotion{brandSale},
+			wantLines: []int64{10000, 0},
+			wantTotal: 120000,
+		},
+		{
+			name:      "best automatic line promotion wins",
+			lines:     cart,
+			promos:    []Promotion{brandSale, brandFixed},
+			wantLines: []int64{16000, 0},
+			wantTotal: 114000,
+		},
+		{
+			name:      "inactive promotion is ignored",
+			lines:     cart,
+			promos:    []Promotion{with(brandSale, func(p *Promotion) { p.Active = false })},
+			wantLines: []int64{0, 0},
+			wantTotal: 130000,
+		},
+		{
+			name:      "cart coupon applies after automatic cart discount",
+			lines:     cart,
+			promos:    []Promotion{cartFixed, coupon},
+			coupon:    "SAVE10",
+			wantLines: []int64{0, 0},
+			wantCart:  []int64{5000, 12500},
+			wantTotal: 112500,
+		},
+		{
+			name:      "coupon code is case-insensitive",
+			lines:     cart,
+			promos:    []Promotion{coupon},
+			coupon:    " save10 ",
+			wantLines: []int64{0, 0},
+			wantCart:  []int64{13000},
+			wantTotal: 117000,
+		},
+		{
+			name:      "line coupon stacks on automatic line discount",
+			lines:     cart,
+			promos:    []Promotion{brandSale, with(brandFixed, func(p *Promotion) { p.Code = "BRAND50"; p.Value = money.Baht(50) })},
+			coupon:    "BRAND50",
+			wantLines: []int64{20000, 0},
+			wantTotal: 110000,
+		},
+		{
+			name:      "fixed cart discount is capped at the subtotal",
+			lines:     cart,
+			promos:    []Promotion{with(cartFixed, func(p *Promotion) { p.Value = money.Baht(5000) })},
+			wantLines: []int64{0, 0},
+			wantCart:  []int64{130000},
+			wantTotal: 0,
+		},
+		{
+			name:      "buy two get the cheapest free",
+			lines:     tees,
+			promos:    []Promotion{{ID: 5, Name: "B2G1", Kind: KindBuyXGetY, Category: "tee", BuyQuantity: 2, GetQuantity: 1, Active: true}},
+			wantLines: []int64{0, 6000},
+			wantTotal: 20000,
+		},
+		{
+			name:          "unknown coupon",
+			lines:         cart,
+			promos:        []Promotion{coupon},
+			coupon:        "NOPE",
+			wantLines:     []int64{0, 0},
+			wantTotal:     130000,
+			wantCouponErr: true,
+		},
+		{
+			name:          "expired coupon",
+			lines:         cart,
+			promos:        []Promotion{with(coupon, func(p *Promotion) { p.EndsAt = &expired })},
+			coupon:        "SAVE10",
+			wantLines:     []int64{0, 0},
+			wantTotal:     130000,
+			wantCouponErr: true,
+		},
+		{
+			name:          "coupon below minimum spend",
+			lines:         cart,
+			promos:        []Promotion{with(coupon, func(p *Promotion) { p.MinSpend = money.Baht(2000) })},
+			coupon:        "SAVE10",
+			wantLines:     []int64{0, 0},
+			wantTotal:     130000,
+			wantCouponErr: true,
+		},
+		{
+			name:          "coupon usage limit reached",
+			lines:         cart,
+			promos:        []Promotion{with(coupon, func(p *Promotion) { p.UsageLimit = 5 })},
+			coupon:        "SAVE10",
+			usage:         Usage{Total: map[int]int{4: 5}},
+			wantLines:     []int64{0, 0},
+			wantTotal:     130000,
+			wantCouponErr: true,
+		},
+		{
+			name:          "coupon per-user limit reached",
+			lines:         cart,
+			promos:        []Promotion{with(coupon, func(p *Promotion) { p.PerUserLimit = 1 })},
+			coupon:        "SAVE10",
+			usage:         Usage{ByUser: map[int]int{4: 1}},
+			wantLines:     []int64{0, 0},
+			wantTotal:     130000,
+			wantCouponErr: true,
+		},
+		{
+			name:          "coupon for a brand not in the cart",
+			lines:         cart,
+			promos:        []Promotion{with(coupon, func(p *Promotion) { p.BrandID = 3 })},
+			coupon:        "SAVE10",
+			wantLines:     []int64{0, 0},
+			wantTotal:     130000,
+			wantCouponErr: true,
+		},
+		{
+			name:          "coupon with nothing left to discount",
+			lines:         cart,
+			promos:        []Promotion{with(brandSale, func(p *Promotion) { p.Value = money.Baht(100) }), with(coupon, func(p *Promotion) { p.BrandID = 1 })},
+			coupon:        "SAVE10",
+			wantLines:     []int64{100000, 0},
+			wantTotal:     30000,
+			wantCouponErr: true,
+		},
+	}
+	for _, tt := range tests {
+		result := Evaluate(tt.lines, tt.promos, tt.coupon, tt.usage, testNow)
+		for i, want := range tt.wantLines {
+			if result.Lines[i].Discount.Amount != want {
+				t.Errorf("%s: line %d discount = %s, want %s", tt.name, i, result.Lines[i].Discount, money.Satang(want))
+			}
+		}
+		if len(result.CartDiscounts) != len(tt.wantCart) {
+			t.Errorf("%s: got %d cart discounts, want %d", tt.name, len(result.CartDiscounts), len(tt.wantCart))
+		} else {
+			for i, want := range tt.wantCart {
+				if result.CartDiscounts[i].Amount.Amount != want {
+					t.Errorf("%s: cart discount %d = %s, want %s", tt.name, i, result.CartDiscounts[i].Amount, money.Satang(want))
+				}
+			}
+		}
+		if result.Total.Amount != tt.wantTotal {
+			t.Errorf("%s: total = %s, want %s", tt.name, result.Total, money.Satang(tt.wantTotal))
+		}
+		if result.Subtotal.Sub(result.DiscountTotal) != result.Total {
+			t.Errorf("%s: subtotal %s - discount %s != total %s", tt.name, result.Subtotal, result.DiscountTotal, result.Total)
+		}
+		if tt.wantCouponErr != (result.CouponErr != nil) {
+			t.Errorf("%s: CouponErr = %v, wantCouponErr %v", tt.name, result.CouponErr, tt.wantCouponErr)
+		}
+		if result.CouponErr != nil && !errors.Is(result.CouponErr, ErrInvalidCoupon) {
+			t.Errorf("%s: CouponErr = %v, want ErrInvalidCoupon", tt.name, result.CouponErr)
+		}
+	}
+}
+
+func TestAllocateBuyXGetY(t *testing.T) {
+	tee := func(qty int) Line { return Line{Category: "tee", Quantity: qty} }
+	tests := []struct {
+		name      string
+		buy, get  int
+		lines     []Line
+		remaining []int64
+		want      []int64
+	}{
+		{name: "cheapest unit of an unevenly priced line", buy: 1, get: 1, lines: []Line{tee(3)}, remaining: []int64{10000}, want: []int64{3333}},
+		{name: "free units come from the cheaper line", buy: 1, get: 1, lines: []Line{tee(2), tee(2)}, remaining: []int64{20000, 10000}, want: []int64{0, 10000}},
+		{name: "free units span lines", buy: 2, get: 1, lines: []Line{tee(5), tee(1)}, remaining: []int64{50000, 3000}, want: []int64{10000, 3000}},
+		{name: "not enough units", buy: 2, get: 1, lines: []Line{tee(2)}, remaining: []int64{20000}, want: []int64{0}},
+		{name: "uncovered lines are skipped", buy: 1, get: 1, lines: []Line{{Category: "hat", Quantity: 4}, tee(2)}, remaining: []int64{400, 20000}, want: []int64{0, 10000}},
+		{name: "equal prices take the first line", buy: 1, get: 1, lines: []Line{tee(1), tee(1)}, remaining: []int64{5000, 5000}, want: []int64{5000, 0}},
+		{name: "large quantities", buy: 1, get: 1, lines: []Line{tee(1000000)}, remaining: []int64{100000000}, want: []int64{50000000}},
+	}
+	for _, tt := range tests {
+		p := Promotion{Kind: KindBuyXGetY, Category: "tee", BuyQuantity: tt.buy, GetQuantity: tt.get}
+		remaining := make([]money.Money, len(tt.remaining))
+		for i, amount := range tt.remaining {
+			remaining[i] = money.Satang(amount)
+		}
+		got := allocate(p, tt.lines, remaining)
+		for i, want := range tt.want {
+			if got[i].Amount != want {
+				t.Errorf("%s: line %d = %s, want %s", tt.name, i, got[i], money.Satang(want))
+			}
+		}
+	}
+}
+
+func TestResultApplied(t *testing.T) {
+	r := Result{
+		Lines: []LineResult{
+			{Discounts: []Applied{{PromotionID: 2, Amount: money.Baht(10)}}},
+			{Discounts: []Applied{{PromotionID: 1, Amount: money.Baht(5)}, {PromotionID: 2, Amount: money.Baht(3)}}},
+		},
+		CartDiscounts: []Applied{{PromotionID: 1, Amount: money.Baht(1)}},
+	}
+	applied := r.Applied()
+	want := []Applied{{PromotionID: 1, Amount: money.Baht(6)}, {PromotionID: 2, Amount: money.Baht(13)}}
+	if len(applied) != len(want) {
+		t.Fatalf("Applied() = %+v, want %+v", applied, want)
+	}
+	for i := range want {
+		if applied[i] != want[i] {
+			t.Errorf("Applied()[%d] = %+v, want %+v", i, applied[i], want[i])
+		}
+	}
+}
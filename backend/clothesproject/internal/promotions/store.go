@@ -0,0 +1,260 @@
+package promotions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Querier คือสิ่งที่ query ได้ ใช้ได้ทั้ง *sql.DB และ *sql.Tx
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Store จัดการโปรโมชันสำหรับผู้ดูแลระบบ (ปิดโปรโมชันด้วย Active = false แทนการลบ เพื่อเก็บประวัติการใช้)
+type Store interface {
+	ListPromotions(ctx context.Context) ([]Promotion, error)
+	GetPromotion(ctx context.Context, id int) (Promotion, error)
+	CreatePromotion(ctx context.Context, promotion Promotion) (Promotion, error)
+	UpdatePromotion(ctx context.Context, promotion Promotion) (Promotion, error)
+}
+
+type PostgresStore struct {
+	// db คืน connection pool ปัจจุบัน (PostgresDatabase.DB) จึงใช้ pool ใหม่ได้หลัง Reconnect
+	db func() *sql.DB
+}
+
+func NewPostgresStore(db func() *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const promotionColumns = `promotion_id, name, COALESCE(code, ''), kind, value, COALESCE(brand_id, 0), COALESCE(category, ''),
+               COALESCE(buy_quantity, 0), COALESCE(get_quantity, 0), min_spend, COALESCE(usage_limit, 0),
+               COALESCE(per_user_limit, 0), starts_at, ends_at, active`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPromotion(row rowScanner) (Promotion, error) {
+	var p Promotion
+	var startsAt, endsAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.Name, &p.Code, &p.Kind, &p.Value, &p.BrandID, &p.Category,
+		&p.BuyQuantity, &p.GetQuantity, &p.MinSpend, &p.UsageLimit,
+		&p.PerUserLimit, &startsAt, &endsAt, &p.Active); err != nil {
+		return Promotion{}, err
+	}
+	if startsAt.Valid {
+		p.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		p.EndsAt = &endsAt.Time
+	}
+	return p, nil
+}
+
+func queryPromotions(ctx context.Context, q Querier, query string, args ...interface{}) ([]Promotion, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotions: %v", err)
+	}
+	defer rows.Close()
+
+	promotions := []Promotion{}
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promotion: %v", err)
+		}
+		promotions = append(promotions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return promotions, nil
+}
+
+// nullInt และ nullString แปลงค่าศูนย์/ค่าว่างของ field ที่ไม่บังคับเป็น NULL
+func nullInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
+
+func nullString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
+
+func (s *PostgresStore) ListPromotions(ctx context.Context) ([]Promotion, error) {
+	return queryPromotions(ctx, s.db(), `SELECT `+promotionColumns+` FROM promotions ORDER BY promotion_id`)
+}
+
+func (s *PostgresStore) GetPromotion(ctx context.Context, id int) (Promotion, error) {
+	p, err := scanPromotion(s.db().QueryRowContext(ctx, `SELECT `+promotionColumns+` FROM promotions WHERE promotion_id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Promotion{}, ErrPromotionNotFound
+		}
+		return Promotion{}, fmt.Errorf("failed to get promotion: %v", err)
+	}
+	return p, nil
+}
+
+func (s *PostgresStore) CreatePromotion(ctx context.Context, p Promotion) (Promotion, error) {
+	if err := p.Validate(); err != nil {
+		return Promotion{}, err
+	}
+	err := s.db().QueryRowContext(ctx, `
+        INSERT INTO promotions (name, code, kind, value, brand_id, category, buy_quantity, get_quantity,
+                                min_spend, usage_limit, per_user_limit, starts_at, ends_at, active)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+        RETURNING promotion_id
+    `, p.Name, nullString(p.Code), p.Kind, p.Value, nullInt(p.BrandID), nullString(p.Category), nullInt(p.BuyQuantity), nullInt(p.GetQuantity),
+		p.MinSpend, nullInt(p.UsageLimit), nullInt(p.PerUserLimit), p.StartsAt, p.EndsAt, p.Active).Scan(&p.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return Promotion{}, fmt.Errorf("%w: %s", ErrDuplicateCode, p.Code)
+		}
+		return Promotion{}, fmt.Errorf("failed to create promotion: %v", err)
+	}
+	return p, nil
+}
+
+func (s *PostgresStore) UpdatePromotion(ctx context.Context, p Promotion) (Promotion, error) {
+	if err := p.Validate(); err != nil {
+		return Promotion{}, err
+	}
+	result, err := s.db().ExecContext(ctx, `
+        UPDATE promotions
+        SET name = $2, code = $3, kind = $4, value = $5, brand_id = $6, category = $7, buy_quantity = $8, get_quantity = $9,
+            min_spend = $10, usage_limit = $11, per_user_limit = $12, starts_at = $13, ends_at = $14, active = $15
+        WHERE promotion_id = $1
+    `, p.ID, p.Name, nullString(p.Code), p.Kind, p.Value, nullInt(p.BrandID), nullString(p.Category), nullInt(p.BuyQuantity), nullInt(p.GetQuantity),
+		p.MinSpend, nullInt(p.UsageLimit), nullInt(p.PerUserLimit), p.StartsAt, p.EndsAt, p.Active)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return Promotion{}, fmt.Errorf("%w: %s", ErrDuplicateCode, p.Code)
+		}
+		return Promotion{}, fmt.Errorf("failed to update promotion: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return Promotion{}, ErrPromotionNotFound
+	}
+	return p, nil
+}
+
+// isUniqueViolation ตรวจว่า err มาจาก unique index (รหัส 23505) เช่น idx_promotions_code
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// candidates โหลดโปรโมชันอัตโนมัติที่ใช้ได้ตอนนี้ และคูปอง coupon (ถ้ามี) ไม่ว่าจะหมดอายุหรือปิดไปแล้ว
+// เพื่อให้ Evaluate บอกเหตุผลที่ใช้คูปองไม่ได้
+func candidates(ctx context.Context, q Querier, coupon string) ([]Promotion, error) {
+	return queryPromotions(ctx, q, `
+        SELECT `+promotionColumns+`
+        FROM promotions
+        WHERE (code IS NULL AND active
+               AND (starts_at IS NULL OR starts_at <= now()) AND (ends_at IS NULL OR ends_at > now()))
+           OR ($1 <> '' AND lower(code) = lower($1))
+        ORDER BY promotion_id
+    `, coupon)
+}
+
+// countUsage นับจำนวนครั้งที่โปรโมชันที่จำกัดการใช้ถูกใช้ไปแล้ว (ไม่นับคำสั่งซื้อที่ยกเลิก)
+func countUsage(ctx context.Context, q Querier, promos []Promotion, userID string) (Usage, error) {
+	usage := Usage{Total: make(map[int]int), ByUser: make(map[int]int)}
+	ids := limitedIDs(promos)
+	if len(ids) == 0 {
+		return usage, nil
+	}
+
+	rows, err := q.QueryContext(ctx, `
+        SELECT d.promotion_id, COUNT(*), COUNT(*) FILTER (WHERE o.user_id = NULLIF($2, '')::uuid)
+        FROM order_discounts d
+        JOIN orders o ON o.order_id = d.order_id
+        WHERE d.promotion_id = ANY($1) AND o.status <> 'cancelled'
+        GROUP BY d.promotion_id
+    `, pq.Array(ids), userID)
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to count promotion usage: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, total, byUser int
+		if err := rows.Scan(&id, &total, &byUser); err != nil {
+			return Usage{}, fmt.Errorf("failed to scan promotion usage: %v", err)
+		}
+		usage.Total[id], usage.ByUser[id] = total, byUser
+	}
+	if err := rows.Err(); err != nil {
+		return Usage{}, fmt.Errorf("row iteration error: %v", err)
+	}
+	return usage, nil
+}
+
+func limitedIDs(promos []Promotion) []int64 {
+	var ids []int64
+	for _, p := range promos {
+		if p.UsageLimit > 0 || p.PerUserLimit > 0 {
+			ids = append(ids, int64(p.ID))
+		}
+	}
+	return ids
+}
+
+// Apply คำนวณส่วนลดของตะกร้าด้วยโปรโมชันที่ใช้ได้ตอนนี้ สำหรับแสดงผล (ไม่ล็อกและไม่บันทึกการใช้)
+// userID ว่าง (ผู้เยี่ยมชม) จะไม่นับการใช้ต่อผู้ใช้
+func Apply(ctx context.Context, q Querier, lines []Line, coupon string, userID string) (Result, error) {
+	coupon = strings.TrimSpace(coupon)
+	promos, err := candidates(ctx, q, coupon)
+	if err != nil {
+		return Result{}, err
+	}
+	usage, err := countUsage(ctx, q, promos, userID)
+	if err != nil {
+		return Result{}, err
+	}
+	return Evaluate(lines, promos, coupon, usage, time.Now()), nil
+}
+
+// ApplyForUpdate เหมือน Apply แต่ล็อกโปรโมชันที่จำกัดการใช้ไว้จนจบ transaction ก่อนนับการใช้
+// เพื่อไม่ให้คำสั่งซื้อที่สร้างพร้อมกันใช้เกินจำนวนที่กำหนด ต้องเรียก Record ใน transaction เดียวกัน
+func ApplyForUpdate(ctx context.Context, tx *sql.Tx, lines []Line, coupon string, userID string) (Result, error) {
+	coupon = strings.TrimSpace(coupon)
+	promos, err := candidates(ctx, tx, coupon)
+	if err != nil {
+		return Result{}, err
+	}
+	if ids := limitedIDs(promos); len(ids) > 0 {
+		// ล็อกตามลำดับรหัสเพื่อไม่ให้เกิด deadlock
+		if _, err := tx.ExecContext(ctx, `
+            SELECT 1 FROM promotions WHERE promotion_id = ANY($1) ORDER BY promotion_id FOR UPDATE
+        `, pq.Array(ids)); err != nil {
+			return Result{}, fmt.Errorf("failed to lock promotions: %v", err)
+		}
+	}
+	usage, err := countUsage(ctx, tx, promos, userID)
+	if err != nil {
+		return Result{}, err
+	}
+	return Evaluate(lines, promos, coupon, usage, time.Now()), nil
+}
+
+// Record บันทึกส่วนลดที่คำสั่งซื้อได้รับแยกตามโปรโมชัน ใช้ทั้งเป็นรายละเอียดของคำสั่งซื้อและนับจำนวนการใช้
+func Record(ctx context.Context, tx *sql.Tx, orderID int, result Result) error {
+	for _, a := range result.Applied() {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO order_discounts (order_id, promotion_id, name, code, amount)
+            VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+        `, orderID, a.PromotionID, a.Name, a.Code, a.Amount)
+		if err != nil {
+			return fmt.Errorf("failed to record order discount: %v", err)
+		}
+	}
+	return nil
+}
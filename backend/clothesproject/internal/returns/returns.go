@@ -0,0 +1,135 @@
+package returns
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"clothesproject/internal/money"
+	"clothesproject/internal/pricing"
+)
+
+// MaxPhotos คือจำนวนรูปสูงสุดที่แนบกับคำขอคืนสินค้าได้
+const MaxPhotos = 5
+
+var (
+	// ErrReturnNotFound คืนค่าเมื่อไม่พบคำขอคืนสินค้า
+	ErrReturnNotFound = errors.New("return request not found")
+	// ErrInvalidReturn คืนค่าเมื่อข้อมูลคำขอคืนสินค้าไม่ถูกต้อง (จำนวน เหตุผล หรือรูป)
+	ErrInvalidReturn = errors.New("invalid return request")
+	// ErrNotReturnable คืนค่าเมื่อรายการสินค้าขอคืนไม่ได้ (ยังไม่ได้รับสินค้า เลยกำหนด หรือแบรนด์ไม่รับคืน)
+	ErrNotReturnable = errors.New("order item is not returnable")
+	// ErrInvalidTransition คืนค่าเมื่อเปลี่ยนสถานะคำขอคืนสินค้าข้ามขั้นตอน
+	ErrInvalidTransition = errors.New("invalid return status transition")
+)
+
+// Status คือขั้นตอนของคำขอคืนสินค้า
+// requested → approved → received → refunded หรือ requested → rejected
+type Status string
+
+const (
+	StatusRequested Status = "requested"
+	StatusApproved  Status = "approved"
+	StatusRejected  Status = "rejected"
+	StatusReceived  Status = "received"
+	StatusRefunded  Status = "refunded"
+)
+
+// IsValid ตรวจสอบว่าเป็นสถานะที่รู้จัก
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusRequested, StatusApproved, StatusRejected, StatusReceived, StatusRefunded:
+		return true
+	}
+	return false
+}
+
+// transitions กำหนดสถานะถัดไปที่อนุญาตจากแต่ละสถานะ rejected และ refunded เป็นสถานะสุดท้าย
+var transitions = map[Status][]Status{
+	StatusRequested: {StatusApproved, StatusRejected},
+	StatusApproved:  {StatusReceived},
+	StatusReceived:  {StatusRefunded},
+}
+
+// CanTransitionTo ตรวจสอบว่าเปลี่ยนจากสถานะนี้ไปยัง next ได้หรือไม่
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Request คือคำขอคืนสินค้าของลูกค้าสำหรับรายการหนึ่งในคำสั่งซื้อ Photos คือ URL ของรูปที่อัปโหลดแล้ว
+type Request struct {
+	OrderItemID int
+	Quantity    int
+	Reason      string
+	Photos      []string
+}
+
+// Return คือคำขอคืนสินค้า RefundAmount มีค่าเมื่อคืนเงินแล้ว
+type Return struct {
+	ID           int          `json:"id"`
+	OrderID      int          `json:"order_id"`
+	OrderItemID  int          `json:"order_item_id"`
+	UserID       string       `json:"user_id"`
+	SKU          string       `json:"sku"`
+	ProductName  string       `json:"product_name"`
+	Quantity     int          `json:"quantity"`
+	Reason       string       `json:"reason"`
+	Photos       []string     `json:"photos"`
+	Status       Status       `json:"status"`
+	StaffNote    string       `json:"staff_note,omitempty"`
+	RefundAmount *money.Money `json:"refund_amount,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	ReviewedAt   *time.Time   `json:"reviewed_at,omitempty"`
+	ReceivedAt   *time.Time   `json:"received_at,omitempty"`
+	RefundedAt   *time.Time   `json:"refunded_at,omitempty"`
+}
+
+// Store จัดการคำขอคืนสินค้า ลูกค้าสร้างคำขอ ส่วนพนักงานอนุมัติ/ปฏิเสธ รับของ และคืนเงิน
+type Store interface {
+	CreateReturn(ctx context.Context, userID string, orderID int, req Request) (Return, error)
+	GetReturnsByUser(ctx context.Context, userID string) ([]Return, error)
+	GetReturnByPhoto(ctx context.Context, photo string) (Return, error)
+	ListReturns(ctx context.Context, status Status) ([]Return, error)
+	Review(ctx context.Context, returnID int, approve bool, staffID string, note string) (Return, error)
+	Receive(ctx context.Context, returnID int, staffID string) (Return, error)
+	Refund(ctx context.Context, returnID int, staffID string) (Return, error)
+}
+
+// orderLine คือรายการในคำสั่งซื้อที่ใช้คิดยอดคืนเงิน Net คือราคาหลังหักส่วนลดระดับรายการ
+type orderLine struct {
+	ID           int
+	Quantity     int
+	Net          money.Money
+	VATInclusive bool
+}
+
+// lineRefunds คิดยอดที่ลูกค้าจ่ายจริงของแต่ละรายการ: ราคาหลังหักส่วนลดระดับรายการ หักส่วนแบ่งส่วนลดระดับตะกร้า
+// (pricing.Taxable แบบเดียวกับตอนสร้างคำสั่งซื้อ) และบวก VAT ถ้าแบรนด์แสดงราคาไม่รวม VAT
+// ค่าส่งไม่รวมในยอดคืนเงิน lines ต้องเรียงตามลำดับรายการในคำสั่งซื้อ
+func lineRefunds(lines []orderLine, cartDiscount money.Money) []money.Money {
+	priced := make([]pricing.Line, len(lines))
+	for i, line := range lines {
+		priced[i] = pricing.Line{Subtotal: line.Net}
+	}
+	refunds := pricing.Taxable(priced, cartDiscount)
+	for i, line := range lines {
+		if !line.VATInclusive {
+			refunds[i] = refunds[i].Add(refunds[i].MulRatio(pricing.VATPercent, 100))
+		}
+	}
+	return refunds
+}
+
+// partialRefund คิดยอดคืนเงินของ quantity ชิ้นจากรายการที่จ่ายไป linePaid สำหรับ lineQuantity ชิ้น
+// คืนไปแล้ว refundedQuantity ชิ้นเป็นเงิน refunded การคืนครั้งที่ครบทุกชิ้นได้ส่วนที่เหลือทั้งหมด ผลรวมจึงเท่ากับยอดที่จ่ายพอดี
+func partialRefund(linePaid money.Money, lineQuantity, quantity, refundedQuantity int, refunded money.Money) money.Money {
+	if refundedQuantity+quantity >= lineQuantity {
+		return linePaid.Sub(refunded)
+	}
+	return linePaid.MulRatio(int64(quantity), int64(lineQuantity))
+}
@@ -0,0 +1,104 @@
+package returns
+
+import (
+	"testing"
+
+	"clothesproject/internal/money"
+)
+
+func TestStatusCanTransitionTo(t *testing.T) {
+	all := []Status{StatusRequested, StatusApproved, StatusRejected, StatusReceived, StatusRefunded}
+	tests := []struct {
+		from Status
+		to   []Status
+	}{
+		{from: StatusRequested, to: []Status{StatusApproved, StatusRejected}},
+		{from: StatusApproved, to: []Status{StatusReceived}},
+		{from: StatusReceived, to: []Status{StatusRefunded}},
+		{from: StatusRejected},
+		{from: StatusRefunded},
+	}
+	for _, tt := range tests {
+		allowed := make(map[Status]bool)
+		for _, to := range tt.to {
+			allowed[to] = true
+		}
+		for _, to := range all {
+			if got := tt.from.CanTransitionTo(to); got != allowed[to] {
+				t.Errorf("%q.CanTransitionTo(%q) = %v, want %v", tt.from, to, got, allowed[to])
+			}
+		}
+	}
+}
+
+func TestLineRefunds(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []orderLine
+		cartDiscount money.Money
+		want         []int64
+	}{
+		{
+			name:  "VAT-inclusive line without discount",
+			lines: []orderLine{{Net: money.Baht(1000), VATInclusive: true}},
+			want:  []int64{100000},
+		},
+		{
+			name:         "cart discount split by price",
+			lines:        []orderLine{{Net: money.Baht(300), VATInclusive: true}, {Net: money.Baht(100), VATInclusive: true}},
+			cartDiscount: money.Baht(100),
+			want:         []int64{22500, 7500},
+		},
+		{
+			name:  "VAT added for VAT-exclusive brands",
+			lines: []orderLine{{Net: money.Baht(100)}},
+			want:  []int64{10700},
+		},
+		{
+			name:         "uneven split and rounded VAT",
+			lines:        []orderLine{{Net: money.Baht(100), VATInclusive: true}, {Net: money.Baht(50)}},
+			cartDiscount: money.Baht(10),
+			want:         []int64{9333, 4994},
+		},
+		{
+			name:         "cart discount above the total",
+			lines:        []orderLine{{Net: money.Baht(50), VATInclusive: true}},
+			cartDiscount: money.Baht(80),
+			want:         []int64{0},
+		},
+	}
+	for _, tt := range tests {
+		got := lineRefunds(tt.lines, tt.cartDiscount)
+		for i, want := range tt.want {
+			if got[i].Amount != want {
+				t.Errorf("%s: line %d = %s, want %s", tt.name, i, got[i], money.Satang(want))
+			}
+		}
+	}
+}
+
+func TestPartialRefund(t *testing.T) {
+	paid := money.Baht(100)
+	tests := []struct {
+		name             string
+		lineQuantity     int
+		quantity         int
+		refundedQuantity int
+		refunded         int64
+		want             int64
+	}{
+		{name: "first of three", lineQuantity: 3, quantity: 1, want: 3333},
+		{name: "second of three", lineQuantity: 3, quantity: 1, refundedQuantity: 1, refunded: 3333, want: 3333},
+		{name: "last of three takes the remainder", lineQuantity: 3, quantity: 1, refundedQuantity: 2, refunded: 6666, want: 3334},
+		{name: "two of three rounds half up", lineQuantity: 3, quantity: 2, want: 6667},
+		{name: "last two after the rounded first", lineQuantity: 3, quantity: 2, refundedQuantity: 1, refunded: 3333, want: 6667},
+		{name: "whole line at once", lineQuantity: 3, quantity: 3, want: 10000},
+		{name: "single item", lineQuantity: 1, quantity: 1, want: 10000},
+	}
+	for _, tt := range tests {
+		got := partialRefund(paid, tt.lineQuantity, tt.quantity, tt.refundedQuantity, money.Satang(tt.refunded))
+		if got != money.Satang(tt.want) {
+			t.Errorf("%s: partialRefund = %s, want %s", tt.name, got, money.Satang(tt.want))
+		}
+	}
+}
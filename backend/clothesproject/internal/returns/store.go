@@ -0,0 +1,384 @@
+package returns
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"clothesproject/internal/clothesstore"
+	"clothesproject/internal/money"
+	"clothesproject/internal/orders"
+
+	"github.com/lib/pq"
+)
+
+// defaultReturnWindowDays ใช้เมื่อแบรนด์ของรายการถูกลบไปแล้ว (ตรงกับค่าเริ่มต้นของ brand.return_window_days)
+const defaultReturnWindowDays = 14
+
+type PostgresStore struct {
+	pdb *clothesstore.PostgresDatabase
+}
+
+func NewPostgresStore(pdb *clothesstore.PostgresDatabase) *PostgresStore {
+	return &PostgresStore{pdb: pdb}
+}
+
+const returnColumns = `r.return_id, r.order_id, r.order_item_id, r.user_id, oi.sku, oi.product_name, r.quantity, r.reason, r.photos,
+               r.status, COALESCE(r.staff_note, ''), r.refund_amount, r.created_at, r.reviewed_at, r.received_at, r.refunded_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReturn(row rowScanner) (Return, error) {
+	var r Return
+	var reviewedAt, receivedAt, refundedAt sql.NullTime
+	if err := row.Scan(&r.ID, &r.OrderID, &r.OrderItemID, &r.UserID, &r.SKU, &r.ProductName, &r.Quantity, &r.Reason, pq.Array(&r.Photos),
+		&r.Status, &r.StaffNote, &r.RefundAmount, &r.CreatedAt, &reviewedAt, &receivedAt, &refundedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Return{}, err
+		}
+		return Return{}, fmt.Errorf("failed to scan return request: %v", err)
+	}
+	if r.Photos == nil {
+		r.Photos = []string{}
+	}
+	for _, t := range []struct {
+		src sql.NullTime
+		dst **time.Time
+	}{{reviewedAt, &r.ReviewedAt}, {receivedAt, &r.ReceivedAt}, {refundedAt, &r.RefundedAt}} {
+		if t.src.Valid {
+			at := t.src.Time
+			*t.dst = &at
+		}
+	}
+	return r, nil
+}
+
+func (s *PostgresStore) queryReturns(ctx context.Context, where string, args ...interface{}) ([]Return, error) {
+	rows, err := s.pdb.DB().QueryContext(ctx, `
+        SELECT `+returnColumns+`
+        FROM return_requests r
+        JOIN order_items oi ON oi.order_item_id = r.order_item_id
+        `+where+`
+        ORDER BY r.created_at DESC, r.return_id DESC
+    `, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return requests: %v", err)
+	}
+	defer rows.Close()
+
+	list := []Return{}
+	for rows.Next() {
+		r, err := scanReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate return requests: %v", err)
+	}
+	return list, nil
+}
+
+// CreateReturn สร้างคำขอคืนสินค้าของรายการในคำสั่งซื้อที่ได้รับสินค้าแล้ว ภายในจำนวนวันที่แบรนด์กำหนด
+// (นับจากเวลาที่คำสั่งซื้อเป็น delivered) จำนวนที่ขอคืนรวมกับคำขอเดิมที่ไม่ถูกปฏิเสธต้องไม่เกินจำนวนที่ซื้อ
+func (s *PostgresStore) CreateReturn(ctx context.Context, userID string, orderID int, req Request) (Return, error) {
+	req.Reason = strings.TrimSpace(req.Reason)
+	switch {
+	case req.Quantity <= 0:
+		return Return{}, fmt.Errorf("%w: quantity must be positive", ErrInvalidReturn)
+	case req.Reason == "":
+		return Return{}, fmt.Errorf("%w: reason is required", ErrInvalidReturn)
+	case len(req.Photos) > MaxPhotos:
+		return Return{}, fmt.Errorf("%w: at most %d photos", ErrInvalidReturn, MaxPhotos)
+	}
+
+	tx, err := s.pdb.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return Return{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// ล็อกคำสั่งซื้อไว้เพื่อไม่ให้คำขอคืนของรายการเดียวกันสองคำขอเกินจำนวนที่ซื้อ
+	var status orders.Status
+	var deliveredAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+        SELECT o.status,
+               (SELECT MAX(h.changed_at) FROM order_status_history h WHERE h.order_id = o.order_id AND h.to_status = 'delivered')
+        FROM orders o
+        WHERE o.order_id = $1 AND o.user_id = $2
+        FOR UPDATE OF o
+    `, orderID, userID).Scan(&status, &deliveredAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Return{}, orders.ErrOrderNotFound
+		}
+		return Return{}, fmt.Errorf("failed to get order: %v", err)
+	}
+	if status != orders.StatusDelivered || !deliveredAt.Valid {
+		return Return{}, fmt.Errorf("%w: order is %s", ErrNotReturnable, status)
+	}
+
+	var purchased, windowDays int
+	err = tx.QueryRowContext(ctx, `
+        SELECT oi.quantity, COALESCE(b.return_window_days, $3)
+        FROM order_items oi
+        LEFT JOIN brand b ON b.id = oi.brand_id
+        WHERE oi.order_item_id = $1 AND oi.order_id = $2
+    `, req.OrderItemID, orderID, defaultReturnWindowDays).Scan(&purchased, &windowDays)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Return{}, fmt.Errorf("%w: order item %d is not in order %d", ErrInvalidReturn, req.OrderItemID, orderID)
+		}
+		return Return{}, fmt.Errorf("failed to get order item: %v", err)
+	}
+	if windowDays == 0 {
+		return Return{}, fmt.Errorf("%w: this brand does not accept returns", ErrNotReturnable)
+	}
+	if time.Now().After(deliveredAt.Time.AddDate(0, 0, windowDays)) {
+		return Return{}, fmt.Errorf("%w: the %d-day return window has ended", ErrNotReturnable, windowDays)
+	}
+
+	var requested int
+	err = tx.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(quantity), 0) FROM return_requests WHERE order_item_id = $1 AND status <> 'rejected'
+    `, req.OrderItemID).Scan(&requested)
+	if err != nil {
+		return Return{}, fmt.Errorf("failed to get existing return requests: %v", err)
+	}
+	if req.Quantity > purchased-requested {
+		return Return{}, fmt.Errorf("%w: only %d of this item can still be returned", ErrInvalidReturn, purchased-requested)
+	}
+
+	var returnID int
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO return_requests (order_id, order_item_id, user_id, quantity, reason, photos)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING return_id
+    `, orderID, req.OrderItemID, userID, req.Quantity, req.Reason, pq.Array(req.Photos)).Scan(&returnID)
+	if err != nil {
+		return Return{}, fmt.Errorf("failed to create return request: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Return{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return s.getReturn(ctx, s.pdb.DB(), returnID, false)
+}
+
+// querier คือ *sql.DB หรือ *sql.Tx
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// getReturn ดึงคำขอคืนสินค้า lock เป็น true จะล็อกแถวไว้ (ต้องใช้ภายใน transaction)
+func (s *PostgresStore) getReturn(ctx context.Context, q querier, returnID int, lock bool) (Return, error) {
+	query := `
+        SELECT ` + returnColumns + `
+        FROM return_requests r
+        JOIN order_items oi ON oi.order_item_id = r.order_item_id
+        WHERE r.return_id = $1`
+	if lock {
+		query += " FOR UPDATE OF r"
+	}
+	r, err := scanReturn(q.QueryRowContext(ctx, query, returnID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Return{}, ErrReturnNotFound
+		}
+		return Return{}, err
+	}
+	return r, nil
+}
+
+// GetReturnsByUser ดึงคำขอคืนสินค้าทั้งหมดของผู้ใช้ เรียงจากล่าสุด
+func (s *PostgresStore) GetReturnsByUser(ctx context.Context, userID string) ([]Return, error) {
+	return s.queryReturns(ctx, "WHERE r.user_id = $1", userID)
+}
+
+// GetReturnByPhoto ดึงคำขอคืนสินค้าที่แนบรูป photo (URL ที่บันทึกไว้ใน photos) ใช้ตรวจสิทธิ์ก่อนส่งไฟล์รูป
+func (s *PostgresStore) GetReturnByPhoto(ctx context.Context, photo string) (Return, error) {
+	list, err := s.queryReturns(ctx, "WHERE r.photos @> ARRAY[$1]::text[]", photo)
+	if err != nil {
+		return Return{}, err
+	}
+	if len(list) == 0 {
+		return Return{}, ErrReturnNotFound
+	}
+	return list[0], nil
+}
+
+// ListReturns ดึงคำขอคืนสินค้าสำหรับพนักงาน status ว่างคือทุกสถานะ
+func (s *PostgresStore) ListReturns(ctx context.Context, status Status) ([]Return, error) {
+	if status == "" {
+		return s.queryReturns(ctx, "")
+	}
+	return s.queryReturns(ctx, "WHERE r.status = $1", status)
+}
+
+// advance เปลี่ยนสถานะคำขอคืนสินค้าเป็น to ตาม transitions พร้อมบันทึกพนักงานและเวลาของขั้นตอนนั้น (column_by/column_at)
+func (s *PostgresStore) advance(ctx context.Context, tx *sql.Tx, returnID int, to Status, column, staffID, note string) (Return, error) {
+	r, err := s.getReturn(ctx, tx, returnID, true)
+	if err != nil {
+		return Return{}, err
+	}
+	if !r.Status.CanTransitionTo(to) {
+		return Return{}, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, r.Status, to)
+	}
+	_, err = tx.ExecContext(ctx, `
+        UPDATE return_requests
+        SET status = $2, staff_note = COALESCE(NULLIF($3, ''), staff_note),
+            `+column+`_by = NULLIF($4, '')::uuid, `+column+`_at = CURRENT_TIMESTAMP
+        WHERE return_id = $1
+    `, returnID, to, note, staffID)
+	if err != nil {
+		return Return{}, fmt.Errorf("failed to update return request: %v", err)
+	}
+	return s.getReturn(ctx, tx, returnID, false)
+}
+
+// Review อนุมัติหรือปฏิเสธคำขอคืนสินค้าที่รอตรวจสอบ note คือเหตุผลที่แจ้งลูกค้า
+func (s *PostgresStore) Review(ctx context.Context, returnID int, approve bool, staffID string, note string) (Return, error) {
+	to := StatusRejected
+	if approve {
+		to = StatusApproved
+	}
+	return s.inTx(ctx, func(tx *sql.Tx) (Return, error) {
+		return s.advance(ctx, tx, returnID, to, "reviewed", staffID, note)
+	})
+}
+
+// Receive บันทึกว่าได้รับสินค้าที่ลูกค้าส่งคืนแล้ว
+func (s *PostgresStore) Receive(ctx context.Context, returnID int, staffID string) (Return, error) {
+	return s.inTx(ctx, func(tx *sql.Tx) (Return, error) {
+		return s.advance(ctx, tx, returnID, StatusReceived, "received", staffID, "")
+	})
+}
+
+// Refund คืนเงินสินค้าที่ได้รับคืนแล้วและนำสินค้ากลับเข้าสต็อกของคลัง (SKU ที่ถูกลบไปแล้วจะไม่คืนสต็อก)
+// ยอดคืนเงินคือส่วนที่ลูกค้าจ่ายจริงของจำนวนที่คืน ถ้าคืนครบทุกรายการของคำสั่งซื้อ คำสั่งซื้อจะเป็น refunded
+func (s *PostgresStore) Refund(ctx context.Context, returnID int, staffID string) (Return, error) {
+	return s.inTx(ctx, func(tx *sql.Tx) (Return, error) {
+		r, err := s.advance(ctx, tx, returnID, StatusRefunded, "refunded", staffID, "")
+		if err != nil {
+			return Return{}, err
+		}
+
+		amount, variantID, err := s.refundAmount(ctx, tx, r)
+		if err != nil {
+			return Return{}, err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE return_requests SET refund_amount = $2 WHERE return_id = $1", r.ID, amount); err != nil {
+			return Return{}, fmt.Errorf("failed to record refund amount: %v", err)
+		}
+		r.RefundAmount = &amount
+
+		if variantID.Valid {
+			if err := clothesstore.RestockVariant(ctx, tx, int(variantID.Int64), r.Quantity); err != nil {
+				return Return{}, err
+			}
+		}
+
+		var purchased, refunded int
+		err = tx.QueryRowContext(ctx, `
+            SELECT (SELECT SUM(quantity) FROM order_items WHERE order_id = $1),
+                   (SELECT COALESCE(SUM(quantity), 0) FROM return_requests WHERE order_id = $1 AND status = 'refunded')
+        `, r.OrderID).Scan(&purchased, &refunded)
+		if err != nil {
+			return Return{}, fmt.Errorf("failed to count refunded items: %v", err)
+		}
+		if refunded >= purchased {
+			err := orders.MarkRefunded(ctx, tx, r.OrderID, staffID, "all items returned")
+			if err != nil && !errors.Is(err, orders.ErrInvalidTransition) {
+				return Return{}, err
+			}
+		}
+		return r, nil
+	})
+}
+
+// refundAmount คิดยอดคืนเงินของคำขอ r (ต้องเรียกหลังเปลี่ยนสถานะเป็น refunded แล้ว แต่ก่อนบันทึกยอด)
+// และคืน variant_id ของรายการสำหรับคืนสต็อก
+func (s *PostgresStore) refundAmount(ctx context.Context, tx *sql.Tx, r Return) (money.Money, sql.NullInt64, error) {
+	var discountTotal, vatAdded money.Money
+	err := tx.QueryRowContext(ctx, "SELECT discount_total, vat_added FROM orders WHERE order_id = $1", r.OrderID).Scan(&discountTotal, &vatAdded)
+	if err != nil {
+		return money.Money{}, sql.NullInt64{}, fmt.Errorf("failed to get order totals: %v", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT oi.order_item_id, oi.variant_id, oi.quantity, oi.line_total, oi.discount, COALESCE(b.vat_inclusive, TRUE)
+        FROM order_items oi
+        LEFT JOIN brand b ON b.id = oi.brand_id
+        WHERE oi.order_id = $1
+        ORDER BY oi.order_item_id
+    `, r.OrderID)
+	if err != nil {
+		return money.Money{}, sql.NullInt64{}, fmt.Errorf("failed to get order items: %v", err)
+	}
+	defer rows.Close()
+
+	var lines []orderLine
+	var variantID sql.NullInt64
+	cartDiscount := discountTotal
+	target := -1
+	for rows.Next() {
+		var line orderLine
+		var lineVariant sql.NullInt64
+		var lineTotal, discount money.Money
+		if err := rows.Scan(&line.ID, &lineVariant, &line.Quantity, &lineTotal, &discount, &line.VATInclusive); err != nil {
+			return money.Money{}, sql.NullInt64{}, fmt.Errorf("failed to scan order item: %v", err)
+		}
+		// คำสั่งซื้อที่ไม่มี VAT เพิ่มเลยแปลว่าทุกแบรนด์รวม VAT แล้วตอนสั่งซื้อ ไม่ต้องอิงค่าปัจจุบันของแบรนด์
+		if vatAdded.IsZero() {
+			line.VATInclusive = true
+		}
+		line.Net = lineTotal.Sub(discount)
+		cartDiscount = cartDiscount.Sub(discount)
+		if line.ID == r.OrderItemID {
+			target, variantID = len(lines), lineVariant
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return money.Money{}, sql.NullInt64{}, fmt.Errorf("failed to iterate order items: %v", err)
+	}
+	if target < 0 {
+		return money.Money{}, sql.NullInt64{}, fmt.Errorf("order item %d not found in order %d", r.OrderItemID, r.OrderID)
+	}
+	linePaid := lineRefunds(lines, cartDiscount)[target]
+
+	var refundedQuantity int
+	var refunded money.Money
+	err = tx.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(quantity), 0), COALESCE(SUM(refund_amount), 0)
+        FROM return_requests
+        WHERE order_item_id = $1 AND status = 'refunded' AND return_id <> $2
+    `, r.OrderItemID, r.ID).Scan(&refundedQuantity, &refunded)
+	if err != nil {
+		return money.Money{}, sql.NullInt64{}, fmt.Errorf("failed to get previous refunds: %v", err)
+	}
+	return partialRefund(linePaid, lines[target].Quantity, r.Quantity, refundedQuantity, refunded), variantID, nil
+}
+
+func (s *PostgresStore) inTx(ctx context.Context, fn func(tx *sql.Tx) (Return, error)) (Return, error) {
+	tx, err := s.pdb.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return Return{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	r, err := fn(tx)
+	if err != nil {
+		return Return{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Return{}, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,90 @@
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"clothesproject/internal/clothesstore"
+)
+
+const (
+	// snippetContext คือจำนวนตัวอักษรก่อนคำแรกที่ตรงที่แสดงใน snippet
+	snippetContext = 30
+	// snippetLength คือความยาวสูงสุด (ตัวอักษร) ของ snippet
+	snippetLength = 120
+)
+
+type span struct{ start, end int }
+
+// highlights สร้างข้อความไฮไลต์ของทุก field ที่มีคำค้นตรง
+func highlights(doc document, terms map[string]bool) []clothesstore.Highlight {
+	var result []clothesstore.Highlight
+	for f, fd := range fields {
+		spans := matchedSpans(doc.tokens[f], terms)
+		if len(spans) == 0 {
+			continue
+		}
+		result = append(result, clothesstore.Highlight{Field: fd.name, Snippet: snippet(doc.texts[f], spans)})
+	}
+	return result
+}
+
+// matchedSpans คืนช่วงข้อความที่ตรงกับคำค้น รวมช่วงที่ซ้อนหรือติดกันเป็นช่วงเดียว
+// (คำย่อยของคำประสมซ้อนอยู่ในคำเต็ม)
+func matchedSpans(tokens []token, terms map[string]bool) []span {
+	var spans []span
+	for _, tok := range tokens {
+		if terms[tok.Term] {
+			spans = append(spans, span{tok.Start, tok.End})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.start <= merged[n-1].end {
+			merged[n-1].end = max(merged[n-1].end, s.end)
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// snippet ตัดข้อความรอบคำแรกที่ตรงให้ยาวไม่เกิน snippetLength ตัวอักษร แล้วครอบคำที่ตรงด้วย <mark>
+func snippet(text string, spans []span) string {
+	start := spans[0].start
+	for i := 0; i < snippetContext && start > 0; i++ {
+		_, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+	}
+	end := start
+	for i := 0; i < snippetLength && end < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, s := range spans {
+		if s.end <= start || s.start >= end {
+			continue
+		}
+		s.start, s.end = max(s.start, start), min(s.end, end)
+		b.WriteString(html.EscapeString(text[pos:s.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[s.start:s.end]))
+		b.WriteString("</mark>")
+		pos = s.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,350 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"clothesproject/internal/clothesstore"
+)
+
+// ค่าคงที่ของ BM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// field คือส่วนของสินค้าที่นำมาค้นหา พร้อมน้ำหนัก (ชื่อสำคัญกว่าคำอธิบาย)
+type field struct {
+	name   string
+	weight float64
+	text   func(clothesstore.Clothes) string
+}
+
+// nameField คือตำแหน่งของชื่อสินค้าใน fields
+const nameField = 0
+
+var fields = []field{
+	{name: "name", weight: 2.0, text: func(p clothesstore.Clothes) string { return p.Name }},
+	{name: "description", weight: 1.0, text: func(p clothesstore.Clothes) string { return p.Description }},
+}
+
+// Source คือแหล่งข้อมูลสินค้าและคำแนะนำสำหรับสร้าง index (PostgresDatabase ใช้ได้เลย)
+type Source interface {
+	ListProducts(ctx context.Context, query clothesstore.ProductQuery) (clothesstore.ProductPage, error)
+	ListSuggestionTerms(ctx context.Context) ([]clothesstore.Suggestion, error)
+}
+
+// document คือสินค้าหนึ่งชิ้นใน index พร้อมข้อความและคำของแต่ละ field
+type document struct {
+	productID int
+	texts     []string
+	tokens    [][]token
+}
+
+// posting คือจำนวนครั้งที่คำหนึ่งปรากฏในแต่ละ field ของเอกสารหนึ่ง
+type posting struct {
+	doc int
+	tf  []int
+}
+
+// snapshot คือ index ที่สร้างเสร็จแล้ว อ่านอย่างเดียว เปลี่ยนทั้งก้อนตอน Rebuild หรือเมื่อสินค้าเปลี่ยน
+type snapshot struct {
+	docs        []document
+	postings    map[string][]posting
+	avgFieldLen []float64
+	// surface คือรูปที่พบบ่อยที่สุด (ตัวพิมพ์เล็ก) ของแต่ละคำในข้อความสินค้า ใช้แสดงคำที่แก้ให้ใน DidYouMean
+	surface     map[string]string
+	suggestions []suggestEntry
+}
+
+// Index คือ Searcher แบบ inverted index ในหน่วยความจำ จัดอันดับด้วย BM25 และเป็น Suggester สำหรับเติมคำค้น
+// ตัดคำภาษาไทยด้วยพจนานุกรมและตัดคำภาษาอังกฤษพร้อมตัดคำต่อท้าย
+// เป็น SearchIndexer ด้วย สินค้าที่เพิ่ม แก้ไข หรือลบจึงเห็นในผลค้นหาทันที ส่วน Rebuild ใช้สร้างครั้งแรก
+// และเรียกเป็นระยะเผื่อข้อมูลเปลี่ยนจากที่อื่น (เช่นแก้ฐานข้อมูลตรง) และเพื่ออัพเดตความนิยมของคำแนะนำ
+type Index struct {
+	source    Source
+	segmenter *segmenter
+	current   atomic.Pointer[snapshot]
+
+	// mu ให้ Rebuild และการอัพเดตทีละสินค้าแทนที่ snapshot ทีละตัว
+	mu sync.Mutex
+	// rebuilds คือจำนวน Rebuild ที่กำลังดึงข้อมูล ระหว่างนั้น pending เก็บสินค้าที่เปลี่ยน (nil คือถูกลบ)
+	// เพื่อนำไปใช้ซ้ำกับข้อมูลที่ Rebuild ดึงมา ไม่ให้ข้อมูลเก่าทับการเปลี่ยนแปลงที่เกิดระหว่างนั้น
+	rebuilds int
+	pending  map[int]*document
+}
+
+// NewIndex สร้าง Index ที่ดึงสินค้าจาก source (ยังค้นหาไม่ได้จนกว่าจะเรียก Rebuild ครั้งแรก)
+func NewIndex(source Source) *Index {
+	return &Index{source: source, segmenter: newSegmenter()}
+}
+
+// Rebuild ดึงสินค้าและคำแนะนำทั้งหมดแล้วสร้าง index ใหม่ แทนที่ของเดิมเมื่อสร้างเสร็จ
+// ระหว่างสร้างยังค้นหาด้วย index เดิมได้
+func (ix *Index) Rebuild(ctx context.Context) (int, error) {
+	ix.mu.Lock()
+	if ix.rebuilds == 0 {
+		ix.pending = make(map[int]*document)
+	}
+	ix.rebuilds++
+	ix.mu.Unlock()
+	defer func() {
+		ix.mu.Lock()
+		ix.rebuilds--
+		if ix.rebuilds == 0 {
+			ix.pending = nil
+		}
+		ix.mu.Unlock()
+	}()
+
+	var docs []document
+	query := clothesstore.ProductQuery{Sort: clothesstore.SortNewest, Limit: clothesstore.MaxPageSize}
+	for {
+		page, err := ix.source.ListProducts(ctx, query)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load products for search index: %v", err)
+		}
+		for _, product := range page.Items {
+			docs = append(docs, ix.newDocument(product))
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	terms, err := ix.source.ListSuggestionTerms(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load suggestion terms for search index: %v", err)
+	}
+	suggestions := make([]suggestEntry, 0, len(terms))
+	for _, term := range terms {
+		suggestions = append(suggestions, ix.segmenter.newSuggestEntry(term))
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if len(ix.pending) > 0 {
+		for productID, doc := range ix.pending {
+			docs = replaceDocument(docs, productID, doc)
+		}
+		suggestions = ix.syncProductSuggestions(suggestions, docs)
+	}
+	snap := buildSnapshot(docs, suggestions)
+	ix.current.Store(snap)
+	return len(snap.docs), nil
+}
+
+// IndexProduct เพิ่มสินค้าหรือแทนที่ข้อมูลเดิมของสินค้าใน index
+// ชื่อสินค้าใหม่จะอยู่ในคำแนะนำด้วยความนิยม 0 จนกว่าจะ Rebuild ครั้งถัดไป
+func (ix *Index) IndexProduct(product clothesstore.Clothes) {
+	doc := ix.newDocument(product)
+	ix.update(product.ID, &doc)
+}
+
+// RemoveProduct ลบสินค้าออกจาก index (ไม่มีผลถ้าไม่มีสินค้านี้)
+func (ix *Index) RemoveProduct(productID int) {
+	ix.update(productID, nil)
+}
+
+// update แทนที่เอกสารของสินค้า productID ด้วย doc (nil คือลบ) แล้วสร้าง snapshot ใหม่จากเอกสารที่ตัดคำไว้แล้ว
+// ถ้ายังไม่เคยสร้าง index จะรอให้ Rebuild ที่กำลังทำอยู่นำไปใช้แทน
+func (ix *Index) update(productID int, doc *document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.pending != nil {
+		ix.pending[productID] = doc
+	}
+	snap := ix.current.Load()
+	if snap == nil {
+		return
+	}
+	docs := replaceDocument(snap.docs, productID, doc)
+	ix.current.Store(buildSnapshot(docs, ix.syncProductSuggestions(snap.suggestions, docs)))
+}
+
+// newDocument ตัดคำทุก field ของสินค้าสำหรับใส่ใน index
+func (ix *Index) newDocument(product clothesstore.Clothes) document {
+	doc := document{productID: product.ID, texts: make([]string, len(fields)), tokens: make([][]token, len(fields))}
+	for f, fd := range fields {
+		doc.texts[f] = fd.text(product)
+		doc.tokens[f] = ix.segmenter.tokenizeForIndex(doc.texts[f])
+	}
+	return doc
+}
+
+// replaceDocument คืนรายการเอกสารชุดใหม่ที่แทนสินค้า productID ด้วย doc (nil คือเอาออก) โดยไม่แก้ docs เดิม
+// เพราะ snapshot ปัจจุบันอาจกำลังถูกอ่านอยู่
+func replaceDocument(docs []document, productID int, doc *document) []document {
+	next := make([]document, 0, len(docs)+1)
+	for _, d := range docs {
+		if d.productID != productID {
+			next = append(next, d)
+		}
+	}
+	if doc != nil {
+		next = append(next, *doc)
+	}
+	return next
+}
+
+// syncProductSuggestions ปรับคำแนะนำชื่อสินค้าให้ตรงกับ docs ตัดชื่อที่ไม่มีสินค้าใช้แล้วและเพิ่มชื่อใหม่
+// คำแนะนำที่มีอยู่แล้วคงความนิยมเดิมไว้ (ความนิยมคำนวณใหม่ตอน Rebuild)
+func (ix *Index) syncProductSuggestions(entries []suggestEntry, docs []document) []suggestEntry {
+	names := make(map[int]string, len(docs))
+	owners := make(map[string]int)
+	for _, d := range docs {
+		name := d.texts[nameField]
+		names[d.productID] = name
+		if _, ok := owners[name]; !ok && name != "" {
+			owners[name] = d.productID
+		}
+	}
+
+	next := make([]suggestEntry, 0, len(entries)+1)
+	for _, entry := range entries {
+		if entry.suggestion.Type == clothesstore.SuggestionProduct {
+			owner, ok := owners[entry.suggestion.Text]
+			if !ok {
+				continue
+			}
+			if names[entry.suggestion.ID] != entry.suggestion.Text {
+				entry.suggestion.ID = owner
+			}
+			delete(owners, entry.suggestion.Text)
+		}
+		next = append(next, entry)
+	}
+	for name, productID := range owners {
+		next = append(next, ix.segmenter.newSuggestEntry(clothesstore.Suggestion{Text: name, Type: clothesstore.SuggestionProduct, ID: productID}))
+	}
+	return next
+}
+
+// buildSnapshot สร้าง posting list และสถิติของ BM25 จากเอกสารที่ตัดคำแล้ว
+func buildSnapshot(docs []document, suggestions []suggestEntry) *snapshot {
+	snap := &snapshot{
+		docs:        docs,
+		postings:    make(map[string][]posting),
+		avgFieldLen: make([]float64, len(fields)),
+		surface:     make(map[string]string),
+		suggestions: suggestions,
+	}
+	surfaceCounts := make(map[string]map[string]int)
+	for docIndex, doc := range docs {
+		for f := range fields {
+			snap.avgFieldLen[f] += float64(len(doc.tokens[f]))
+			for _, tok := range doc.tokens[f] {
+				list := snap.postings[tok.Term]
+				if len(list) == 0 || list[len(list)-1].doc != docIndex {
+					list = append(list, posting{doc: docIndex, tf: make([]int, len(fields))})
+				}
+				list[len(list)-1].tf[f]++
+				snap.postings[tok.Term] = list
+
+				if surfaceCounts[tok.Term] == nil {
+					surfaceCounts[tok.Term] = make(map[string]int)
+				}
+				surfaceCounts[tok.Term][strings.ToLower(doc.texts[f][tok.Start:tok.End])]++
+			}
+		}
+	}
+	if len(docs) > 0 {
+		for f := range fields {
+			snap.avgFieldLen[f] /= float64(len(docs))
+		}
+	}
+	for term, counts := range surfaceCounts {
+		best := ""
+		for form, count := range counts {
+			if best == "" || count > counts[best] || (count == counts[best] && form < best) {
+				best = form
+			}
+		}
+		snap.surface[term] = best
+	}
+	return snap
+}
+
+// Search ค้นหาสินค้าด้วย BM25 รวมทุก field ตามน้ำหนัก แล้วคูณด้วยสัดส่วนคำค้นที่ตรง
+// เพื่อให้สินค้าที่ตรงครบทุกคำอยู่ก่อนสินค้าที่ตรงบางคำ
+func (ix *Index) Search(ctx context.Context, text string, limit int) ([]clothesstore.SearchMatch, error) {
+	snap := ix.current.Load()
+	if snap == nil {
+		return nil, clothesstore.ErrSearchUnavailable
+	}
+
+	terms := uniqueTerms(ix.segmenter.tokenizeQuery(text))
+	if len(terms) == 0 {
+		return []clothesstore.SearchMatch{}, nil
+	}
+
+	n := float64(len(snap.docs))
+	scores := make(map[int]float64)
+	matched := make(map[int]int)
+	for _, term := range terms {
+		list := snap.postings[term]
+		if len(list) == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-float64(len(list))+0.5)/(float64(len(list))+0.5))
+		for _, p := range list {
+			var score float64
+			for f, fd := range fields {
+				tf := float64(p.tf[f])
+				if tf == 0 {
+					continue
+				}
+				norm := 1 - bm25B + bm25B*float64(len(snap.docs[p.doc].tokens[f]))/math.Max(snap.avgFieldLen[f], 1)
+				score += fd.weight * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+			}
+			scores[p.doc] += idf * score
+			matched[p.doc]++
+		}
+	}
+
+	docs := make([]int, 0, len(scores))
+	for doc := range scores {
+		scores[doc] *= float64(matched[doc]) / float64(len(terms))
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if scores[docs[i]] != scores[docs[j]] {
+			return scores[docs[i]] > scores[docs[j]]
+		}
+		return snap.docs[docs[i]].productID < snap.docs[docs[j]].productID
+	})
+	if limit > 0 && len(docs) > limit {
+		docs = docs[:limit]
+	}
+
+	termSet := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		termSet[term] = true
+	}
+	matches := make([]clothesstore.SearchMatch, len(docs))
+	for i, doc := range docs {
+		matches[i] = clothesstore.SearchMatch{
+			ProductID:  snap.docs[doc].productID,
+			Score:      math.Round(scores[doc]*1000) / 1000,
+			Highlights: highlights(snap.docs[doc], termSet),
+		}
+	}
+	return matches, nil
+}
+
+func uniqueTerms(tokens []token) []string {
+	seen := make(map[string]bool, len(tokens))
+	var terms []string
+	for _, tok := range tokens {
+		if !seen[tok.Term] {
+			seen[tok.Term] = true
+			terms = append(terms, tok.Term)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,150 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"clothesproject/internal/clothesstore"
+)
+
+// fakeSource คือ Source ในหน่วยความจำ onList ถูกเรียกทุกครั้งที่ Rebuild ดึงสินค้า
+type fakeSource struct {
+	products []clothesstore.Clothes
+	terms    []clothesstore.Suggestion
+	onList   func()
+}
+
+func (s *fakeSource) ListProducts(ctx context.Context, query clothesstore.ProductQuery) (clothesstore.ProductPage, error) {
+	if s.onList != nil {
+		s.onList()
+	}
+	return clothesstore.ProductPage{Items: s.products}, nil
+}
+
+func (s *fakeSource) ListSuggestionTerms(ctx context.Context) ([]clothesstore.Suggestion, error) {
+	return s.terms, nil
+}
+
+var testProducts = []clothesstore.Clothes{
+	{ID: 1, Name: "Red shirt", Description: "Soft cotton"},
+	{ID: 2, Name: "Blue jeans", Description: "Goes well with a red shirt"},
+	{ID: 3, Name: "Red dress", Description: "A summer dress"},
+	{ID: 4, Name: "กางเกงขายาว", Description: "ผ้าฝ้าย สีแดง"},
+}
+
+func newTestIndex(t *testing.T, source *fakeSource) *Index {
+	t.Helper()
+	ix := NewIndex(source)
+	if _, err := ix.Rebuild(context.Background()); err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	return ix
+}
+
+func matchIDs(t *testing.T, ix *Index, text string) []int {
+	t.Helper()
+	matches, err := ix.Search(context.Background(), text, 10)
+	if err != nil {
+		t.Fatalf("Search(%q): %v", text, err)
+	}
+	ids := make([]int, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ProductID
+	}
+	return ids
+}
+
+func TestSearchUnavailableBeforeRebuild(t *testing.T) {
+	ix := NewIndex(&fakeSource{})
+	if _, err := ix.Search(context.Background(), "shirt", 10); !errors.Is(err, clothesstore.ErrSearchUnavailable) {
+		t.Errorf("Search before Rebuild = %v, want ErrSearchUnavailable", err)
+	}
+	if _, err := ix.Suggest(context.Background(), "shirt", 10); !errors.Is(err, clothesstore.ErrSearchUnavailable) {
+		t.Errorf("Suggest before Rebuild = %v, want ErrSearchUnavailable", err)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	ix := newTestIndex(t, &fakeSource{products: testProducts})
+	tests := []struct {
+		text string
+		want []int
+	}{
+		// ตรงทุกคำในชื่อ > ตรงทุกคำในคำอธิบาย > ตรงบางคำ
+		{text: "red shirt", want: []int{1, 2, 3}},
+		{text: "shirts", want: []int{1, 2}},
+		{text: "DRESS", want: []int{3}},
+		{text: "ขายาว", want: []int{4}},
+		{text: "ยาว", want: []int{4}},
+		{text: "กางเกง สีแดง", want: []int{4}},
+		{text: "hat", want: []int{}},
+		{text: " ", want: []int{}},
+	}
+	for _, tt := range tests {
+		if got := matchIDs(t, ix, tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearchLimitAndHighlights(t *testing.T) {
+	ix := newTestIndex(t, &fakeSource{products: testProducts})
+	matches, err := ix.Search(context.Background(), "red", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("Search with limit 2 returned %d matches", len(matches))
+	}
+	want := []clothesstore.Highlight{{Field: "name", Snippet: "<mark>Red</mark> shirt"}}
+	if matches[0].ProductID != 1 || !reflect.DeepEqual(matches[0].Highlights, want) {
+		t.Errorf("first match = %+v, want product 1 with highlights %+v", matches[0], want)
+	}
+}
+
+func TestIndexProductAndRemoveProduct(t *testing.T) {
+	ix := newTestIndex(t, &fakeSource{products: testProducts[:1]})
+
+	ix.IndexProduct(clothesstore.Clothes{ID: 5, Name: "Green hoodie"})
+	if got := matchIDs(t, ix, "hoodie"); !reflect.DeepEqual(got, []int{5}) {
+		t.Errorf("after IndexProduct, Search(hoodie) = %v, want [5]", got)
+	}
+
+	ix.IndexProduct(clothesstore.Clothes{ID: 1, Name: "Yellow shirt"})
+	if got := matchIDs(t, ix, "red"); len(got) != 0 {
+		t.Errorf("after renaming, Search(red) = %v, want none", got)
+	}
+	if got := matchIDs(t, ix, "yellow"); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("after renaming, Search(yellow) = %v, want [1]", got)
+	}
+
+	ix.RemoveProduct(5)
+	ix.RemoveProduct(99)
+	if got := matchIDs(t, ix, "hoodie"); len(got) != 0 {
+		t.Errorf("after RemoveProduct, Search(hoodie) = %v, want none", got)
+	}
+}
+
+func TestRebuildKeepsChangesMadeWhileLoading(t *testing.T) {
+	source := &fakeSource{products: testProducts}
+	ix := newTestIndex(t, source)
+
+	// สินค้าถูกลบและเพิ่มระหว่างที่ Rebuild กำลังดึงข้อมูลชุดเก่า
+	source.onList = func() {
+		ix.RemoveProduct(3)
+		ix.IndexProduct(clothesstore.Clothes{ID: 6, Name: "Striped scarf"})
+	}
+	if _, err := ix.Rebuild(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	source.onList = nil
+
+	if got := matchIDs(t, ix, "dress"); len(got) != 0 {
+		t.Errorf("Search(dress) = %v, want the product removed during Rebuild to stay removed", got)
+	}
+	if got := matchIDs(t, ix, "scarf"); !reflect.DeepEqual(got, []int{6}) {
+		t.Errorf("Search(scarf) = %v, want the product added during Rebuild to stay", got)
+	}
+}
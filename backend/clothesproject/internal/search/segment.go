@@ -0,0 +1,253 @@
+package search
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed thai_words.txt
+var thaiWordList string
+
+// token คือคำหนึ่งคำจากข้อความ Term เป็นรูปที่ normalize แล้ว (ตัวพิมพ์เล็ก/ตัดคำต่อท้ายภาษาอังกฤษ)
+// Start/End เป็นตำแหน่ง byte ในข้อความเดิม ใช้สำหรับไฮไลต์
+type token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// trie เก็บคำในพจนานุกรมภาษาไทยเพื่อหาคำที่ยาวที่สุดที่ตรงจากตำแหน่งหนึ่ง
+type trie struct {
+	children map[rune]*trie
+	word     bool
+}
+
+func newTrie(words []string) *trie {
+	root := &trie{}
+	for _, word := range words {
+		node := root
+		for _, r := range word {
+			if node.children == nil {
+				node.children = make(map[rune]*trie)
+			}
+			next, ok := node.children[r]
+			if !ok {
+				next = &trie{}
+				node.children[r] = next
+			}
+			node = next
+		}
+		node.word = true
+	}
+	return root
+}
+
+// longestMatch คืนจำนวน rune ของคำที่ยาวที่สุดในพจนานุกรมที่ขึ้นต้นที่ runes[0]
+// โดยไม่เกิน maxLen rune (0 ถ้าไม่มีคำที่ตรง)
+func (t *trie) longestMatch(runes []rune, maxLen int) int {
+	node, best := t, 0
+	for i := 0; i < len(runes) && i < maxLen; i++ {
+		node = node.children[runes[i]]
+		if node == nil {
+			break
+		}
+		if node.word {
+			best = i + 1
+		}
+	}
+	return best
+}
+
+// contains ตรวจสอบว่า word เป็นคำในพจนานุกรมทั้งคำ
+func (t *trie) contains(word string) bool {
+	node := t
+	for _, r := range word {
+		node = node.children[r]
+		if node == nil {
+			return false
+		}
+	}
+	return node.word
+}
+
+// segmenter ตัดคำภาษาไทยด้วยพจนานุกรม (longest matching) และตัดคำภาษาอังกฤษตามช่องว่าง/เครื่องหมาย
+type segmenter struct {
+	dict *trie
+}
+
+// newSegmenter สร้าง segmenter จากพจนานุกรมภาษาไทยที่ฝังมากับโปรแกรม และคำเพิ่มเติม extraWords (ถ้ามี)
+func newSegmenter(extraWords ...string) *segmenter {
+	var words []string
+	scanner := bufio.NewScanner(strings.NewReader(thaiWordList))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	words = append(words, extraWords...)
+	return &segmenter{dict: newTrie(words)}
+}
+
+func isThai(r rune) bool {
+	return r >= 0x0E00 && r <= 0x0E7F
+}
+
+// isThaiCombining คือสระบน/ล่างและวรรณยุกต์ที่ต้องอยู่ติดกับพยัญชนะตัวหน้า
+func isThaiCombining(r rune) bool {
+	return r == 0x0E31 || (r >= 0x0E34 && r <= 0x0E3A) || (r >= 0x0E47 && r <= 0x0E4E)
+}
+
+// isThaiLeadingVowel คือสระหน้า (เ แ โ ใ ไ) ที่ต้องอยู่ติดกับพยัญชนะตัวถัดไป
+func isThaiLeadingVowel(r rune) bool {
+	return r >= 0x0E40 && r <= 0x0E44
+}
+
+// clusterLen คืนจำนวน rune ของกลุ่มตัวอักษรไทยที่แยกจากกันไม่ได้ที่ขึ้นต้นที่ runes[0]
+func clusterLen(runes []rune) int {
+	n := 1
+	if isThaiLeadingVowel(runes[0]) && len(runes) > 1 {
+		n = 2
+	}
+	for n < len(runes) && isThaiCombining(runes[n]) {
+		n++
+	}
+	return n
+}
+
+// tokenizeQuery ตัดข้อความค้นหาเป็นคำ
+func (s *segmenter) tokenizeQuery(text string) []token {
+	return s.tokenize(text, false)
+}
+
+// tokenizeForIndex เหมือน Tokenize แต่เพิ่มคำย่อยของคำประสมภาษาไทยด้วย
+// (เช่น "กางเกงขายาว" ได้ "กางเกง" "ขายาว" "ขา" "ยาว" เพิ่ม) เพื่อให้ค้นด้วยคำที่สั้นกว่าเจอ
+func (s *segmenter) tokenizeForIndex(text string) []token {
+	return s.tokenize(text, true)
+}
+
+func (s *segmenter) tokenize(text string, withSubwords bool) []token {
+	var tokens []token
+	i := 0
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case isThai(r):
+			end := i
+			for end < len(text) {
+				r, size := utf8.DecodeRuneInString(text[end:])
+				if !isThai(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, s.segmentThai(text[i:end], i, withSubwords)...)
+			i = end
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			end := i
+			for end < len(text) {
+				r, size := utf8.DecodeRuneInString(text[end:])
+				if isThai(r) || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, token{Term: stem(strings.ToLower(text[i:end])), Start: i, End: end})
+			i = end
+		default:
+			i += size
+		}
+	}
+	return tokens
+}
+
+// segmentThai ตัดข้อความภาษาไทยล้วนด้วย longest matching
+// ส่วนที่ไม่อยู่ในพจนานุกรมจะรวมกลุ่มตัวอักษรที่ติดกันเป็นคำเดียว
+func (s *segmenter) segmentThai(text string, offset int, withSubwords bool) []token {
+	runes := []rune(text)
+	byteAt := make([]int, len(runes)+1)
+	for i, pos := 0, 0; i < len(runes); i++ {
+		byteAt[i] = pos
+		pos += utf8.RuneLen(runes[i])
+	}
+	byteAt[len(runes)] = len(text)
+
+	return s.segmentRunes(runes, byteAt, offset, len(runes), withSubwords)
+}
+
+func (s *segmenter) segmentRunes(runes []rune, byteAt []int, offset int, maxLen int, withSubwords bool) []token {
+	var tokens []token
+	unknownStart := -1
+	flushUnknown := func(end int) {
+		if unknownStart >= 0 {
+			tokens = append(tokens, token{
+				Term:  string(runes[unknownStart:end]),
+				Start: offset + byteAt[unknownStart],
+				End:   offset + byteAt[end],
+			})
+			unknownStart = -1
+		}
+	}
+
+	for i := 0; i < len(runes); {
+		n := s.dict.longestMatch(runes[i:], maxLen)
+		if n == 0 {
+			if unknownStart < 0 {
+				unknownStart = i
+			}
+			i += clusterLen(runes[i:])
+			continue
+		}
+		flushUnknown(i)
+		word := runes[i : i+n]
+		whole := token{Term: string(word), Start: offset + byteAt[i], End: offset + byteAt[i+n]}
+		tokens = append(tokens, whole)
+		if withSubwords && n > 1 {
+			for _, sub := range s.segmentRunes(word, byteAt[i:i+n+1], offset, n-1, true) {
+				// คำย่อยที่ไม่รู้จักไม่ช่วยในการค้นหา เก็บเฉพาะคำที่อยู่ในพจนานุกรมและไม่ใช่คำเดิมทั้งคำ
+				if s.dict.contains(sub.Term) && (sub.Start != whole.Start || sub.End != whole.End) {
+					tokens = append(tokens, sub)
+				}
+			}
+		}
+		i += n
+	}
+	flushUnknown(len(runes))
+	return tokens
+}
+
+// stem ตัดคำต่อท้ายภาษาอังกฤษแบบง่าย (พหูพจน์ -s/-es/-ies และ -ing/-ed) ให้ค้นรูปต่างกันแล้วเจอ
+// คำที่สั้นมากหรือเป็นตัวเลขจะไม่ถูกตัด
+func stem(word string) string {
+	if len(word) <= 3 || !unicode.IsLetter(rune(word[len(word)-1])) {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "xes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return word[:len(word)-1]
+	case strings.HasSuffix(word, "ing") && len(word) > 5:
+		return undouble(word[:len(word)-3])
+	case strings.HasSuffix(word, "ed") && len(word) > 4:
+		return undouble(word[:len(word)-2])
+	}
+	return word
+}
+
+// undouble ตัดพยัญชนะซ้ำท้ายคำที่เกิดจากการเติม -ing/-ed (เช่น running → run)
+func undouble(word string) string {
+	n := len(word)
+	if n >= 3 && word[n-1] == word[n-2] && !strings.ContainsRune("aeioulsz", rune(word[n-1])) {
+		return word[:n-1]
+	}
+	return word
+}
@@ -0,0 +1,90 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func terms(tokens []token) []string {
+	out := make([]string, len(tokens))
+	for i, tok := range tokens {
+		out[i] = tok.Term
+	}
+	return out
+}
+
+func TestTokenizeQuery(t *testing.T) {
+	s := newSegmenter()
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Red Shirts", want: []string{"red", "shirt"}},
+		{text: "เสื้อยืดสีแดง", want: []string{"เสื้อยืด", "สีแดง"}},
+		{text: "กางเกงขายาว", want: []string{"กางเกงขายาว"}},
+		{text: "cotton เสื้อ, size-XL", want: []string{"cotton", "เสื้อ", "size", "xl"}},
+		{text: "Nike2024เสื้อ", want: []string{"nike2024", "เสื้อ"}},
+		{text: "  !!  ", want: []string{}},
+	}
+	for _, tt := range tests {
+		if got := terms(s.tokenizeQuery(tt.text)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenizeQuery(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeForIndexAddsSubwords(t *testing.T) {
+	s := newSegmenter()
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "กางเกงขายาว", want: []string{"กางเกงขายาว", "กางเกง", "ขายาว", "ขา", "ยาว"}},
+		{text: "เสื้อยืด", want: []string{"เสื้อยืด", "เสื้อ", "ยืด"}},
+		{text: "jeans", want: []string{"jean"}},
+	}
+	for _, tt := range tests {
+		if got := terms(s.tokenizeForIndex(tt.text)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenizeForIndex(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenOffsets(t *testing.T) {
+	s := newSegmenter()
+	text := "Red เสื้อยืด"
+	for _, tok := range s.tokenizeForIndex(text) {
+		if tok.Start < 0 || tok.End > len(text) || tok.Start >= tok.End {
+			t.Fatalf("token %+v has invalid offsets for %q", tok, text)
+		}
+		if got := stem(strings.ToLower(text[tok.Start:tok.End])); got != tok.Term {
+			t.Errorf("token %+v covers %q", tok, text[tok.Start:tok.End])
+		}
+	}
+}
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{word: "shirts", want: "shirt"},
+		{word: "dresses", want: "dress"},
+		{word: "dress", want: "dress"},
+		{word: "boxes", want: "box"},
+		{word: "watches", want: "watch"},
+		{word: "hoodies", want: "hoody"},
+		{word: "running", want: "run"},
+		{word: "washed", want: "wash"},
+		{word: "bus", want: "bus"},
+		{word: "xs", want: "xs"},
+		{word: "king", want: "king"},
+		{word: "2000", want: "2000"},
+	}
+	for _, tt := range tests {
+		if got := stem(tt.word); got != tt.want {
+			t.Errorf("stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
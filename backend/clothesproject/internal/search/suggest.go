@@ -0,0 +1,253 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"clothesproject/internal/clothesstore"
+)
+
+// minFuzzyRunes คือความยาวขั้นต่ำของข้อความที่เริ่มยอมให้พิมพ์ผิด (สั้นกว่านี้ต้องตรงส่วนต้นเท่านั้น)
+const minFuzzyRunes = 3
+
+// suggestEntry คือชื่อสินค้าหรือชื่อแบรนด์หนึ่งรายการในตัวเติมคำค้น
+// starts คือข้อความตัวพิมพ์เล็กตั้งแต่ต้นชื่อและตั้งแต่ต้นแต่ละคำ ใช้เทียบกับข้อความที่พิมพ์
+type suggestEntry struct {
+	suggestion clothesstore.Suggestion
+	starts     [][]rune
+}
+
+// suggestMatch คือผลการเทียบข้อความที่พิมพ์กับ suggestEntry
+// rank 0 คือตรงต้นชื่อ 1 คือตรงต้นคำใดคำหนึ่ง 2 คือตรงแบบพิมพ์ผิด (distance คือจำนวนตัวที่ผิด)
+type suggestMatch struct {
+	entry    *suggestEntry
+	rank     int
+	distance int
+}
+
+// maxTypos คือจำนวนตัวอักษรที่ยอมให้พิมพ์ผิดตามความยาวของคำ
+func maxTypos(runes int) int {
+	switch {
+	case runes < minFuzzyRunes:
+		return 0
+	case runes <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// newSuggestEntry เตรียมชื่อสำหรับเทียบ ตำแหน่งต้นคำได้จากการตัดคำ (รวมคำภาษาไทย)
+func (s *segmenter) newSuggestEntry(suggestion clothesstore.Suggestion) suggestEntry {
+	entry := suggestEntry{suggestion: suggestion}
+	seen := make(map[int]bool)
+	for _, start := range append([]int{0}, tokenStarts(s.tokenizeQuery(suggestion.Text))...) {
+		if !seen[start] {
+			seen[start] = true
+			entry.starts = append(entry.starts, []rune(strings.ToLower(suggestion.Text[start:])))
+		}
+	}
+	return entry
+}
+
+func tokenStarts(tokens []token) []int {
+	starts := make([]int, len(tokens))
+	for i, tok := range tokens {
+		starts[i] = tok.Start
+	}
+	return starts
+}
+
+// match เทียบข้อความที่พิมพ์ (ตัวพิมพ์เล็กแล้ว) กับชื่อนี้ คืน false ถ้าไม่ตรงแม้จะยอมให้พิมพ์ผิด
+func (e *suggestEntry) match(typed []rune) (suggestMatch, bool) {
+	if hasRunePrefix(e.starts[0], typed) {
+		return suggestMatch{entry: e, rank: 0}, true
+	}
+	for _, start := range e.starts[1:] {
+		if hasRunePrefix(start, typed) {
+			return suggestMatch{entry: e, rank: 1}, true
+		}
+	}
+
+	budget := maxTypos(len(typed))
+	if budget == 0 {
+		return suggestMatch{}, false
+	}
+	best := budget + 1
+	for _, start := range e.starts {
+		// ส่วนต้นที่ยาวกว่าข้อความที่พิมพ์เกิน budget ตัวไม่มีทางตรง
+		best = min(best, editDistance(typed, start[:min(len(start), len(typed)+budget)], true))
+	}
+	if best > budget {
+		return suggestMatch{}, false
+	}
+	return suggestMatch{entry: e, rank: 2, distance: best}, true
+}
+
+func hasRunePrefix(s, prefix []rune) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// editDistance คืนระยะ Damerau-Levenshtein (แบบ optimal string alignment) ระหว่าง a กับ b
+// นับการสลับตัวอักษรที่ติดกันเป็นการผิดครั้งเดียว (เช่น "hoodei" กับ "hoodie" ห่างกัน 1)
+// ถ้า prefix เป็น true จะคืนระยะที่น้อยที่สุดระหว่าง a กับส่วนต้นของ b ทุกความยาว
+func editDistance(a, b []rune, prefix bool) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d := min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d = min(d, rows[i-2][j-2]+1)
+			}
+			rows[i][j] = d
+		}
+	}
+
+	last := rows[len(a)]
+	if !prefix {
+		return last[len(b)]
+	}
+	best := last[0]
+	for _, d := range last[1:] {
+		best = min(best, d)
+	}
+	return best
+}
+
+// Suggest เติมคำค้นจากชื่อสินค้าและชื่อแบรนด์ที่ตรงกับข้อความที่พิมพ์
+// เรียงชื่อที่ตรงต้นชื่อก่อน ตามด้วยชื่อที่ตรงต้นคำ และชื่อที่ตรงแบบพิมพ์ผิด แต่ละกลุ่มเรียงตามความนิยม
+// ถ้าคำค้นมีคำที่สะกดผิด จะแนะนำคำค้นที่แก้แล้วใน DidYouMean
+func (ix *Index) Suggest(ctx context.Context, text string, limit int) (clothesstore.Suggestions, error) {
+	snap := ix.current.Load()
+	if snap == nil {
+		return clothesstore.Suggestions{}, clothesstore.ErrSearchUnavailable
+	}
+
+	result := clothesstore.Suggestions{Query: text, Suggestions: []clothesstore.Suggestion{}}
+	typed := []rune(strings.ToLower(strings.TrimSpace(text)))
+	if len(typed) == 0 {
+		return result, nil
+	}
+
+	var matches []suggestMatch
+	for i := range snap.suggestions {
+		if m, ok := snap.suggestions[i].match(typed); ok {
+			matches = append(matches, m)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.entry.suggestion.Popularity != b.entry.suggestion.Popularity {
+			return a.entry.suggestion.Popularity > b.entry.suggestion.Popularity
+		}
+		if len(a.entry.starts[0]) != len(b.entry.starts[0]) {
+			return len(a.entry.starts[0]) < len(b.entry.starts[0])
+		}
+		return a.entry.suggestion.Text < b.entry.suggestion.Text
+	})
+
+	// สินค้าที่ชื่อเดียวกับแบรนด์แสดงรายการเดียว (รายการที่อันดับดีกว่า)
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		key := string(m.entry.starts[0])
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result.Suggestions = append(result.Suggestions, m.entry.suggestion)
+		if len(result.Suggestions) == limit {
+			break
+		}
+	}
+
+	result.DidYouMean = ix.correct(snap, text)
+	return result, nil
+}
+
+// correct แก้คำในข้อความค้นหาที่ไม่มีใน index เป็นคำใน index ที่ใกล้ที่สุด
+// คำที่เป็นส่วนต้นของคำใน index ถือว่ายังพิมพ์ไม่จบและไม่แก้ คืนค่าว่างถ้าไม่มีคำที่ต้องแก้
+func (ix *Index) correct(snap *snapshot, text string) string {
+	var b strings.Builder
+	changed := false
+	pos := 0
+	for _, tok := range ix.segmenter.tokenizeQuery(text) {
+		b.WriteString(text[pos:tok.Start])
+		pos = tok.End
+		if fix, ok := snap.correction(tok.Term); ok {
+			b.WriteString(fix)
+			changed = true
+			continue
+		}
+		b.WriteString(text[tok.Start:tok.End])
+	}
+	if !changed {
+		return ""
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
+
+// correction คืนคำใน index ที่ใกล้กับ term ที่สุด (ในรูปที่พบบ่อยในชื่อ/คำอธิบายสินค้า)
+// ถ้าหลายคำใกล้เท่ากัน เลือกคำที่อยู่ในสินค้ามากที่สุด
+func (snap *snapshot) correction(term string) (string, bool) {
+	if len(snap.postings[term]) > 0 {
+		return "", false
+	}
+	typed := []rune(term)
+	budget := maxTypos(len(typed))
+	if budget == 0 {
+		return "", false
+	}
+
+	best, bestDistance, bestDocs := "", budget+1, 0
+	for candidate, list := range snap.postings {
+		if strings.HasPrefix(candidate, term) {
+			return "", false
+		}
+		if abs(utf8.RuneCountInString(candidate)-len(typed)) > budget {
+			continue
+		}
+		d := editDistance(typed, []rune(candidate), false)
+		if d < bestDistance || (d == bestDistance && (len(list) > bestDocs || (len(list) == bestDocs && candidate < best))) {
+			best, bestDistance, bestDocs = candidate, d, len(list)
+		}
+	}
+	if bestDistance > budget {
+		return "", false
+	}
+	return snap.surface[best], true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
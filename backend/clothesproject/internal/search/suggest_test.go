@@ -0,0 +1,101 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"clothesproject/internal/clothesstore"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		prefix bool
+		want   int
+	}{
+		{a: "shirt", b: "shirt", want: 0},
+		{a: "hoodei", b: "hoodie", want: 1},
+		{a: "kitten", b: "sitting", want: 3},
+		{a: "", b: "abc", want: 3},
+		{a: "abc", b: "", want: 3},
+		{a: "shi", b: "shirt", prefix: true, want: 0},
+		{a: "sjh", b: "shirt", prefix: true, want: 1},
+		{a: "shi", b: "shirt", want: 2},
+		{a: "เสือ", b: "เสื้อ", want: 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b), tt.prefix); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %v) = %d, want %d", tt.a, tt.b, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	ix := newTestIndex(t, &fakeSource{
+		products: testProducts,
+		terms: []clothesstore.Suggestion{
+			{Text: "Red shirt", Type: clothesstore.SuggestionProduct, ID: 1, Popularity: 5},
+			{Text: "Redwood", Type: clothesstore.SuggestionBrand, ID: 7, Popularity: 10},
+			{Text: "Blue shirt", Type: clothesstore.SuggestionProduct, ID: 8, Popularity: 1},
+			{Text: "เสื้อยืดสีแดง", Type: clothesstore.SuggestionProduct, ID: 4},
+		},
+	})
+	tests := []struct {
+		text       string
+		limit      int
+		want       []string
+		didYouMean string
+	}{
+		// ตรงต้นชื่อเรียงตามความนิยม
+		{text: "red", limit: 10, want: []string{"Redwood", "Red shirt"}},
+		// ตรงต้นคำที่ไม่ใช่คำแรก
+		{text: "shi", limit: 10, want: []string{"Red shirt", "Blue shirt"}},
+		{text: "สีแดง", limit: 10, want: []string{"เสื้อยืดสีแดง"}},
+		// พิมพ์ผิดหนึ่งตัว
+		{text: "bleu", limit: 10, want: []string{"Blue shirt"}, didYouMean: "blue"},
+		{text: "red", limit: 1, want: []string{"Redwood"}},
+		// สั้นเกินกว่าจะยอมให้พิมพ์ผิด
+		{text: "xr", limit: 10, want: []string{}},
+		{text: "  ", limit: 10, want: []string{}},
+		{text: "red shrit", limit: 10, want: []string{"Red shirt"}, didYouMean: "red shirt"},
+	}
+	for _, tt := range tests {
+		result, err := ix.Suggest(context.Background(), tt.text, tt.limit)
+		if err != nil {
+			t.Fatalf("Suggest(%q): %v", tt.text, err)
+		}
+		got := make([]string, len(result.Suggestions))
+		for i, s := range result.Suggestions {
+			got[i] = s.Text
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Suggest(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+		}
+		if result.DidYouMean != tt.didYouMean {
+			t.Errorf("Suggest(%q) DidYouMean = %q, want %q", tt.text, result.DidYouMean, tt.didYouMean)
+		}
+	}
+}
+
+func TestSuggestFollowsProductChanges(t *testing.T) {
+	ix := newTestIndex(t, &fakeSource{
+		products: testProducts[:1],
+		terms:    []clothesstore.Suggestion{{Text: "Red shirt", Type: clothesstore.SuggestionProduct, ID: 1, Popularity: 5}},
+	})
+
+	ix.IndexProduct(clothesstore.Clothes{ID: 9, Name: "Red scarf"})
+	result, _ := ix.Suggest(context.Background(), "red", 10)
+	if want := []clothesstore.Suggestion{
+		{Text: "Red shirt", Type: clothesstore.SuggestionProduct, ID: 1, Popularity: 5},
+		{Text: "Red scarf", Type: clothesstore.SuggestionProduct, ID: 9},
+	}; !reflect.DeepEqual(result.Suggestions, want) {
+		t.Errorf("after IndexProduct, Suggest(red) = %+v, want %+v", result.Suggestions, want)
+	}
+
+	ix.RemoveProduct(1)
+	result, _ = ix.Suggest(context.Background(), "red", 10)
+	if want := []clothesstore.Suggestion{{Text: "Red scarf", Type: clothesstore.SuggestionProduct, ID: 9}}; !reflect.DeepEqual(result.Suggestions, want) {
+		t.Errorf("after RemoveProduct, Suggest(red) = %+v, want %+v", result.Suggestions, want)
+	}
+}
@@ -55,7 +55,10 @@ func main() {
 	}()
 	userRepo := repository.NewUserRepository(db.DB)
 	// userRepo := repository.NewUserRepository(db)
-	authService := service.NewAuthService(userRepo, cfg)
+	cartRepo := repository.NewCartRepository(db.DB)
+	sessionRepo := repository.NewSessionRepository(db.DB)
+	loginRepo := repository.NewLoginRepository(db.DB)
+	authService := service.NewAuthService(userRepo, cartRepo, sessionRepo, loginRepo, cfg)
 	authHandler := handler.NewAuthHandler(authService)
 
 	gin.SetMode(gin.ReleaseMode)
@@ -65,7 +68,7 @@ func main() {
 	configCors := cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "API-Key"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "API-Key", "X-Guest-Token"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -85,11 +88,18 @@ func main() {
 		{
 			auth.GET("/client-id", middleware.APIKeyMiddleware(cfg), authHandler.GetClientID)
 			auth.POST("/google/verify", authHandler.VerifyGoogleToken)
-			auth.POST("/logout", middleware.AuthMiddleware(cfg), authHandler.Logout)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", middleware.AuthMiddleware(cfg, sessionRepo), authHandler.Logout)
+			auth.POST("/logout-all", middleware.AuthMiddleware(cfg, sessionRepo), authHandler.LogoutAll)
 		}
 		users := v1.Group("/users")
 		{
-			users.GET("/me", middleware.AuthMiddleware(cfg), authHandler.GetCurrentUser)
+			users.GET("/me", middleware.AuthMiddleware(cfg, sessionRepo), authHandler.GetCurrentUser)
+			users.GET("/me/logins", middleware.AuthMiddleware(cfg, sessionRepo), authHandler.GetMyLogins)
+		}
+		admin := v1.Group("/admin", middleware.AuthMiddleware(cfg, sessionRepo), middleware.RequireRole("admin"))
+		{
+			admin.GET("/logins", authHandler.SearchLogins)
 		}
 	}
 
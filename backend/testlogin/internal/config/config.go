@@ -4,27 +4,43 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+// minJWTSecretLength คือความยาวขั้นต่ำ (ไบต์) ของ JWT_SECRET สำหรับ HS256
+const minJWTSecretLength = 32
+
 type Config struct {
 	AppPort        string
 	DatabaseURL    string
 	GoogleClientID string
 	JWTSecret      string
 	APIKey         string
+	// AccessTokenTTL คืออายุของ access token (JWT) ส่วน RefreshTokenTTL คืออายุของ session นับจากการ refresh ครั้งล่าสุด
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
 }
 
 func New() (*Config, error) {
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.SetDefault("ACCESS_TOKEN_TTL", "15m")
+	viper.SetDefault("REFRESH_TOKEN_TTL", "720h")
 
 	config := &Config{
-		AppPort:        viper.GetString("APP_PORT"),
-		GoogleClientID: viper.GetString("GOOGLE_CLIENT_ID"),
-		JWTSecret:      viper.GetString("JWT_SECRET"),
-		APIKey:         viper.GetString("API_KEY"),
+		AppPort:         viper.GetString("APP_PORT"),
+		GoogleClientID:  viper.GetString("GOOGLE_CLIENT_ID"),
+		JWTSecret:       viper.GetString("JWT_SECRET"),
+		APIKey:          viper.GetString("API_KEY"),
+		AccessTokenTTL:  viper.GetDuration("ACCESS_TOKEN_TTL"),
+		RefreshTokenTTL: viper.GetDuration("REFRESH_TOKEN_TTL"),
+	}
+
+	// secret ว่างหรือสั้นเกินไปทำให้ใครก็ปลอม token ได้ จึงไม่ยอมให้เซิร์ฟเวอร์เริ่มทำงาน
+	if len(config.JWTSecret) < minJWTSecretLength {
+		return nil, fmt.Errorf("JWT_SECRET must be at least %d bytes", minJWTSecretLength)
 	}
 
 	// Construct DatabaseURL
@@ -1,14 +1,23 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
+	"login/internal/model"
 	"login/internal/service"
-	"login/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	guestTokenHeader = "X-Guest-Token"
+	guestTokenCookie = "guest_token"
+	// refreshTokenCookie ส่งเฉพาะกับ route ของ /api/v1/auth
+	refreshTokenCookie = "refresh_token"
+	refreshTokenPath   = "/api/v1/auth"
+)
+
 type AuthHandler struct {
 	authService *service.AuthService
 }
@@ -35,26 +44,98 @@ func (h *AuthHandler) VerifyGoogleToken(c *gin.Context) {
 		return
 	}
 
-	authResponse, err := h.authService.VerifyGoogleToken(c.Request.Context(), req.IDToken)
+	// guest token ของตะกร้าที่ออกโดย clothesproject (ส่งมาทาง header หรือ cookie)
+	guestToken := c.GetHeader(guestTokenHeader)
+	if guestToken == "" {
+		guestToken, _ = c.Cookie(guestTokenCookie)
+	}
+
+	authResponse, err := h.authService.VerifyGoogleToken(c.Request.Context(), req.IDToken, guestToken, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 		return
 	}
 
-	c.SetCookie("token", authResponse.AccessToken, 3600, "/", "localhost", false, true)
+	h.setAuthCookies(c, authResponse)
+	if guestToken != "" {
+		// ตะกร้าของ guest ถูกรวมเข้ากับตะกร้าของผู้ใช้แล้ว
+		c.SetCookie(guestTokenCookie, "", -1, "/", "", false, true)
+	}
+
+	c.JSON(http.StatusOK, authResponse)
+}
+
+// Refresh ออก access token ใหม่จาก refresh token (ใน body หรือ cookie) และคืน refresh token ใบใหม่
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken, _ = c.Cookie(refreshTokenCookie)
+	}
+	if req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token is required"})
+		return
+	}
+
+	authResponse, err := h.authService.RefreshTokens(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRefreshTokenReused):
+			h.clearAuthCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected, please log in again"})
+		case errors.Is(err, service.ErrInvalidRefreshToken):
+			h.clearAuthCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		}
+		return
+	}
 
+	h.setAuthCookies(c, authResponse)
 	c.JSON(http.StatusOK, authResponse)
 }
 
+// setAuthCookies เก็บ access token และ refresh token ใน cookie แบบ HttpOnly ตามอายุของแต่ละ token
+func (h *AuthHandler) setAuthCookies(c *gin.Context, authResponse *model.AuthResponse) {
+	cfg := h.authService.Cfg
+	c.SetCookie("token", authResponse.AccessToken, int(cfg.AccessTokenTTL.Seconds()), "/", "localhost", false, true)
+	c.SetCookie(refreshTokenCookie, authResponse.RefreshToken, int(cfg.RefreshTokenTTL.Seconds()), refreshTokenPath, "localhost", false, true)
+}
+
+func (h *AuthHandler) clearAuthCookies(c *gin.Context) {
+	c.SetCookie("token", "", -1, "/", "localhost", false, true)
+	c.SetCookie(refreshTokenCookie, "", -1, refreshTokenPath, "localhost", false, true)
+}
+
+// Logout ออกจากระบบ session ของ access token ที่ใช้เรียก
 func (h *AuthHandler) Logout(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	if err := h.authService.Logout(c.Request.Context(), userID.(string)); err != nil {
+	if err := h.authService.Logout(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
 		return
 	}
+	h.clearAuthCookies(c)
 	c.Status(http.StatusNoContent)
 }
 
+// LogoutAll ออกจากระบบทุกอุปกรณ์ ทุก access token และ refresh token ของผู้ใช้ใช้ไม่ได้ทันที
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	revoked, err := h.authService.LogoutAll(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+	h.clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"sessions_revoked": revoked})
+}
+
 // func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 // 	userID, _ := c.Get("user_id")
 // 	user, err := h.authService.GetUserByID(c.Request.Context(), userID.(string))
@@ -65,20 +146,9 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 // 	c.JSON(http.StatusOK, user)
 // }
 
+// GetCurrentUser ดึงข้อมูลผู้ใช้ของ access token ที่ AuthMiddleware ตรวจแล้ว (รวมถึงว่า session ยังไม่ logout)
 func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
-	token, err := c.Cookie("token")
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid token"})
-		return
-	}
-
-	userID, err := utils.ParseToken(token, h.authService.Cfg.JWTSecret)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		return
-	}
-
-	user, err := h.authService.GetUserByID(c.Request.Context(), userID)
+	user, err := h.authService.GetUserByID(c.Request.Context(), c.GetString("user_id"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user information"})
 		return
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"login/internal/model"
+	"login/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLoginHistoryLimit = 20
+	maxLoginHistoryLimit     = 200
+)
+
+// loginHistoryLimit อ่าน ?limit= (ค่าเริ่มต้น 20 สูงสุด 200)
+func loginHistoryLimit(c *gin.Context) (int, bool) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return defaultLoginHistoryLimit, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+		return 0, false
+	}
+	return min(limit, maxLoginHistoryLimit), true
+}
+
+// GetMyLogins ดึงการล็อกอินล่าสุดของผู้ใช้ปัจจุบัน (รวมถึงครั้งที่ไม่สำเร็จ) ให้ผู้ใช้ตรวจสอบการเข้าใช้งานบัญชี
+func (h *AuthHandler) GetMyLogins(c *gin.Context) {
+	limit, ok := loginHistoryLimit(c)
+	if !ok {
+		return
+	}
+	logins, err := h.authService.GetLoginHistory(c.Request.Context(), c.GetString("user_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get login history"})
+		return
+	}
+	c.JSON(http.StatusOK, logins)
+}
+
+// SearchLogins ค้นหาประวัติการล็อกอินของทุกผู้ใช้สำหรับผู้ดูแลระบบ
+// กรองด้วย ?user_id= ?ip= ?success=true|false และ ?since= (RFC 3339) เช่น ?success=false&ip=203.0.113.7
+func (h *AuthHandler) SearchLogins(c *gin.Context) {
+	limit, ok := loginHistoryLimit(c)
+	if !ok {
+		return
+	}
+	filter := model.LoginFilter{UserID: c.Query("user_id"), Limit: limit}
+	if filter.UserID != "" && !utils.ValidUUID(filter.UserID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be a UUID"})
+		return
+	}
+
+	if ip := c.Query("ip"); ip != "" {
+		if net.ParseIP(ip) == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ip must be a valid IP address"})
+			return
+		}
+		filter.IPAddress = ip
+	}
+	if raw := c.Query("success"); raw != "" {
+		success, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "success must be true or false"})
+			return
+		}
+		filter.Success = &success
+	}
+	if raw := c.Query("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+			return
+		}
+		filter.Since = since
+	}
+
+	logins, err := h.authService.SearchLoginHistory(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get login history"})
+		return
+	}
+	c.JSON(http.StatusOK, logins)
+}
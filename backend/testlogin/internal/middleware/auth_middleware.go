@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
@@ -10,7 +11,14 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// SessionChecker ตรวจว่า session ของ access token ยังไม่ถูก logout หรือ revoke และ tokenID (jti) ยังเป็น token ล่าสุดของ session
+type SessionChecker interface {
+	SessionActive(ctx context.Context, sessionID, tokenID string) (bool, error)
+}
+
+// AuthMiddleware ตรวจสอบ access token และ session ของ token แล้วเก็บ user_id, role และ session_id ไว้ใน context
+// token ที่ไม่มี session (ออกก่อนมี refresh token) หรือ session ถูก logout แล้วจะถูกปฏิเสธ
+func AuthMiddleware(cfg *config.Config, sessions SessionChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -27,13 +35,27 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		claims, err := utils.VerifyToken(parts[1], cfg.JWTSecret)
-		if err != nil {
+		if err != nil || !utils.ValidUUID(claims.Subject) || !utils.ValidUUID(claims.SessionID) || !utils.ValidUUID(claims.Id) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
+		active, err := sessions.SessionActive(c.Request.Context(), claims.SessionID, claims.Id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify session"})
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", claims.Subject)
+		c.Set("role", claims.Role)
+		c.Set("session_id", claims.SessionID)
 		c.Next()
 	}
 }
@@ -55,3 +77,17 @@ func APIKeyMiddleware(cfg *config.Config) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireRole อนุญาตเฉพาะผู้ใช้ที่ role ใน access token อยู่ใน roles ต้องใช้หลัง AuthMiddleware
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, r := range roles {
+			if r == role {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	}
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// สาเหตุของการล็อกอินที่ไม่สำเร็จ (user_login_history.failure_reason)
+const (
+	LoginFailureInvalidToken = "invalid_google_token"
+	LoginFailureInternal     = "internal_error"
+)
+
+// LoginAttempt คือการพยายามล็อกอินหนึ่งครั้ง UserID เป็น nil เมื่อไม่รู้ว่าเป็นผู้ใช้คนไหน
+type LoginAttempt struct {
+	ID            string    `json:"id" db:"login_id"`
+	UserID        *string   `json:"user_id,omitempty" db:"user_id"`
+	Timestamp     time.Time `json:"timestamp" db:"login_timestamp"`
+	IPAddress     *string   `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent     *string   `json:"user_agent,omitempty" db:"user_agent"`
+	Success       bool      `json:"success" db:"success"`
+	FailureReason *string   `json:"failure_reason,omitempty" db:"failure_reason"`
+}
+
+// LoginFilter คือเงื่อนไขค้นหาประวัติการล็อกอินของผู้ดูแลระบบ ค่าว่าง/nil คือไม่กรอง
+type LoginFilter struct {
+	UserID    string
+	IPAddress string
+	Success   *bool
+	Since     time.Time
+	Limit     int
+}
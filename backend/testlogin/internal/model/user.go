@@ -16,7 +16,11 @@ type User struct {
 	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// AuthResponse คือ token ที่ออกให้ผู้ใช้ ExpiresIn คืออายุของ access token เป็นวินาที
+// RefreshToken ใช้ได้ครั้งเดียวกับ /auth/refresh ซึ่งจะคืน refresh token ใหม่ทุกครั้ง
 type AuthResponse struct {
-	AccessToken string `json:"access_token"`
-	User        *User  `json:"user"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	User         *User  `json:"user"`
 }
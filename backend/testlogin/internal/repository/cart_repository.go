@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type CartRepository struct {
+	db *sqlx.DB
+}
+
+func NewCartRepository(db *sqlx.DB) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+// reservationTTL คืออายุการจองสต็อกของรายการในตะกร้า ต้องตรงกับ clothesstore.ReservationTTL ของ clothesproject
+const reservationTTL = 15 * time.Minute
+
+// MergeGuestCart ย้ายสินค้าในตะกร้าของ guest token ไปยังตะกร้าของผู้ใช้ คืนจำนวนรายการที่ถูกลดจำนวนเพราะสต็อกไม่พอ
+// ทั้งหมดทำใน transaction เดียว (ดู mergeGuestCart)
+func (r *CartRepository) MergeGuestCart(ctx context.Context, guestToken, userID string) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	clamped, err := mergeGuestCart(ctx, postgresCartTx{tx: tx}, guestToken, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return clamped, nil
+}
+
+// cartLine คือรายการหนึ่งในตะกร้า Price คือราคาปัจจุบันของ SKU
+type cartLine struct {
+	CartID    int    `db:"cart_id"`
+	VariantID int    `db:"variant_id"`
+	Quantity  int    `db:"quantity"`
+	Price     string `db:"price"`
+}
+
+// cartTx คือการอ่านและแก้ตะกร้ากับสต็อกภายใน transaction ที่ mergeGuestCart ใช้
+type cartTx interface {
+	// GuestLines ล็อกและคืนรายการในตะกร้าของ guest token เรียงตาม variant_id
+	GuestLines(ctx context.Context, guestToken string) ([]cartLine, error)
+	// UserLine ล็อกและคืนรายการ SKU นี้ในตะกร้าของผู้ใช้ CartID เป็น 0 ถ้ายังไม่มี
+	UserLine(ctx context.Context, userID string, variantID int) (cartLine, error)
+	// AvailableStock ล็อกสต็อกของ SKU แล้วคืนจำนวนที่ยังใช้ได้ โดยไม่นับการจองของ excludeCartIDs
+	AvailableStock(ctx context.Context, variantID int, excludeCartIDs ...int) (int, error)
+	// SetUserLine ตั้งจำนวนและราคาของ SKU ในตะกร้าของผู้ใช้ (สร้างใหม่ถ้ายังไม่มี) และคืน cart_id
+	SetUserLine(ctx context.Context, userID string, line cartLine) (int, error)
+	Reserve(ctx context.Context, cartID, variantID, quantity int) error
+	DeleteLine(ctx context.Context, cartID int) error
+	// ClearGuestCart ลบตะกร้าของ guest token พร้อมการจองของรายการเหล่านั้น
+	ClearGuestCart(ctx context.Context, guestToken string) error
+}
+
+// mergeGuestCart ย้ายสินค้าในตะกร้าของ guest token ไปยังตะกร้าของผู้ใช้ คืนจำนวนรายการที่ถูกลดจำนวนเพราะสต็อกไม่พอ
+// ถ้าผู้ใช้มี SKU เดียวกันอยู่แล้วจะรวมจำนวนเข้าด้วยกันและใช้ราคาปัจจุบันของ SKU
+// รายการที่รวมแล้วถูกจองสต็อกใหม่แบบเดียวกับการเพิ่มลงตะกร้า (ล็อกแถว inventory แล้วนับการจองของคนอื่น)
+// ถ้าสต็อกที่เหลือไม่พอจะลดจำนวนลงเท่าที่มี และลบรายการที่ไม่เหลือสต็อกเลย
+// ตะกร้าของ guest ถูกลบเมื่อรวมเสร็จ การใช้ guest token เดิมซ้ำจึงไม่เพิ่มสินค้าอีก
+func mergeGuestCart(ctx context.Context, tx cartTx, guestToken, userID string) (int, error) {
+	guestLines, err := tx.GuestLines(ctx, guestToken)
+	if err != nil {
+		return 0, err
+	}
+
+	clamped := 0
+	for _, line := range guestLines {
+		userLine, err := tx.UserLine(ctx, userID, line.VariantID)
+		if err != nil {
+			return 0, err
+		}
+
+		available, err := tx.AvailableStock(ctx, line.VariantID, line.CartID, userLine.CartID)
+		if err != nil {
+			return 0, err
+		}
+		quantity := min(userLine.Quantity+line.Quantity, available)
+		if quantity < userLine.Quantity+line.Quantity {
+			clamped++
+		}
+
+		if quantity == 0 {
+			if userLine.CartID != 0 {
+				if err := tx.DeleteLine(ctx, userLine.CartID); err != nil {
+					return 0, err
+				}
+			}
+			continue
+		}
+
+		cartID, err := tx.SetUserLine(ctx, userID, cartLine{VariantID: line.VariantID, Quantity: quantity, Price: line.Price})
+		if err != nil {
+			return 0, err
+		}
+		if err := tx.Reserve(ctx, cartID, line.VariantID, quantity); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.ClearGuestCart(ctx, guestToken); err != nil {
+		return 0, err
+	}
+	return clamped, nil
+}
+
+// postgresCartTx คือ cartTx บน transaction ของ PostgreSQL
+type postgresCartTx struct {
+	tx *sqlx.Tx
+}
+
+func (t postgresCartTx) GuestLines(ctx context.Context, guestToken string) ([]cartLine, error) {
+	var lines []cartLine
+	// ล็อกตามลำดับ variant_id เหมือนกันทุก transaction เพื่อไม่ให้ deadlock กับการเพิ่มลงตะกร้า
+	query := `
+		SELECT g.cart_id, g.variant_id, g.quantity, COALESCE(v.price, p.price)::text AS price
+		FROM cart g
+		JOIN product_variants v ON v.variant_id = g.variant_id
+		JOIN products p ON p.id = v.product_id
+		WHERE g.guest_token = $1
+		ORDER BY g.variant_id
+		FOR UPDATE OF g
+	`
+	if err := t.tx.SelectContext(ctx, &lines, query, guestToken); err != nil {
+		return nil, fmt.Errorf("failed to get guest cart: %w", err)
+	}
+	return lines, nil
+}
+
+func (t postgresCartTx) UserLine(ctx context.Context, userID string, variantID int) (cartLine, error) {
+	line := cartLine{VariantID: variantID}
+	err := t.tx.QueryRowContext(ctx, "SELECT cart_id, quantity FROM cart WHERE user_id = $1 AND variant_id = $2 FOR UPDATE",
+		userID, variantID).Scan(&line.CartID, &line.Quantity)
+	if err != nil && err != sql.ErrNoRows {
+		return cartLine{}, fmt.Errorf("failed to get user cart line: %w", err)
+	}
+	return line, nil
+}
+
+func (t postgresCartTx) AvailableStock(ctx context.Context, variantID int, excludeCartIDs ...int) (int, error) {
+	return lockAvailableStock(ctx, t.tx, variantID, excludeCartIDs...)
+}
+
+func (t postgresCartTx) SetUserLine(ctx context.Context, userID string, line cartLine) (int, error) {
+	var cartID int
+	err := t.tx.QueryRowContext(ctx, `
+		INSERT INTO cart (user_id, variant_id, quantity, price)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, variant_id) DO UPDATE
+		SET quantity = EXCLUDED.quantity,
+			price = EXCLUDED.price,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING cart_id
+	`, userID, line.VariantID, line.Quantity, line.Price).Scan(&cartID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to merge guest cart: %w", err)
+	}
+	return cartID, nil
+}
+
+func (t postgresCartTx) Reserve(ctx context.Context, cartID, variantID, quantity int) error {
+	_, err := t.tx.ExecContext(ctx, `
+		INSERT INTO stock_reservations (cart_id, variant_id, quantity, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+		ON CONFLICT (cart_id) DO UPDATE
+		SET quantity = EXCLUDED.quantity, expires_at = EXCLUDED.expires_at
+	`, cartID, variantID, quantity, reservationTTL.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+	return nil
+}
+
+func (t postgresCartTx) DeleteLine(ctx context.Context, cartID int) error {
+	if _, err := t.tx.ExecContext(ctx, "DELETE FROM cart WHERE cart_id = $1", cartID); err != nil {
+		return fmt.Errorf("failed to remove out-of-stock cart line: %w", err)
+	}
+	return nil
+}
+
+func (t postgresCartTx) ClearGuestCart(ctx context.Context, guestToken string) error {
+	// การจองของรายการ guest ถูกลบตามไปด้วย (ON DELETE CASCADE) หลังย้ายไปเป็นการจองของผู้ใช้แล้ว
+	if _, err := t.tx.ExecContext(ctx, "DELETE FROM cart WHERE guest_token = $1", guestToken); err != nil {
+		return fmt.Errorf("failed to clear guest cart: %w", err)
+	}
+	return nil
+}
+
+// lockAvailableStock ล็อกแถวสต็อกของ SKU (FOR UPDATE) แล้วคืนจำนวนที่ยังใช้ได้ เหมือน lockAvailableStock ของ clothesproject
+// โดยไม่นับการจองของรายการในตะกร้า excludeCartIDs (รายการที่กำลังรวมกัน)
+func lockAvailableStock(ctx context.Context, tx *sqlx.Tx, variantID int, excludeCartIDs ...int) (int, error) {
+	var onHand int
+	err := tx.QueryRowContext(ctx, "SELECT on_hand FROM inventory WHERE variant_id = $1 FOR UPDATE", variantID).Scan(&onHand)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to lock stock: %w", err)
+	}
+
+	var reserved int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(quantity), 0)
+		FROM stock_reservations
+		WHERE variant_id = $1 AND cart_id <> ALL($2) AND expires_at > NOW()
+	`, variantID, pq.Array(excludeCartIDs)).Scan(&reserved)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum reservations: %w", err)
+	}
+
+	return max(onHand-reserved, 0), nil
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type memoryCartLine struct {
+	userID, guestToken string
+	variantID          int
+	quantity           int
+	price              string
+}
+
+// memoryCart คือ cartTx ในหน่วยความจำ ทำงานแบบเดียวกับตาราง cart, inventory และ stock_reservations
+// prices คือราคาปัจจุบันของแต่ละ SKU ส่วน reserved คือการจองตาม cart_id
+type memoryCart struct {
+	lines    map[int]*memoryCartLine
+	onHand   map[int]int
+	reserved map[int]int
+	prices   map[int]string
+	nextID   int
+}
+
+func newMemoryCart(onHand map[int]int, prices map[int]string) *memoryCart {
+	return &memoryCart{lines: map[int]*memoryCartLine{}, onHand: onHand, reserved: map[int]int{}, prices: prices}
+}
+
+// add เพิ่มรายการที่จองสต็อกไว้แล้ว แบบเดียวกับการเพิ่มลงตะกร้า
+func (m *memoryCart) add(line memoryCartLine) int {
+	m.nextID++
+	m.lines[m.nextID] = &line
+	m.reserved[m.nextID] = line.quantity
+	return m.nextID
+}
+
+func (m *memoryCart) GuestLines(ctx context.Context, guestToken string) ([]cartLine, error) {
+	var lines []cartLine
+	for id, line := range m.lines {
+		if line.guestToken == guestToken {
+			lines = append(lines, cartLine{CartID: id, VariantID: line.variantID, Quantity: line.quantity, Price: m.prices[line.variantID]})
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].VariantID < lines[j].VariantID })
+	return lines, nil
+}
+
+func (m *memoryCart) UserLine(ctx context.Context, userID string, variantID int) (cartLine, error) {
+	for id, line := range m.lines {
+		if line.userID == userID && line.variantID == variantID {
+			return cartLine{CartID: id, VariantID: variantID, Quantity: line.quantity, Price: line.price}, nil
+		}
+	}
+	return cartLine{VariantID: variantID}, nil
+}
+
+func (m *memoryCart) AvailableStock(ctx context.Context, variantID int, excludeCartIDs ...int) (int, error) {
+	available := m.onHand[variantID]
+	for id, quantity := range m.reserved {
+		excluded := false
+		for _, exclude := range excludeCartIDs {
+			excluded = excluded || exclude == id
+		}
+		if !excluded && m.lines[id].variantID == variantID {
+			available -= quantity
+		}
+	}
+	return max(available, 0), nil
+}
+
+func (m *memoryCart) SetUserLine(ctx context.Context, userID string, line cartLine) (int, error) {
+	existing, err := m.UserLine(ctx, userID, line.VariantID)
+	if err != nil {
+		return 0, err
+	}
+	if existing.CartID == 0 {
+		m.nextID++
+		existing.CartID = m.nextID
+		m.lines[existing.CartID] = &memoryCartLine{userID: userID, variantID: line.VariantID}
+	}
+	m.lines[existing.CartID].quantity = line.Quantity
+	m.lines[existing.CartID].price = line.Price
+	return existing.CartID, nil
+}
+
+func (m *memoryCart) Reserve(ctx context.Context, cartID, variantID, quantity int) error {
+	m.reserved[cartID] = quantity
+	return nil
+}
+
+func (m *memoryCart) DeleteLine(ctx context.Context, cartID int) error {
+	delete(m.lines, cartID)
+	delete(m.reserved, cartID)
+	return nil
+}
+
+func (m *memoryCart) ClearGuestCart(ctx context.Context, guestToken string) error {
+	for id, line := range m.lines {
+		if line.guestToken == guestToken {
+			m.DeleteLine(ctx, id)
+		}
+	}
+	return nil
+}
+
+type userCartLine struct {
+	quantity, reserved int
+	price              string
+}
+
+// userCart คืนจำนวน จำนวนที่จองไว้ และราคา ของแต่ละ SKU ในตะกร้าผู้ใช้
+func (m *memoryCart) userCart(userID string) map[int]userCartLine {
+	cart := map[int]userCartLine{}
+	for id, line := range m.lines {
+		if line.userID == userID {
+			cart[line.variantID] = userCartLine{quantity: line.quantity, reserved: m.reserved[id], price: line.price}
+		}
+	}
+	return cart
+}
+
+func (m *memoryCart) guestLineCount(guestToken string) int {
+	count := 0
+	for _, line := range m.lines {
+		if line.guestToken == guestToken {
+			count++
+		}
+	}
+	return count
+}
+
+const (
+	testGuest = "guest-1"
+	testUser  = "11111111-1111-4111-8111-111111111111"
+)
+
+func TestMergeGuestCartIntoExistingLine(t *testing.T) {
+	cart := newMemoryCart(map[int]int{1: 10, 2: 10}, map[int]string{1: "450.00", 2: "990.00"})
+	cart.add(memoryCartLine{userID: testUser, variantID: 1, quantity: 2, price: "390.00"})
+	cart.add(memoryCartLine{guestToken: testGuest, variantID: 1, quantity: 3})
+	cart.add(memoryCartLine{guestToken: testGuest, variantID: 2, quantity: 1})
+
+	clamped, err := mergeGuestCart(context.Background(), cart, testGuest, testUser)
+	if err != nil {
+		t.Fatalf("mergeGuestCart = %v", err)
+	}
+	if clamped != 0 {
+		t.Errorf("clamped = %d, want 0", clamped)
+	}
+	// รวมจำนวนเข้ากับรายการเดิมและใช้ราคาปัจจุบันของ SKU
+	want := map[int]userCartLine{
+		1: {quantity: 5, reserved: 5, price: "450.00"},
+		2: {quantity: 1, reserved: 1, price: "990.00"},
+	}
+	if got := cart.userCart(testUser); !reflect.DeepEqual(got, want) {
+		t.Errorf("user cart = %+v, want %+v", got, want)
+	}
+	if n := cart.guestLineCount(testGuest); n != 0 {
+		t.Errorf("guest cart still has %d lines", n)
+	}
+}
+
+func TestMergeGuestCartClampsToAvailableStock(t *testing.T) {
+	cart := newMemoryCart(map[int]int{1: 5, 2: 0, 3: 2}, map[int]string{1: "450.00", 2: "990.00", 3: "120.00"})
+	// ผู้ซื้อคนอื่นจองไว้ 1 ชิ้น เหลือให้ผู้ใช้ 4 ชิ้น ส่วนการจองของรายการที่กำลังรวมกันไม่ถูกนับ
+	cart.add(memoryCartLine{userID: "someone-else", variantID: 1, quantity: 1})
+	cart.add(memoryCartLine{userID: testUser, variantID: 1, quantity: 2})
+	cart.add(memoryCartLine{guestToken: testGuest, variantID: 1, quantity: 3})
+	// SKU ที่หมดสต็อก รายการเดิมของผู้ใช้ถูกลบ
+	cart.add(memoryCartLine{userID: testUser, variantID: 2, quantity: 1})
+	cart.add(memoryCartLine{guestToken: testGuest, variantID: 2, quantity: 1})
+	// สต็อกพอดี ไม่ถูกลด
+	cart.add(memoryCartLine{guestToken: testGuest, variantID: 3, quantity: 2})
+
+	clamped, err := mergeGuestCart(context.Background(), cart, testGuest, testUser)
+	if err != nil {
+		t.Fatalf("mergeGuestCart = %v", err)
+	}
+	if clamped != 2 {
+		t.Errorf("clamped = %d, want 2", clamped)
+	}
+	want := map[int]userCartLine{
+		1: {quantity: 4, reserved: 4, price: "450.00"},
+		3: {quantity: 2, reserved: 2, price: "120.00"},
+	}
+	if got := cart.userCart(testUser); !reflect.DeepEqual(got, want) {
+		t.Errorf("user cart = %+v, want %+v", got, want)
+	}
+}
+
+func TestMergeGuestCartReusedGuestToken(t *testing.T) {
+	cart := newMemoryCart(map[int]int{1: 10}, map[int]string{1: "450.00"})
+	cart.add(memoryCartLine{guestToken: testGuest, variantID: 1, quantity: 3})
+	ctx := context.Background()
+
+	if _, err := mergeGuestCart(ctx, cart, testGuest, testUser); err != nil {
+		t.Fatalf("first merge = %v", err)
+	}
+	// ล็อกอินซ้ำด้วย guest token เดิม (เช่น client ส่ง cookie เดิมมาอีก) ต้องไม่เพิ่มจำนวนซ้ำ
+	if _, err := mergeGuestCart(ctx, cart, testGuest, testUser); err != nil {
+		t.Fatalf("second merge = %v", err)
+	}
+	// และผู้ใช้อื่นที่ได้ guest token เดิมไปไม่ได้สินค้าของตะกร้านั้น
+	other := "22222222-2222-4222-8222-222222222222"
+	if _, err := mergeGuestCart(ctx, cart, testGuest, other); err != nil {
+		t.Fatalf("merge for another user = %v", err)
+	}
+
+	want := map[int]userCartLine{1: {quantity: 3, reserved: 3, price: "450.00"}}
+	if got := cart.userCart(testUser); !reflect.DeepEqual(got, want) {
+		t.Errorf("user cart = %+v, want %+v", got, want)
+	}
+	if got := cart.userCart(other); len(got) != 0 {
+		t.Errorf("other user cart = %+v, want empty", got)
+	}
+}
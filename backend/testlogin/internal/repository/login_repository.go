@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"login/internal/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const loginColumns = `login_id, user_id, login_timestamp, host(ip_address) AS ip_address, user_agent, success, failure_reason`
+
+type LoginRepository struct {
+	db *sqlx.DB
+}
+
+func NewLoginRepository(db *sqlx.DB) *LoginRepository {
+	return &LoginRepository{db: db}
+}
+
+// RecordLogin บันทึกการพยายามล็อกอิน ip และ user agent ที่ว่างเก็บเป็น NULL
+func (r *LoginRepository) RecordLogin(ctx context.Context, userID string, ip string, userAgent string, failureReason string) error {
+	query := `
+		INSERT INTO user_login_history (user_id, ip_address, user_agent, success, failure_reason)
+		VALUES (NULLIF($1, '')::uuid, NULLIF($2, '')::inet, NULLIF($3, ''), $4 = '', NULLIF($4, ''))
+	`
+	if _, err := r.db.ExecContext(ctx, query, userID, ip, userAgent, failureReason); err != nil {
+		return fmt.Errorf("failed to record login: %w", err)
+	}
+	return nil
+}
+
+// ListUserLogins ดึงการล็อกอินล่าสุดของผู้ใช้ limit รายการ เรียงจากใหม่ไปเก่า
+func (r *LoginRepository) ListUserLogins(ctx context.Context, userID string, limit int) ([]model.LoginAttempt, error) {
+	return r.SearchLogins(ctx, model.LoginFilter{UserID: userID, Limit: limit})
+}
+
+// SearchLogins ค้นหาประวัติการล็อกอินตาม filter เรียงจากใหม่ไปเก่า
+func (r *LoginRepository) SearchLogins(ctx context.Context, filter model.LoginFilter) ([]model.LoginAttempt, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.UserID != "" {
+		add("user_id = $%d::uuid", filter.UserID)
+	}
+	if filter.IPAddress != "" {
+		add("ip_address = $%d::inet", filter.IPAddress)
+	}
+	if filter.Success != nil {
+		add("success = $%d", *filter.Success)
+	}
+	if !filter.Since.IsZero() {
+		add("login_timestamp >= $%d", filter.Since)
+	}
+
+	query := "SELECT " + loginColumns + " FROM user_login_history"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY login_timestamp DESC LIMIT $%d", len(args))
+
+	logins := []model.LoginAttempt{}
+	if err := r.db.SelectContext(ctx, &logins, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to fetch login history: %w", err)
+	}
+	return logins, nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrSessionNotFound คืนค่าเมื่อไม่พบ session ของ refresh token
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionExpired คืนค่าเมื่อ session หมดอายุหรือถูก revoke แล้ว
+	ErrSessionExpired = errors.New("session expired or revoked")
+	// ErrRefreshTokenReused คืนค่าเมื่อมีการใช้ refresh token ที่หมุนไปแล้วซ้ำ session จะถูก revoke ทันที
+	ErrRefreshTokenReused = errors.New("refresh token reused, session revoked")
+)
+
+type SessionRepository struct {
+	db *sqlx.DB
+}
+
+func NewSessionRepository(db *sqlx.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// CreateSession สร้าง session ใหม่ของผู้ใช้พร้อม hash ของ refresh token แรก
+// คืน session_id และ access_token_id ที่ใช้เป็น jti ของ access token แรก
+func (r *SessionRepository) CreateSession(ctx context.Context, userID, tokenHash string, expiresAt time.Time) (string, string, error) {
+	query := `
+		INSERT INTO user_sessions (user_id, refresh_token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING session_id, access_token_id
+	`
+	var sessionID, accessTokenID string
+	if err := r.db.QueryRowContext(ctx, query, userID, tokenHash, expiresAt).Scan(&sessionID, &accessTokenID); err != nil {
+		return "", "", fmt.Errorf("failed to create session: %w", err)
+	}
+	return sessionID, accessTokenID, nil
+}
+
+// RotateSession เปลี่ยน refresh token ของ session จาก presentedHash เป็น newHash และต่ออายุ session ถึง expiresAt
+// คืน user_id เจ้าของ session และ access_token_id ใหม่ (access token เดิมของ session ใช้ไม่ได้อีก) ถ้า presentedHash ไม่ใช่ token ปัจจุบันแปลว่า token เก่าถูกนำมาใช้ซ้ำ
+// (ถูกขโมยหรือถูกใช้ไปแล้ว) จึง revoke session ทั้งหมดและคืน ErrRefreshTokenReused
+func (r *SessionRepository) RotateSession(ctx context.Context, sessionID, presentedHash, newHash string, expiresAt time.Time) (string, string, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var session refreshSession
+	query := `
+		SELECT user_id, refresh_token_hash, expires_at, revoked_at
+		FROM user_sessions
+		WHERE session_id = $1::uuid
+		FOR UPDATE
+	`
+	if err := tx.GetContext(ctx, &session, query, sessionID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", ErrSessionNotFound
+		}
+		return "", "", fmt.Errorf("failed to get session: %w", err)
+	}
+	if err := session.verify(presentedHash, time.Now()); err != nil {
+		if !errors.Is(err, ErrRefreshTokenReused) {
+			return "", "", err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE session_id = $1::uuid", sessionID); err != nil {
+			return "", "", fmt.Errorf("failed to revoke session: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return "", "", fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return "", "", ErrRefreshTokenReused
+	}
+
+	var accessTokenID string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE user_sessions
+		SET refresh_token_hash = $2, expires_at = $3, access_token_id = uuid_generate_v4(), updated_at = CURRENT_TIMESTAMP
+		WHERE session_id = $1::uuid
+		RETURNING access_token_id
+	`, sessionID, newHash, expiresAt).Scan(&accessTokenID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to rotate session: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return session.UserID, accessTokenID, nil
+}
+
+// refreshSession คือสถานะของ session ที่ RotateSession อ่านมาตรวจ refresh token
+type refreshSession struct {
+	UserID    string       `db:"user_id"`
+	TokenHash string       `db:"refresh_token_hash"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	RevokedAt sql.NullTime `db:"revoked_at"`
+}
+
+// verify ตรวจว่า presentedHash หมุน session นี้ได้ ณ เวลา now
+// คืน ErrSessionExpired ถ้า session ถูก revoke หรือหมดอายุ และ ErrRefreshTokenReused ถ้าไม่ใช่ token ปัจจุบันของ session
+func (s refreshSession) verify(presentedHash string, now time.Time) error {
+	if s.RevokedAt.Valid || !s.ExpiresAt.After(now) {
+		return ErrSessionExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(s.TokenHash), []byte(presentedHash)) != 1 {
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+// SessionActive ตรวจว่า session ยังใช้งานได้ (ยังไม่ logout ถูก revoke หรือหมดอายุ) และ tokenID (jti)
+// เป็น access token ล่าสุดของ session AuthMiddleware เรียกทุก request เพื่อให้ access token ที่ถูก logout
+// หรือถูกแทนด้วยการ refresh แล้วใช้ไม่ได้ทันที
+func (r *SessionRepository) SessionActive(ctx context.Context, sessionID, tokenID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_sessions
+			WHERE session_id = $1::uuid AND access_token_id = $2::uuid
+			  AND revoked_at IS NULL AND expires_at > NOW()
+		)
+	`
+	var active bool
+	if err := r.db.QueryRowContext(ctx, query, sessionID, tokenID).Scan(&active); err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return active, nil
+}
+
+// DeleteSession ลบ session ของผู้ใช้ ทั้ง refresh token และ access token ของ session นี้จะใช้ไม่ได้อีก
+func (r *SessionRepository) DeleteSession(ctx context.Context, userID, sessionID string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM user_sessions WHERE session_id = $1::uuid AND user_id = $2", sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserSessions ลบทุก session ของผู้ใช้ (ออกจากระบบทุกอุปกรณ์) คืนจำนวน session ที่ลบ
+func (r *SessionRepository) DeleteUserSessions(ctx context.Context, userID string) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM user_sessions WHERE user_id = $1", userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRefreshSessionVerify(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	active := refreshSession{UserID: "u1", TokenHash: "current", ExpiresAt: now.Add(time.Hour)}
+	revoked := active
+	revoked.RevokedAt = sql.NullTime{Time: now.Add(-time.Minute), Valid: true}
+	expired := active
+	expired.ExpiresAt = now
+
+	tests := []struct {
+		name      string
+		session   refreshSession
+		presented string
+		want      error
+	}{
+		{name: "current token", session: active, presented: "current", want: nil},
+		{name: "rotated token is reuse", session: active, presented: "previous", want: ErrRefreshTokenReused},
+		{name: "revoked session", session: revoked, presented: "current", want: ErrSessionExpired},
+		{name: "revoked session with old token", session: revoked, presented: "previous", want: ErrSessionExpired},
+		{name: "expired session", session: expired, presented: "current", want: ErrSessionExpired},
+	}
+	for _, tt := range tests {
+		if err := tt.session.verify(tt.presented, now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: verify = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
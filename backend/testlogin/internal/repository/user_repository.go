@@ -63,3 +63,9 @@ func (r *UserRepository) GetUserByID(ctx context.Context, userID string) (*model
 	}
 	return &user, err
 }
+
+// UpdateLastLogin บันทึกเวลาที่ผู้ใช้ล็อกอินสำเร็จครั้งล่าสุด
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE users SET last_login_at = CURRENT_TIMESTAMP WHERE user_id = $1", userID)
+	return err
+}
@@ -3,8 +3,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"login/internal/config"
 	"login/internal/model"
@@ -14,29 +16,56 @@ import (
 	"google.golang.org/api/idtoken"
 )
 
+var (
+	// ErrInvalidRefreshToken คืนค่าเมื่อ refresh token ผิดรูปแบบ ไม่มี session หมดอายุ หรือผู้ใช้ไม่มีอยู่หรือถูกระงับ
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused คืนค่าเมื่อ refresh token ที่หมุนไปแล้วถูกใช้ซ้ำ session ถูก revoke และต้องล็อกอินใหม่
+	ErrRefreshTokenReused = repository.ErrRefreshTokenReused
+)
+
+// userStore คือเมธอดของ UserRepository ที่ AuthService ใช้
+type userStore interface {
+	CreateUser(ctx context.Context, user *model.User) error
+	GetUserByGoogleID(ctx context.Context, googleID string) (*model.User, error)
+	GetUserByID(ctx context.Context, userID string) (*model.User, error)
+	UpdateLastLogin(ctx context.Context, userID string) error
+}
+
+// sessionStore คือเมธอดของ SessionRepository ที่ AuthService ใช้
+type sessionStore interface {
+	CreateSession(ctx context.Context, userID, tokenHash string, expiresAt time.Time) (string, string, error)
+	RotateSession(ctx context.Context, sessionID, presentedHash, newHash string, expiresAt time.Time) (string, string, error)
+	DeleteSession(ctx context.Context, userID, sessionID string) error
+	DeleteUserSessions(ctx context.Context, userID string) (int64, error)
+}
+
 type AuthService struct {
-	userRepo *repository.UserRepository
-	Cfg      *config.Config
+	userRepo    userStore
+	cartRepo    *repository.CartRepository
+	sessionRepo sessionStore
+	loginRepo   *repository.LoginRepository
+	Cfg         *config.Config
 }
 
-func NewAuthService(userRepo *repository.UserRepository, cfg *config.Config) *AuthService {
-	return &AuthService{userRepo: userRepo, Cfg: cfg}
+func NewAuthService(userRepo *repository.UserRepository, cartRepo *repository.CartRepository, sessionRepo *repository.SessionRepository, loginRepo *repository.LoginRepository, cfg *config.Config) *AuthService {
+	return &AuthService{userRepo: userRepo, cartRepo: cartRepo, sessionRepo: sessionRepo, loginRepo: loginRepo, Cfg: cfg}
 }
 
 func (s *AuthService) GetClientID() (string, error) {
 	return s.Cfg.GoogleClientID, nil
 }
 
-func (s *AuthService) VerifyGoogleToken(ctx context.Context, idToken string) (*model.AuthResponse, error) {
-	log.Println(idToken, s.Cfg.GoogleClientID)
+// VerifyGoogleToken ตรวจสอบ Google ID token และออก JWT ให้ผู้ใช้
+// ถ้ามี guestToken จะย้ายสินค้าในตะกร้าของ guest ไปรวมกับตะกร้าของผู้ใช้
+// ทุกครั้งที่เรียก (รวมถึงที่ไม่สำเร็จ) ถูกบันทึกใน user_login_history พร้อม ip และ userAgent ของ client
+func (s *AuthService) VerifyGoogleToken(ctx context.Context, idToken string, guestToken string, ip string, userAgent string) (*model.AuthResponse, error) {
 	payload, err := idtoken.Validate(ctx, idToken, s.Cfg.GoogleClientID)
 	if err != nil {
 		log.Println("Google ID Token validation failed:", err)
+		s.recordLogin(ctx, "", ip, userAgent, model.LoginFailureInvalidToken)
 		return nil, err
 	}
 
-	log.Println("Google ID Token validation successful:", payload)
-
 	user, err := s.userRepo.GetUserByGoogleID(ctx, payload.Subject)
 	if err != nil {
 		log.Println("Error retrieving user by Google ID:", err)
@@ -56,24 +85,121 @@ func (s *AuthService) VerifyGoogleToken(ctx context.Context, idToken string) (*m
 		err = s.userRepo.CreateUser(ctx, user)
 		if err != nil {
 			log.Println("Error creating user:", err)
+			s.recordLogin(ctx, "", ip, userAgent, model.LoginFailureInternal)
 			return nil, err
 		}
 	}
 
-	token, err := utils.GenerateToken(user.ID, s.Cfg.JWTSecret)
+	if guestToken != "" {
+		// การ merge ตะกร้าไม่ควรทำให้การล็อกอินล้มเหลว
+		if clamped, err := s.cartRepo.MergeGuestCart(ctx, guestToken, user.ID); err != nil {
+			log.Println("Error merging guest cart:", err)
+		} else if clamped > 0 {
+			log.Printf("Reduced %d merged cart lines to the available stock", clamped)
+		}
+	}
+
+	secret, err := utils.NewRefreshSecret()
+	if err != nil {
+		s.recordLogin(ctx, user.ID, ip, userAgent, model.LoginFailureInternal)
+		return nil, err
+	}
+	sessionID, tokenID, err := s.sessionRepo.CreateSession(ctx, user.ID, utils.HashRefreshSecret(secret), time.Now().Add(s.Cfg.RefreshTokenTTL))
+	if err != nil {
+		s.recordLogin(ctx, user.ID, ip, userAgent, model.LoginFailureInternal)
+		return nil, err
+	}
+	authResponse, err := s.authResponse(user, sessionID, tokenID, secret)
+	if err != nil {
+		s.recordLogin(ctx, user.ID, ip, userAgent, model.LoginFailureInternal)
+		return nil, err
+	}
+
+	s.recordLogin(ctx, user.ID, ip, userAgent, "")
+	if err := s.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
+		log.Println("Error updating last login:", err)
+	}
+	user.LastLoginAt = time.Now()
+	return authResponse, nil
+}
+
+// recordLogin บันทึกการพยายามล็อกอิน failureReason ว่างคือสำเร็จ
+// การบันทึกไม่สำเร็จไม่ควรทำให้การล็อกอินล้มเหลว จึงแค่ log ไว้
+func (s *AuthService) recordLogin(ctx context.Context, userID, ip, userAgent, failureReason string) {
+	if err := s.loginRepo.RecordLogin(ctx, userID, ip, userAgent, failureReason); err != nil {
+		log.Println("Error recording login attempt:", err)
+	}
+}
+
+// GetLoginHistory ดึงการล็อกอินล่าสุดของผู้ใช้ limit รายการ
+func (s *AuthService) GetLoginHistory(ctx context.Context, userID string, limit int) ([]model.LoginAttempt, error) {
+	return s.loginRepo.ListUserLogins(ctx, userID, limit)
+}
+
+// SearchLoginHistory ค้นหาประวัติการล็อกอินของทุกผู้ใช้ สำหรับผู้ดูแลระบบตรวจกิจกรรมที่น่าสงสัย
+func (s *AuthService) SearchLoginHistory(ctx context.Context, filter model.LoginFilter) ([]model.LoginAttempt, error) {
+	return s.loginRepo.SearchLogins(ctx, filter)
+}
+
+// RefreshTokens ออก access token ใหม่จาก refresh token และหมุน refresh token ของ session
+// refresh token เดิมใช้ไม่ได้อีก ถ้าถูกนำมาใช้ซ้ำ session จะถูก revoke และคืน ErrRefreshTokenReused
+func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*model.AuthResponse, error) {
+	sessionID, presented, err := utils.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	secret, err := utils.NewRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	userID, tokenID, err := s.sessionRepo.RotateSession(ctx, sessionID, utils.HashRefreshSecret(presented), utils.HashRefreshSecret(secret), time.Now().Add(s.Cfg.RefreshTokenTTL))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRefreshTokenReused):
+			log.Printf("Refresh token reuse detected, session %s revoked", sessionID)
+			return nil, ErrRefreshTokenReused
+		case errors.Is(err, repository.ErrSessionNotFound), errors.Is(err, repository.ErrSessionExpired):
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	// อ่าน role และสถานะล่าสุดทุกครั้ง role ที่เปลี่ยนจึงมีผลภายในอายุของ access token
+	// และผู้ใช้ที่ถูกระงับต่ออายุ session ไม่ได้อีก
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || user.Status != "active" {
+		return nil, ErrInvalidRefreshToken
+	}
+	return s.authResponse(user, sessionID, tokenID, secret)
+}
+
+// authResponse ออก access token ของ session (jti คือ tokenID) และรวมกับ refresh token ที่ส่งให้ client
+func (s *AuthService) authResponse(user *model.User, sessionID, tokenID, refreshSecret string) (*model.AuthResponse, error) {
+	token, err := utils.GenerateToken(user.ID, user.Role, sessionID, tokenID, s.Cfg.AccessTokenTTL, s.Cfg.JWTSecret)
 	if err != nil {
 		return nil, err
 	}
 
 	return &model.AuthResponse{
-		AccessToken: token,
-		User:        user,
+		AccessToken:  token,
+		RefreshToken: utils.RefreshToken(sessionID, refreshSecret),
+		ExpiresIn:    int(s.Cfg.AccessTokenTTL.Seconds()),
+		User:         user,
 	}, nil
 }
 
-func (s *AuthService) Logout(ctx context.Context, userID string) error {
-	// Implement any necessary logout logic
-	return nil
+// Logout ออกจากระบบ session ปัจจุบันโดยลบ session ทิ้ง refresh token และ access token ของ session ใช้ไม่ได้ทันที
+func (s *AuthService) Logout(ctx context.Context, userID string, sessionID string) error {
+	return s.sessionRepo.DeleteSession(ctx, userID, sessionID)
+}
+
+// LogoutAll ออกจากระบบทุกอุปกรณ์ของผู้ใช้ คืนจำนวน session ที่ถูกลบ
+func (s *AuthService) LogoutAll(ctx context.Context, userID string) (int64, error) {
+	return s.sessionRepo.DeleteUserSessions(ctx, userID)
 }
 
 // func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*model.User, error) {
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"login/internal/config"
+	"login/internal/middleware"
+	"login/internal/model"
+	"login/internal/repository"
+	"login/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeUserStore เก็บผู้ใช้ในหน่วยความจำ เมธอดอื่นของ userStore ไม่ถูกเรียกใน test นี้
+type fakeUserStore struct {
+	userStore
+	users map[string]*model.User
+}
+
+func (s *fakeUserStore) GetUserByID(ctx context.Context, userID string) (*model.User, error) {
+	return s.users[userID], nil
+}
+
+type fakeSession struct {
+	userID, tokenHash, tokenID string
+	revoked                    bool
+}
+
+// fakeSessionStore ทำงานแบบเดียวกับ SessionRepository แต่เก็บในหน่วยความจำ
+type fakeSessionStore struct {
+	sessions map[string]*fakeSession
+	nextID   int
+}
+
+func (s *fakeSessionStore) newID() string {
+	s.nextID++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", s.nextID)
+}
+
+func (s *fakeSessionStore) CreateSession(ctx context.Context, userID, tokenHash string, expiresAt time.Time) (string, string, error) {
+	sessionID, tokenID := s.newID(), s.newID()
+	s.sessions[sessionID] = &fakeSession{userID: userID, tokenHash: tokenHash, tokenID: tokenID}
+	return sessionID, tokenID, nil
+}
+
+func (s *fakeSessionStore) RotateSession(ctx context.Context, sessionID, presentedHash, newHash string, expiresAt time.Time) (string, string, error) {
+	session, ok := s.sessions[sessionID]
+	switch {
+	case !ok:
+		return "", "", repository.ErrSessionNotFound
+	case session.revoked:
+		return "", "", repository.ErrSessionExpired
+	case session.tokenHash != presentedHash:
+		session.revoked = true
+		return "", "", repository.ErrRefreshTokenReused
+	}
+	session.tokenHash, session.tokenID = newHash, s.newID()
+	return session.userID, session.tokenID, nil
+}
+
+func (s *fakeSessionStore) SessionActive(ctx context.Context, sessionID, tokenID string) (bool, error) {
+	session, ok := s.sessions[sessionID]
+	return ok && !session.revoked && session.tokenID == tokenID, nil
+}
+
+func (s *fakeSessionStore) DeleteSession(ctx context.Context, userID, sessionID string) error {
+	if session, ok := s.sessions[sessionID]; ok && session.userID == userID {
+		delete(s.sessions, sessionID)
+	}
+	return nil
+}
+
+func (s *fakeSessionStore) DeleteUserSessions(ctx context.Context, userID string) (int64, error) {
+	var deleted int64
+	for id, session := range s.sessions {
+		if session.userID == userID {
+			delete(s.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func newTestAuthService(users ...*model.User) (*AuthService, *fakeSessionStore) {
+	userRepo := &fakeUserStore{users: map[string]*model.User{}}
+	for _, user := range users {
+		userRepo.users[user.ID] = user
+	}
+	sessions := &fakeSessionStore{sessions: map[string]*fakeSession{}}
+	cfg := &config.Config{
+		JWTSecret:       "0123456789abcdef0123456789abcdef",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	}
+	return &AuthService{userRepo: userRepo, sessionRepo: sessions, Cfg: cfg}, sessions
+}
+
+// testLogin สร้าง session และออก token แบบเดียวกับ VerifyGoogleToken หลังตรวจ Google ID token แล้ว
+func testLogin(t *testing.T, s *AuthService, user *model.User) *model.AuthResponse {
+	t.Helper()
+	secret, err := utils.NewRefreshSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID, tokenID, err := s.sessionRepo.CreateSession(context.Background(), user.ID, utils.HashRefreshSecret(secret), time.Now().Add(s.Cfg.RefreshTokenTTL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := s.authResponse(user, sessionID, tokenID, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func tokenID(t *testing.T, s *AuthService, accessToken string) string {
+	t.Helper()
+	claims, err := utils.VerifyToken(accessToken, s.Cfg.JWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims.Id
+}
+
+func TestRefreshTokensRotatesSession(t *testing.T) {
+	user := &model.User{ID: "11111111-1111-4111-8111-111111111111", Role: "customer", Status: "active"}
+	s, _ := newTestAuthService(user)
+	ctx := context.Background()
+
+	first := testLogin(t, s, user)
+	second, err := s.RefreshTokens(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshTokens = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Errorf("refresh token was not rotated")
+	}
+	if tokenID(t, s, second.AccessToken) == tokenID(t, s, first.AccessToken) {
+		t.Errorf("access token jti was not rotated")
+	}
+	if _, err := s.RefreshTokens(ctx, second.RefreshToken); err != nil {
+		t.Errorf("RefreshTokens with the rotated token = %v", err)
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	user := &model.User{ID: "11111111-1111-4111-8111-111111111111", Role: "customer", Status: "active"}
+	s, _ := newTestAuthService(user)
+	ctx := context.Background()
+
+	first := testLogin(t, s, user)
+	second, err := s.RefreshTokens(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshTokens = %v", err)
+	}
+	if _, err := s.RefreshTokens(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("reusing the rotated token = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	// session ถูก revoke แล้ว token ล่าสุดก็ใช้ไม่ได้
+	if _, err := s.RefreshTokens(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refresh after reuse = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestRefreshTokensRejectsInvalidTokens(t *testing.T) {
+	active := &model.User{ID: "11111111-1111-4111-8111-111111111111", Role: "customer", Status: "active"}
+	suspended := &model.User{ID: "22222222-2222-4222-8222-222222222222", Role: "customer", Status: "suspended"}
+	missing := &model.User{ID: "33333333-3333-4333-8333-333333333333", Role: "customer", Status: "active"}
+	s, _ := newTestAuthService(active, suspended)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "malformed", token: "not-a-token"},
+		{name: "unknown session", token: utils.RefreshToken("44444444-4444-4444-8444-444444444444", "secret")},
+		{name: "suspended user", token: testLogin(t, s, suspended).RefreshToken},
+		{name: "deleted user", token: testLogin(t, s, missing).RefreshToken},
+	}
+	for _, tt := range tests {
+		if _, err := s.RefreshTokens(context.Background(), tt.token); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("%s: RefreshTokens = %v, want %v", tt.name, err, ErrInvalidRefreshToken)
+		}
+	}
+}
+
+// TestRevokedAccessTokenRejected ส่ง access token ผ่าน AuthMiddleware หลัง logout และหลัง refresh
+// token ที่ถูกแทนหรือ session ที่ถูกลบต้องได้ 401 ใน request ถัดไป
+func TestRevokedAccessTokenRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := &model.User{ID: "11111111-1111-4111-8111-111111111111", Role: "customer", Status: "active"}
+	s, sessions := newTestAuthService(user)
+	ctx := context.Background()
+
+	r := gin.New()
+	r.GET("/me", middleware.AuthMiddleware(s.Cfg, sessions), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id")})
+	})
+	get := func(accessToken string) int {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	sessionOf := func(response *model.AuthResponse) string {
+		claims, err := utils.VerifyToken(response.AccessToken, s.Cfg.JWTSecret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return claims.SessionID
+	}
+
+	first := testLogin(t, s, user)
+	if code := get(first.AccessToken); code != http.StatusOK {
+		t.Fatalf("fresh token = %d, want %d", code, http.StatusOK)
+	}
+	refreshed, err := s.RefreshTokens(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshTokens = %v", err)
+	}
+	if code := get(first.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("token replaced by refresh = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := get(refreshed.AccessToken); code != http.StatusOK {
+		t.Errorf("refreshed token = %d, want %d", code, http.StatusOK)
+	}
+
+	other := testLogin(t, s, user)
+	if err := s.Logout(ctx, user.ID, sessionOf(refreshed)); err != nil {
+		t.Fatalf("Logout = %v", err)
+	}
+	if code := get(refreshed.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("token after logout = %d, want %d", code, http.StatusUnauthorized)
+	}
+	// logout ออกเฉพาะ session ปัจจุบัน
+	if code := get(other.AccessToken); code != http.StatusOK {
+		t.Errorf("token of another session after logout = %d, want %d", code, http.StatusOK)
+	}
+
+	if _, err := s.LogoutAll(ctx, user.ID); err != nil {
+		t.Fatalf("LogoutAll = %v", err)
+	}
+	if code := get(other.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("token after logging out everywhere = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
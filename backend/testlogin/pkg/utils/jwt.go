@@ -1,21 +1,49 @@
 package utils
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 )
 
-func GenerateToken(userID string, secret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-		Subject:   userID,
-		ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
+// errEmptySecret กันไม่ให้ใช้ token ที่เซ็นด้วย key ว่าง (config ต้องตั้ง JWT_SECRET เสมอ)
+var errEmptySecret = errors.New("JWT secret is not configured")
+
+// Claims คือ claims ของ JWT ที่ออกให้ผู้ใช้ Subject คือ user_id และ Role คือ users.role (customer, seller, admin)
+// clothesproject อ่าน role จาก claim นี้เพื่อตรวจสิทธิ์ จึงต้องออก token ใหม่เมื่อ role เปลี่ยน
+// SessionID คือ user_sessions.session_id ที่ออก access token นี้ และ Id (jti) คือ user_sessions.access_token_id ตอนออก token
+type Claims struct {
+	jwt.StandardClaims
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
+}
+
+// GenerateToken ออก access token อายุ ttl ของ session ให้ผู้ใช้ (ต่ออายุด้วย refresh token ของ session)
+// tokenID คือ jti ของ token ซึ่ง AuthMiddleware ตรวจกับ access_token_id ปัจจุบันของ session
+func GenerateToken(userID string, role string, sessionID string, tokenID string, ttl time.Duration, secret string) (string, error) {
+	if secret == "" {
+		return "", errEmptySecret
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID,
+			Subject:   userID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+		Role:      role,
+		SessionID: sessionID,
 	})
 	return token.SignedString([]byte(secret))
 }
 
-func VerifyToken(tokenString string, secret string) (*jwt.StandardClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
+func VerifyToken(tokenString string, secret string) (*Claims, error) {
+	if secret == "" {
+		return nil, errEmptySecret
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
 	})
 	if err != nil {
@@ -23,7 +51,7 @@ func VerifyToken(tokenString string, secret string) (*jwt.StandardClaims, error)
 	}
 
 	// ตรวจสอบว่า token valid หรือไม่
-	if claims, ok := token.Claims.(*jwt.StandardClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
 		return claims, nil
 	}
 
@@ -31,14 +59,17 @@ func VerifyToken(tokenString string, secret string) (*jwt.StandardClaims, error)
 }
 
 func ParseToken(tokenString string, secret string) (string, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
+	if secret == "" {
+		return "", errEmptySecret
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
 	})
 	if err != nil {
 		return "", err
 	}
 
-	if claims, ok := token.Claims.(*jwt.StandardClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
 		return claims.Subject, nil // คืนค่า userID (หรือ subject) จาก JWT
 	}
 
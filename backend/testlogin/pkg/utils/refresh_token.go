@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMalformedRefreshToken คืนค่าเมื่อ refresh token ไม่อยู่ในรูป "<session_id>.<secret>"
+var ErrMalformedRefreshToken = errors.New("malformed refresh token")
+
+// NewRefreshSecret สุ่มส่วน secret ของ refresh token (32 bytes)
+func NewRefreshSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashRefreshSecret คืน SHA-256 ของ secret ฐานข้อมูลเก็บเฉพาะค่านี้ ไม่เก็บ token จริง
+func HashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshToken รวม session_id กับ secret เป็น refresh token ที่ส่งให้ client
+func RefreshToken(sessionID string, secret string) string {
+	return sessionID + "." + secret
+}
+
+// ParseRefreshToken แยก refresh token เป็น session_id และ secret (session_id ต้องเป็น UUID)
+func ParseRefreshToken(token string) (sessionID string, secret string, err error) {
+	sessionID, secret, ok := strings.Cut(token, ".")
+	if !ok || !ValidUUID(sessionID) || secret == "" {
+		return "", "", ErrMalformedRefreshToken
+	}
+	return sessionID, secret, nil
+}
@@ -26,17 +26,58 @@ CREATE TABLE IF NOT EXISTS products (
     name VARCHAR(255) NOT NULL,
     description TEXT,
     brand VARCHAR(255) NOT NULL,
-    price FLOAT NOT NULL,
+    price NUMERIC(12, 2) NOT NULL,
+    weight_grams INT NOT NULL DEFAULT 500 CHECK (weight_grams > 0),
     isNew BOOLEAN,
     createdate DATE DEFAULT CURRENT_DATE,
     updatedate DATE DEFAULT CURRENT_DATE
 );
 
+-- สร้างตาราง product_variants (SKU ของสินค้าแต่ละไซซ์/สี)
+-- price เป็น NULL เมื่อใช้ราคาเดียวกับสินค้าหลัก
+CREATE TABLE IF NOT EXISTS product_variants (
+    variant_id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    size VARCHAR(20) NOT NULL,
+    colour VARCHAR(50) NOT NULL,
+    price NUMERIC(12, 2),
+    barcode VARCHAR(64) UNIQUE,
+    imgsrc VARCHAR(255),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    UNIQUE (product_id, size, colour)
+);
+
 -- สร้างตาราง brand
+-- vat_inclusive คือราคาสินค้าของแบรนด์รวม VAT แล้ว free_shipping_threshold เป็น NULL คือไม่มีส่งฟรี
+-- return_window_days คือจำนวนวันหลังได้รับสินค้าที่ลูกค้ายังขอคืนได้ (0 คือไม่รับคืน)
 CREATE TABLE IF NOT EXISTS brand (
     id SERIAL PRIMARY KEY,
     brandname VARCHAR(100),
-    brandlogo VARCHAR(255)
+    brandlogo VARCHAR(255),
+    vat_inclusive BOOLEAN NOT NULL DEFAULT TRUE,
+    free_shipping_threshold NUMERIC(12, 2) CHECK (free_shipping_threshold >= 0),
+    return_window_days INT NOT NULL DEFAULT 14 CHECK (return_window_days >= 0),
+    tax_id VARCHAR(13),
+    address TEXT
+);
+
+-- สร้างตาราง shipping_zones (ค่าส่ง: base_fee สำหรับน้ำหนักไม่เกิน base_weight_grams แล้วเพิ่ม per_kg_fee ทุกกิโลกรัมที่เกิน)
+-- จังหวัดที่ไม่อยู่ใน shipping_zone_provinces ใช้พื้นที่ที่ is_default
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    zone_id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    base_fee NUMERIC(12, 2) NOT NULL CHECK (base_fee >= 0),
+    base_weight_grams INT NOT NULL CHECK (base_weight_grams > 0),
+    per_kg_fee NUMERIC(12, 2) NOT NULL CHECK (per_kg_fee >= 0),
+    is_default BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- สร้างตาราง shipping_zone_provinces (จังหวัดในแต่ละพื้นที่จัดส่ง)
+CREATE TABLE IF NOT EXISTS shipping_zone_provinces (
+    province VARCHAR(255) PRIMARY KEY,
+    zone_id INT NOT NULL,
+    FOREIGN KEY (zone_id) REFERENCES shipping_zones(zone_id) ON DELETE CASCADE
 );
 
 -- สร้างตาราง about_page
@@ -57,17 +98,6 @@ CREATE TABLE IF NOT EXISTS branch (
     banch_location VARCHAR(255)
 );
 
--- สร้างตาราง cart
-CREATE TABLE IF NOT EXISTS cart (
-    cart_id SERIAL PRIMARY KEY,
-    product_id INT NOT NULL,
-    quantity INT NOT NULL DEFAULT 1,
-    price FLOAT NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
-);
-
 -- สร้าง ENUM สำหรับ user_status และ user_role
 DO $$
 BEGIN
@@ -95,26 +125,327 @@ CREATE TABLE IF NOT EXISTS users (
     updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 );
 
--- สร้างตาราง user_sessions
+-- สร้างตาราง seller_brands (แบรนด์ที่ผู้ใช้ role seller แต่ละคนดูแล ผู้ขายแก้ไขได้เฉพาะสินค้า สาขา และหน้า about ของแบรนด์เหล่านี้)
+CREATE TABLE IF NOT EXISTS seller_brands (
+    user_id UUID NOT NULL,
+    brand_id INT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, brand_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (brand_id) REFERENCES brand(id) ON DELETE CASCADE
+);
+
+-- สร้างตาราง cart (ตะกร้าของผู้ใช้ที่ล็อกอิน หรือของผู้เยี่ยมชมผ่าน guest_token)
+CREATE TABLE IF NOT EXISTS cart (
+    cart_id SERIAL PRIMARY KEY,
+    user_id UUID,
+    guest_token VARCHAR(64),
+    variant_id INT NOT NULL,
+    quantity INT NOT NULL DEFAULT 1,
+    price NUMERIC(12, 2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    CHECK ((user_id IS NULL) <> (guest_token IS NULL)),
+    UNIQUE (user_id, variant_id),
+    UNIQUE (guest_token, variant_id)
+);
+
+-- สร้างตาราง inventory (สต็อกในคลังของแต่ละ SKU)
+CREATE TABLE IF NOT EXISTS inventory (
+    variant_id INT PRIMARY KEY,
+    on_hand INT NOT NULL DEFAULT 0 CHECK (on_hand >= 0),
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE
+);
+
+-- สร้างตาราง stock_reservations (การจองสต็อกชั่วคราวของรายการในตะกร้า)
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    reservation_id SERIAL PRIMARY KEY,
+    cart_id INT NOT NULL UNIQUE,
+    variant_id INT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cart_id) REFERENCES cart(cart_id) ON DELETE CASCADE,
+    FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE
+);
+
+-- สร้างตาราง branch_inventory (สต็อกของแต่ละ SKU ที่แต่ละสาขา)
+CREATE TABLE IF NOT EXISTS branch_inventory (
+    branch_id INT NOT NULL,
+    variant_id INT NOT NULL,
+    quantity INT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (branch_id, variant_id),
+    FOREIGN KEY (branch_id) REFERENCES branch(id) ON DELETE CASCADE,
+    FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE
+);
+
+-- สร้างตาราง promotions (คูปองและโปรโมชันอัตโนมัติ code เป็น NULL คือโปรโมชันที่ไม่ต้องกรอกรหัส)
+CREATE TABLE IF NOT EXISTS promotions (
+    promotion_id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    code VARCHAR(50),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('percentage', 'fixed', 'buy_x_get_y')),
+    value NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    brand_id INT,
+    category VARCHAR(100),
+    buy_quantity INT CHECK (buy_quantity > 0),
+    get_quantity INT CHECK (get_quantity > 0),
+    min_spend NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    usage_limit INT CHECK (usage_limit > 0),
+    per_user_limit INT CHECK (per_user_limit > 0),
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (brand_id) REFERENCES brand(id) ON DELETE CASCADE,
+    CHECK (kind <> 'buy_x_get_y' OR (buy_quantity IS NOT NULL AND get_quantity IS NOT NULL))
+);
+
+-- สร้างตาราง exchange_rates (อัตราแลกเปลี่ยนสำหรับแสดงราคาเป็นสกุลเงินอื่น: บาทต่อเงิน 1 หน่วย)
+-- เปลี่ยนอัตราด้วยการเพิ่มแถวใหม่ แถวล่าสุดของแต่ละสกุลเงินคืออัตราปัจจุบัน แถวเดิมเก็บไว้ให้คำสั่งซื้อที่อ้างถึง
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    rate_id SERIAL PRIMARY KEY,
+    currency CHAR(3) NOT NULL CHECK (currency <> 'THB'),
+    thb_per_unit NUMERIC(18, 6) NOT NULL CHECK (thb_per_unit > 0),
+    created_by UUID,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+-- สร้างตาราง orders (คำสั่งซื้อที่สร้างจากตะกร้า)
+-- display_currency/exchange_rate คือสกุลเงินและอัตราที่ลูกค้าเห็นตอนสั่งซื้อ (NULL คือบาท) ยอดที่เก็บเป็นบาทเสมอ
+CREATE TABLE IF NOT EXISTS orders (
+    order_id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'paid', 'packed', 'shipped', 'delivered', 'cancelled', 'refunded', 'expired')),
+    subtotal NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    discount_total NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    shipping_fee NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    vat_included NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    vat_added NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    total NUMERIC(12, 2) NOT NULL,
+    coupon_code VARCHAR(50),
+    shipping_province VARCHAR(255),
+    display_currency CHAR(3),
+    exchange_rate NUMERIC(18, 6),
+    exchange_rate_id INT,
+    fulfilment VARCHAR(20) NOT NULL DEFAULT 'shipping' CHECK (fulfilment IN ('shipping', 'pickup')),
+    pickup_branch_id INT,
+    pickup_code VARCHAR(6),
+    pickup_window_start TIMESTAMPTZ,
+    pickup_window_end TIMESTAMPTZ,
+    collected_at TIMESTAMPTZ,
+    buyer_name VARCHAR(255),
+    buyer_tax_id VARCHAR(13),
+    buyer_address TEXT,
+    buyer_branch VARCHAR(100),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id),
+    FOREIGN KEY (pickup_branch_id) REFERENCES branch(id),
+    FOREIGN KEY (exchange_rate_id) REFERENCES exchange_rates(rate_id),
+    CHECK ((display_currency IS NULL) = (exchange_rate IS NULL)),
+    CHECK (fulfilment <> 'pickup' OR (pickup_branch_id IS NOT NULL AND pickup_code IS NOT NULL
+                                      AND pickup_window_start IS NOT NULL AND pickup_window_end IS NOT NULL))
+);
+
+-- สร้างตาราง branch_stock_holds (สต็อกของสาขาที่กันไว้ให้คำสั่งซื้อแบบรับที่สาขา จนกว่าจะรับสินค้าหรือหมดเวลารับ)
+CREATE TABLE IF NOT EXISTS branch_stock_holds (
+    hold_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    branch_id INT NOT NULL,
+    variant_id INT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'held' CHECK (status IN ('held', 'collected', 'released')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (branch_id) REFERENCES branch(id) ON DELETE CASCADE,
+    FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE
+);
+
+-- สร้างตาราง order_items (snapshot ของสินค้า ณ เวลาที่สั่งซื้อ แก้ไขไม่ได้)
+CREATE TABLE IF NOT EXISTS order_items (
+    order_item_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    product_id INT,
+    variant_id INT,
+    sku VARCHAR(64) NOT NULL,
+    size VARCHAR(20) NOT NULL,
+    colour VARCHAR(50) NOT NULL,
+    product_name VARCHAR(255) NOT NULL,
+    brand_id INT,
+    brand_name VARCHAR(100),
+    imgsrc VARCHAR(255),
+    unit_price NUMERIC(12, 2) NOT NULL,
+    quantity INT NOT NULL,
+    line_total NUMERIC(12, 2) NOT NULL,
+    discount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE SET NULL,
+    FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE SET NULL
+);
+
+-- สร้างตาราง order_shipments (พัสดุของแต่ละแบรนด์ในคำสั่งซื้อแบบจัดส่ง พร้อมค่าส่ง)
+CREATE TABLE IF NOT EXISTS order_shipments (
+    order_shipment_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    brand_id INT NOT NULL,
+    weight_grams INT NOT NULL,
+    fee NUMERIC(12, 2) NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    UNIQUE (order_id, brand_id)
+);
+
+-- สร้างตาราง order_discounts (ส่วนลดที่คำสั่งซื้อได้รับแยกตามโปรโมชัน ใช้นับจำนวนการใช้โปรโมชันด้วย)
+CREATE TABLE IF NOT EXISTS order_discounts (
+    order_discount_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    promotion_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    code VARCHAR(50),
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (promotion_id) REFERENCES promotions(promotion_id),
+    UNIQUE (order_id, promotion_id)
+);
+
+-- สร้างตาราง order_status_history (ประวัติการเปลี่ยนสถานะคำสั่งซื้อ)
+CREATE TABLE IF NOT EXISTS order_status_history (
+    history_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    changed_by UUID, -- NULL เมื่อระบบเปลี่ยนสถานะเอง (เช่น หมดเวลารับสินค้าที่สาขา)
+    note TEXT,
+    changed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (changed_by) REFERENCES users(user_id)
+);
+
+-- สร้างตาราง return_requests (คำขอคืนสินค้าของรายการในคำสั่งซื้อ)
+-- requested → approved/rejected → received → refunded ส่วน photos คือ URL ของรูปที่ลูกค้าอัปโหลด
+CREATE TABLE IF NOT EXISTS return_requests (
+    return_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    order_item_id INT NOT NULL,
+    user_id UUID NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    reason TEXT NOT NULL,
+    photos TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'requested'
+        CHECK (status IN ('requested', 'approved', 'rejected', 'received', 'refunded')),
+    staff_note TEXT,
+    refund_amount NUMERIC(12, 2) CHECK (refund_amount >= 0),
+    reviewed_by UUID,
+    reviewed_at TIMESTAMPTZ,
+    received_by UUID,
+    received_at TIMESTAMPTZ,
+    refunded_by UUID,
+    refunded_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (order_item_id) REFERENCES order_items(order_item_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id),
+    FOREIGN KEY (reviewed_by) REFERENCES users(user_id),
+    FOREIGN KEY (received_by) REFERENCES users(user_id),
+    FOREIGN KEY (refunded_by) REFERENCES users(user_id),
+    CHECK (status <> 'refunded' OR refund_amount IS NOT NULL)
+);
+
+-- สร้างตาราง brand_invoice_counters (เลขที่เอกสารล่าสุดของแต่ละแบรนด์ เพิ่มทีละหนึ่งใน transaction ที่ออกเอกสาร)
+CREATE TABLE IF NOT EXISTS brand_invoice_counters (
+    brand_id INT PRIMARY KEY,
+    last_number INT NOT NULL CHECK (last_number > 0)
+);
+
+-- สร้างตาราง invoices (ใบเสร็จรับเงิน/ใบกำกับภาษีของแต่ละแบรนด์ในคำสั่งซื้อ ออกครั้งเดียวและไม่เปลี่ยนภายหลัง)
+-- ข้อมูลผู้ขายและยอดเงินบันทึกไว้ ณ เวลาที่ออก ส่วนรายการสินค้าอ่านจาก order_items
+CREATE TABLE IF NOT EXISTS invoices (
+    invoice_id SERIAL PRIMARY KEY,
+    invoice_number VARCHAR(30) NOT NULL UNIQUE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('tax_invoice', 'receipt')),
+    order_id INT NOT NULL,
+    brand_id INT NOT NULL,
+    sequence INT NOT NULL CHECK (sequence > 0),
+    seller_name VARCHAR(100) NOT NULL,
+    seller_tax_id VARCHAR(13),
+    seller_address TEXT,
+    vat_inclusive BOOLEAN NOT NULL,
+    subtotal NUMERIC(12, 2) NOT NULL,
+    discount NUMERIC(12, 2) NOT NULL,
+    shipping_fee NUMERIC(12, 2) NOT NULL,
+    vat NUMERIC(12, 2) NOT NULL,
+    total NUMERIC(12, 2) NOT NULL,
+    issued_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    UNIQUE (order_id, brand_id),
+    UNIQUE (brand_id, sequence)
+);
+
+-- สร้างตาราง payments (การชำระเงินของคำสั่งซื้อ ลองชำระได้หลายครั้ง)
+-- reference คือรหัสที่ผู้ให้บริการส่งกลับมาใน callback ยอดเงินคัดลอกจากยอดรวมของคำสั่งซื้อตอนเริ่มชำระ
+CREATE TABLE IF NOT EXISTS payments (
+    payment_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    method VARCHAR(20) NOT NULL CHECK (method IN ('promptpay', 'card')),
+    reference VARCHAR(25) NOT NULL,
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    qr_payload TEXT,
+    note TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    paid_at TIMESTAMPTZ,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id) ON DELETE CASCADE,
+    UNIQUE (method, reference)
+);
+
+-- สร้างตาราง idempotency_keys (response ของ POST ที่ส่ง Idempotency-Key มา เก็บไว้ส่งซ้ำเมื่อ retry)
+-- scope คือ hash ของผู้เรียก status_code เป็น NULL ขณะที่ request แรกยังทำงานอยู่
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope CHAR(64) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT,
+    response_headers JSONB,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, idempotency_key)
+);
+
+-- สร้างตาราง user_sessions (session ของการล็อกอินแต่ละครั้ง เก็บ hash ของ refresh token ปัจจุบันเท่านั้น)
+-- refresh token หมุนทุกครั้งที่ใช้ ถ้ามีการใช้ token เก่าของ session ซ้ำจะถูก revoke ทั้ง session
+-- access_token_id คือ jti ของ access token ล่าสุดของ session เปลี่ยนทุกครั้งที่ refresh access token ก่อนหน้าจึงใช้ไม่ได้อีก
 CREATE TABLE IF NOT EXISTS user_sessions (
     session_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID NOT NULL,
-    id_token TEXT NOT NULL,
+    refresh_token_hash VARCHAR(64) NOT NULL,
+    access_token_id UUID NOT NULL DEFAULT uuid_generate_v4(),
     expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
     created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
 );
 
--- สร้างตาราง user_login_history
+-- สร้างตาราง user_login_history (ทุกครั้งที่พยายามล็อกอิน รวมถึงครั้งที่ไม่สำเร็จ)
+-- user_id เป็น NULL เมื่อไม่รู้ว่าเป็นผู้ใช้คนไหน (เช่น Google ID token ไม่ถูกต้อง) failure_reason บอกสาเหตุที่ไม่สำเร็จ
 CREATE TABLE IF NOT EXISTS user_login_history (
     login_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    user_id UUID NOT NULL,
+    user_id UUID,
     login_timestamp TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
     ip_address INET,
     user_agent TEXT,
     success BOOLEAN NOT NULL,
-    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+    failure_reason VARCHAR(50),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    CHECK (success = (failure_reason IS NULL))
 );
 
 -- สร้างตาราง api_keys
@@ -141,12 +472,71 @@ BEFORE UPDATE ON users
 FOR EACH ROW
 EXECUTE FUNCTION update_updated_at_column();
 
+-- ป้องกันการแก้ไขรายการสินค้าในคำสั่งซื้อหลังจากสร้างแล้ว
+-- (อนุญาตให้ ON DELETE SET NULL ของ product_id/variant_id ทำงานได้ เพื่อให้ลบสินค้าได้โดย snapshot ยังอยู่)
+CREATE OR REPLACE FUNCTION prevent_order_items_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND (NEW.product_id IS NULL OR NEW.product_id IS NOT DISTINCT FROM OLD.product_id)
+        AND (NEW.variant_id IS NULL OR NEW.variant_id IS NOT DISTINCT FROM OLD.variant_id)
+        AND (NEW.order_id, NEW.sku, NEW.size, NEW.colour, NEW.product_name, NEW.brand_id, NEW.brand_name, NEW.imgsrc,
+             NEW.unit_price, NEW.quantity, NEW.line_total)
+        IS NOT DISTINCT FROM
+        (OLD.order_id, OLD.sku, OLD.size, OLD.colour, OLD.product_name, OLD.brand_id, OLD.brand_name, OLD.imgsrc,
+         OLD.unit_price, OLD.quantity, OLD.line_total) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'order_items are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER order_items_immutable
+BEFORE UPDATE OR DELETE ON order_items
+FOR EACH ROW
+EXECUTE FUNCTION prevent_order_items_change();
+
+CREATE TRIGGER update_orders_updated_at
+BEFORE UPDATE ON orders
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
 -- สร้าง Indexes
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 CREATE INDEX IF NOT EXISTS idx_users_google_id ON users(google_id);
 CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id);
-CREATE INDEX IF NOT EXISTS idx_user_login_history_user_id ON user_login_history(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_login_history_user_id ON user_login_history(user_id, login_timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_user_login_history_timestamp ON user_login_history(login_timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_user_login_history_ip_address ON user_login_history(ip_address);
 CREATE INDEX IF NOT EXISTS idx_api_keys_api_key ON api_keys(api_key);
+CREATE INDEX IF NOT EXISTS idx_cart_user_id ON cart(user_id);
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants(product_id);
+CREATE INDEX IF NOT EXISTS idx_products_category ON products(category);
+CREATE INDEX IF NOT EXISTS idx_products_brand ON products(brand);
+CREATE INDEX IF NOT EXISTS idx_products_price_id ON products(price, id);
+CREATE INDEX IF NOT EXISTS idx_products_createdate_id ON products(createdate, id);
+CREATE INDEX IF NOT EXISTS idx_products_name_id ON products(name, id);
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_variant_id ON stock_reservations(variant_id, expires_at);
+CREATE INDEX IF NOT EXISTS idx_branch_inventory_variant_id ON branch_inventory(variant_id);
+CREATE INDEX IF NOT EXISTS idx_cart_guest_token ON cart(guest_token);
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history(order_id);
+CREATE INDEX IF NOT EXISTS idx_branch_stock_holds_order_id ON branch_stock_holds(order_id);
+CREATE INDEX IF NOT EXISTS idx_branch_stock_holds_held ON branch_stock_holds(branch_id, variant_id) WHERE status = 'held';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipping_zones_default ON shipping_zones(is_default) WHERE is_default;
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_currency ON exchange_rates(currency, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promotions_code ON promotions(lower(code)) WHERE code IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_order_discounts_promotion_id ON order_discounts(promotion_id);
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+CREATE INDEX IF NOT EXISTS idx_return_requests_order_item_id ON return_requests(order_item_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_user_id ON return_requests(user_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_status ON return_requests(status);
+CREATE INDEX IF NOT EXISTS idx_return_requests_photos ON return_requests USING GIN (photos);
+CREATE INDEX IF NOT EXISTS idx_orders_pickup_expiry ON orders(pickup_window_end) WHERE fulfilment = 'pickup' AND collected_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_seller_brands_brand_id ON seller_brands(brand_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_about_page_brand_id ON about_page(brand_id);
 
 COMMIT;
 
@@ -209,6 +599,17 @@ THE LOOK - Drawstring hem for a fresh look',2, 960.00, TRUE),
 ('men', '/images/product_50.jpg', 'เสื้อเชิ้ตผ้าพลิ้ว', 'เสื้อเชิ้ต relaxed fit ทำจากผ้าวิสโคสผสม เสื้อเชิ้ตคอโบว์ลิ่งและแขนยาวข้อมือติดกระดุม ชายผ่าด้านข้าง ทรงสั้น ด้านหน้าติดชุดกระดุม', 9, 2490.00, TRUE),
 ('men', '/images/product_51.jpg', 'เสื้อเชิ้ตผ้าทอผิวไม่เรียบ', 'เสื้อเชิ้ต relaxed fit ทำจากผ้าทอผสมผ้าฝ้าย คอปกโบว์ลิ่งและแขนสั้น เปิดปิดด้านหน้าด้วยชุดกระดุม', 9, 1990.00, FALSE);
 
+-- สร้าง SKU เริ่มต้นให้สินค้าทุกชิ้น (ไซซ์ S-XL สีมาตรฐาน ใช้ราคาของสินค้าหลัก)
+INSERT INTO product_variants (product_id, sku, size, colour)
+SELECT p.id, 'P' || LPAD(p.id::text, 4, '0') || '-' || s.size, s.size, 'Standard'
+FROM products p
+CROSS JOIN (VALUES (1, 'S'), (2, 'M'), (3, 'L'), (4, 'XL')) AS s(sort_order, size)
+ORDER BY p.id, s.sort_order;
+
+-- สต็อกเริ่มต้นของทุก SKU
+INSERT INTO inventory (variant_id, on_hand)
+SELECT variant_id, 20 FROM product_variants;
+
 INSERT INTO brand (brandname, brandlogo) VALUES 
 ('bossini', '/images-logo/brand-bossini.jpg'),
 ('Champion', '/images-logo/brand-champion.png'),
@@ -220,6 +621,40 @@ INSERT INTO brand (brandname, brandlogo) VALUES
 ('H&M', '/images-logo/brand-hm.png'),
 ('Zara', '/images-logo/brand-zara.png');
 
+-- ค่าส่งตามพื้นที่
+INSERT INTO shipping_zones (name, base_fee, base_weight_grams, per_kg_fee, is_default) VALUES
+('กรุงเทพฯ และปริมณฑล', 40, 1000, 15, FALSE),
+('ต่างจังหวัด', 60, 1000, 20, TRUE),
+('พื้นที่ห่างไกล', 90, 1000, 30, FALSE);
+
+INSERT INTO shipping_zone_provinces (province, zone_id) VALUES
+('กรุงเทพมหานคร', 1),
+('นนทบุรี', 1),
+('ปทุมธานี', 1),
+('สมุทรปราการ', 1),
+('สมุทรสาคร', 1),
+('นครปฐม', 1),
+('แม่ฮ่องสอน', 3),
+('นราธิวาส', 3),
+('ปัตตานี', 3),
+('ยะลา', 3);
+
+-- แบรนด์ที่ส่งฟรีเมื่อซื้อครบยอด
+UPDATE brand SET free_shipping_threshold = 1500 WHERE brandname IN ('bossini', 'Champion', 'CPS');
+
+-- แบรนด์ที่ตั้งราคาไม่รวม VAT (บวก VAT ตอนคิดเงิน)
+UPDATE brand SET vat_inclusive = FALSE WHERE brandname = 'GQ';
+
+-- อัตราแลกเปลี่ยนเริ่มต้น (บาทต่อเงิน 1 หน่วย) สำหรับแสดงราคาให้นักท่องเที่ยว
+INSERT INTO exchange_rates (currency, thb_per_unit) VALUES
+('USD', 35.50),
+('EUR', 38.40),
+('GBP', 45.10),
+('JPY', 0.235),
+('CNY', 4.90),
+('KRW', 0.026),
+('SGD', 26.40);
+
 INSERT INTO about_page (id,brand_id, img, title, description) VALUES 
 (1, 1,'/images-about/brand_bossini4.jpg',
 'Bossini International Holdings Limited 
@@ -303,6 +738,9 @@ Unique Clothing Warehouse ในเมือง Hiroshima (ฮิโรชิม
  และกลายเป็นหนึ่งในแบรนด์แฟชั่นที่ใหญ่ที่สุดในโลกในปัจจุบัน เกร็ดเล็กๆ ที่แอดอยากบอกต่อคือ อามันซิโอ ออร์เตกา เจ้าของแบรนด์ซาร่า 
  เป็นบุคคลที่รวยที่สุดใน “สเปน” และเป็นเศรษฐีอันดับ 6 ของโลก ในปี 2019');
 
+-- ข้อมูลตัวอย่างกำหนด id เอง จึงต้องเลื่อน sequence ให้หน้า about ที่เพิ่มผ่าน API ได้ id ถัดไป
+SELECT setval(pg_get_serial_sequence('about_page', 'id'), (SELECT MAX(id) FROM about_page));
+
 INSERT INTO branch (brand_id, province, banch, banch_location) VALUES 
 (1, 'กรุงเทพมหานคร', 'Central Bangna (เซ็นทรัล บางนา)', 'ชั้น 2'),
 (1, 'กรุงเทพมหานคร', 'Seacon Square (ซีคอนสแควร์)', 'โซนโรบินสันชั้น 2'),
@@ -581,4 +1019,11 @@ INSERT INTO branch (brand_id, province, banch, banch_location) VALUES
 (9, 'กรุงเทพมหานคร','cental Ladprao(เซ็นทรัลลาดพร้าว)','ชั้น 1'),
 (9, 'กรุงเทพมหานคร','Mega Bangna(เมกาบางนา)','ชั้น 1'),
 (9, 'กรุงเทพมหานคร','centalwOrld(เซ็นทรัลเวิร์ด)','ชั้น 2'),
-(9, 'กรุงเทพมหานคร','Siam Paragon(สยาม พารากอน)','ชั้น 2');
\ No newline at end of file
+(9, 'กรุงเทพมหานคร','Siam Paragon(สยาม พารากอน)','ชั้น 2');
+
+-- สต็อกเริ่มต้นของแต่ละสาขา (เฉพาะสินค้าของแบรนด์ที่สาขานั้นขาย)
+INSERT INTO branch_inventory (branch_id, variant_id, quantity)
+SELECT b.id, v.variant_id, (b.id + v.variant_id) % 4
+FROM branch b
+JOIN products p ON p.brand = b.brand_id::text
+JOIN product_variants v ON v.product_id = p.id;
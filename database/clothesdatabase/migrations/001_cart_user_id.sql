@@ -0,0 +1,16 @@
+-- แยกตะกร้าตามผู้ใช้ (cart.user_id) สำหรับฐานข้อมูลที่สร้างจาก init.sql เวอร์ชันแรก
+-- ตะกร้าเดิมเป็นตะกร้ารวมที่ไม่มีเจ้าของ จึงระบุผู้ใช้ไม่ได้และถูกล้างทิ้ง
+-- รันด้วย: psql -d clothesstore -f migrations/001_cart_user_id.sql
+
+BEGIN;
+
+DELETE FROM cart;
+
+ALTER TABLE cart
+    ADD COLUMN user_id UUID NOT NULL,
+    ADD FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    ADD UNIQUE (user_id, product_id);
+
+CREATE INDEX IF NOT EXISTS idx_cart_user_id ON cart(user_id);
+
+COMMIT;
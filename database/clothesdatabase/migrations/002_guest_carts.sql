@@ -0,0 +1,15 @@
+-- เพิ่มตะกร้าของผู้เยี่ยมชม (cart.guest_token) ต่อจาก migrations/001_cart_user_id.sql
+-- รันด้วย: psql -d clothesstore -f migrations/002_guest_carts.sql
+
+BEGIN;
+
+-- รายการในตะกร้าเป็นของผู้ใช้ที่ล็อกอิน หรือของ guest_token อย่างใดอย่างหนึ่ง
+ALTER TABLE cart
+    ALTER COLUMN user_id DROP NOT NULL,
+    ADD COLUMN guest_token VARCHAR(64),
+    ADD CHECK ((user_id IS NULL) <> (guest_token IS NULL)),
+    ADD UNIQUE (guest_token, product_id);
+
+CREATE INDEX IF NOT EXISTS idx_cart_guest_token ON cart(guest_token);
+
+COMMIT;
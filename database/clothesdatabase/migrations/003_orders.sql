@@ -0,0 +1,61 @@
+-- เพิ่มตาราง orders และ order_items สำหรับการสั่งซื้อจากตะกร้า ต่อจาก migrations/002_guest_carts.sql
+-- รันด้วย: psql -d clothesstore -f migrations/003_orders.sql
+
+BEGIN;
+
+-- สร้างตาราง orders (คำสั่งซื้อที่สร้างจากตะกร้า)
+CREATE TABLE IF NOT EXISTS orders (
+    order_id SERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total FLOAT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id)
+);
+
+-- สร้างตาราง order_items (snapshot ของสินค้า ณ เวลาที่สั่งซื้อ แก้ไขไม่ได้)
+CREATE TABLE IF NOT EXISTS order_items (
+    order_item_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    product_id INT,
+    product_name VARCHAR(255) NOT NULL,
+    brand_id INT,
+    brand_name VARCHAR(100),
+    imgsrc VARCHAR(255),
+    unit_price FLOAT NOT NULL,
+    quantity INT NOT NULL,
+    line_total FLOAT NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE SET NULL
+);
+
+-- ป้องกันการแก้ไขรายการสินค้าในคำสั่งซื้อหลังจากสร้างแล้ว
+-- (อนุญาตให้ ON DELETE SET NULL ของ product_id ทำงานได้ เพื่อให้ลบสินค้าได้โดย snapshot ยังอยู่)
+CREATE OR REPLACE FUNCTION prevent_order_items_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.product_id IS NULL
+        AND (NEW.order_id, NEW.product_name, NEW.brand_id, NEW.brand_name, NEW.imgsrc, NEW.unit_price, NEW.quantity, NEW.line_total)
+        IS NOT DISTINCT FROM
+        (OLD.order_id, OLD.product_name, OLD.brand_id, OLD.brand_name, OLD.imgsrc, OLD.unit_price, OLD.quantity, OLD.line_total) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'order_items are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER order_items_immutable
+BEFORE UPDATE OR DELETE ON order_items
+FOR EACH ROW
+EXECUTE FUNCTION prevent_order_items_change();
+
+CREATE TRIGGER update_orders_updated_at
+BEFORE UPDATE ON orders
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+CREATE INDEX IF NOT EXISTS idx_orders_user_id ON orders(user_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id);
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- จำกัดสถานะของคำสั่งซื้อและเพิ่มตาราง order_status_history ต่อจาก migrations/003_orders.sql
+-- รันด้วย: psql -d clothesstore -f migrations/004_order_status_history.sql
+
+BEGIN;
+
+ALTER TABLE orders
+    ADD CHECK (status IN ('pending', 'paid', 'packed', 'shipped', 'delivered', 'cancelled', 'refunded'));
+
+-- สร้างตาราง order_status_history (ประวัติการเปลี่ยนสถานะคำสั่งซื้อ)
+CREATE TABLE IF NOT EXISTS order_status_history (
+    history_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    changed_by UUID NOT NULL,
+    note TEXT,
+    changed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (changed_by) REFERENCES users(user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history(order_id);
+
+COMMIT;
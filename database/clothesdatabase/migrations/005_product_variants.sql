@@ -0,0 +1,78 @@
+-- เพิ่ม SKU แยกไซซ์/สี (product_variants) และให้ตะกร้ากับรายการสั่งซื้ออ้างอิง SKU ต่อจาก migrations/004_order_status_history.sql
+-- รันด้วย: psql -d clothesstore -f migrations/005_product_variants.sql
+
+BEGIN;
+
+-- สร้างตาราง product_variants (SKU ของสินค้าแต่ละไซซ์/สี)
+-- price เป็น NULL เมื่อใช้ราคาเดียวกับสินค้าหลัก
+CREATE TABLE IF NOT EXISTS product_variants (
+    variant_id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    size VARCHAR(20) NOT NULL,
+    colour VARCHAR(50) NOT NULL,
+    price FLOAT,
+    barcode VARCHAR(64) UNIQUE,
+    imgsrc VARCHAR(255),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    UNIQUE (product_id, size, colour)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants(product_id);
+
+-- สร้าง SKU เริ่มต้นให้สินค้าทุกชิ้น (ไซซ์ S-XL สีมาตรฐาน ใช้ราคาของสินค้าหลัก) เหมือนข้อมูลใน init.sql
+INSERT INTO product_variants (product_id, sku, size, colour)
+SELECT p.id, 'P' || LPAD(p.id::text, 4, '0') || '-' || s.size, s.size, 'Standard'
+FROM products p
+CROSS JOIN (VALUES (1, 'S'), (2, 'M'), (3, 'L'), (4, 'XL')) AS s(sort_order, size)
+ORDER BY p.id, s.sort_order
+ON CONFLICT DO NOTHING;
+
+-- รายการในตะกร้าเดิมไม่ได้เลือกไซซ์ จึงย้ายไปที่ SKU แรกของสินค้า
+-- (การลบ product_id ลบ foreign key และ unique ที่อ้างถึงคอลัมน์นี้ไปด้วย)
+ALTER TABLE cart ADD COLUMN variant_id INT;
+
+UPDATE cart c
+SET variant_id = (SELECT MIN(v.variant_id) FROM product_variants v WHERE v.product_id = c.product_id);
+
+ALTER TABLE cart
+    DROP COLUMN product_id,
+    ALTER COLUMN variant_id SET NOT NULL,
+    ADD FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+    ADD UNIQUE (user_id, variant_id),
+    ADD UNIQUE (guest_token, variant_id);
+
+-- รายการสั่งซื้อเดิมไม่มี SKU จึงเก็บ sku/size/colour เป็นค่าว่าง
+-- (เพิ่มคอลัมน์พร้อม DEFAULT ไม่ผ่าน trigger order_items_immutable)
+ALTER TABLE order_items
+    ADD COLUMN variant_id INT,
+    ADD COLUMN sku VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN size VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN colour VARCHAR(50) NOT NULL DEFAULT '',
+    ADD FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE SET NULL;
+
+ALTER TABLE order_items
+    ALTER COLUMN sku DROP DEFAULT,
+    ALTER COLUMN size DROP DEFAULT,
+    ALTER COLUMN colour DROP DEFAULT;
+
+-- ป้องกันการแก้ไขรายการสินค้าในคำสั่งซื้อหลังจากสร้างแล้ว
+-- (อนุญาตให้ ON DELETE SET NULL ของ product_id/variant_id ทำงานได้ เพื่อให้ลบสินค้าได้โดย snapshot ยังอยู่)
+CREATE OR REPLACE FUNCTION prevent_order_items_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND (NEW.product_id IS NULL OR NEW.product_id IS NOT DISTINCT FROM OLD.product_id)
+        AND (NEW.variant_id IS NULL OR NEW.variant_id IS NOT DISTINCT FROM OLD.variant_id)
+        AND (NEW.order_id, NEW.sku, NEW.size, NEW.colour, NEW.product_name, NEW.brand_id, NEW.brand_name, NEW.imgsrc,
+             NEW.unit_price, NEW.quantity, NEW.line_total)
+        IS NOT DISTINCT FROM
+        (OLD.order_id, OLD.sku, OLD.size, OLD.colour, OLD.product_name, OLD.brand_id, OLD.brand_name, OLD.imgsrc,
+         OLD.unit_price, OLD.quantity, OLD.line_total) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'order_items are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
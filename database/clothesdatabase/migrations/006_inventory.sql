@@ -0,0 +1,29 @@
+-- เพิ่มสต็อกของแต่ละ SKU (inventory) และการจองสต็อกของรายการในตะกร้า ต่อจาก migrations/005_product_variants.sql
+-- SKU ที่ยังไม่มีแถวใน inventory ถือว่าสต็อกเป็น 0 ตั้งสต็อกจริงผ่าน API หลังรัน
+-- รันด้วย: psql -d clothesstore -f migrations/006_inventory.sql
+
+BEGIN;
+
+-- สร้างตาราง inventory (สต็อกในคลังของแต่ละ SKU)
+CREATE TABLE IF NOT EXISTS inventory (
+    variant_id INT PRIMARY KEY,
+    on_hand INT NOT NULL DEFAULT 0 CHECK (on_hand >= 0),
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE
+);
+
+-- สร้างตาราง stock_reservations (การจองสต็อกชั่วคราวของรายการในตะกร้า)
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    reservation_id SERIAL PRIMARY KEY,
+    cart_id INT NOT NULL UNIQUE,
+    variant_id INT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cart_id) REFERENCES cart(cart_id) ON DELETE CASCADE,
+    FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_variant_id ON stock_reservations(variant_id, expires_at);
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- เพิ่มสต็อกของแต่ละ SKU ที่แต่ละสาขา (branch_inventory) ต่อจาก migrations/006_inventory.sql
+-- รันด้วย: psql -d clothesstore -f migrations/007_branch_inventory.sql
+
+BEGIN;
+
+-- สร้างตาราง branch_inventory (สต็อกของแต่ละ SKU ที่แต่ละสาขา)
+CREATE TABLE IF NOT EXISTS branch_inventory (
+    branch_id INT NOT NULL,
+    variant_id INT NOT NULL,
+    quantity INT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (branch_id, variant_id),
+    FOREIGN KEY (branch_id) REFERENCES branch(id) ON DELETE CASCADE,
+    FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_branch_inventory_variant_id ON branch_inventory(variant_id);
+
+COMMIT;
@@ -0,0 +1,39 @@
+-- เพิ่มการรับสินค้าที่สาขา (คอลัมน์ pickup ของ orders และ branch_stock_holds) ต่อจาก migrations/007_branch_inventory.sql
+-- รันด้วย: psql -d clothesstore -f migrations/008_pickup_orders.sql
+
+BEGIN;
+
+ALTER TABLE orders
+    ADD COLUMN fulfilment VARCHAR(20) NOT NULL DEFAULT 'shipping' CHECK (fulfilment IN ('shipping', 'pickup')),
+    ADD COLUMN pickup_branch_id INT,
+    ADD COLUMN pickup_code VARCHAR(6),
+    ADD COLUMN pickup_window_start TIMESTAMPTZ,
+    ADD COLUMN pickup_window_end TIMESTAMPTZ,
+    ADD COLUMN collected_at TIMESTAMPTZ,
+    ADD FOREIGN KEY (pickup_branch_id) REFERENCES branch(id),
+    ADD CHECK (fulfilment <> 'pickup' OR (pickup_branch_id IS NOT NULL AND pickup_code IS NOT NULL
+                                         AND pickup_window_start IS NOT NULL AND pickup_window_end IS NOT NULL));
+
+-- สร้างตาราง branch_stock_holds (สต็อกของสาขาที่กันไว้ให้คำสั่งซื้อแบบรับที่สาขา จนกว่าจะรับสินค้าหรือหมดเวลารับ)
+CREATE TABLE IF NOT EXISTS branch_stock_holds (
+    hold_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    branch_id INT NOT NULL,
+    variant_id INT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'held' CHECK (status IN ('held', 'collected', 'released')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (branch_id) REFERENCES branch(id) ON DELETE CASCADE,
+    FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE
+);
+
+-- NULL เมื่อระบบเปลี่ยนสถานะเอง (เช่น หมดเวลารับสินค้าที่สาขา)
+ALTER TABLE order_status_history ALTER COLUMN changed_by DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_branch_stock_holds_order_id ON branch_stock_holds(order_id);
+CREATE INDEX IF NOT EXISTS idx_branch_stock_holds_held ON branch_stock_holds(branch_id, variant_id) WHERE status = 'held';
+CREATE INDEX IF NOT EXISTS idx_orders_pickup_expiry ON orders(pickup_window_end) WHERE fulfilment = 'pickup' AND collected_at IS NULL;
+
+COMMIT;
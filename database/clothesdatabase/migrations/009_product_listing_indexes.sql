@@ -0,0 +1,12 @@
+-- เพิ่ม index สำหรับการกรองและเรียงรายการสินค้าแบบ cursor ต่อจาก migrations/008_pickup_orders.sql
+-- รันด้วย: psql -d clothesstore -f migrations/009_product_listing_indexes.sql
+
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_products_category ON products(category);
+CREATE INDEX IF NOT EXISTS idx_products_brand ON products(brand);
+CREATE INDEX IF NOT EXISTS idx_products_price_id ON products(price, id);
+CREATE INDEX IF NOT EXISTS idx_products_createdate_id ON products(createdate, id);
+CREATE INDEX IF NOT EXISTS idx_products_name_id ON products(name, id);
+
+COMMIT;
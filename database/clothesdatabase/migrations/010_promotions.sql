@@ -0,0 +1,55 @@
+-- เพิ่มโปรโมชันและคูปอง (promotions, order_discounts และยอดส่วนลดของคำสั่งซื้อ) ต่อจาก migrations/009_product_listing_indexes.sql
+-- รันด้วย: psql -d clothesstore -f migrations/010_promotions.sql
+
+BEGIN;
+
+-- สร้างตาราง promotions (คูปองและโปรโมชันอัตโนมัติ code เป็น NULL คือโปรโมชันที่ไม่ต้องกรอกรหัส)
+CREATE TABLE IF NOT EXISTS promotions (
+    promotion_id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    code VARCHAR(50),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('percentage', 'fixed', 'buy_x_get_y')),
+    value FLOAT NOT NULL DEFAULT 0,
+    brand_id INT,
+    category VARCHAR(100),
+    buy_quantity INT CHECK (buy_quantity > 0),
+    get_quantity INT CHECK (get_quantity > 0),
+    min_spend FLOAT NOT NULL DEFAULT 0,
+    usage_limit INT CHECK (usage_limit > 0),
+    per_user_limit INT CHECK (per_user_limit > 0),
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (brand_id) REFERENCES brand(id) ON DELETE CASCADE,
+    CHECK (kind <> 'buy_x_get_y' OR (buy_quantity IS NOT NULL AND get_quantity IS NOT NULL))
+);
+
+ALTER TABLE orders
+    ADD COLUMN subtotal FLOAT NOT NULL DEFAULT 0,
+    ADD COLUMN discount_total FLOAT NOT NULL DEFAULT 0,
+    ADD COLUMN coupon_code VARCHAR(50);
+
+-- คำสั่งซื้อเดิมไม่มีส่วนลด ยอดก่อนหักส่วนลดจึงเท่ากับยอดรวม
+UPDATE orders SET subtotal = total;
+
+-- เพิ่มคอลัมน์พร้อม DEFAULT ไม่ผ่าน trigger order_items_immutable
+ALTER TABLE order_items ADD COLUMN discount FLOAT NOT NULL DEFAULT 0;
+
+-- สร้างตาราง order_discounts (ส่วนลดที่คำสั่งซื้อได้รับแยกตามโปรโมชัน ใช้นับจำนวนการใช้โปรโมชันด้วย)
+CREATE TABLE IF NOT EXISTS order_discounts (
+    order_discount_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    promotion_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    code VARCHAR(50),
+    amount FLOAT NOT NULL CHECK (amount > 0),
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (promotion_id) REFERENCES promotions(promotion_id),
+    UNIQUE (order_id, promotion_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promotions_code ON promotions(lower(code)) WHERE code IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_order_discounts_promotion_id ON order_discounts(promotion_id);
+
+COMMIT;
@@ -0,0 +1,74 @@
+-- เพิ่ม VAT และค่าส่ง (น้ำหนักสินค้า การตั้งค่าของแบรนด์ พื้นที่จัดส่ง และพัสดุของคำสั่งซื้อ) ต่อจาก migrations/010_promotions.sql
+-- รันด้วย: psql -d clothesstore -f migrations/011_pricing_shipping.sql
+
+BEGIN;
+
+ALTER TABLE products
+    ADD COLUMN weight_grams INT NOT NULL DEFAULT 500 CHECK (weight_grams > 0);
+
+-- vat_inclusive คือราคาสินค้าของแบรนด์รวม VAT แล้ว free_shipping_threshold เป็น NULL คือไม่มีส่งฟรี
+ALTER TABLE brand
+    ADD COLUMN vat_inclusive BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN free_shipping_threshold FLOAT CHECK (free_shipping_threshold >= 0);
+
+-- สร้างตาราง shipping_zones (ค่าส่ง: base_fee สำหรับน้ำหนักไม่เกิน base_weight_grams แล้วเพิ่ม per_kg_fee ทุกกิโลกรัมที่เกิน)
+-- จังหวัดที่ไม่อยู่ใน shipping_zone_provinces ใช้พื้นที่ที่ is_default
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    zone_id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    base_fee FLOAT NOT NULL CHECK (base_fee >= 0),
+    base_weight_grams INT NOT NULL CHECK (base_weight_grams > 0),
+    per_kg_fee FLOAT NOT NULL CHECK (per_kg_fee >= 0),
+    is_default BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- สร้างตาราง shipping_zone_provinces (จังหวัดในแต่ละพื้นที่จัดส่ง)
+CREATE TABLE IF NOT EXISTS shipping_zone_provinces (
+    province VARCHAR(255) PRIMARY KEY,
+    zone_id INT NOT NULL,
+    FOREIGN KEY (zone_id) REFERENCES shipping_zones(zone_id) ON DELETE CASCADE
+);
+
+-- คำสั่งซื้อเดิมไม่มีค่าส่งและ VAT แยก จึงเป็น 0
+ALTER TABLE orders
+    ADD COLUMN shipping_fee FLOAT NOT NULL DEFAULT 0,
+    ADD COLUMN vat_included FLOAT NOT NULL DEFAULT 0,
+    ADD COLUMN vat_added FLOAT NOT NULL DEFAULT 0,
+    ADD COLUMN shipping_province VARCHAR(255);
+
+-- สร้างตาราง order_shipments (พัสดุของแต่ละแบรนด์ในคำสั่งซื้อแบบจัดส่ง พร้อมค่าส่ง)
+CREATE TABLE IF NOT EXISTS order_shipments (
+    order_shipment_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    brand_id INT NOT NULL,
+    weight_grams INT NOT NULL,
+    fee FLOAT NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    UNIQUE (order_id, brand_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipping_zones_default ON shipping_zones(is_default) WHERE is_default;
+
+-- ค่าส่งตามพื้นที่ เหมือนข้อมูลใน init.sql (การคิดค่าส่งต้องมีพื้นที่ is_default)
+INSERT INTO shipping_zones (name, base_fee, base_weight_grams, per_kg_fee, is_default) VALUES
+('กรุงเทพฯ และปริมณฑล', 40, 1000, 15, FALSE),
+('ต่างจังหวัด', 60, 1000, 20, TRUE),
+('พื้นที่ห่างไกล', 90, 1000, 30, FALSE);
+
+INSERT INTO shipping_zone_provinces (province, zone_id)
+SELECT p.province, z.zone_id
+FROM (VALUES
+    ('กรุงเทพมหานคร', 'กรุงเทพฯ และปริมณฑล'),
+    ('นนทบุรี', 'กรุงเทพฯ และปริมณฑล'),
+    ('ปทุมธานี', 'กรุงเทพฯ และปริมณฑล'),
+    ('สมุทรปราการ', 'กรุงเทพฯ และปริมณฑล'),
+    ('สมุทรสาคร', 'กรุงเทพฯ และปริมณฑล'),
+    ('นครปฐม', 'กรุงเทพฯ และปริมณฑล'),
+    ('แม่ฮ่องสอน', 'พื้นที่ห่างไกล'),
+    ('นราธิวาส', 'พื้นที่ห่างไกล'),
+    ('ปัตตานี', 'พื้นที่ห่างไกล'),
+    ('ยะลา', 'พื้นที่ห่างไกล')
+) AS p(province, zone_name)
+JOIN shipping_zones z ON z.name = p.zone_name;
+
+COMMIT;
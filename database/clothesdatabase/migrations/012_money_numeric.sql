@@ -0,0 +1,46 @@
+-- เปลี่ยนคอลัมน์จำนวนเงินจาก FLOAT เป็น NUMERIC(12, 2) ต่อจาก migrations/011_pricing_shipping.sql
+-- (ฐานข้อมูลใหม่ได้ NUMERIC จาก init.sql อยู่แล้ว) ค่าเดิมปัดเป็นทศนิยม 2 ตำแหน่งแบบครึ่งออกจากศูนย์ ตรงกับกฎของ money.Money
+-- รันด้วย: psql -d clothesstore -f migrations/012_money_numeric.sql
+
+BEGIN;
+
+ALTER TABLE products
+    ALTER COLUMN price TYPE NUMERIC(12, 2) USING round(price::numeric, 2);
+
+ALTER TABLE product_variants
+    ALTER COLUMN price TYPE NUMERIC(12, 2) USING round(price::numeric, 2);
+
+ALTER TABLE cart
+    ALTER COLUMN price TYPE NUMERIC(12, 2) USING round(price::numeric, 2);
+
+ALTER TABLE brand
+    ALTER COLUMN free_shipping_threshold TYPE NUMERIC(12, 2) USING round(free_shipping_threshold::numeric, 2);
+
+ALTER TABLE shipping_zones
+    ALTER COLUMN base_fee TYPE NUMERIC(12, 2) USING round(base_fee::numeric, 2),
+    ALTER COLUMN per_kg_fee TYPE NUMERIC(12, 2) USING round(per_kg_fee::numeric, 2);
+
+ALTER TABLE promotions
+    ALTER COLUMN value TYPE NUMERIC(12, 2) USING round(value::numeric, 2),
+    ALTER COLUMN min_spend TYPE NUMERIC(12, 2) USING round(min_spend::numeric, 2);
+
+ALTER TABLE orders
+    ALTER COLUMN subtotal TYPE NUMERIC(12, 2) USING round(subtotal::numeric, 2),
+    ALTER COLUMN discount_total TYPE NUMERIC(12, 2) USING round(discount_total::numeric, 2),
+    ALTER COLUMN shipping_fee TYPE NUMERIC(12, 2) USING round(shipping_fee::numeric, 2),
+    ALTER COLUMN vat_included TYPE NUMERIC(12, 2) USING round(vat_included::numeric, 2),
+    ALTER COLUMN vat_added TYPE NUMERIC(12, 2) USING round(vat_added::numeric, 2),
+    ALTER COLUMN total TYPE NUMERIC(12, 2) USING round(total::numeric, 2);
+
+ALTER TABLE order_items
+    ALTER COLUMN unit_price TYPE NUMERIC(12, 2) USING round(unit_price::numeric, 2),
+    ALTER COLUMN line_total TYPE NUMERIC(12, 2) USING round(line_total::numeric, 2),
+    ALTER COLUMN discount TYPE NUMERIC(12, 2) USING round(discount::numeric, 2);
+
+ALTER TABLE order_shipments
+    ALTER COLUMN fee TYPE NUMERIC(12, 2) USING round(fee::numeric, 2);
+
+ALTER TABLE order_discounts
+    ALTER COLUMN amount TYPE NUMERIC(12, 2) USING round(amount::numeric, 2);
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- เพิ่มตารางอัตราแลกเปลี่ยนและคอลัมน์สกุลเงินที่แสดงในคำสั่งซื้อ สำหรับฐานข้อมูลที่สร้างจาก init.sql เวอร์ชันก่อน
+-- รันด้วย: psql -d clothesstore -f migrations/013_exchange_rates.sql
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    rate_id SERIAL PRIMARY KEY,
+    currency CHAR(3) NOT NULL CHECK (currency <> 'THB'),
+    thb_per_unit NUMERIC(18, 6) NOT NULL CHECK (thb_per_unit > 0),
+    created_by UUID,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_currency ON exchange_rates(currency, created_at DESC);
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS display_currency CHAR(3),
+    ADD COLUMN IF NOT EXISTS exchange_rate NUMERIC(18, 6),
+    ADD COLUMN IF NOT EXISTS exchange_rate_id INT REFERENCES exchange_rates(rate_id),
+    ADD CHECK ((display_currency IS NULL) = (exchange_rate IS NULL));
+
+INSERT INTO exchange_rates (currency, thb_per_unit)
+SELECT v.currency, v.thb_per_unit
+FROM (VALUES ('USD', 35.50), ('EUR', 38.40), ('GBP', 45.10), ('JPY', 0.235),
+             ('CNY', 4.90), ('KRW', 0.026), ('SGD', 26.40)) AS v(currency, thb_per_unit)
+WHERE NOT EXISTS (SELECT 1 FROM exchange_rates);
+
+COMMIT;
@@ -0,0 +1,25 @@
+-- เพิ่มตาราง payments สำหรับฐานข้อมูลที่สร้างจาก init.sql เวอร์ชันก่อน
+-- รันด้วย: psql -d clothesstore -f migrations/014_payments.sql
+
+BEGIN;
+
+-- สร้างตาราง payments (การชำระเงินของคำสั่งซื้อ ลองชำระได้หลายครั้ง)
+-- reference คือรหัสที่ผู้ให้บริการส่งกลับมาใน callback ยอดเงินคัดลอกจากยอดรวมของคำสั่งซื้อตอนเริ่มชำระ
+CREATE TABLE IF NOT EXISTS payments (
+    payment_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    method VARCHAR(20) NOT NULL CHECK (method IN ('promptpay', 'card')),
+    reference VARCHAR(25) NOT NULL,
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    qr_payload TEXT,
+    note TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    paid_at TIMESTAMPTZ,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id) ON DELETE CASCADE,
+    UNIQUE (method, reference)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id);
+
+COMMIT;
@@ -0,0 +1,22 @@
+-- เพิ่มตาราง idempotency_keys สำหรับฐานข้อมูลที่สร้างจาก init.sql เวอร์ชันก่อน
+-- รันด้วย: psql -d clothesstore -f migrations/015_idempotency_keys.sql
+
+BEGIN;
+
+-- สร้างตาราง idempotency_keys (response ของ POST ที่ส่ง Idempotency-Key มา เก็บไว้ส่งซ้ำเมื่อ retry)
+-- scope คือ hash ของผู้เรียก status_code เป็น NULL ขณะที่ request แรกยังทำงานอยู่
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope CHAR(64) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT,
+    response_headers JSONB,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+COMMIT;
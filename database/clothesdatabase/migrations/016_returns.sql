@@ -0,0 +1,43 @@
+-- เพิ่มระยะเวลารับคืนของแบรนด์และตาราง return_requests สำหรับฐานข้อมูลที่สร้างจาก init.sql เวอร์ชันก่อน
+-- รันด้วย: psql -d clothesstore -f migrations/016_returns.sql
+
+BEGIN;
+
+ALTER TABLE brand
+    ADD COLUMN IF NOT EXISTS return_window_days INT NOT NULL DEFAULT 14 CHECK (return_window_days >= 0);
+
+-- สร้างตาราง return_requests (คำขอคืนสินค้าของรายการในคำสั่งซื้อ)
+-- requested → approved/rejected → received → refunded ส่วน photos คือ URL ของรูปที่ลูกค้าอัปโหลด
+CREATE TABLE IF NOT EXISTS return_requests (
+    return_id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL,
+    order_item_id INT NOT NULL,
+    user_id UUID NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    reason TEXT NOT NULL,
+    photos TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'requested'
+        CHECK (status IN ('requested', 'approved', 'rejected', 'received', 'refunded')),
+    staff_note TEXT,
+    refund_amount NUMERIC(12, 2) CHECK (refund_amount >= 0),
+    reviewed_by UUID,
+    reviewed_at TIMESTAMPTZ,
+    received_by UUID,
+    received_at TIMESTAMPTZ,
+    refunded_by UUID,
+    refunded_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    FOREIGN KEY (order_item_id) REFERENCES order_items(order_item_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id),
+    FOREIGN KEY (reviewed_by) REFERENCES users(user_id),
+    FOREIGN KEY (received_by) REFERENCES users(user_id),
+    FOREIGN KEY (refunded_by) REFERENCES users(user_id),
+    CHECK (status <> 'refunded' OR refund_amount IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_return_requests_order_item_id ON return_requests(order_item_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_user_id ON return_requests(user_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_status ON return_requests(status);
+
+COMMIT;
@@ -0,0 +1,46 @@
+-- เพิ่มข้อมูลผู้ซื้อ/ผู้ขายสำหรับใบกำกับภาษี และตาราง invoices สำหรับฐานข้อมูลที่สร้างจาก init.sql เวอร์ชันก่อน
+-- รันด้วย: psql -d clothesstore -f migrations/017_invoices.sql
+
+BEGIN;
+
+ALTER TABLE brand
+    ADD COLUMN IF NOT EXISTS tax_id VARCHAR(13),
+    ADD COLUMN IF NOT EXISTS address TEXT;
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS buyer_name VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS buyer_tax_id VARCHAR(13),
+    ADD COLUMN IF NOT EXISTS buyer_address TEXT,
+    ADD COLUMN IF NOT EXISTS buyer_branch VARCHAR(100);
+
+-- สร้างตาราง brand_invoice_counters (เลขที่เอกสารล่าสุดของแต่ละแบรนด์ เพิ่มทีละหนึ่งใน transaction ที่ออกเอกสาร)
+CREATE TABLE IF NOT EXISTS brand_invoice_counters (
+    brand_id INT PRIMARY KEY,
+    last_number INT NOT NULL CHECK (last_number > 0)
+);
+
+-- สร้างตาราง invoices (ใบเสร็จรับเงิน/ใบกำกับภาษีของแต่ละแบรนด์ในคำสั่งซื้อ ออกครั้งเดียวและไม่เปลี่ยนภายหลัง)
+-- ข้อมูลผู้ขายและยอดเงินบันทึกไว้ ณ เวลาที่ออก ส่วนรายการสินค้าอ่านจาก order_items
+CREATE TABLE IF NOT EXISTS invoices (
+    invoice_id SERIAL PRIMARY KEY,
+    invoice_number VARCHAR(30) NOT NULL UNIQUE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('tax_invoice', 'receipt')),
+    order_id INT NOT NULL,
+    brand_id INT NOT NULL,
+    sequence INT NOT NULL CHECK (sequence > 0),
+    seller_name VARCHAR(100) NOT NULL,
+    seller_tax_id VARCHAR(13),
+    seller_address TEXT,
+    vat_inclusive BOOLEAN NOT NULL,
+    subtotal NUMERIC(12, 2) NOT NULL,
+    discount NUMERIC(12, 2) NOT NULL,
+    shipping_fee NUMERIC(12, 2) NOT NULL,
+    vat NUMERIC(12, 2) NOT NULL,
+    total NUMERIC(12, 2) NOT NULL,
+    issued_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES orders(order_id),
+    UNIQUE (order_id, brand_id),
+    UNIQUE (brand_id, sequence)
+);
+
+COMMIT;
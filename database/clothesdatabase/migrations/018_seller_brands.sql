@@ -0,0 +1,28 @@
+-- เพิ่มตาราง seller_brands และให้แต่ละแบรนด์มีหน้า about ได้หน้าเดียว สำหรับฐานข้อมูลที่สร้างจาก init.sql เวอร์ชันก่อน
+-- รันด้วย: psql -d clothesstore -f migrations/018_seller_brands.sql
+
+BEGIN;
+
+-- สร้างตาราง seller_brands (แบรนด์ที่ผู้ใช้ role seller แต่ละคนดูแล ผู้ขายแก้ไขได้เฉพาะสินค้า สาขา และหน้า about ของแบรนด์เหล่านี้)
+CREATE TABLE IF NOT EXISTS seller_brands (
+    user_id UUID NOT NULL,
+    brand_id INT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, brand_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (brand_id) REFERENCES brand(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_seller_brands_brand_id ON seller_brands(brand_id);
+
+-- หน้า about ที่ซ้ำกันของแบรนด์เดียวกันเก็บไว้เฉพาะหน้าล่าสุด
+DELETE FROM about_page a
+USING about_page newer
+WHERE a.brand_id = newer.brand_id AND a.id < newer.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_about_page_brand_id ON about_page(brand_id);
+
+-- ข้อมูลตัวอย่างกำหนด id เอง จึงต้องเลื่อน sequence ให้หน้า about ที่เพิ่มผ่าน API ได้ id ถัดไป
+SELECT setval(pg_get_serial_sequence('about_page', 'id'), (SELECT COALESCE(MAX(id), 0) + 1 FROM about_page), false);
+
+COMMIT;
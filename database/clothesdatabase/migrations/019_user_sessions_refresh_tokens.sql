@@ -0,0 +1,14 @@
+-- เปลี่ยน user_sessions ให้เก็บ hash ของ refresh token แทน id_token สำหรับฐานข้อมูลที่สร้างจาก init.sql เวอร์ชันก่อน
+-- ตารางนี้ยังไม่เคยถูกเขียน แถวเดิม (ถ้ามี) ใช้ต่อไม่ได้จึงลบทิ้ง
+-- รันด้วย: psql -d clothesstore -f migrations/019_user_sessions_refresh_tokens.sql
+
+BEGIN;
+
+DELETE FROM user_sessions;
+
+ALTER TABLE user_sessions
+    DROP COLUMN IF EXISTS id_token,
+    ADD COLUMN IF NOT EXISTS refresh_token_hash VARCHAR(64) NOT NULL,
+    ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1,21 @@
+-- ให้ user_login_history เก็บการล็อกอินที่ไม่สำเร็จได้ (user_id เป็น NULL ได้และมีสาเหตุ) สำหรับฐานข้อมูลที่สร้างจาก init.sql เวอร์ชันก่อน
+-- รันด้วย: psql -d clothesstore -f migrations/020_login_history.sql
+
+BEGIN;
+
+ALTER TABLE user_login_history
+    ALTER COLUMN user_id DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS failure_reason VARCHAR(50);
+
+-- แถวเดิมที่ไม่สำเร็จไม่มีสาเหตุบันทึกไว้
+UPDATE user_login_history SET failure_reason = 'unknown' WHERE NOT success AND failure_reason IS NULL;
+
+ALTER TABLE user_login_history
+    ADD CONSTRAINT user_login_history_failure_reason_check CHECK (success = (failure_reason IS NULL));
+
+DROP INDEX IF EXISTS idx_user_login_history_user_id;
+CREATE INDEX IF NOT EXISTS idx_user_login_history_user_id ON user_login_history(user_id, login_timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_user_login_history_timestamp ON user_login_history(login_timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_user_login_history_ip_address ON user_login_history(ip_address);
+
+COMMIT;
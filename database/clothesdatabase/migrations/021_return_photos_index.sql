@@ -0,0 +1,8 @@
+-- เพิ่ม index ของรูปในคำขอคืนสินค้า ใช้หาคำขอของรูปเพื่อตรวจสิทธิ์ก่อนส่งไฟล์รูป
+-- รันด้วย: psql -d clothesstore -f migrations/021_return_photos_index.sql
+
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_return_requests_photos ON return_requests USING GIN (photos);
+
+COMMIT;
@@ -0,0 +1,10 @@
+-- เพิ่ม jti ของ access token ล่าสุดของแต่ละ session (user_sessions.access_token_id)
+-- access token ที่ออกก่อนหน้านี้ไม่มี jti จึงใช้ไม่ได้อีก client ต้อง refresh ด้วย refresh token เดิมเพื่อรับ token ใหม่
+-- รันด้วย: psql -d clothesstore -f migrations/022_user_sessions_access_token_id.sql
+
+BEGIN;
+
+ALTER TABLE user_sessions
+    ADD COLUMN IF NOT EXISTS access_token_id UUID NOT NULL DEFAULT uuid_generate_v4();
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- เพิ่มสถานะ expired ของคำสั่งซื้อแบบรับที่สาขาที่ชำระเงินแล้วแต่ไม่มารับภายในเวลา (ต้องคืนเงินต่อ)
+-- ต่อจาก migrations/004_order_status_history.sql
+-- รันด้วย: psql -d clothesstore -f migrations/023_pickup_expired_status.sql
+
+BEGIN;
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders
+    ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('pending', 'paid', 'packed', 'shipped', 'delivered', 'cancelled', 'refunded', 'expired'));
+
+COMMIT;
@@ -19,9 +19,7 @@ const BrandSelectDropdown = ({ onBrandSelect }) => {
 
   const handleBrandChange = (event) => {
     const selectedBrandId = event.target.value;
-    if (selectedBrandId) {
-      onBrandSelect(selectedBrandId); // เรียก callback พร้อมกับ id ของแบรนด์ที่เลือก
-    }
+    onBrandSelect(selectedBrandId || null); // เรียก callback พร้อมกับ id ของแบรนด์ที่เลือก (null เมื่อเลือกทุกแบรนด์)
   };
 
   return (
@@ -1,29 +1,15 @@
 import React from 'react';
-import { Link } from 'react-router-dom';
-import axios from 'axios';
+import { Link, useNavigate } from 'react-router-dom';
+import { formatDisplayPrice } from '../utils/displayCurrency';
 
 const ClothesCard = ({ product }) => {
-  // ฟังก์ชันเพิ่มสินค้าลงในตะกร้าโดยตรง
-  const handleAddToCart = async () => {
-    try {
-      const response = await axios.post('http://localhost:8080/api/v1/cart', {
-        product_id: product.id,
-        quantity: 1, // ปริมาณที่ต้องการ
-      });
+  const navigate = useNavigate();
 
-      if (response.status === 200) {
-        alert('Product added to cart');
-      } else {
-        alert('Something went wrong. Please try again later.');
-      }
-    } catch (error) {
-      console.error('Error adding product to cart:', error);
-      alert('Failed to add product to cart');
-    }
+  // สินค้ามีหลายไซซ์/สี ต้องเลือก SKU ที่หน้ารายละเอียดสินค้าก่อนเพิ่มลงตะกร้า
+  const handleAddToCart = () => {
+    navigate(`/product/${product.id}`);
   };
 
-  
-
   return (
     <div className="product-item">
       <Link to={`/product/${product.id}`} className="product-link">
@@ -41,6 +27,9 @@ const ClothesCard = ({ product }) => {
               <Link to={`/product/${product.id}`}>{product.name}</Link>
             </h6>
             <div className="product_price">฿{product.price}</div>
+            {product.display_price && (
+              <div className="product_display_price">{formatDisplayPrice(product.display_price)}</div>
+            )}
           </div>
         </div>
       </Link>
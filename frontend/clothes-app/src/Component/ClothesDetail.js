@@ -1,13 +1,19 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useRef } from 'react';
 import { useParams, useNavigate } from 'react-router-dom';
 import { FontAwesomeIcon } from '@fortawesome/react-fontawesome'; 
 import { faArrowLeft } from '@fortawesome/free-solid-svg-icons';
 import axios from 'axios';
+import cartRequestConfig from '../utils/cartRequestConfig';
+import newIdempotencyKey from '../utils/idempotencyKey';
+import { formatDisplayPrice } from '../utils/displayCurrency';
 
 const ProductDetail = () => {
   const { productId } = useParams();
   const [product, setProduct] = useState(null);
   const [quantity, setQuantity] = useState(1);
+  const [variantId, setVariantId] = useState(null); // SKU (ไซซ์/สี) ที่เลือก
+  const [availability, setAvailability] = useState([]); // สาขาที่มีสินค้าในสต็อก
+  const [province, setProvince] = useState('');
   const navigate = useNavigate();
 
   useEffect(() => {
@@ -15,14 +21,31 @@ const ProductDetail = () => {
       try {
         const response = await axios.get(`http://localhost:8080/api/v1/products/${productId}`);
         setProduct(response.data);
+        // เลือก SKU แรกไว้เป็นค่าเริ่มต้น
+        const variants = response.data.variants || [];
+        setVariantId(variants.length > 0 ? variants[0].id : null);
       } catch (error) {
         console.error('There was a problem with the fetch operation:', error);
       }
     };
 
+    // ดึงสาขาที่มีสินค้านี้ในสต็อก (ทุกจังหวัด) เพื่อแสดง "in stock near you"
+    const fetchAvailability = async () => {
+      try {
+        const response = await axios.get(`http://localhost:8080/api/v1/products/${productId}/availability`);
+        setAvailability(response.data || []);
+      } catch (error) {
+        console.error('Error fetching availability:', error);
+      }
+    };
+
     fetchProduct();
+    fetchAvailability();
   }, [productId]);
 
+  const provinces = [...new Set(availability.map(branch => branch.province))];
+  const nearbyBranches = availability.filter(branch => branch.province === province);
+
   if (!product) {
     return <h2>Product not found!</h2>;
   }
@@ -34,23 +57,38 @@ const ProductDetail = () => {
 
   const handleGoBack = () => navigate(-1);
 
+  // คีย์ของการเพิ่มลงตะกร้าครั้งนี้ ดับเบิลคลิกจะส่งคีย์เดิมจึงไม่เพิ่มจำนวนซ้ำ
+  // สร้างคีย์ใหม่เมื่อเพิ่มสำเร็จหรือเปลี่ยนไซซ์/จำนวน
+  const addToCartKey = useRef(newIdempotencyKey());
+  useEffect(() => {
+    addToCartKey.current = newIdempotencyKey();
+  }, [variantId, quantity]);
+
   const handleAddToCart = async () => {
+    if (!variantId) {
+      alert('Please select a size');
+      return;
+    }
     try {
       // เรียก API เพื่อเพิ่มสินค้าไปยังตะกร้า
       const response = await axios.post(`http://localhost:8080/api/v1/cart`, {
-        product_id: product.id,
+        variant_id: variantId,
       quantity: quantity
-    });
+    }, cartRequestConfig(addToCartKey.current));
 
     // แสดงข้อมูลจาก Response
     console.log(response.data);
 
     if (response.status === 200) {
+      addToCartKey.current = newIdempotencyKey();
       alert('Product added to cart');
     } else {
       alert('Something went wrong. Please try again later.');
     }
   } catch (error) {
+    // 409 คือคลิกซ้ำระหว่างที่ request แรกยังทำงานอยู่ ผลของ request แรกจะแจ้งเอง
+    if (error.response && error.response.status === 409) return;
+
     // แสดงข้อผิดพลาดที่เกิดขึ้นจาก API
     console.error('Error adding product to cart:', error);
 
@@ -75,6 +113,9 @@ const ProductDetail = () => {
           <h2>{product.name}</h2>
           <div className="product-price">
             <span>฿ {product.price}</span>
+            {product.display_price && (
+              <small className="product_display_price"> {formatDisplayPrice(product.display_price)}</small>
+            )}
           </div>
         </div>
 
@@ -83,6 +124,18 @@ const ProductDetail = () => {
             <p>{product.description}</p>
           </div>
         </div>
+        {product.variants && product.variants.length > 0 && (
+          <div className="variant-selector">
+            <p>Size / Colour</p>
+            <select value={variantId || ''} onChange={(e) => setVariantId(Number(e.target.value))}>
+              {product.variants.map(variant => (
+                <option key={variant.id} value={variant.id}>
+                  {variant.size} - {variant.colour}{variant.price ? ` (฿${variant.price})` : ''}
+                </option>
+              ))}
+            </select>
+          </div>
+        )}
         <div className='text-quantity'>
           <p>Quantity</p>
         </div>
@@ -97,6 +150,26 @@ const ProductDetail = () => {
             Add to cart
           </button>
         </div>
+
+        {provinces.length > 0 && (
+          <div className="store-availability">
+            <p>In stock near you</p>
+            <select value={province} onChange={(e) => setProvince(e.target.value)}>
+              <option value="">Select province</option>
+              {provinces.map(name => (
+                <option key={name} value={name}>{name}</option>
+              ))}
+            </select>
+            {nearbyBranches.map(branch => (
+              <div key={branch.id} className="store-availability-branch">
+                <strong>{branch.banch}</strong> ({branch.banch_location})
+                <div>
+                  {branch.sizes.map(size => `${size.size} ${size.colour}: ${size.quantity}`).join(', ')}
+                </div>
+              </div>
+            ))}
+          </div>
+        )}
       </div>
     </main>
   );
@@ -0,0 +1,45 @@
+import React from 'react';
+
+const priceLabel = (bucket) =>
+  bucket.max ? `฿${bucket.min.toLocaleString()} - ฿${bucket.max.toLocaleString()}` : `฿${bucket.min.toLocaleString()}+`;
+
+// แถบตัวกรองจาก facet ของผลการค้นหา แต่ละตัวเลือกแสดงจำนวนสินค้า
+// selected คือ { brand, category, priceKey } และ onChange รับ selected ใหม่ (กดซ้ำเพื่อยกเลิก)
+const FacetSidebar = ({ facets, selected, onChange }) => {
+  if (!facets) return null;
+
+  const toggle = (key, value) => {
+    onChange({ ...selected, [key]: selected[key] === value ? null : value });
+  };
+
+  const renderGroup = (title, items, key) => (
+    <div className="mb-3">
+      <h6>{title}</h6>
+      <ul className="list-unstyled">
+        {items.map((item) => (
+          <li key={item.value}>
+            <label>
+              <input
+                type="checkbox"
+                checked={selected[key] === item.value}
+                disabled={item.count === 0 && selected[key] !== item.value}
+                onChange={() => toggle(key, item.value)}
+              />{' '}
+              {item.label} ({item.count})
+            </label>
+          </li>
+        ))}
+      </ul>
+    </div>
+  );
+
+  return (
+    <div className="facet-sidebar">
+      {renderGroup('Brand', facets.brands.map((f) => ({ value: f.value, label: f.label || f.value, count: f.count })), 'brand')}
+      {renderGroup('Category', facets.categories.map((f) => ({ value: f.value, label: f.value, count: f.count })), 'category')}
+      {renderGroup('Price', facets.price_buckets.map((b) => ({ value: b.key, label: priceLabel(b), count: b.count })), 'priceKey')}
+    </div>
+  );
+};
+
+export default FacetSidebar;
@@ -14,9 +14,10 @@ const GoogleAuth = ({ setUser, handleClose }) => {
       console.log('Decoded token:', decodedToken);
 
       // ส่ง token ไปยัง backend เพื่อตรวจสอบและรับข้อมูลผู้ใช้
+      // withCredentials เพื่อส่ง cookie guest_token ให้ backend รวมตะกร้าของ guest เข้ากับตะกร้าของผู้ใช้
       const backendResponse = await axios.post('http://localhost:8081/api/v1/auth/google/verify', {
         id_token: credentialResponse.credential,
-      });
+      }, { withCredentials: true });
 
       if (backendResponse.status === 200) {
         console.log('Backend response:', backendResponse.data);
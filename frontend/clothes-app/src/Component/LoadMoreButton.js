@@ -0,0 +1,15 @@
+import React from 'react';
+
+// ปุ่มโหลดสินค้าหน้าถัดไป แสดงจำนวนที่แสดงอยู่เทียบกับทั้งหมด
+const LoadMoreButton = ({ shown, total, hasMore, onLoadMore }) => {
+  if (!hasMore) return null;
+  return (
+    <div className="text-center my-3">
+      <button className="btn btn-outline-dark" onClick={onLoadMore}>
+        Load more ({shown} / {total})
+      </button>
+    </div>
+  );
+};
+
+export default LoadMoreButton;
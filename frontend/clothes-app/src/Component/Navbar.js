@@ -5,6 +5,9 @@ import React, { useState, useEffect, useRef } from 'react';  // นำเข้
 import { Button, Modal, Dropdown } from 'react-bootstrap'; // เพิ่ม Dropdown จาก react-bootstrap
 import GoogleAuth from './GoogleAuth';
 import axios from 'axios';
+import cartRequestConfig from '../utils/cartRequestConfig';
+import useSuggestions from '../utils/useSuggestions';
+import { getDisplayCurrency, setDisplayCurrency } from '../utils/displayCurrency';
 
 const Header = () => {
   const [searchTerm, setSearchTerm] = useState(''); // เก็บคำค้นหา
@@ -14,6 +17,23 @@ const Header = () => {
   const searchInputRef = useRef(null); // อ้างอิงถึงช่องค้นหา
   const historyDropdownRef = useRef(null); // อ้างอิงถึง dropdown
   const [cartCount, setCartCount] = useState(0);
+  const { suggestions, didYouMean } = useSuggestions(searchTerm); // คำแนะนำขณะพิมพ์
+  const [currencies, setCurrencies] = useState([]); // สกุลเงินที่แสดงราคาได้
+  const [displayCurrency, setDisplayCurrencyState] = useState(getDisplayCurrency());
+
+  // โหลดรายการสกุลเงินจาก /api/v1/currencies
+  useEffect(() => {
+    axios.get('http://localhost:8080/api/v1/currencies')
+      .then((response) => setCurrencies((response.data.rates || []).map((rate) => rate.currency)))
+      .catch((error) => console.error('Error fetching currencies:', error));
+  }, []);
+
+  // เปลี่ยนสกุลเงินแล้วโหลดหน้าใหม่เพื่อให้ทุกราคาดึงมาด้วยสกุลเงินใหม่
+  const handleCurrencyChange = (e) => {
+    setDisplayCurrency(e.target.value);
+    setDisplayCurrencyState(e.target.value);
+    window.location.reload();
+  };
 
   // โหลดประวัติการค้นหาจาก localStorage
   useEffect(() => {
@@ -43,6 +63,14 @@ const Header = () => {
     setShowHistory(false);
   };
 
+  // เลือกคำแนะนำ: แบรนด์ไปหน้าแบรนด์ สินค้าไปหน้าสินค้า
+  const handleSuggestionClick = (suggestion) => {
+    updateSearchHistory(suggestion.text);
+    setSearchTerm(suggestion.text);
+    navigate(suggestion.type === 'brand' ? `/brand/${suggestion.id}` : `/product/${suggestion.id}`);
+    setShowHistory(false);
+  };
+
   const handleFocus = () => {
     setShowHistory(true); // แสดง dropdown เมื่อ focus ช่องค้นหา
   };
@@ -102,8 +130,8 @@ const Header = () => {
 
   const fetchCartCount = async () => {
     try {
-      const response = await axios.get('http://localhost:8080/api/v1/cart');
-      const totalItems = response.data.reduce((total, item) => total + item.quantity, 0);
+      const response = await axios.get('http://localhost:8080/api/v1/cart', cartRequestConfig());
+      const totalItems = (response.data.items || []).reduce((total, item) => total + item.quantity, 0);
       setCartCount(totalItems); // อัพเดต cartCount
     } catch (error) {
       console.error('Error fetching cart count:', error);
@@ -117,7 +145,7 @@ const Header = () => {
   // ฟังก์ชันการเพิ่มสินค้าในตะกร้า
   const handleAddToCart = async (productId) => {
     try {
-      await axios.post('http://localhost:8080/api/v1/cart', { product_id: productId, quantity: 1 });
+      await axios.post('http://localhost:8080/api/v1/cart', { product_id: productId, quantity: 1 }, cartRequestConfig());
       fetchCartCount(); // เรียกฟังก์ชันเพื่ออัพเดตจำนวนสินค้าหลังจากเพิ่มสินค้า
     } catch (error) {
       console.error('Error adding product to cart:', error);
@@ -154,8 +182,57 @@ const Header = () => {
           <button type="submit" className="search-button">
             <i className="fas fa-search"></i>
           </button>
-          {/* แสดง dropdown ของประวัติการค้นหา */}
-          {showHistory && searchHistory.length > 0 && (
+          {/* แสดงคำแนะนำขณะพิมพ์ และ "คุณหมายถึง" เมื่อสะกดผิด */}
+          {showHistory && searchTerm.trim() && (suggestions.length > 0 || didYouMean) && (
+            <ul
+              ref={historyDropdownRef}
+              className="search-history-dropdown"
+              style={{
+                position: 'absolute',
+                top: '100%',
+                left: 0,
+                right: 0,
+                backgroundColor: 'white',
+                border: '1px solid #ccc',
+                maxHeight: '300px',
+                overflowY: 'auto',
+                zIndex: 10,
+                listStyle: 'none',
+                margin: 0,
+                padding: 0,
+              }}
+            >
+              {didYouMean && (
+                <li
+                  onClick={() => handleHistoryClick(didYouMean)}
+                  style={{ padding: '8px', borderBottom: '1px solid #eee', cursor: 'pointer' }}
+                >
+                  คุณหมายถึง <strong>{didYouMean}</strong>
+                </li>
+              )}
+              {suggestions.map((suggestion) => (
+                <li
+                  key={`${suggestion.type}-${suggestion.id}`}
+                  onClick={() => handleSuggestionClick(suggestion)}
+                  style={{
+                    display: 'flex',
+                    justifyContent: 'space-between',
+                    alignItems: 'center',
+                    padding: '8px',
+                    borderBottom: '1px solid #eee',
+                    cursor: 'pointer'
+                  }}
+                >
+                  <span style={{ flex: 1 }}>{suggestion.text}</span>
+                  <small style={{ color: '#888', marginLeft: '10px' }}>
+                    {suggestion.type === 'brand' ? 'แบรนด์' : 'สินค้า'}
+                  </small>
+                </li>
+              ))}
+            </ul>
+          )}
+          {/* แสดง dropdown ของประวัติการค้นหา (เมื่อยังไม่ได้พิมพ์) */}
+          {showHistory && !searchTerm.trim() && searchHistory.length > 0 && (
             <ul
               ref={historyDropdownRef}
               className="search-history-dropdown"
@@ -212,6 +289,13 @@ const Header = () => {
           )}
         </form>
 
+        <select className="currency-select" value={displayCurrency} onChange={handleCurrencyChange}>
+          <option value="THB">THB</option>
+          {currencies.map((code) => (
+            <option key={code} value={code}>{code}</option>
+          ))}
+        </select>
+
         <i className="fas fa-shopping-bag" onClick={handleCartClick}>
             {cartCount > 0 && (
               <span className="cart-count">{cartCount}</span> // แสดงจำนวนสินค้าถ้ามี
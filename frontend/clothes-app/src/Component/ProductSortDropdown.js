@@ -1,12 +1,17 @@
 // src/components/ProductSortDropdown.js
 import React from 'react';
 
-const ProductSortDropdown = ({ sortOrder, onSortChange }) => {
+// ค่าของแต่ละตัวเลือกตรงกับพารามิเตอร์ sort ของ /api/v1/products
+// showRelevance ใช้ในหน้าผลการค้นหา ซึ่งเรียงตามความเกี่ยวข้องได้
+const ProductSortDropdown = ({ sortOrder, onSortChange, showRelevance = false }) => {
   return (
     <select value={sortOrder} onChange={onSortChange}>
-      <option value="default">Price</option>
-      <option value="price-asc">price (Min-Max)</option>
-      <option value="price-desc">price (Max-Min)</option>
+      {showRelevance && <option value="relevance">Relevance</option>}
+      <option value="newest">Newest</option>
+      <option value="price_asc">price (Min-Max)</option>
+      <option value="price_desc">price (Max-Min)</option>
+      <option value="name_asc">Name (A-Z)</option>
+      <option value="name_desc">Name (Z-A)</option>
     </select>
   );
 };
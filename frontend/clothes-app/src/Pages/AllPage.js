@@ -1,91 +1,27 @@
-import React, { useState, useEffect } from 'react';
-import axios from 'axios';
+import React, { useState } from 'react';
 import ClothesCard from '../Component/ClothesCard';
 import ProductSortDropdown from '../Component/ProductSortDropdown';
 import BrandSelectDropdown from '../Component/BrandSelectDropdown';
-
-const api_url = '/api/v1/products';
+import LoadMoreButton from '../Component/LoadMoreButton';
+import useProductPage from '../utils/useProductPage';
 
 const AllPage = () => {
-  const [clothesData, setClothesData] = useState([]);
-  const [loading, setLoading] = useState(true);
-  const [error, setError] = useState(null);
-  const [sortOrder, setSortOrder] = useState('default');
+  const [sortOrder, setSortOrder] = useState('newest');
   const [selectedBrandId, setSelectedBrandId] = useState(null);
-
-  useEffect(() => {
-    // ฟังก์ชันสำหรับดึงข้อมูลจาก API
-    const fetchData = async () => {
-      try {
-        const response = await fetch(api_url);
-        if (!response.ok) {
-          console.error('Response status:', response.status);
-          throw new Error(`Failed to fetch clothes, status code:
-    ${response.status}`);
-        }
-        const data = await response.json();
-        // ตรวจสอบว่าข้อมูลทีBได้รับเป็นอาเรย์หรือไม่
-        if (Array.isArray(data)) {
-          // ถ้าเป็นอาเรย์ก็จัดเรียงได้เลย
-          const sortedBooks = data.sort(
-            (a, b) => new Date(b.dateAdded) - new Date(a.dateAdded)
-          );
-          setClothesData(sortedBooks); // เลือก 3 เล่ม
-        } else if (Array.isArray(data.books)) {
-          // ถ้าข้อมูลอยู่ในฟิลด์ "books"
-          const sortedBooks = data.books.sort(
-            (a, b) => new Date(b.dateAdded) - new Date(a.dateAdded)
-          );
-          setClothesData(sortedBooks);
-        } else {
-          throw new Error('Unexpected data format');
-        }
-        setLoading(false);
-      } catch (error) {
-        console.error('Error fetching clothes:', error);
-        setError(error.message);
-        setLoading(false);
-      }
-    };
-    fetchData(selectedBrandId);
-  }, [selectedBrandId]);
+  // การเรียงลำดับและกรองแบรนด์ทำที่ API
+  const { products, total, loading, error, hasMore, loadMore } = useProductPage({
+    brand: selectedBrandId,
+    sort: sortOrder,
+  });
 
   const handleSortChange = (e) => {
     setSortOrder(e.target.value);
   };
 
-  const fetchData = async (brandId = '') => {
-    try {
-      const response = await axios.get(`/api/v1/products${brandId ? `/brand/${brandId}` : ''}`);
-      const data = response.data;
-      if (Array.isArray(data)) {
-        const sortedProducts = data.sort((a, b) => new Date(b.dateAdded) - new Date(a.dateAdded));
-        setClothesData(sortedProducts);
-      } else {
-        setClothesData([]);
-      }
-      setLoading(false);
-    } catch (error) {
-      console.error('Error fetching clothes:', error);
-      setError(error.message);
-      setLoading(false);
-    }
-  };
-
-  useEffect(() => {
-    fetchData(selectedBrandId);
-  }, [selectedBrandId]);
-
   const handleBrandSelect = (brandId) => {
     setSelectedBrandId(brandId);
   };
 
-  const sortedData = [...clothesData].sort((a, b) => {
-    if (sortOrder === 'price-asc') return a.price - b.price;
-    if (sortOrder === 'price-desc') return b.price - a.price;
-    return 0;
-  });
-
   if (loading) {
     return <div>Loading...</div>; // แสดงข้อความระหว่างโหลด
   }
@@ -115,14 +51,15 @@ const AllPage = () => {
         <div className="row">
           <div className="col">
             <div className="product-grid">
-              {sortedData.length > 0 ? (
-                sortedData.map((product) => (
+              {products.length > 0 ? (
+                products.map((product) => (
                   <ClothesCard key={product.id} product={product} />
                 ))
               ) : (
-                <p>Unavailable</p> // Message displayed when no products are available
+                <p>Unavailable</p> // ข้อความที่แสดงเมื่อไม่มีสินค้า
               )}
             </div>
+            <LoadMoreButton shown={products.length} total={total} hasMore={hasMore} onLoadMore={loadMore} />
           </div>
         </div>
       </div>
@@ -3,32 +3,25 @@ import { useParams, Link } from 'react-router-dom';
 import ClothesCard from '../Component/ClothesCard';
 import '../Style/BrandPage.css';
 import ProductSortDropdown from '../Component/ProductSortDropdown';
+import LoadMoreButton from '../Component/LoadMoreButton';
+import useProductPage from '../utils/useProductPage';
 
 const BrandPage = () => {
   const { brandId } = useParams();
   //const brandProducts = clothesData.filter(product => product.brandId === parseInt(brandId));
   //const brand = brands.find(brand => brand.id === parseInt(brandId));
-  const [brandProducts, setBrandProducts] = useState([]); // เริ่มต้นเป็นอาเรย์ว่าง
   const [brandName, setBrandName] = useState('');
-  const [loading, setLoading] = useState(true);
-  const [error, setError] = useState(null);
-  const [sortOrder, setSortOrder] = useState('priceAsc');
+  const [brandError, setError] = useState(null);
+  const [sortOrder, setSortOrder] = useState('price_asc');
+  // ดึงสินค้าของแบรนด์แบบแบ่งหน้า เรียงลำดับที่ API
+  const { products: sortedData, total, loading, error: productsError, hasMore, loadMore } = useProductPage({
+    brand: brandId,
+    sort: sortOrder,
+  });
+  const error = productsError || brandError;
 
-  // ดึงข้อมูลสินค้าและแบรนด์
+  // ดึงข้อมูลแบรนด์
   useEffect(() => {
-    const fetchBrandProducts = async () => {
-      try {
-        const response = await fetch(`http://localhost:8080/api/v1/products/brand/${brandId}`);
-        if (!response.ok) throw new Error('Failed to fetch products');
-        const data = await response.json();
-        setBrandProducts(data || []); // หากข้อมูลเป็น null ให้ตั้งค่าเป็นอาเรย์ว่าง
-      } catch (err) {
-        setError(err.message);
-      } finally {
-        setLoading(false);
-      }
-    };
-
     const fetchBrandName = async () => {
       try {
         const response = await fetch(`http://localhost:8080/api/v1/brand/${brandId}`);
@@ -40,16 +33,9 @@ const BrandPage = () => {
       }
     };
 
-    fetchBrandProducts();
     fetchBrandName();
   }, [brandId]);
 
-  const sortedData = [...brandProducts].sort((a, b) => {
-    if (sortOrder === 'price-asc') return a.price - b.price;
-    if (sortOrder === 'price-desc') return b.price - a.price;
-    return 0;
-  });
-
   const handleSortChange = (e) => {
     setSortOrder(e.target.value);
   };
@@ -71,7 +57,7 @@ const BrandPage = () => {
 
         <div className="row">
           <div className="col">
-            <ProductSortDropdown onSortChange={handleSortChange} />
+            <ProductSortDropdown sortOrder={sortOrder} onSortChange={handleSortChange} />
           </div>
         </div>
 
@@ -86,6 +72,7 @@ const BrandPage = () => {
                 <p>Unavailable</p> // หากไม่มีสินค้าจะแสดงข้อความนี้
               )}
             </div>
+            <LoadMoreButton shown={sortedData.length} total={total} hasMore={hasMore} onLoadMore={loadMore} />
           </div>
         </div>
 
@@ -1,32 +1,83 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useCallback } from 'react';
 import axios from 'axios';
+import cartRequestConfig from '../utils/cartRequestConfig';
+import { formatDisplayPrice } from '../utils/displayCurrency';
 import { Link } from 'react-router-dom';
 import '../Style/CartPage.css';
 
+// คูปองและจังหวัดปลายทางที่กรอกไว้เก็บใน localStorage เพื่อส่งไปตอนสั่งซื้อด้วย
+const coupon_key = 'cartCoupon';
+const province_key = 'cartProvince';
+
 const CartPage = () => {
-  const [cartItems, setCartItems] = useState([]);
+  const [cart, setCart] = useState(null);
+  const [coupon, setCoupon] = useState(localStorage.getItem(coupon_key) || '');
+  const [couponInput, setCouponInput] = useState(coupon);
+  const [province, setProvince] = useState(localStorage.getItem(province_key) || '');
+  const [provinceInput, setProvinceInput] = useState(province);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState(null);
 
-  // ฟังก์ชันเรียก API เพื่อดึงข้อมูลตะกร้า
+  // ฟังก์ชันเรียก API เพื่อดึงข้อมูลตะกร้าพร้อมส่วนลด VAT และค่าส่ง
+  const fetchCart = useCallback(async () => {
+    try {
+      const config = cartRequestConfig();
+      const params = {};
+      if (coupon) params.coupon = coupon;
+      if (province) params.province = province;
+      const response = await axios.get('http://localhost:8080/api/v1/cart', {
+        ...config,
+        params,
+      });
+      setCart(response.data);
+      setError(null);
+    } catch (err) {
+      setError('Failed to load cart items.');
+    } finally {
+      setLoading(false);
+    }
+  }, [coupon, province]);
+
   useEffect(() => {
-    const fetchCartItems = async () => {
-      try {
-        const response = await axios.get('http://localhost:8080/api/v1/cart');
-        setCartItems(response.data || []); // ถ้าข้อมูลเป็น null ให้ใช้ค่าเริ่มต้นเป็นอาร์เรย์ว่าง
-        setLoading(false);
-      } catch (err) {
-        setError('Failed to load cart items.');
-        setLoading(false);
-      }
-    };
-
-    fetchCartItems();
-  }, []); // ทำงานเมื่อ component โหลดครั้งแรก
-
-  // คำนวณราคาทั้งหมดในตะกร้า
-  const calculateTotal = () => {
-    return cartItems.reduce((total, item) => total + item.price * item.quantity, 0);
+    fetchCart();
+  }, [fetchCart]); // ดึงใหม่เมื่อเปลี่ยนคูปองหรือจังหวัด
+
+  // ฟังก์ชันลบสินค้าออกจากตะกร้า แล้วดึงตะกร้าใหม่เพราะส่วนลดอาจเปลี่ยน
+  const handleRemoveFromCart = async (cartId) => {
+    try {
+      await axios.delete(`http://localhost:8080/api/v1/cart/${cartId}`, cartRequestConfig());
+      fetchCart();
+    } catch (err) {
+      console.error('Error removing item from cart:', err);
+    }
+  };
+
+  const handleApplyCoupon = (e) => {
+    e.preventDefault();
+    const code = couponInput.trim();
+    setCoupon(code);
+    if (code) {
+      localStorage.setItem(coupon_key, code);
+    } else {
+      localStorage.removeItem(coupon_key);
+    }
+  };
+
+  const handleApplyProvince = (e) => {
+    e.preventDefault();
+    const name = provinceInput.trim();
+    setProvince(name);
+    if (name) {
+      localStorage.setItem(province_key, name);
+    } else {
+      localStorage.removeItem(province_key);
+    }
+  };
+
+  const handleRemoveCoupon = () => {
+    setCoupon('');
+    setCouponInput('');
+    localStorage.removeItem(coupon_key);
   };
 
   if (loading) {
@@ -37,24 +88,14 @@ const CartPage = () => {
     return <div>{error}</div>;
   }
 
-  // ฟังก์ชันลบสินค้าออกจากตะกร้า
-const handleRemoveFromCart = async (cartId) => {
-    try {
-      await axios.delete(`http://localhost:8080/api/v1/cart/${cartId}`);
-      // หลังจากลบสินค้า, รีเฟรชข้อมูลในตะกร้า
-      const updatedCart = cartItems.filter(item => item.cart_id !== cartId);
-      setCartItems(updatedCart);
-    } catch (err) {
-      console.error('Error removing item from cart:', err);
-    }
-  };
+  const cartItems = cart?.items || [];
 
   return (
     <div className="cart-page">
       <h2>Your Shopping Cart</h2>
 
       {/* ตรวจสอบว่าตะกร้าว่างหรือไม่ */}
-      {Array.isArray(cartItems) && cartItems.length === 0 ? (
+      {cartItems.length === 0 ? (
         <p>Your cart is empty.</p>
       ) : (
         <div className="cart-items">
@@ -63,9 +104,19 @@ const handleRemoveFromCart = async (cartId) => {
               <img src={item.imgsrc} alt={item.name} className="cart-item-image" />
               <div className="cart-item-details">
                 <h3>{item.name}</h3>
-                <p>Price: ฿{item.price}</p>
+                <p>Price: ฿{item.unit_price} {formatDisplayPrice(item.display_unit_price)}</p>
                 <p>Quantity: {item.quantity}</p>
-                <p>Total: ฿{item.price * item.quantity}</p>
+                {/* ส่วนลดระดับรายการแยกตามโปรโมชัน */}
+                {(item.discounts || []).map(discount => (
+                  <p key={discount.promotion_id} className="cart-discount">
+                    {discount.name}: -฿{discount.amount}
+                  </p>
+                ))}
+                <p>
+                  Total:{' '}
+                  {item.discount > 0 && <s>฿{item.price}</s>}{' '}
+                  ฿{(item.price - item.discount).toFixed(2)}
+                </p>
               </div>
               <div className="cart-item-actions">
                 <button onClick={() => handleRemoveFromCart(item.cart_id)}>Remove</button>
@@ -75,13 +126,63 @@ const handleRemoveFromCart = async (cartId) => {
         </div>
       )}
 
-      {/* คำนวณรวมราคาสินค้า */}
+      {/* สรุปยอดพร้อมส่วนลดและคูปอง */}
       {cartItems.length > 0 && (
         <div className="cart-summary">
-          <h3>Total: ฿{calculateTotal()}</h3>
-          <Link to="/checkout">
-            <button className="checkout-btn">Proceed to Checkout</button>
-          </Link>
+          <div>
+            <form onSubmit={handleApplyCoupon} className="coupon-form">
+              <input
+                type="text"
+                placeholder="Coupon code"
+                value={couponInput}
+                onChange={(e) => setCouponInput(e.target.value)}
+              />
+              <button type="submit">Apply</button>
+              {coupon && (
+                <button type="button" onClick={handleRemoveCoupon}>Remove</button>
+              )}
+            </form>
+            {cart.coupon_error && <p className="coupon-error">{cart.coupon_error}</p>}
+            {/* จังหวัดปลายทางสำหรับคิดค่าส่ง ถ้าไม่ระบุจะเป็นค่าส่งโดยประมาณ */}
+            <form onSubmit={handleApplyProvince} className="coupon-form">
+              <input
+                type="text"
+                placeholder="Province"
+                value={provinceInput}
+                onChange={(e) => setProvinceInput(e.target.value)}
+              />
+              <button type="submit">Update shipping</button>
+            </form>
+          </div>
+          <div className="cart-totals">
+            <p>Subtotal: ฿{cart.subtotal}</p>
+            {cart.line_discount > 0 && <p>Item discounts: -฿{cart.line_discount}</p>}
+            {cart.cart_discounts.map(discount => (
+              <p key={discount.promotion_id} className="cart-discount">
+                {discount.name}: -฿{discount.amount}
+              </p>
+            ))}
+            <p>
+              Shipping{!province && ' (estimated)'}: {cart.shipping_fee > 0 ? `฿${cart.shipping_fee}` : 'Free'}
+            </p>
+            {(cart.shipments || []).filter(shipment => shipment.free_shipping_remaining > 0).map(shipment => (
+              <p key={shipment.brand_id} className="free-shipping-hint">
+                Add ฿{shipment.free_shipping_remaining} more from this brand for free shipping
+              </p>
+            ))}
+            {cart.vat_added > 0 && <p>VAT 7%: ฿{cart.vat_added}</p>}
+            {cart.vat_included > 0 && <p className="vat-included">Includes VAT ฿{cart.vat_included}</p>}
+            <h3>Total: ฿{cart.total}</h3>
+            {/* ยอดในสกุลเงินที่เลือก คิดเงินจริงเป็นบาท */}
+            {cart.display && (
+              <p className="display-total">
+                {formatDisplayPrice({ amount: cart.display.total, currency: cart.display.currency })} (charged in THB)
+              </p>
+            )}
+            <Link to="/checkout">
+              <button className="checkout-btn">Proceed to Checkout</button>
+            </Link>
+          </div>
         </div>
       )}
     </div>
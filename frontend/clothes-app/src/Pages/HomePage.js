@@ -1,35 +1,17 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState } from 'react';
 import ClothesCard from '../Component/ClothesCard';
 import BrandSlider from '../Component/BrandSlider';
-import axios from 'axios';
 import BannerCarousel from '../Component/BannerCarousel';
-
-const api_url = '/api/v1/products';
+import useProductPage from '../utils/useProductPage';
 
 const HomePage = () => {
   const [filter, setFilter] = useState('*');
-  const [products, setProducts] = useState([]);
-  const [loading, setLoading] = useState(true);
-  const [error, setError] = useState(null);
-  
-  useEffect(() => {
-    const fetchData = async () => {
-      try {
-        const response = await axios.get(api_url);
-        setProducts(response.data);
-        setLoading(false);
-      } catch (error) {
-        console.error('Error fetching products:', error);
-        setError(error.message);
-        setLoading(false);
-      }
-    };
-    fetchData();
-  }, []);
-
-  const filteredProducts = products.filter(
-    (product) => (filter === '*' || product.category === filter) && product.isnew
-  );
+  // ดึงเฉพาะสินค้าใหม่ กรองหมวดหมู่ที่ API
+  const { products: filteredProducts, loading, error } = useProductPage({
+    isnew: true,
+    category: filter === '*' ? undefined : filter,
+    sort: 'newest',
+  });
 
   if (loading) {
     return <div>Loading...</div>;
@@ -1,41 +1,19 @@
-import React, { useState, useEffect } from 'react'; 
+import React, { useState } from 'react';
 import ClothesCard from '../Component/ClothesCard';
 import ProductSortDropdown from '../Component/ProductSortDropdown';
 import BrandSelectDropdown from '../Component/BrandSelectDropdown';
-
-const api_url = '/api/v1/products/category/men';
+import LoadMoreButton from '../Component/LoadMoreButton';
+import useProductPage from '../utils/useProductPage';
 
 const MenPage = () => {
-  const [menProducts, setMenData] = useState([]);
-  const [loading, setLoading] = useState(true);
-  const [error, setError] = useState(null);
-  const [sortOrder, setSortOrder] = useState('default');
+  const [sortOrder, setSortOrder] = useState('newest');
   const [selectedBrandId, setSelectedBrandId] = useState(null);
-
-  useEffect(() => {
-    const fetchData = async () => {
-      try {
-        const response = await fetch(api_url);
-        if (!response.ok) {
-          console.error('Response status:', response.status);
-          throw new Error(`ไม่สามารถดึงข้อมูลได้, status code: ${response.status}`);
-        }
-        const data = await response.json();
-        if (Array.isArray(data)) {
-          const sortedProducts = data.sort((a, b) => new Date(b.dateAdded) - new Date(a.dateAdded));
-          setMenData(sortedProducts);
-        } else {
-          throw new Error('รูปแบบข้อมูลไม่ถูกต้อง');
-        }
-        setLoading(false);
-      } catch (error) {
-        console.error('Error fetching clothes:', error);
-        setError(error.message);
-        setLoading(false);
-      }
-    };
-    fetchData();
-  }, [selectedBrandId]);
+  // การเรียงลำดับและกรองแบรนด์ทำที่ API
+  const { products, total, loading, error, hasMore, loadMore } = useProductPage({
+    category: 'men',
+    brand: selectedBrandId,
+    sort: sortOrder,
+  });
 
   const handleSortChange = (e) => {
     setSortOrder(e.target.value);
@@ -45,23 +23,11 @@ const MenPage = () => {
     setSelectedBrandId(brandId);
   };
 
-  // กรองข้อมูลตามแบรนด์ที่เลือก
-  const filteredData = menProducts.filter(product => 
-    !selectedBrandId || product.brandId === selectedBrandId
-  );
-
-  // การจัดเรียงข้อมูลตามราคาหรือเริ่มต้น
-  const sortedData = [...filteredData].sort((a, b) => {
-    if (sortOrder === 'price-asc') return a.price - b.price;
-    if (sortOrder === 'price-desc') return b.price - a.price;
-    return 0;
-  });
-
-  if (loading) return <div>Loading...</div>;
-  if (error) return <div>Error: {error}</div>;
-
-  if (filteredData.length === 0) {
-    return <div>Unavailableา</div>; // แสดงข้อความเมื่อไม่มีสินค้าจากแบรนด์ที่เลือก
+  if (loading) {
+    return <div>Loading...</div>; // แสดงข้อความระหว่างโหลด
+  }
+  if (error) {
+    return <div>Error: {error}</div>; // แสดงข้อผิดพลาดถ้าเกิดปัญหา
   }
 
   return (
@@ -76,7 +42,6 @@ const MenPage = () => {
           </div>
         </div>
 
-        {/* เพิ่ม ProductSortDropdown และ BrandSelectDropdown */}
         <div className="row mb-3">
           <div className="col-12 d-flex justify-content-between">
             <ProductSortDropdown sortOrder={sortOrder} onSortChange={handleSortChange} />
@@ -87,14 +52,15 @@ const MenPage = () => {
         <div className="row">
           <div className="col">
             <div className="product-grid">
-              {sortedData.length > 0 ? (
-                sortedData.map((product) => (
+              {products.length > 0 ? (
+                products.map((product) => (
                   <ClothesCard key={product.id} product={product} />
                 ))
               ) : (
                 <p>Unavailable</p> // ข้อความที่แสดงเมื่อไม่มีสินค้า
               )}
             </div>
+            <LoadMoreButton shown={products.length} total={total} hasMore={hasMore} onLoadMore={loadMore} />
           </div>
         </div>
       </div>
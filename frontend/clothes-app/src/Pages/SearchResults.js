@@ -1,50 +1,44 @@
 import React, { useState, useEffect } from 'react';
-import axios from 'axios';
 import { useSearchParams } from 'react-router-dom';
 import ClothesCard from '../Component/ClothesCard';
 import ProductSortDropdown from '../Component/ProductSortDropdown'; // Import dropdown
+import LoadMoreButton from '../Component/LoadMoreButton';
+import FacetSidebar from '../Component/FacetSidebar';
+import useProductPage from '../utils/useProductPage';
 
 const SearchResults = () => {
-  const [products, setProducts] = useState([]);
-  const [error, setError] = useState(null);
-  const [sortOrder, setSortOrder] = useState('default'); // State for sorting
+  const [sortOrder, setSortOrder] = useState('relevance'); // State for sorting
   const [searchParams] = useSearchParams();
   const searchQuery = searchParams.get('q');
+  const [selectedFacets, setSelectedFacets] = useState({ brand: null, category: null, priceKey: null });
+  const [priceBuckets, setPriceBuckets] = useState([]);
+  const selectedBucket = priceBuckets.find((b) => b.key === selectedFacets.priceKey);
+  const { products: sortedData, total, facets, error: fetchError, hasMore, loadMore } = useProductPage({
+    q: searchQuery,
+    brand: selectedFacets.brand,
+    category: selectedFacets.category,
+    min_price: selectedBucket ? selectedBucket.min : undefined,
+    max_price: selectedBucket ? selectedBucket.max : undefined,
+    sort: sortOrder,
+    facets: true,
+  });
 
+  // เก็บช่วงราคาไว้เพื่อแปลง key ที่เลือกเป็น min_price/max_price
   useEffect(() => {
-    const fetchProducts = async () => {
-      if (searchQuery) {
-        try {
-          const response = await axios.get(`http://localhost:8080/api/v1/products/search?name=${searchQuery}`);
-          if (response.data && Array.isArray(response.data)) {
-            setProducts(response.data);
-            setError(null);
-          } else {
-            setError('No products found matching your search');
-          }
-        } catch (error) {
-          console.error('Error fetching products:', error);
-          setError('Unable to fetch products');
-        }
-      } else {
-        setProducts([]);
-        setError('Please enter your search query');
-      }
-    };
+    if (facets) setPriceBuckets(facets.price_buckets);
+  }, [facets]);
 
-    fetchProducts();
-  }, [searchQuery]);
+  let error = null;
+  if (!searchQuery) {
+    error = 'Please enter your search query';
+  } else if (fetchError) {
+    error = 'Unable to fetch products';
+  }
 
   const handleSortChange = (e) => {
     setSortOrder(e.target.value);
   };
 
-  const sortedData = [...products].sort((a, b) => {
-    if (sortOrder === 'price-asc') return a.price - b.price;
-    if (sortOrder === 'price-desc') return b.price - a.price;
-    return 0;
-  });
-
   return (
     <div className="new_arrivals">
       <div className="container">
@@ -60,20 +54,34 @@ const SearchResults = () => {
         {/* Sorting section on the left */}
         <div className="row mb-3">
           <div className="col-12 d-flex justify-content-start">
-            <ProductSortDropdown sortOrder={sortOrder} onSortChange={handleSortChange} />
+            <ProductSortDropdown sortOrder={sortOrder} onSortChange={handleSortChange} showRelevance />
           </div>
         </div>
 
         <div className="row">
-          <div className="col">
+          <div className="col-md-3">
+            <FacetSidebar facets={facets} selected={selectedFacets} onChange={setSelectedFacets} />
+          </div>
+          <div className="col-md-9">
             {error ? (
               <p className="text-center text-danger">{error}</p>
             ) : sortedData.length > 0 ? (
-              <div className="product-grid">
-                {sortedData.map((product) => (
-                  <ClothesCard key={product.id} product={product} />
-                ))}
-              </div>
+              <>
+                <div className="product-grid">
+                  {sortedData.map((product) => (
+                    <div key={product.id}>
+                      <ClothesCard product={product} />
+                      {(product.highlights || [])
+                        .filter((h) => h.field === 'description')
+                        .map((h) => (
+                          // snippet ถูก escape HTML ที่ API แล้ว มีเฉพาะแท็ก <mark> ที่ไฮไลต์คำที่ตรง
+                          <p key={h.field} className="search-snippet" dangerouslySetInnerHTML={{ __html: h.snippet }} />
+                        ))}
+                    </div>
+                  ))}
+                </div>
+                <LoadMoreButton shown={sortedData.length} total={total} hasMore={hasMore} onLoadMore={loadMore} />
+              </>
             ) : (
               <p className="text-center">No products found for your search</p>
             )}
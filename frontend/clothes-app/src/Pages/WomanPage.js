@@ -1,42 +1,19 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState } from 'react';
 import ClothesCard from '../Component/ClothesCard';
-import ProductSortDropdown from '../Component/ProductSortDropdown'; // นำเข้า dropdown
+import ProductSortDropdown from '../Component/ProductSortDropdown';
 import BrandSelectDropdown from '../Component/BrandSelectDropdown';
-
-const api_url = '/api/v1/products/category/women';
+import LoadMoreButton from '../Component/LoadMoreButton';
+import useProductPage from '../utils/useProductPage';
 
 const WomanPage = () => {
-  const [womenData, setWomenData] = useState([]);
-  const [loading, setLoading] = useState(true);
-  const [error, setError] = useState(null);
-  const [sortOrder, setSortOrder] = useState('default'); // สร้าง state สำหรับการจัดเรียง
+  const [sortOrder, setSortOrder] = useState('newest');
   const [selectedBrandId, setSelectedBrandId] = useState(null);
-
-  useEffect(() => {
-    const fetchData = async () => {
-      try {
-        const response = await fetch(api_url);
-        if (!response.ok) {
-          console.error('Response status:', response.status);
-          throw new Error(`ไม่สามารถดึงข้อมูลได้, status code: ${response.status}`);
-        }
-        const data = await response.json();
-        if (Array.isArray(data)) {
-          setWomenData(data); // บันทึกข้อมูลที่ดึงมา
-        } else if (Array.isArray(data.books)) {
-          setWomenData(data.books); // ถ้าข้อมูลอยู่ใน books
-        } else {
-          throw new Error('รูปแบบข้อมูลไม่ถูกต้อง');
-        }
-        setLoading(false);
-      } catch (error) {
-        console.error('Error fetching clothes:', error);
-        setError(error.message);
-        setLoading(false);
-      }
-    };
-    fetchData();
-  }, [selectedBrandId]);  // Refetch data when selectedBrandId changes
+  // การเรียงลำดับและกรองแบรนด์ทำที่ API
+  const { products, total, loading, error, hasMore, loadMore } = useProductPage({
+    category: 'women',
+    brand: selectedBrandId,
+    sort: sortOrder,
+  });
 
   const handleSortChange = (e) => {
     setSortOrder(e.target.value);
@@ -46,22 +23,11 @@ const WomanPage = () => {
     setSelectedBrandId(brandId);
   };
 
-  const sortedData = () => {
-    // Sorting logic based on the selected order
-    if (sortOrder === 'price-asc') {
-      return [...womenData].sort((a, b) => a.price - b.price);
-    } else if (sortOrder === 'price-desc') {
-      return [...womenData].sort((a, b) => b.price - a.price);
-    }
-    return womenData; // No sorting if default
-  };
-
   if (loading) {
-    return <div>Loading...</div>;
+    return <div>Loading...</div>; // แสดงข้อความระหว่างโหลด
   }
-
   if (error) {
-    return <div>Error: {error}</div>;
+    return <div>Error: {error}</div>; // แสดงข้อผิดพลาดถ้าเกิดปัญหา
   }
 
   return (
@@ -76,7 +42,6 @@ const WomanPage = () => {
           </div>
         </div>
 
-        {/* เพิ่ม ProductSortDropdown และ BrandSelectDropdown */}
         <div className="row mb-3">
           <div className="col-12 d-flex justify-content-between">
             <ProductSortDropdown sortOrder={sortOrder} onSortChange={handleSortChange} />
@@ -87,14 +52,15 @@ const WomanPage = () => {
         <div className="row">
           <div className="col">
             <div className="product-grid">
-              {sortedData().length > 0 ? (
-                sortedData().map((product) => (
+              {products.length > 0 ? (
+                products.map((product) => (
                   <ClothesCard key={product.id} product={product} />
                 ))
               ) : (
-                <p>Unavailable</p>
+                <p>Unavailable</p> // ข้อความที่แสดงเมื่อไม่มีสินค้า
               )}
             </div>
+            <LoadMoreButton shown={products.length} total={total} hasMore={hasMore} onLoadMore={loadMore} />
           </div>
         </div>
       </div>
@@ -0,0 +1,16 @@
+// ตั้งค่า request ของ API ตะกร้า
+// ถ้าล็อกอินแล้วจะส่ง Bearer token ถ้ายังไม่ล็อกอินจะใช้ cookie guest_token ที่ backend ออกให้
+// idempotencyKey (ไม่บังคับ) ส่งเป็น header Idempotency-Key เพื่อไม่ให้ POST ซ้ำเพิ่มสินค้าซ้ำ
+const cartRequestConfig = (idempotencyKey) => {
+  const accessToken = sessionStorage.getItem('accessToken');
+  const headers = accessToken ? { Authorization: `Bearer ${accessToken}` } : {};
+  if (idempotencyKey) {
+    headers['Idempotency-Key'] = idempotencyKey;
+  }
+  return {
+    withCredentials: true,
+    headers,
+  };
+};
+
+export default cartRequestConfig;
@@ -0,0 +1,29 @@
+import axios from 'axios';
+
+// สกุลเงินที่ลูกค้าเลือกแสดงราคา เก็บใน localStorage และส่งไปกับทุก request ด้วย header Accept-Currency
+// backend คิดเงินเป็นบาทเสมอ ราคาในสกุลอื่นเป็นราคาโดยประมาณสำหรับแสดงเท่านั้น
+const currency_key = 'displayCurrency';
+
+export const getDisplayCurrency = () => localStorage.getItem(currency_key) || 'THB';
+
+const applyHeader = (code) => {
+  if (code === 'THB') {
+    delete axios.defaults.headers.common['Accept-Currency'];
+  } else {
+    axios.defaults.headers.common['Accept-Currency'] = code;
+  }
+};
+
+export const setDisplayCurrency = (code) => {
+  if (code === 'THB') {
+    localStorage.removeItem(currency_key);
+  } else {
+    localStorage.setItem(currency_key, code);
+  }
+  applyHeader(code);
+};
+
+applyHeader(getDisplayCurrency());
+
+// แสดงราคาในสกุลเงินที่ backend แปลงให้ เช่น display_price = { amount: 42.1, currency: 'USD' }
+export const formatDisplayPrice = (price) => (price ? `≈ ${price.currency} ${price.amount}` : '');
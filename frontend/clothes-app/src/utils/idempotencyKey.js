@@ -0,0 +1,10 @@
+// สร้าง Idempotency-Key ใหม่สำหรับการกระทำหนึ่งครั้ง (เช่น กดเพิ่มลงตะกร้า)
+// ใช้คีย์เดิมเมื่อส่งซ้ำ (ดับเบิลคลิกหรือ retry) backend จะทำงานครั้งเดียวและตอบผลเดิม
+const newIdempotencyKey = () => {
+  if (window.crypto && window.crypto.randomUUID) {
+    return window.crypto.randomUUID();
+  }
+  return `${Date.now()}-${Math.random().toString(36).slice(2)}`;
+};
+
+export default newIdempotencyKey;
@@ -0,0 +1,70 @@
+import { useState, useEffect, useCallback } from 'react';
+import axios from 'axios';
+
+const api_url = '/api/v1/products';
+
+// ดึงรายการสินค้าแบบแบ่งหน้าจาก /api/v1/products
+// params คือตัวกรอง/การเรียงลำดับ เช่น { category: 'women', brand: '1', sort: 'price_asc' }
+// เปลี่ยน params แล้วจะเริ่มหน้าแรกใหม่ ส่วน loadMore ดึงหน้าถัดไปต่อท้าย
+const useProductPage = (params) => {
+  const [products, setProducts] = useState([]);
+  const [total, setTotal] = useState(0);
+  const [nextCursor, setNextCursor] = useState('');
+  const [facets, setFacets] = useState(null);
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState(null);
+
+  // ตัดค่าว่างออกเพื่อไม่ให้ส่ง filter ที่ไม่ได้เลือกไปยัง API
+  const query = JSON.stringify(
+    Object.fromEntries(Object.entries(params).filter(([, value]) => value !== undefined && value !== null && value !== ''))
+  );
+
+  const fetchPage = useCallback(async (cursor) => {
+    const response = await axios.get(api_url, {
+      params: { ...JSON.parse(query), ...(cursor ? { cursor } : {}) },
+    });
+    return response.data;
+  }, [query]);
+
+  useEffect(() => {
+    let cancelled = false;
+    setLoading(true);
+    fetchPage('')
+      .then((data) => {
+        if (cancelled) return;
+        setProducts(data.items || []);
+        setTotal(data.total || 0);
+        setNextCursor(data.next_cursor || '');
+        setFacets(data.facets || null); // มีเมื่อขอ facets: true
+        setError(null);
+      })
+      .catch((err) => {
+        if (cancelled) return;
+        console.error('Error fetching products:', err);
+        setError(err.message);
+      })
+      .finally(() => {
+        if (!cancelled) setLoading(false);
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, [fetchPage]);
+
+  const loadMore = async () => {
+    if (!nextCursor) return;
+    try {
+      const data = await fetchPage(nextCursor);
+      setProducts((prev) => [...prev, ...(data.items || [])]);
+      setTotal(data.total || 0);
+      setNextCursor(data.next_cursor || '');
+    } catch (err) {
+      console.error('Error fetching products:', err);
+      setError(err.message);
+    }
+  };
+
+  return { products, total, facets, loading, error, hasMore: Boolean(nextCursor), loadMore };
+};
+
+export default useProductPage;
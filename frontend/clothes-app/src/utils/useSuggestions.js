@@ -0,0 +1,45 @@
+import { useState, useEffect } from 'react';
+import axios from 'axios';
+
+const api_url = '/api/v1/products/suggest';
+
+// หน่วงเวลาหลังพิมพ์ก่อนเรียก API เพื่อไม่ให้ยิงทุกตัวอักษร
+const debounce_ms = 200;
+
+// ดึงคำแนะนำ (ชื่อสินค้าและแบรนด์) จาก /api/v1/products/suggest ตามข้อความที่กำลังพิมพ์
+// คืน { suggestions, didYouMean } ถ้าข้อความว่างจะคืนรายการว่าง
+const useSuggestions = (text) => {
+  const [suggestions, setSuggestions] = useState([]);
+  const [didYouMean, setDidYouMean] = useState('');
+
+  useEffect(() => {
+    const query = text.trim();
+    if (!query) {
+      setSuggestions([]);
+      setDidYouMean('');
+      return undefined;
+    }
+
+    let cancelled = false;
+    const timer = setTimeout(async () => {
+      try {
+        const response = await axios.get(api_url, { params: { q: query } });
+        if (!cancelled) {
+          setSuggestions(response.data.suggestions || []);
+          setDidYouMean(response.data.did_you_mean || '');
+        }
+      } catch (error) {
+        console.error('Error fetching suggestions:', error);
+      }
+    }, debounce_ms);
+
+    return () => {
+      cancelled = true;
+      clearTimeout(timer);
+    };
+  }, [text]);
+
+  return { suggestions, didYouMean };
+};
+
+export default useSuggestions;